@@ -0,0 +1,191 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package jwt
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+)
+
+const (
+	// AuthorizationKey is the gRPC metadata key holding a bearer token.
+	AuthorizationKey = "authorization"
+
+	bearerPrefix = "Bearer "
+)
+
+// BearerToken returns the first bearer token in md, if any.
+func BearerToken(md metadata.MD) (string, bool) {
+	for _, v := range md.Get(AuthorizationKey) {
+		if strings.HasPrefix(v, bearerPrefix) {
+			return strings.TrimPrefix(v, bearerPrefix), true
+		}
+	}
+	return "", false
+}
+
+// withoutToken returns a copy of md with any authorization removed.
+func withoutToken(md metadata.MD) metadata.MD {
+	md = md.Copy()
+	delete(md, AuthorizationKey)
+	return md
+}
+
+type principalKey struct{}
+
+// PrincipalFromContext returns the principal of a verified bearer token
+// attached to ctx by the server interceptors, or nil if there isn't one.
+func PrincipalFromContext(ctx context.Context) *rpcauth.PrincipalAuthInput {
+	p, _ := ctx.Value(principalKey{}).(*rpcauth.PrincipalAuthInput)
+	return p
+}
+
+// authenticate verifies any bearer token in the incoming metadata of ctx.
+// The returned context carries the token's principal, both on its own and
+// as the principal of the peer so that anything reading the peer from the
+// context, such as proxied identity forwarding and MPA, sees it. It no
+// longer contains the token, so it isn't logged or passed along to
+// handlers. Requests without a token are passed through unchanged.
+func (v *Verifier) authenticate(ctx context.Context) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx, nil
+	}
+	token, ok := BearerToken(md)
+	if !ok {
+		return ctx, nil
+	}
+	p, err := v.Verify(token)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	ctx = metadata.NewIncomingContext(ctx, withoutToken(md))
+	peer := &rpcauth.PeerAuthInput{}
+	if existing := rpcauth.PeerInputFromContext(ctx); existing != nil {
+		*peer = *existing
+	}
+	peer.Principal = p
+	ctx = rpcauth.AddPeerToContext(ctx, peer)
+	return context.WithValue(ctx, principalKey{}, p), nil
+}
+
+// UnaryServerInterceptor returns an interceptor which verifies bearer
+// tokens sent by clients. Requests with an invalid token are rejected with
+// codes.Unauthenticated. It must run before the authz interceptor.
+func (v *Verifier) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := v.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming version of UnaryServerInterceptor.
+func (v *Verifier) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := v.authenticate(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticatedStream wraps a grpc.ServerStream to replace its context.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// see: grpc.ServerStream.Context
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// PeerPrincipalHook returns a hook which sets input.peer.principal from the
+// claims of the caller's bearer token, so policies can authorize users by
+// their SSO identity and groups. The token normally has already been
+// verified by the server interceptors. If they aren't installed, any token
+// still in input.metadata is verified here and then removed, so it never
+// reaches the policy or audit logs. Requests without a token are left
+// alone. It should run after rpcauth.PeerPrincipalFromCertHook so that a
+// token takes precedence over the certificate identity, and before hooks
+// which look at the principal such as the MPA hooks.
+func (v *Verifier) PeerPrincipalHook() rpcauth.RPCAuthzHook {
+	return rpcauth.RPCAuthzHookFunc(func(ctx context.Context, input *rpcauth.RPCAuthInput) error {
+		p := PrincipalFromContext(ctx)
+		if token, ok := BearerToken(input.Metadata); ok {
+			input.Metadata = withoutToken(input.Metadata)
+			if p == nil {
+				var err error
+				if p, err = v.Verify(token); err != nil {
+					return status.Error(codes.Unauthenticated, err.Error())
+				}
+			}
+		}
+		if p == nil {
+			return nil
+		}
+		if input.Peer == nil {
+			input.Peer = &rpcauth.PeerAuthInput{}
+		}
+		input.Peer.Principal = p
+		return nil
+	})
+}
+
+// tokenFileCredentials sends the token in a file as a bearer token.
+type tokenFileCredentials struct {
+	path string
+}
+
+// NewTokenFileCredentials returns credentials which send the token stored
+// in the file at path with every RPC. The file is read on each call so a
+// refreshed token is picked up without restarting. They are only sent over
+// secure connections.
+func NewTokenFileCredentials(path string) credentials.PerRPCCredentials {
+	return &tokenFileCredentials{path: path}
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials.
+func (t *tokenFileCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	b, err := os.ReadFile(t.path)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "can't read token: %v", err)
+	}
+	token := strings.TrimSpace(string(b))
+	if token == "" {
+		return nil, status.Errorf(codes.Unauthenticated, "token file %s is empty", t.path)
+	}
+	return map[string]string{AuthorizationKey: bearerPrefix + token}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials.
+func (t *tokenFileCredentials) RequireTransportSecurity() bool {
+	return true
+}
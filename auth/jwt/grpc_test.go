@@ -0,0 +1,224 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package jwt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/Snowflake-Labs/sansshell/auth/opa"
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+	"github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+const groupPolicy = `
+package sansshell.authz
+
+default allow = false
+
+allow {
+  input.peer.principal.groups[_] == "sre"
+  not input.metadata.authorization
+}
+`
+
+func TestServerInterceptorAndHook(t *testing.T) {
+	ctx := context.Background()
+	key := newTestKey(t, "one")
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, key)
+	v, err := NewVerifier(path)
+	testutil.FatalOnErr("NewVerifier", err, t)
+
+	policy, err := opa.NewOpaAuthzPolicy(ctx, groupPolicy)
+	testutil.FatalOnErr("NewOpaAuthzPolicy", err, t)
+	authz := rpcauth.NewRPCAuthorizer(policy, rpcauth.PeerPrincipalFromCertHook(), v.PeerPrincipalHook())
+
+	exp := time.Now().Add(time.Hour).Unix()
+	sre := key.sign(t, map[string]any{"sub": "alice", "exp": exp, "groups": []string{"sre"}})
+	dev := key.sign(t, map[string]any{"sub": "bob", "exp": exp, "groups": []string{"dev"}})
+
+	for _, tc := range []struct {
+		name     string
+		md       metadata.MD
+		wantCode codes.Code
+		wantID   string
+	}{
+		{
+			name:   "group allowed",
+			md:     metadata.Pairs(AuthorizationKey, "Bearer "+sre, "other", "kept"),
+			wantID: "alice",
+		},
+		{
+			name:     "group denied",
+			md:       metadata.Pairs(AuthorizationKey, "Bearer "+dev),
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "invalid token",
+			md:       metadata.Pairs(AuthorizationKey, "Bearer junk"),
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "no token",
+			md:       metadata.Pairs("other", "kept"),
+			wantCode: codes.PermissionDenied,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var got context.Context
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				got = ctx
+				return req, nil
+			}
+			info := &grpc.UnaryServerInfo{FullMethod: "/Test.Test/Method"}
+			inner := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return authz.Authorize(ctx, req, info, handler)
+			}
+			ctx := metadata.NewIncomingContext(ctx, tc.md)
+			_, err := v.UnaryServerInterceptor()(ctx, &emptypb.Empty{}, info, inner)
+			if got, want := status.Code(err), tc.wantCode; got != want {
+				t.Fatalf("code = %v, want %v (err %v)", got, want, err)
+			}
+			if err != nil {
+				return
+			}
+			if p := PrincipalFromContext(got); p == nil || p.ID != tc.wantID {
+				t.Errorf("PrincipalFromContext = %+v, want ID %q", p, tc.wantID)
+			}
+			if p := rpcauth.PeerInputFromContext(got); p == nil || p.Principal == nil || p.Principal.ID != tc.wantID {
+				t.Errorf("peer input = %+v, want principal %q", p, tc.wantID)
+			}
+			md, _ := metadata.FromIncomingContext(got)
+			if _, ok := md[AuthorizationKey]; ok {
+				t.Errorf("handler saw token in metadata: %v", md)
+			}
+			if len(md.Get("other")) != 1 {
+				t.Errorf("other metadata was dropped: %v", md)
+			}
+		})
+	}
+}
+
+func TestInterceptorsSetPeerPrincipal(t *testing.T) {
+	key := newTestKey(t, "one")
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, key)
+	v, err := NewVerifier(path)
+	testutil.FatalOnErr("NewVerifier", err, t)
+	token := key.sign(t, map[string]any{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()})
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(AuthorizationKey, "Bearer "+token))
+
+	// Handlers and hooks reading the peer from the context, such as
+	// proxied identity forwarding, see the token's principal even before
+	// authz has run.
+	check := func(ctx context.Context) {
+		t.Helper()
+		if p := rpcauth.PeerInputFromContext(ctx); p == nil || p.Principal == nil || p.Principal.ID != "alice" {
+			t.Errorf("peer input = %+v, want principal alice", p)
+		}
+	}
+	_, err = v.UnaryServerInterceptor()(ctx, &emptypb.Empty{}, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		check(ctx)
+		return req, nil
+	})
+	testutil.FatalOnErr("unary", err, t)
+	err = v.StreamServerInterceptor()(nil, &fakeServerStream{ctx: ctx}, &grpc.StreamServerInfo{}, func(_ interface{}, ss grpc.ServerStream) error {
+		check(ss.Context())
+		return nil
+	})
+	testutil.FatalOnErr("stream", err, t)
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (f *fakeServerStream) Context() context.Context {
+	return f.ctx
+}
+
+func TestPeerPrincipalHook(t *testing.T) {
+	ctx := context.Background()
+	key := newTestKey(t, "one")
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, key)
+	v, err := NewVerifier(path)
+	testutil.FatalOnErr("NewVerifier", err, t)
+	token := key.sign(t, map[string]any{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix(), "groups": "sre"})
+
+	// Without the interceptors the hook verifies the token itself and
+	// overrides the certificate principal.
+	md := metadata.Pairs(AuthorizationKey, "Bearer "+token)
+	input := &rpcauth.RPCAuthInput{
+		Metadata: md,
+		Peer:     &rpcauth.PeerAuthInput{Principal: &rpcauth.PrincipalAuthInput{ID: "sanssh"}},
+	}
+	testutil.FatalOnErr("hook", v.PeerPrincipalHook().Hook(ctx, input), t)
+	if want := (&rpcauth.PrincipalAuthInput{ID: "alice", Groups: []string{"sre"}}); !reflect.DeepEqual(input.Peer.Principal, want) {
+		t.Errorf("principal = %+v, want %+v", input.Peer.Principal, want)
+	}
+	if _, ok := input.Metadata[AuthorizationKey]; ok {
+		t.Error("token was left in input metadata")
+	}
+	if len(md.Get(AuthorizationKey)) != 1 {
+		t.Error("original metadata was modified")
+	}
+
+	input = &rpcauth.RPCAuthInput{Metadata: metadata.Pairs(AuthorizationKey, "Bearer junk")}
+	if err := v.PeerPrincipalHook().Hook(ctx, input); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("invalid token: err = %v, want Unauthenticated", err)
+	}
+
+	input = &rpcauth.RPCAuthInput{}
+	testutil.FatalOnErr("hook without token", v.PeerPrincipalHook().Hook(ctx, input), t)
+	if input.Peer != nil {
+		t.Errorf("peer = %+v, want nil", input.Peer)
+	}
+}
+
+func TestTokenFileCredentials(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "token")
+	creds := NewTokenFileCredentials(path)
+	if !creds.RequireTransportSecurity() {
+		t.Error("token credentials must require transport security")
+	}
+	if _, err := creds.GetRequestMetadata(ctx); err == nil {
+		t.Error("missing token file didn't error")
+	}
+
+	for _, token := range []string{"first", "second"} {
+		testutil.FatalOnErr("WriteFile", os.WriteFile(path, []byte(token+"\n"), 0600), t)
+		md, err := creds.GetRequestMetadata(ctx)
+		testutil.FatalOnErr("GetRequestMetadata", err, t)
+		if got, want := md[AuthorizationKey], "Bearer "+token; got != want {
+			t.Errorf("authorization = %q, want %q", got, want)
+		}
+	}
+}
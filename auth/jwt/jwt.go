@@ -0,0 +1,180 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+// Package jwt verifies JSON Web Tokens, such as OIDC ID tokens, against a
+// local JSON Web Key Set and maps their claims onto SansShell principals.
+package jwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	josejwt "github.com/go-jose/go-jose/v4/jwt"
+
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+)
+
+// DefaultGroupsClaim is the claim groups are read from unless
+// WithGroupsClaim is used.
+const DefaultGroupsClaim = "groups"
+
+// signatureAlgorithms are the asymmetric algorithms accepted in tokens.
+// Symmetric algorithms are deliberately excluded as they would let anyone
+// holding the key set mint tokens.
+var signatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// A Verifier checks signed JWTs against a JSON Web Key Set loaded from a
+// file.
+type Verifier struct {
+	path        string
+	issuer      string
+	audience    string
+	groupsClaim string
+	leeway      time.Duration
+	now         func() time.Time
+
+	keys atomic.Pointer[jose.JSONWebKeySet]
+}
+
+// An Option configures a Verifier.
+type Option func(*Verifier)
+
+// WithIssuer requires the "iss" claim to match issuer.
+func WithIssuer(issuer string) Option {
+	return func(v *Verifier) {
+		v.issuer = issuer
+	}
+}
+
+// WithAudience requires the "aud" claim to contain audience.
+func WithAudience(audience string) Option {
+	return func(v *Verifier) {
+		v.audience = audience
+	}
+}
+
+// WithGroupsClaim sets the claim which holds the groups of the principal.
+// The claim may be a string or a list of strings.
+func WithGroupsClaim(claim string) Option {
+	return func(v *Verifier) {
+		v.groupsClaim = claim
+	}
+}
+
+// NewVerifier returns a Verifier using the JSON Web Key Set in the file at
+// jwksPath.
+func NewVerifier(jwksPath string, opts ...Option) (*Verifier, error) {
+	v := &Verifier{
+		path:        jwksPath,
+		groupsClaim: DefaultGroupsClaim,
+		leeway:      josejwt.DefaultLeeway,
+		now:         time.Now,
+	}
+	for _, o := range opts {
+		o(v)
+	}
+	if err := v.Reload(); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Reload re-reads the key set. If it can't be loaded the current keys stay
+// in use.
+func (v *Verifier) Reload() error {
+	b, err := os.ReadFile(v.path)
+	if err != nil {
+		return fmt.Errorf("can't read JWKS: %v", err)
+	}
+	keys := &jose.JSONWebKeySet{}
+	if err := json.Unmarshal(b, keys); err != nil {
+		return fmt.Errorf("can't parse JWKS %s: %v", v.path, err)
+	}
+	if len(keys.Keys) == 0 {
+		return fmt.Errorf("JWKS %s has no keys", v.path)
+	}
+	v.keys.Store(keys)
+	return nil
+}
+
+// Verify checks the signature, expiry, issuer and audience of token and
+// returns the principal it identifies. The principal's ID is the "sub"
+// claim.
+func (v *Verifier) Verify(token string) (*rpcauth.PrincipalAuthInput, error) {
+	tok, err := josejwt.ParseSigned(token, signatureAlgorithms)
+	if err != nil {
+		return nil, fmt.Errorf("invalid token: %v", err)
+	}
+	keys := v.keys.Load()
+	var candidates []jose.JSONWebKey
+	if kid := tok.Headers[0].KeyID; kid != "" {
+		candidates = keys.Key(kid)
+	} else {
+		candidates = keys.Keys
+	}
+	if len(candidates) == 0 {
+		return nil, errors.New("invalid token: no matching key")
+	}
+
+	var claims josejwt.Claims
+	var custom map[string]any
+	verified := false
+	for _, k := range candidates {
+		if err := tok.Claims(k.Public(), &claims, &custom); err == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("invalid token: bad signature")
+	}
+	if claims.Expiry == nil {
+		return nil, errors.New("invalid token: no expiry")
+	}
+	expected := josejwt.Expected{Issuer: v.issuer, Time: v.now()}
+	if v.audience != "" {
+		expected.AnyAudience = josejwt.Audience{v.audience}
+	}
+	if err := claims.ValidateWithLeeway(expected, v.leeway); err != nil {
+		return nil, fmt.Errorf("invalid token: %v", err)
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("invalid token: no subject")
+	}
+
+	p := &rpcauth.PrincipalAuthInput{ID: claims.Subject}
+	switch g := custom[v.groupsClaim].(type) {
+	case string:
+		p.Groups = []string{g}
+	case []any:
+		for _, e := range g {
+			if s, ok := e.(string); ok {
+				p.Groups = append(p.Groups, s)
+			}
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,180 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	josejwt "github.com/go-jose/go-jose/v4/jwt"
+
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+	"github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+type testKey struct {
+	key *ecdsa.PrivateKey
+	kid string
+}
+
+func newTestKey(t *testing.T, kid string) *testKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testutil.FatalOnErr("GenerateKey", err, t)
+	return &testKey{key: key, kid: kid}
+}
+
+func (k *testKey) sign(t *testing.T, claims any) string {
+	t.Helper()
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: jose.JSONWebKey{Key: k.key, KeyID: k.kid}}, nil)
+	testutil.FatalOnErr("NewSigner", err, t)
+	token, err := josejwt.Signed(signer).Claims(claims).Serialize()
+	testutil.FatalOnErr("Serialize", err, t)
+	return token
+}
+
+// writeJWKS writes the public halves of keys as a JSON Web Key Set.
+func writeJWKS(t *testing.T, path string, keys ...*testKey) {
+	t.Helper()
+	set := jose.JSONWebKeySet{}
+	for _, k := range keys {
+		set.Keys = append(set.Keys, jose.JSONWebKey{Key: &k.key.PublicKey, KeyID: k.kid, Algorithm: string(jose.ES256), Use: "sig"})
+	}
+	b, err := json.Marshal(set)
+	testutil.FatalOnErr("Marshal", err, t)
+	testutil.FatalOnErr("WriteFile", os.WriteFile(path, b, 0644), t)
+}
+
+func TestVerify(t *testing.T) {
+	key := newTestKey(t, "one")
+	other := newTestKey(t, "two")
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, key)
+
+	v, err := NewVerifier(path, WithIssuer("https://idp.example.com"), WithAudience("sansshell"))
+	testutil.FatalOnErr("NewVerifier", err, t)
+	now := time.Now()
+	v.now = func() time.Time { return now }
+
+	claims := func(mod func(map[string]any)) map[string]any {
+		c := map[string]any{
+			"iss":    "https://idp.example.com",
+			"aud":    "sansshell",
+			"sub":    "alice",
+			"exp":    now.Add(time.Hour).Unix(),
+			"groups": []string{"sre", "dba"},
+		}
+		if mod != nil {
+			mod(c)
+		}
+		return c
+	}
+
+	for _, tc := range []struct {
+		name    string
+		token   string
+		want    *rpcauth.PrincipalAuthInput
+		wantErr bool
+	}{
+		{
+			name:  "valid",
+			token: key.sign(t, claims(nil)),
+			want:  &rpcauth.PrincipalAuthInput{ID: "alice", Groups: []string{"sre", "dba"}},
+		},
+		{
+			name:  "single group",
+			token: key.sign(t, claims(func(c map[string]any) { c["groups"] = "sre" })),
+			want:  &rpcauth.PrincipalAuthInput{ID: "alice", Groups: []string{"sre"}},
+		},
+		{
+			name:    "wrong issuer",
+			token:   key.sign(t, claims(func(c map[string]any) { c["iss"] = "https://evil.example.com" })),
+			wantErr: true,
+		},
+		{
+			name:    "wrong audience",
+			token:   key.sign(t, claims(func(c map[string]any) { c["aud"] = "other" })),
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			token:   key.sign(t, claims(func(c map[string]any) { c["exp"] = now.Add(-time.Hour).Unix() })),
+			wantErr: true,
+		},
+		{
+			name:    "no expiry",
+			token:   key.sign(t, claims(func(c map[string]any) { delete(c, "exp") })),
+			wantErr: true,
+		},
+		{
+			name:    "no subject",
+			token:   key.sign(t, claims(func(c map[string]any) { delete(c, "sub") })),
+			wantErr: true,
+		},
+		{
+			name:    "unknown key",
+			token:   other.sign(t, claims(nil)),
+			wantErr: true,
+		},
+		{
+			name:    "garbage",
+			token:   "not.a.token",
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := v.Verify(tc.token)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("Verify succeeded with %+v, want error", got)
+				}
+				return
+			}
+			testutil.FatalOnErr("Verify", err, t)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("Verify = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	// Rotating in the other key makes its tokens valid.
+	writeJWKS(t, path, key, other)
+	testutil.FatalOnErr("Reload", v.Reload(), t)
+	if _, err := v.Verify(other.sign(t, claims(nil))); err != nil {
+		t.Errorf("Verify with rotated key: %v", err)
+	}
+}
+
+func TestNewVerifierErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewVerifier(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("missing JWKS didn't fail")
+	}
+	empty := filepath.Join(dir, "empty.json")
+	testutil.FatalOnErr("WriteFile", os.WriteFile(empty, []byte(`{"keys": []}`), 0644), t)
+	if _, err := NewVerifier(empty); err == nil {
+		t.Error("empty JWKS didn't fail")
+	}
+}
@@ -0,0 +1,283 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+// Package certissuer provides an mTLS credentials loader for clients which
+// fetches short-lived client certificates from a proxy's CertIssuer service
+// and caches them on disk until they are close to expiring.
+//
+// The issuer is authenticated to with an OIDC token if TokenFile is set, or
+// otherwise with the client certificate of BootstrapLoader. The root of trust
+// always comes from BootstrapLoader.
+package certissuer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/Snowflake-Labs/sansshell/auth/mtls"
+	mtlsFlags "github.com/Snowflake-Labs/sansshell/auth/mtls/flags"
+	pb "github.com/Snowflake-Labs/sansshell/services/certissuer"
+)
+
+const (
+	loaderName = "certissuer"
+
+	// minRenewMargin is the least amount of validity left at which a
+	// certificate is renewed.
+	minRenewMargin = 30 * time.Second
+)
+
+var (
+	// IssuerAddr is the host:port of the CertIssuer service. Binding this to a flag is often useful.
+	IssuerAddr = ""
+
+	// TokenFile, if set, is a file holding an OIDC token to identify with
+	// instead of a client certificate. Binding this to a flag is often useful.
+	TokenFile = ""
+
+	// Lifetime is how long to ask for certificates to be valid for. If zero
+	// the issuer's default is used. Binding this to a flag is often useful.
+	Lifetime time.Duration
+
+	// BootstrapLoader names the loader providing the root of trust and, when
+	// no token is used, the long-lived identity presented to the issuer.
+	BootstrapLoader = mtlsFlags.Name()
+
+	// CacheDir is where issued certificates are kept between runs.
+	CacheDir = filepath.Join(os.Getenv("HOME"), ".sansshell/issued")
+
+	// IssueTimeout bounds each request to the issuer.
+	IssueTimeout = 30 * time.Second
+)
+
+// Name returns the loader to use to fetch client certificates from a
+// CertIssuer service.
+func Name() string { return loaderName }
+
+// issuerLoader implements mtls.CredentialsLoader by requesting client
+// certificates from a CertIssuer service.
+type issuerLoader struct {
+	mu   sync.Mutex
+	cert *tls.Certificate // GUARDED_BY(mu)
+	// now is replaceable for testing.
+	now func() time.Time
+}
+
+func (l *issuerLoader) bootstrap() (mtls.CredentialsLoader, error) {
+	return mtls.Loader(BootstrapLoader)
+}
+
+func (l *issuerLoader) LoadClientCA(ctx context.Context) (*x509.CertPool, error) {
+	b, err := l.bootstrap()
+	if err != nil {
+		return nil, err
+	}
+	return b.LoadClientCA(ctx)
+}
+
+func (l *issuerLoader) LoadRootCA(ctx context.Context) (*x509.CertPool, error) {
+	b, err := l.bootstrap()
+	if err != nil {
+		return nil, err
+	}
+	return b.LoadRootCA(ctx)
+}
+
+func (l *issuerLoader) LoadServerCertificate(context.Context) (tls.Certificate, error) {
+	return tls.Certificate{}, errors.New("the certissuer loader only provides client certificates")
+}
+
+// LoadClientCertificate returns the cached certificate if it isn't due for
+// renewal and otherwise requests a new one. If renewal fails but the cached
+// certificate is still valid it keeps being used.
+func (l *issuerLoader) LoadClientCertificate(ctx context.Context) (tls.Certificate, error) {
+	logger := logr.FromContextOrDiscard(ctx)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cached, err := readCache()
+	if err == nil && !l.needsRenewal(cached.Leaf) {
+		l.cert = cached
+		return *cached, nil
+	}
+	cert, err := l.issue(ctx)
+	if err != nil {
+		if cached != nil && l.now().Before(cached.Leaf.NotAfter) {
+			logger.Error(err, "can't renew client certificate, using cached one", "notAfter", cached.Leaf.NotAfter)
+			l.cert = cached
+			return *cached, nil
+		}
+		return tls.Certificate{}, err
+	}
+	if err := writeCache(cert); err != nil {
+		logger.Error(err, "can't cache issued client certificate", "dir", CacheDir)
+	}
+	logger.Info("issued new client certificate", "notAfter", cert.Leaf.NotAfter)
+	l.cert = cert
+	return *cert, nil
+}
+
+// CertsRefreshed returns true once the last loaded certificate is due for
+// renewal.
+func (l *issuerLoader) CertsRefreshed() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cert != nil && l.needsRenewal(l.cert.Leaf)
+}
+
+// needsRenewal is true once less than a fifth of the certificate's lifetime,
+// or minRenewMargin, is left.
+func (l *issuerLoader) needsRenewal(leaf *x509.Certificate) bool {
+	margin := leaf.NotAfter.Sub(leaf.NotBefore) / 5
+	if margin < minRenewMargin {
+		margin = minRenewMargin
+	}
+	return l.now().After(leaf.NotAfter.Add(-margin))
+}
+
+// issue creates a new key and has the issuer sign a certificate for it.
+func (l *issuerLoader) issue(ctx context.Context) (*tls.Certificate, error) {
+	if IssuerAddr == "" {
+		return nil, errors.New("no CertIssuer address set")
+	}
+	b, err := l.bootstrap()
+	if err != nil {
+		return nil, err
+	}
+	pool, err := b.LoadRootCA(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+	}
+	if TokenFile != "" {
+		token, err := os.ReadFile(TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't read token: %v", err)
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+strings.TrimSpace(string(token)))
+	} else {
+		cert, err := b.LoadClientCertificate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{}, key)
+	if err != nil {
+		return nil, err
+	}
+	req := &pb.IssueCertificateRequest{
+		Csr: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr}),
+	}
+	if Lifetime > 0 {
+		req.Lifetime = durationpb.New(Lifetime)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, IssueTimeout)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, IssuerAddr, grpc.WithTransportCredentials(credentials.NewTLS(config)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	resp, err := pb.NewCertIssuerClient(conn).IssueCertificate(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("can't get client certificate from %s: %w", IssuerAddr, err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.X509KeyPair(resp.CertificateChain, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+	if err == nil {
+		cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid certificate from issuer: %v", err)
+	}
+	return &cert, nil
+}
+
+func cachePaths() (string, string) {
+	return filepath.Join(CacheDir, "client.pem"), filepath.Join(CacheDir, "client.key")
+}
+
+// readCache returns the cached certificate, with Leaf set.
+func readCache() (*tls.Certificate, error) {
+	certFile, keyFile := cachePaths()
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+func writeCache(cert *tls.Certificate) error {
+	if err := os.MkdirAll(CacheDir, 0700); err != nil {
+		return err
+	}
+	var chain []byte
+	for _, c := range cert.Certificate {
+		chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c})...)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		return err
+	}
+	certFile, keyFile := cachePaths()
+	// A concurrent reader may see a new key with an old certificate, which
+	// fails to load and is treated as a cache miss.
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return err
+	}
+	return os.WriteFile(certFile, chain, 0600)
+}
+
+func init() {
+	if err := mtls.Register(loaderName, &issuerLoader{now: time.Now}); err != nil {
+		panic(err)
+	}
+}
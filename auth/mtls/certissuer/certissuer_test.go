@@ -0,0 +1,162 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package certissuer
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/Snowflake-Labs/sansshell/auth/mtls"
+	certissuer "github.com/Snowflake-Labs/sansshell/services/certissuer/server"
+	"github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+const testdata = "../testdata"
+
+// bootstrapLoader provides the long-lived test client certificate.
+type bootstrapLoader struct{}
+
+func (bootstrapLoader) LoadClientCA(context.Context) (*x509.CertPool, error) {
+	return mtls.LoadRootOfTrust(filepath.Join(testdata, "root.pem"))
+}
+
+func (bootstrapLoader) LoadRootCA(context.Context) (*x509.CertPool, error) {
+	return mtls.LoadRootOfTrust(filepath.Join(testdata, "root.pem"))
+}
+
+func (bootstrapLoader) LoadClientCertificate(context.Context) (tls.Certificate, error) {
+	return tls.LoadX509KeyPair(filepath.Join(testdata, "client.pem"), filepath.Join(testdata, "client.key"))
+}
+
+func (bootstrapLoader) LoadServerCertificate(context.Context) (tls.Certificate, error) {
+	return tls.LoadX509KeyPair(filepath.Join(testdata, "leaf.pem"), filepath.Join(testdata, "leaf.key"))
+}
+
+func (bootstrapLoader) CertsRefreshed() bool { return false }
+
+func init() {
+	if err := mtls.Register("certissuer-test-bootstrap", bootstrapLoader{}); err != nil {
+		panic(err)
+	}
+}
+
+// startIssuer serves a CertIssuer signing with the test root CA.
+func startIssuer(t *testing.T) *grpc.Server {
+	t.Helper()
+	ctx := context.Background()
+	caCert, caKey, err := certissuer.LoadCA(filepath.Join(testdata, "root.pem"), filepath.Join(testdata, "root.key"))
+	testutil.FatalOnErr("LoadCA", err, t)
+	issuer, err := certissuer.New(caCert, caKey)
+	testutil.FatalOnErr("New", err, t)
+
+	b := bootstrapLoader{}
+	serverCert, err := b.LoadServerCertificate(ctx)
+	testutil.FatalOnErr("LoadServerCertificate", err, t)
+	pool, err := b.LoadClientCA(ctx)
+	testutil.FatalOnErr("LoadClientCA", err, t)
+	creds := mtls.NewServerCredentials(serverCert, pool)
+
+	lis, err := net.Listen("tcp", "localhost:0")
+	testutil.FatalOnErr("Listen", err, t)
+	s := grpc.NewServer(grpc.Creds(creds))
+	issuer.Register(s)
+	go s.Serve(lis) //nolint:errcheck
+	t.Cleanup(s.Stop)
+	IssuerAddr = lis.Addr().String()
+	return s
+}
+
+func TestLoader(t *testing.T) {
+	ctx := context.Background()
+	BootstrapLoader = "certissuer-test-bootstrap"
+	CacheDir = t.TempDir()
+	t.Cleanup(func() { IssuerAddr = "" })
+	s := startIssuer(t)
+
+	now := time.Now()
+	l := &issuerLoader{now: func() time.Time { return now }}
+
+	if l.CertsRefreshed() {
+		t.Error("CertsRefreshed true before loading")
+	}
+	cert, err := l.LoadClientCertificate(ctx)
+	testutil.FatalOnErr("LoadClientCertificate", err, t)
+	// The issued certificate carries the bootstrap certificate's identity.
+	if cn := cert.Leaf.Subject.CommonName; cn != "sanssh" {
+		t.Errorf("issued certificate CN = %q, want sanssh", cn)
+	}
+	fi, err := os.Stat(filepath.Join(CacheDir, "client.key"))
+	testutil.FatalOnErr("Stat", err, t)
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("cached key mode = %v, want 0600", fi.Mode().Perm())
+	}
+
+	// The issued certificate works for mTLS against servers trusting the root.
+	pool, err := l.LoadRootCA(ctx)
+	testutil.FatalOnErr("LoadRootCA", err, t)
+	if _, err := cert.Leaf.Verify(x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+		t.Errorf("issued certificate doesn't verify: %v", err)
+	}
+
+	// A new loader picks up the cached certificate.
+	l2 := &issuerLoader{now: l.now}
+	cached, err := l2.LoadClientCertificate(ctx)
+	testutil.FatalOnErr("LoadClientCertificate", err, t)
+	if cached.Leaf.SerialNumber.Cmp(cert.Leaf.SerialNumber) != 0 {
+		t.Error("second loader didn't use the cached certificate")
+	}
+	if l.CertsRefreshed() {
+		t.Error("CertsRefreshed true for a fresh certificate")
+	}
+
+	// Close to expiry the certificate is renewed.
+	now = cert.Leaf.NotAfter.Add(-time.Minute)
+	if !l.CertsRefreshed() {
+		t.Error("CertsRefreshed false close to expiry")
+	}
+	renewed, err := l.LoadClientCertificate(ctx)
+	testutil.FatalOnErr("LoadClientCertificate", err, t)
+	if renewed.Leaf.SerialNumber.Cmp(cert.Leaf.SerialNumber) == 0 {
+		t.Error("certificate wasn't renewed close to expiry")
+	}
+
+	// If the issuer is unavailable a still valid certificate keeps being used.
+	s.Stop()
+	if _, err := l.LoadClientCertificate(ctx); err != nil {
+		t.Errorf("LoadClientCertificate with issuer down and valid cache: %v", err)
+	}
+	now = renewed.Leaf.NotAfter.Add(time.Minute)
+	IssueTimeout = time.Second
+	if _, err := l.LoadClientCertificate(ctx); err == nil {
+		t.Error("LoadClientCertificate succeeded with issuer down and expired cache")
+	}
+}
+
+func TestLoaderServerCertificate(t *testing.T) {
+	l := &issuerLoader{now: time.Now}
+	if _, err := l.LoadServerCertificate(context.Background()); err == nil {
+		t.Error("LoadServerCertificate succeeded")
+	}
+}
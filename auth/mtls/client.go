@@ -29,21 +29,24 @@ import (
 
 // LoadClientCredentials returns transport credentials for SansShell clients,
 // based on the provided `loaderName`
-func LoadClientCredentials(ctx context.Context, loaderName string) (credentials.TransportCredentials, error) {
+func LoadClientCredentials(ctx context.Context, loaderName string, opts ...Option) (credentials.TransportCredentials, error) {
 	logger := logr.FromContextOrDiscard(ctx)
 	recorder := metrics.RecorderFromContextOrNoop(ctx)
 	mtlsLoader, err := Loader(loaderName)
 	if err != nil {
 		return nil, err
 	}
-	creds, err := internalLoadClientCredentials(ctx, loaderName)
+	loader := func(ctx context.Context, loaderName string) (credentials.TransportCredentials, error) {
+		return internalLoadClientCredentials(ctx, loaderName, opts...)
+	}
+	creds, err := loader(ctx, loaderName)
 	if err != nil {
 		return nil, err
 	}
 	wrapped := &WrappedTransportCredentials{
 		creds:      creds,
 		loaderName: loaderName,
-		loader:     internalLoadClientCredentials,
+		loader:     loader,
 		mtlsLoader: mtlsLoader,
 		logger:     logger,
 		recorder:   recorder,
@@ -51,7 +54,7 @@ func LoadClientCredentials(ctx context.Context, loaderName string) (credentials.
 	return wrapped, nil
 }
 
-func internalLoadClientCredentials(ctx context.Context, loaderName string) (credentials.TransportCredentials, error) {
+func internalLoadClientCredentials(ctx context.Context, loaderName string, opts ...Option) (credentials.TransportCredentials, error) {
 	logger := logr.FromContextOrDiscard(ctx)
 	loader, err := Loader(loaderName)
 	if err != nil {
@@ -68,16 +71,20 @@ func internalLoadClientCredentials(ctx context.Context, loaderName string) (cred
 		return nil, err
 	}
 	logger.Info("loaded new client cert", "error", err)
-	return NewClientCredentials(cert, pool), nil
+	return NewClientCredentials(cert, pool, opts...), nil
 }
 
 // NewClientCredentials returns transport credentials for SansShell clients.
-func NewClientCredentials(cert tls.Certificate, CAPool *x509.CertPool) credentials.TransportCredentials {
-	return credentials.NewTLS(&tls.Config{
+func NewClientCredentials(cert tls.Certificate, CAPool *x509.CertPool, opts ...Option) credentials.TransportCredentials {
+	config := &tls.Config{
 		Certificates: []tls.Certificate{cert},
 		RootCAs:      CAPool,
 		MinVersion:   tls.VersionTLS12,
-	})
+	}
+	for _, o := range opts {
+		o(config)
+	}
+	return credentials.NewTLS(config)
 }
 
 // LoadClientTLS reads the certificates and keys from disk at the supplied paths,
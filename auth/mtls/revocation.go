@@ -0,0 +1,368 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package mtls
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"gocloud.dev/blob"
+	"golang.org/x/crypto/ocsp"
+
+	"github.com/Snowflake-Labs/sansshell/telemetry/metrics"
+)
+
+// Metrics
+var (
+	peerRevokedMetrics = metrics.MetricDefinition{
+		Name: "mtls_peer_revoked", Description: "mtls handshakes rejected because the peer certificate was revoked",
+	}
+	crlRefreshFailureMetrics = metrics.MetricDefinition{
+		Name: "mtls_crl_refresh_failure", Description: "failure to refresh a certificate revocation list",
+	}
+)
+
+// ocspClockSkew is how far a stapled OCSP response's validity period is
+// stretched to allow for clocks which disagree.
+const ocspClockSkew = 5 * time.Minute
+
+// ErrCertificateRevoked is returned (wrapped) from handshakes with a peer
+// whose certificate chain contains a revoked certificate.
+var ErrCertificateRevoked = errors.New("certificate revoked")
+
+// A RevocationChecker rejects TLS peers whose certificates have been
+// revoked. Revocations come from CRLs, which are loaded from local files or
+// blob URLs and can be periodically refreshed, and optionally from OCSP
+// responses stapled to the handshake.
+type RevocationChecker struct {
+	sources      []string
+	issuers      []*x509.Certificate
+	checkOCSP    bool
+	logger       logr.Logger
+	recorder     metrics.MetricsRecorder
+	fetchTimeout time.Duration
+
+	mu sync.RWMutex
+	// crls holds the most recently loaded CRLs for each source.
+	crls map[string][]*x509.RevocationList // GUARDED_BY(mu)
+}
+
+// A RevocationOption configures a RevocationChecker.
+type RevocationOption func(*RevocationChecker)
+
+// WithCRLSources adds CRLs to check peers against. Each source is either a
+// local file path or a blob URL such as s3://bucket/path/ca.crl, and may
+// contain one DER CRL or any number of PEM "X509 CRL" blocks. Blob URLs
+// need the matching gocloud.dev driver linked into the binary.
+func WithCRLSources(sources ...string) RevocationOption {
+	return func(r *RevocationChecker) {
+		r.sources = append(r.sources, sources...)
+	}
+}
+
+// WithCRLIssuers restricts CRLs to those signed by one of issuers. A CRL
+// from anyone else, or with a bad signature, fails to load. Without it a CRL
+// is only checked when it's used, and a bad signature then rejects the peer.
+func WithCRLIssuers(issuers ...*x509.Certificate) RevocationOption {
+	return func(r *RevocationChecker) {
+		r.issuers = append(r.issuers, issuers...)
+	}
+}
+
+// WithOCSPStapling checks OCSP responses stapled to the handshake and
+// rejects peers they report as revoked, or whose response is invalid or
+// expired. Peers which don't staple a response are accepted. Only servers
+// staple OCSP responses, so this only affects credentials used to connect
+// to servers, and only servers whose credentials loader sets
+// tls.Certificate.OCSPStaple.
+func WithOCSPStapling() RevocationOption {
+	return func(r *RevocationChecker) {
+		r.checkOCSP = true
+	}
+}
+
+// NewRevocationChecker returns a RevocationChecker after loading all of its
+// CRL sources. Logging and metrics use the logger and recorder from ctx.
+func NewRevocationChecker(ctx context.Context, opts ...RevocationOption) (*RevocationChecker, error) {
+	r := &RevocationChecker{
+		logger:       logr.FromContextOrDiscard(ctx),
+		recorder:     metrics.RecorderFromContextOrNoop(ctx),
+		fetchTimeout: time.Minute,
+		crls:         make(map[string][]*x509.RevocationList),
+	}
+	for _, o := range opts {
+		o(r)
+	}
+	for _, s := range r.sources {
+		crls, err := r.fetch(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		r.crls[s] = crls
+	}
+	return r, nil
+}
+
+// Refresh reloads every CRL source. A source which can't be loaded, including
+// one which is past its next update, keeps its previous CRLs and the last
+// error is returned.
+func (r *RevocationChecker) Refresh(ctx context.Context) error {
+	var lastErr error
+	for _, s := range r.sources {
+		crls, err := r.fetch(ctx, s)
+		if err != nil {
+			r.logger.Error(err, "keeping previous CRL", "source", s)
+			r.recorder.CounterOrLog(ctx, crlRefreshFailureMetrics, 1)
+			lastErr = err
+			continue
+		}
+		r.mu.Lock()
+		r.crls[s] = crls
+		r.mu.Unlock()
+	}
+	return lastErr
+}
+
+// Watch calls Refresh every interval until ctx is done.
+func (r *RevocationChecker) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = r.Refresh(ctx)
+		}
+	}
+}
+
+// fetch loads and parses the CRLs in source, and checks they're current
+// and signed by a known issuer.
+func (r *RevocationChecker) fetch(ctx context.Context, source string) ([]*x509.RevocationList, error) {
+	data, err := r.read(ctx, source)
+	if err != nil {
+		return nil, fmt.Errorf("can't read CRL %s: %w", source, err)
+	}
+	crls, err := parseCRLs(data)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse CRL %s: %w", source, err)
+	}
+	now := time.Now()
+	for _, crl := range crls {
+		if !crl.NextUpdate.IsZero() && now.After(crl.NextUpdate) {
+			return nil, fmt.Errorf("CRL %s expired at %s", source, crl.NextUpdate)
+		}
+		if err := r.checkIssuer(crl); err != nil {
+			return nil, fmt.Errorf("CRL %s: %w", source, err)
+		}
+	}
+	return crls, nil
+}
+
+// checkIssuer returns an error unless crl is signed by one of the
+// configured issuers, if there are any.
+func (r *RevocationChecker) checkIssuer(crl *x509.RevocationList) error {
+	if len(r.issuers) == 0 {
+		return nil
+	}
+	for _, issuer := range r.issuers {
+		if !bytes.Equal(crl.RawIssuer, issuer.RawSubject) {
+			continue
+		}
+		if err := crl.CheckSignatureFrom(issuer); err != nil {
+			return fmt.Errorf("bad signature from %s: %w", issuer.Subject, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("issued by unknown CA %s", crl.Issuer)
+}
+
+func (r *RevocationChecker) read(ctx context.Context, source string) ([]byte, error) {
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme == "" || u.Scheme == "file" {
+		if err == nil && u.Scheme == "file" {
+			source = u.Path
+		}
+		return os.ReadFile(source)
+	}
+	ctx, cancel := context.WithTimeout(ctx, r.fetchTimeout)
+	defer cancel()
+	// The key is the last path element and everything else identifies
+	// the bucket.
+	key := path.Base(u.Path)
+	u.Path = path.Dir(u.Path)
+	b, err := blob.OpenBucket(ctx, u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer b.Close()
+	rd, err := b.NewReader(ctx, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+	return io.ReadAll(rd)
+}
+
+func parseCRLs(data []byte) ([]*x509.RevocationList, error) {
+	if !bytes.Contains(data, []byte("-----BEGIN")) {
+		crl, err := x509.ParseRevocationList(data)
+		if err != nil {
+			return nil, err
+		}
+		return []*x509.RevocationList{crl}, nil
+	}
+	var crls []*x509.RevocationList
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "X509 CRL" {
+			continue
+		}
+		crl, err := x509.ParseRevocationList(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		crls = append(crls, crl)
+	}
+	if len(crls) == 0 {
+		return nil, errors.New("no X509 CRL PEM blocks found")
+	}
+	return crls, nil
+}
+
+// VerifyConnection checks the verified chains of a completed handshake and
+// returns an error wrapping ErrCertificateRevoked if the peer should be
+// rejected. It is suitable for use as tls.Config.VerifyConnection on a
+// client. Use WithRevocationChecker to configure credentials, which also
+// handles servers.
+func (r *RevocationChecker) VerifyConnection(cs tls.ConnectionState) error {
+	return r.verify(cs, r.checkOCSP)
+}
+
+func (r *RevocationChecker) verify(cs tls.ConnectionState, checkOCSP bool) error {
+	reason, err := r.check(cs, checkOCSP)
+	if err != nil {
+		var subject string
+		if len(cs.PeerCertificates) > 0 {
+			subject = cs.PeerCertificates[0].Subject.String()
+		}
+		r.logger.Info("rejecting revoked peer", "reason", reason, "subject", subject, "error", err.Error())
+		r.recorder.CounterOrLog(context.Background(), peerRevokedMetrics, 1, attribute.String("reason", reason))
+	}
+	return err
+}
+
+// check returns the reason ("crl" or "ocsp") and an error if the peer is
+// revoked.
+func (r *RevocationChecker) check(cs tls.ConnectionState, checkOCSP bool) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, chain := range cs.VerifiedChains {
+		// The last certificate is the trust anchor, which has no issuer
+		// to revoke it.
+		for i := 0; i < len(chain)-1; i++ {
+			if err := r.checkCRLs(chain[i], chain[i+1]); err != nil {
+				return "crl", err
+			}
+		}
+	}
+	if !checkOCSP || len(cs.VerifiedChains) == 0 {
+		return "", nil
+	}
+	if len(cs.OCSPResponse) == 0 {
+		return "", nil
+	}
+	chain := cs.VerifiedChains[0]
+	if len(chain) < 2 {
+		return "", nil
+	}
+	resp, err := ocsp.ParseResponseForCert(cs.OCSPResponse, chain[0], chain[1])
+	if err != nil {
+		return "ocsp", fmt.Errorf("%w: invalid stapled OCSP response: %v", ErrCertificateRevoked, err)
+	}
+	now := time.Now()
+	if now.Add(ocspClockSkew).Before(resp.ThisUpdate) {
+		return "ocsp", fmt.Errorf("%w: stapled OCSP response isn't valid until %s", ErrCertificateRevoked, resp.ThisUpdate)
+	}
+	if !resp.NextUpdate.IsZero() && now.Add(-ocspClockSkew).After(resp.NextUpdate) {
+		return "ocsp", fmt.Errorf("%w: stapled OCSP response expired at %s", ErrCertificateRevoked, resp.NextUpdate)
+	}
+	if resp.Status == ocsp.Revoked {
+		return "ocsp", fmt.Errorf("%w: OCSP reports serial %s revoked at %s", ErrCertificateRevoked, chain[0].SerialNumber, resp.RevokedAt)
+	}
+	return "", nil
+}
+
+// checkCRLs returns an error if any CRL from issuer revokes cert, or claims
+// to be from issuer but isn't signed by it.
+func (r *RevocationChecker) checkCRLs(cert, issuer *x509.Certificate) error {
+	for _, crls := range r.crls {
+		for _, crl := range crls {
+			if !bytes.Equal(crl.RawIssuer, issuer.RawSubject) {
+				continue
+			}
+			if err := crl.CheckSignatureFrom(issuer); err != nil {
+				return fmt.Errorf("%w: CRL for %s has a bad signature: %v", ErrCertificateRevoked, issuer.Subject, err)
+			}
+			for _, rc := range crl.RevokedCertificateEntries {
+				if rc.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+					return fmt.Errorf("%w: serial %s issued by %s is on a CRL", ErrCertificateRevoked, cert.SerialNumber, issuer.Subject)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// An Option customizes credentials created by this package.
+type Option func(*tls.Config)
+
+// WithRevocationChecker rejects peers which checker reports as revoked. Any
+// existing VerifyConnection callback still runs first. OCSP staples are only
+// checked by credentials which verify servers, as clients never send them.
+func WithRevocationChecker(checker *RevocationChecker) Option {
+	return func(c *tls.Config) {
+		prev := c.VerifyConnection
+		checkOCSP := checker.checkOCSP && c.ClientAuth == tls.NoClientCert
+		c.VerifyConnection = func(cs tls.ConnectionState) error {
+			if prev != nil {
+				if err := prev(cs); err != nil {
+					return err
+				}
+			}
+			return checker.verify(cs, checkOCSP)
+		}
+	}
+}
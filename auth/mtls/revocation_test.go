@@ -0,0 +1,332 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package mtls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+type revocationCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newRevocationCA(t *testing.T) *revocationCA {
+	t.Helper()
+	return newNamedRevocationCA(t, "revocation test CA")
+}
+
+func newNamedRevocationCA(t *testing.T, name string) *revocationCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testutil.FatalOnErr("GenerateKey", err, t)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	testutil.FatalOnErr("CreateCertificate", err, t)
+	cert, err := x509.ParseCertificate(der)
+	testutil.FatalOnErr("ParseCertificate", err, t)
+	return &revocationCA{cert: cert, key: key}
+}
+
+func (ca *revocationCA) pool() *x509.CertPool {
+	p := x509.NewCertPool()
+	p.AddCert(ca.cert)
+	return p
+}
+
+// issue returns a certificate for localhost usable by clients and servers.
+func (ca *revocationCA) issue(t *testing.T, serial int64) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testutil.FatalOnErr("GenerateKey", err, t)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	testutil.FatalOnErr("CreateCertificate", err, t)
+	leaf, err := x509.ParseCertificate(der)
+	testutil.FatalOnErr("ParseCertificate", err, t)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// writeCRL writes a PEM CRL revoking serials to path.
+func (ca *revocationCA) writeCRL(t *testing.T, path string, number int64, serials ...int64) {
+	t.Helper()
+	ca.writeCRLUntil(t, path, number, time.Now().Add(time.Hour), serials...)
+}
+
+// writeCRLUntil writes a PEM CRL revoking serials, with the given next
+// update, to path.
+func (ca *revocationCA) writeCRLUntil(t *testing.T, path string, number int64, nextUpdate time.Time, serials ...int64) {
+	t.Helper()
+	tmpl := &x509.RevocationList{
+		Number:     big.NewInt(number),
+		ThisUpdate: nextUpdate.Add(-2 * time.Hour),
+		NextUpdate: nextUpdate,
+	}
+	for _, s := range serials {
+		tmpl.RevokedCertificateEntries = append(tmpl.RevokedCertificateEntries, x509.RevocationListEntry{
+			SerialNumber:   big.NewInt(s),
+			RevocationTime: time.Now().Add(-time.Minute),
+		})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, tmpl, ca.cert, ca.key)
+	testutil.FatalOnErr("CreateRevocationList", err, t)
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), 0644)
+	testutil.FatalOnErr("WriteFile", err, t)
+}
+
+// handshake connects client and server credentials over loopback and
+// returns the errors from each side.
+func handshake(t *testing.T, client, server credentials.TransportCredentials) (clientErr, serverErr error) {
+	t.Helper()
+	lis, err := net.Listen("tcp", "localhost:0")
+	testutil.FatalOnErr("Listen", err, t)
+	defer lis.Close()
+	errc := make(chan error, 1)
+	go func() {
+		c, err := net.Dial("tcp", lis.Addr().String())
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer c.Close()
+		_, _, err = client.ClientHandshake(context.Background(), "localhost", c)
+		errc <- err
+	}()
+	s, err := lis.Accept()
+	testutil.FatalOnErr("Accept", err, t)
+	defer s.Close()
+	_, _, serverErr = server.ServerHandshake(s)
+	// Unblock the client if the server gave up.
+	s.Close()
+	return <-errc, serverErr
+}
+
+func TestRevocationCRL(t *testing.T) {
+	ctx := context.Background()
+	ca := newRevocationCA(t)
+	crlPath := filepath.Join(t.TempDir(), "ca.crl")
+	ca.writeCRL(t, crlPath, 1)
+
+	checker, err := NewRevocationChecker(ctx, WithCRLSources(crlPath))
+	testutil.FatalOnErr("NewRevocationChecker", err, t)
+
+	serverCreds := NewServerCredentials(ca.issue(t, 10), ca.pool(), WithRevocationChecker(checker))
+	clientCreds := NewClientCredentials(ca.issue(t, 11), ca.pool())
+
+	if _, err := handshake(t, clientCreds, serverCreds); err != nil {
+		t.Fatalf("handshake with unrevoked client failed: %v", err)
+	}
+
+	// Revoke the client and a refresh should reject it.
+	ca.writeCRL(t, crlPath, 2, 11)
+	testutil.FatalOnErr("Refresh", checker.Refresh(ctx), t)
+	if _, err := handshake(t, clientCreds, serverCreds); !errors.Is(err, ErrCertificateRevoked) {
+		t.Errorf("handshake with revoked client: got error %v, want %v", err, ErrCertificateRevoked)
+	}
+
+	// A broken CRL keeps the previous one in place.
+	testutil.FatalOnErr("WriteFile", os.WriteFile(crlPath, []byte("garbage"), 0644), t)
+	if err := checker.Refresh(ctx); err == nil {
+		t.Error("Refresh of an invalid CRL didn't fail")
+	}
+	if _, err := handshake(t, clientCreds, serverCreds); !errors.Is(err, ErrCertificateRevoked) {
+		t.Errorf("handshake after bad refresh: got error %v, want %v", err, ErrCertificateRevoked)
+	}
+
+	// CRLs from other issuers don't apply.
+	other := newNamedRevocationCA(t, "other CA")
+	otherPath := filepath.Join(t.TempDir(), "other.crl")
+	other.writeCRL(t, otherPath, 1, 10, 11)
+	otherChecker, err := NewRevocationChecker(ctx, WithCRLSources(otherPath))
+	testutil.FatalOnErr("NewRevocationChecker", err, t)
+	serverCreds = NewServerCredentials(ca.issue(t, 10), ca.pool(), WithRevocationChecker(otherChecker))
+	if _, err := handshake(t, clientCreds, serverCreds); err != nil {
+		t.Errorf("handshake checked against another issuer's CRL failed: %v", err)
+	}
+}
+
+func TestRevocationCRLSignature(t *testing.T) {
+	ctx := context.Background()
+	ca := newRevocationCA(t)
+	// An impostor with the same name as ca but a different key.
+	impostor := newRevocationCA(t)
+	impostorPath := filepath.Join(t.TempDir(), "impostor.crl")
+	impostor.writeCRL(t, impostorPath, 1)
+
+	// Without known issuers the CRL loads, but peers it claims to cover
+	// are rejected rather than being let through unchecked.
+	checker, err := NewRevocationChecker(ctx, WithCRLSources(impostorPath))
+	testutil.FatalOnErr("NewRevocationChecker", err, t)
+	serverCreds := NewServerCredentials(ca.issue(t, 10), ca.pool(), WithRevocationChecker(checker))
+	clientCreds := NewClientCredentials(ca.issue(t, 11), ca.pool())
+	if _, err := handshake(t, clientCreds, serverCreds); !errors.Is(err, ErrCertificateRevoked) {
+		t.Errorf("handshake checked against a badly signed CRL: got error %v, want %v", err, ErrCertificateRevoked)
+	}
+
+	// With known issuers it doesn't load at all.
+	if _, err := NewRevocationChecker(ctx, WithCRLSources(impostorPath), WithCRLIssuers(ca.cert)); err == nil {
+		t.Error("badly signed CRL loaded")
+	}
+	other := newNamedRevocationCA(t, "other CA")
+	if _, err := NewRevocationChecker(ctx, WithCRLSources(impostorPath), WithCRLIssuers(other.cert)); err == nil {
+		t.Error("CRL from an unknown issuer loaded")
+	}
+	crlPath := filepath.Join(t.TempDir(), "ca.crl")
+	ca.writeCRL(t, crlPath, 1)
+	checker, err = NewRevocationChecker(ctx, WithCRLSources(crlPath), WithCRLIssuers(ca.cert))
+	testutil.FatalOnErr("NewRevocationChecker", err, t)
+
+	// Nor does a refresh to one, which keeps the previous CRL.
+	testutil.FatalOnErr("copyFile", copyFile(impostorPath, crlPath), t)
+	if err := checker.Refresh(ctx); err == nil {
+		t.Error("Refresh to a badly signed CRL didn't fail")
+	}
+	serverCreds = NewServerCredentials(ca.issue(t, 10), ca.pool(), WithRevocationChecker(checker))
+	if _, err := handshake(t, clientCreds, serverCreds); err != nil {
+		t.Errorf("handshake after bad refresh failed: %v", err)
+	}
+}
+
+func TestRevocationCRLExpired(t *testing.T) {
+	ctx := context.Background()
+	ca := newRevocationCA(t)
+	crlPath := filepath.Join(t.TempDir(), "ca.crl")
+	ca.writeCRLUntil(t, crlPath, 1, time.Now().Add(-time.Minute))
+	if _, err := NewRevocationChecker(ctx, WithCRLSources(crlPath)); err == nil {
+		t.Error("expired CRL loaded")
+	}
+
+	// Refreshing to an expired CRL keeps the previous one.
+	ca.writeCRL(t, crlPath, 2, 11)
+	checker, err := NewRevocationChecker(ctx, WithCRLSources(crlPath))
+	testutil.FatalOnErr("NewRevocationChecker", err, t)
+	ca.writeCRLUntil(t, crlPath, 3, time.Now().Add(-time.Minute))
+	if err := checker.Refresh(ctx); err == nil {
+		t.Error("Refresh to an expired CRL didn't fail")
+	}
+	serverCreds := NewServerCredentials(ca.issue(t, 10), ca.pool(), WithRevocationChecker(checker))
+	clientCreds := NewClientCredentials(ca.issue(t, 11), ca.pool())
+	if _, err := handshake(t, clientCreds, serverCreds); !errors.Is(err, ErrCertificateRevoked) {
+		t.Errorf("handshake after expired refresh: got error %v, want %v", err, ErrCertificateRevoked)
+	}
+}
+
+func copyFile(from, to string) error {
+	data, err := os.ReadFile(from)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(to, data, 0644)
+}
+
+func TestRevocationCheckerErrors(t *testing.T) {
+	ctx := context.Background()
+	if _, err := NewRevocationChecker(ctx, WithCRLSources(filepath.Join(t.TempDir(), "missing.crl"))); err == nil {
+		t.Error("missing CRL file didn't fail")
+	}
+	path := filepath.Join(t.TempDir(), "bad.crl")
+	testutil.FatalOnErr("WriteFile", os.WriteFile(path, []byte("-----BEGIN CERTIFICATE-----\n-----END CERTIFICATE-----\n"), 0644), t)
+	if _, err := NewRevocationChecker(ctx, WithCRLSources(path)); err == nil {
+		t.Error("PEM file without CRLs didn't fail")
+	}
+}
+
+func TestRevocationOCSPStapling(t *testing.T) {
+	ctx := context.Background()
+	ca := newRevocationCA(t)
+	serverCert := ca.issue(t, 20)
+	clientCert := ca.issue(t, 21)
+
+	staple := func(status int, thisUpdate, nextUpdate time.Time) []byte {
+		resp, err := ocsp.CreateResponse(ca.cert, ca.cert, ocsp.Response{
+			Status:       status,
+			SerialNumber: serverCert.Leaf.SerialNumber,
+			ThisUpdate:   thisUpdate,
+			NextUpdate:   nextUpdate,
+			RevokedAt:    time.Now().Add(-time.Minute),
+		}, ca.key)
+		testutil.FatalOnErr("ocsp.CreateResponse", err, t)
+		return resp
+	}
+
+	now := time.Now()
+	for _, tc := range []struct {
+		name    string
+		staple  []byte
+		revoked bool
+	}{
+		{name: "no staple"},
+		{name: "good staple", staple: staple(ocsp.Good, now.Add(-time.Minute), now.Add(time.Hour))},
+		{name: "revoked staple", staple: staple(ocsp.Revoked, now.Add(-time.Minute), now.Add(time.Hour)), revoked: true},
+		{name: "corrupt staple", staple: []byte("garbage"), revoked: true},
+		{name: "expired staple", staple: staple(ocsp.Good, now.Add(-2*time.Hour), now.Add(-time.Hour)), revoked: true},
+		{name: "future staple", staple: staple(ocsp.Good, now.Add(time.Hour), now.Add(2*time.Hour)), revoked: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			checker, err := NewRevocationChecker(ctx, WithOCSPStapling())
+			testutil.FatalOnErr("NewRevocationChecker", err, t)
+			cert := serverCert
+			cert.OCSPStaple = tc.staple
+			// The server also gets the checker to make sure it doesn't demand
+			// staples from clients.
+			serverCreds := NewServerCredentials(cert, ca.pool(), WithRevocationChecker(checker))
+			clientCreds := NewClientCredentials(clientCert, ca.pool(), WithRevocationChecker(checker))
+			clientErr, serverErr := handshake(t, clientCreds, serverCreds)
+			if got := errors.Is(clientErr, ErrCertificateRevoked); got != tc.revoked {
+				t.Errorf("client handshake error %v, want revoked %v", clientErr, tc.revoked)
+			}
+			if !tc.revoked && serverErr != nil {
+				t.Errorf("server handshake failed: %v", serverErr)
+			}
+		})
+	}
+}
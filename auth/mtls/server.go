@@ -32,28 +32,31 @@ import (
 // used method to generate credentials as this will support reloadable credentials
 // as the TransportCredentials returned are a WrappedTransportCredentials which
 // will check at call time if new certificates are available.
-func LoadServerCredentials(ctx context.Context, loaderName string) (credentials.TransportCredentials, error) {
-	wrapped, _, err := LoadServerCredentialsWithForceRefresh(ctx, loaderName)
+func LoadServerCredentials(ctx context.Context, loaderName string, opts ...Option) (credentials.TransportCredentials, error) {
+	wrapped, _, err := LoadServerCredentialsWithForceRefresh(ctx, loaderName, opts...)
 	return wrapped, err
 }
 
 // LoadServerCredentialsWithForceRefresh returns transport credentials along with
 // a function that allows immediately refreshing the credentials
-func LoadServerCredentialsWithForceRefresh(ctx context.Context, loaderName string) (credentials.TransportCredentials, func() error, error) {
+func LoadServerCredentialsWithForceRefresh(ctx context.Context, loaderName string, opts ...Option) (credentials.TransportCredentials, func() error, error) {
 	logger := logr.FromContextOrDiscard(ctx)
 	recorder := metrics.RecorderFromContextOrNoop(ctx)
 	mtlsLoader, err := Loader(loaderName)
 	if err != nil {
 		return nil, nil, err
 	}
-	creds, err := internalLoadServerCredentials(ctx, loaderName)
+	loader := func(ctx context.Context, loaderName string) (credentials.TransportCredentials, error) {
+		return internalLoadServerCredentials(ctx, loaderName, opts...)
+	}
+	creds, err := loader(ctx, loaderName)
 	if err != nil {
 		return nil, nil, err
 	}
 	wrapped := &WrappedTransportCredentials{
 		creds:      creds,
 		loaderName: loaderName,
-		loader:     internalLoadServerCredentials,
+		loader:     loader,
 		mtlsLoader: mtlsLoader,
 		logger:     logger,
 		recorder:   recorder,
@@ -61,7 +64,7 @@ func LoadServerCredentialsWithForceRefresh(ctx context.Context, loaderName strin
 	return wrapped, wrapped.refreshNow, err
 }
 
-func internalLoadServerCredentials(ctx context.Context, loaderName string) (credentials.TransportCredentials, error) {
+func internalLoadServerCredentials(ctx context.Context, loaderName string, opts ...Option) (credentials.TransportCredentials, error) {
 	logger := logr.FromContextOrDiscard(ctx)
 	loader, err := Loader(loaderName)
 	if err != nil {
@@ -78,18 +81,32 @@ func internalLoadServerCredentials(ctx context.Context, loaderName string) (cred
 		return nil, err
 	}
 	logger.Info("loaded new server cert", "error", err)
-	return NewServerCredentials(cert, pool), nil
+	return NewServerCredentials(cert, pool, opts...), nil
 }
 
 // NewServerCredentials creates transport credentials for a SansShell server.
 // NOTE: This doesn't support reloadable credentials.
-func NewServerCredentials(cert tls.Certificate, CAPool *x509.CertPool) credentials.TransportCredentials {
-	return credentials.NewTLS(&tls.Config{
+func NewServerCredentials(cert tls.Certificate, CAPool *x509.CertPool, opts ...Option) credentials.TransportCredentials {
+	config := &tls.Config{
 		ClientAuth:   tls.RequireAndVerifyClientCert,
 		Certificates: []tls.Certificate{cert},
 		ClientCAs:    CAPool,
 		MinVersion:   tls.VersionTLS12,
-	})
+	}
+	for _, o := range opts {
+		o(config)
+	}
+	return credentials.NewTLS(config)
+}
+
+// WithClientCertOptional makes server credentials accept clients which
+// don't present a certificate. Certificates that are presented are still
+// verified, so callers must authenticate certificate-less clients some other
+// way.
+func WithClientCertOptional() Option {
+	return func(c *tls.Config) {
+		c.ClientAuth = tls.VerifyClientCertIfGiven
+	}
 }
 
 // LoadServerTLS reads the certificates and keys from disk at the supplied paths,
@@ -0,0 +1,230 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+// Package spiffe provides an mTLS credentials loader which fetches X.509
+// SVIDs and trust bundles from the SPIFFE Workload API.
+//
+// The same SVID is presented as both the client and server certificate and
+// every trust bundle the Workload API returns, including federated ones, is
+// trusted. Rotated SVIDs and bundles are picked up automatically. As
+// SansShell clients verify server host names, server SVIDs need to include
+// the DNS names clients connect to alongside the SPIFFE ID.
+package spiffe
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/workloadapi"
+
+	"github.com/Snowflake-Labs/sansshell/auth/mtls"
+)
+
+const (
+	loaderName = "spiffe"
+)
+
+var (
+	// SocketPath is the address of the Workload API, such as
+	// unix:///run/spire/sockets/agent.sock. If empty the
+	// SPIFFE_ENDPOINT_SOCKET environment variable is used. Binding this to
+	// a flag is often useful.
+	SocketPath = ""
+
+	// FetchTimeout bounds how long loading credentials waits for the first
+	// SVID from the Workload API.
+	FetchTimeout = 30 * time.Second
+)
+
+// Name returns the loader to use to fetch mtls params from the SPIFFE
+// Workload API.
+func Name() string { return loaderName }
+
+// spiffeLoader implements mtls.CredentialsLoader by watching the SPIFFE
+// Workload API for X.509 SVIDs and bundles.
+type spiffeLoader struct {
+	// addr overrides SocketPath, for testing.
+	addr string
+
+	startOnce sync.Once
+	// ready is closed once the first update (or a fatal error) arrives.
+	ready chan struct{}
+
+	mu         sync.Mutex
+	x509Ctx    *workloadapi.X509Context // GUARDED_BY(mu)
+	err        error                    // GUARDED_BY(mu)
+	generation uint64                   // GUARDED_BY(mu)
+	// The generation of the certificates last handed out, or 0 if they
+	// haven't been loaded.
+	clientGeneration uint64 // GUARDED_BY(mu)
+	serverGeneration uint64 // GUARDED_BY(mu)
+}
+
+func newLoader(addr string) *spiffeLoader {
+	return &spiffeLoader{addr: addr, ready: make(chan struct{})}
+}
+
+// start begins watching the Workload API. The watch lasts for the life
+// of the process.
+func (s *spiffeLoader) start() {
+	s.startOnce.Do(func() {
+		addr := s.addr
+		if addr == "" {
+			addr = SocketPath
+		}
+		var opts []workloadapi.ClientOption
+		if addr != "" {
+			opts = append(opts, workloadapi.WithAddr(addr))
+		}
+		ctx := context.Background()
+		client, err := workloadapi.New(ctx, opts...)
+		if err != nil {
+			s.mu.Lock()
+			s.err = fmt.Errorf("can't connect to SPIFFE Workload API: %v", err)
+			s.mu.Unlock()
+			close(s.ready)
+			return
+		}
+		go func() {
+			// This only returns once ctx is done, which is never. Errors
+			// are retried with backoff and reported to OnX509ContextWatchError.
+			_ = client.WatchX509Context(ctx, s)
+		}()
+	})
+}
+
+// OnX509ContextUpdate implements workloadapi.X509ContextWatcher.
+func (s *spiffeLoader) OnX509ContextUpdate(x509Ctx *workloadapi.X509Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	first := s.x509Ctx == nil && s.err == nil
+	s.x509Ctx = x509Ctx
+	s.err = nil
+	s.generation++
+	if first {
+		close(s.ready)
+	}
+}
+
+// OnX509ContextWatchError implements workloadapi.X509ContextWatcher. Errors
+// are only reported to callers until the first SVID arrives. After that
+// the last good SVID stays in use while the watch retries.
+func (s *spiffeLoader) OnX509ContextWatchError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.x509Ctx == nil {
+		s.err = err
+	}
+}
+
+// current waits for the first update and returns the latest X.509 context
+// and its generation.
+func (s *spiffeLoader) current(ctx context.Context) (*workloadapi.X509Context, uint64, error) {
+	s.start()
+	ctx, cancel := context.WithTimeout(ctx, FetchTimeout)
+	defer cancel()
+	select {
+	case <-s.ready:
+	case <-ctx.Done():
+		s.mu.Lock()
+		err := s.err
+		s.mu.Unlock()
+		if err == nil {
+			err = ctx.Err()
+		}
+		return nil, 0, fmt.Errorf("no SVID from SPIFFE Workload API: %v", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.x509Ctx == nil {
+		return nil, 0, s.err
+	}
+	return s.x509Ctx, s.generation, nil
+}
+
+func (s *spiffeLoader) loadPool(ctx context.Context) (*x509.CertPool, error) {
+	x509Ctx, _, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	for _, b := range x509Ctx.Bundles.Bundles() {
+		for _, c := range b.X509Authorities() {
+			pool.AddCert(c)
+		}
+	}
+	return pool, nil
+}
+
+// loadCertificate returns the default SVID as a tls.Certificate and
+// records its generation in loaded.
+func (s *spiffeLoader) loadCertificate(ctx context.Context, loaded *uint64) (tls.Certificate, error) {
+	x509Ctx, generation, err := s.current(ctx)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	svid := x509Ctx.DefaultSVID()
+	if svid == nil || len(svid.Certificates) == 0 {
+		return tls.Certificate{}, errors.New("SPIFFE Workload API returned no SVIDs")
+	}
+	cert := tls.Certificate{
+		PrivateKey: svid.PrivateKey,
+		Leaf:       svid.Certificates[0],
+	}
+	for _, c := range svid.Certificates {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+	s.mu.Lock()
+	*loaded = generation
+	s.mu.Unlock()
+	return cert, nil
+}
+
+func (s *spiffeLoader) LoadClientCA(ctx context.Context) (*x509.CertPool, error) {
+	return s.loadPool(ctx)
+}
+
+func (s *spiffeLoader) LoadRootCA(ctx context.Context) (*x509.CertPool, error) {
+	return s.loadPool(ctx)
+}
+
+func (s *spiffeLoader) LoadClientCertificate(ctx context.Context) (tls.Certificate, error) {
+	return s.loadCertificate(ctx, &s.clientGeneration)
+}
+
+func (s *spiffeLoader) LoadServerCertificate(ctx context.Context) (tls.Certificate, error) {
+	return s.loadCertificate(ctx, &s.serverGeneration)
+}
+
+// CertsRefreshed returns true if the Workload API has sent a new SVID or
+// bundle since the client or server certificate was last loaded.
+func (s *spiffeLoader) CertsRefreshed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return (s.clientGeneration != 0 && s.clientGeneration != s.generation) ||
+		(s.serverGeneration != 0 && s.serverGeneration != s.generation)
+}
+
+func init() {
+	if err := mtls.Register(loaderName, newLoader("")); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,246 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package spiffe
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spiffe/go-spiffe/v2/proto/spiffe/workload"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+	"github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+// fakeWorkloadAPI streams whatever is sent on updates to every
+// FetchX509SVID caller.
+type fakeWorkloadAPI struct {
+	workload.UnimplementedSpiffeWorkloadAPIServer
+	updates chan *workload.X509SVIDResponse
+}
+
+func (f *fakeWorkloadAPI) FetchX509SVID(_ *workload.X509SVIDRequest, stream workload.SpiffeWorkloadAPI_FetchX509SVIDServer) error {
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case resp := <-f.updates:
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func startFakeWorkloadAPI(t *testing.T) (string, *fakeWorkloadAPI) {
+	t.Helper()
+	// Unix socket paths are limited in length so avoid t.TempDir().
+	dir, err := os.MkdirTemp("", "spiffe")
+	testutil.FatalOnErr("MkdirTemp", err, t)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "agent.sock")
+	lis, err := net.Listen("unix", path)
+	testutil.FatalOnErr("Listen", err, t)
+	fake := &fakeWorkloadAPI{updates: make(chan *workload.X509SVIDResponse, 10)}
+	s := grpc.NewServer()
+	workload.RegisterSpiffeWorkloadAPIServer(s, fake)
+	go s.Serve(lis) //nolint:errcheck
+	t.Cleanup(s.Stop)
+	return "unix://" + path, fake
+}
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testutil.FatalOnErr("GenerateKey", err, t)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		URIs:                  []*url.URL{{Scheme: "spiffe", Host: "example.org"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	testutil.FatalOnErr("CreateCertificate", err, t)
+	cert, err := x509.ParseCertificate(der)
+	testutil.FatalOnErr("ParseCertificate", err, t)
+	return &testCA{cert: cert, key: key}
+}
+
+// svid issues an SVID for id which is also valid for localhost.
+func (ca *testCA) svid(t *testing.T, id string, serial int64) *workload.X509SVID {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testutil.FatalOnErr("GenerateKey", err, t)
+	u, err := url.Parse(id)
+	testutil.FatalOnErr("url.Parse", err, t)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		URIs:         []*url.URL{u},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	testutil.FatalOnErr("CreateCertificate", err, t)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	testutil.FatalOnErr("MarshalPKCS8PrivateKey", err, t)
+	return &workload.X509SVID{
+		SpiffeId:    id,
+		X509Svid:    der,
+		X509SvidKey: keyDER,
+		Bundle:      ca.cert.Raw,
+	}
+}
+
+func TestLoader(t *testing.T) {
+	ctx := context.Background()
+	addr, fake := startFakeWorkloadAPI(t)
+	ca := newTestCA(t)
+	const id = "spiffe://example.org/sansshell"
+
+	l := newLoader(addr)
+	fake.updates <- &workload.X509SVIDResponse{Svids: []*workload.X509SVID{ca.svid(t, id, 2)}}
+
+	pool, err := l.LoadRootCA(ctx)
+	testutil.FatalOnErr("LoadRootCA", err, t)
+	want := x509.NewCertPool()
+	want.AddCert(ca.cert)
+	if !pool.Equal(want) {
+		t.Error("root CA pool doesn't contain only the bundle")
+	}
+	if l.CertsRefreshed() {
+		t.Error("CertsRefreshed true before any certificates were loaded")
+	}
+	cert, err := l.LoadClientCertificate(ctx)
+	testutil.FatalOnErr("LoadClientCertificate", err, t)
+	if got := cert.Leaf.SerialNumber.Int64(); got != 2 {
+		t.Errorf("client certificate serial = %d, want 2", got)
+	}
+	if _, err := l.LoadServerCertificate(ctx); err != nil {
+		t.Fatalf("LoadServerCertificate: %v", err)
+	}
+	if l.CertsRefreshed() {
+		t.Error("CertsRefreshed true before rotation")
+	}
+
+	// Rotate the SVID and wait for the loader to notice.
+	fake.updates <- &workload.X509SVIDResponse{Svids: []*workload.X509SVID{ca.svid(t, id, 3)}}
+	deadline := time.Now().Add(10 * time.Second)
+	for !l.CertsRefreshed() {
+		if time.Now().After(deadline) {
+			t.Fatal("CertsRefreshed never became true after rotation")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cert, err = l.LoadClientCertificate(ctx)
+	testutil.FatalOnErr("LoadClientCertificate", err, t)
+	if got := cert.Leaf.SerialNumber.Int64(); got != 3 {
+		t.Errorf("rotated client certificate serial = %d, want 3", got)
+	}
+	// The server certificate hasn't been reloaded yet.
+	if !l.CertsRefreshed() {
+		t.Error("CertsRefreshed false while server certificate is stale")
+	}
+	if _, err := l.LoadServerCertificate(ctx); err != nil {
+		t.Fatalf("LoadServerCertificate: %v", err)
+	}
+	if l.CertsRefreshed() {
+		t.Error("CertsRefreshed true after reloading both certificates")
+	}
+}
+
+func TestLoaderUnavailable(t *testing.T) {
+	old := FetchTimeout
+	FetchTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { FetchTimeout = old })
+
+	l := newLoader("unix:///nonexistent/agent.sock")
+	if _, err := l.LoadClientCertificate(context.Background()); err == nil {
+		t.Error("LoadClientCertificate succeeded without a Workload API")
+	}
+}
+
+// TestSPIFFEIDInput checks that the SPIFFE ID of a peer using the loader's
+// credentials shows up in the authz input.
+func TestSPIFFEIDInput(t *testing.T) {
+	ctx := context.Background()
+	addr, fake := startFakeWorkloadAPI(t)
+	ca := newTestCA(t)
+	const id = "spiffe://example.org/sansshell"
+	fake.updates <- &workload.X509SVIDResponse{Svids: []*workload.X509SVID{ca.svid(t, id, 2)}}
+	l := newLoader(addr)
+
+	serverCert, err := l.LoadServerCertificate(ctx)
+	testutil.FatalOnErr("LoadServerCertificate", err, t)
+	clientCert, err := l.LoadClientCertificate(ctx)
+	testutil.FatalOnErr("LoadClientCertificate", err, t)
+	clientCAs, err := l.LoadClientCA(ctx)
+	testutil.FatalOnErr("LoadClientCA", err, t)
+	rootCAs, err := l.LoadRootCA(ctx)
+	testutil.FatalOnErr("LoadRootCA", err, t)
+
+	serverCreds := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	clientCreds := credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{clientCert},
+		RootCAs:      rootCAs,
+	})
+
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+	errc := make(chan error, 1)
+	go func() {
+		_, _, err := clientCreds.ClientHandshake(ctx, "localhost", clientConn)
+		errc <- err
+	}()
+	_, authInfo, err := serverCreds.ServerHandshake(serverConn)
+	testutil.FatalOnErr("ServerHandshake", err, t)
+	testutil.FatalOnErr("ClientHandshake", <-errc, t)
+
+	if got := rpcauth.CertInputFrom(authInfo).SPIFFEID; got != id {
+		t.Errorf("SPIFFEID = %q, want %q", got, id)
+	}
+}
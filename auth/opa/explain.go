@@ -0,0 +1,241 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package opa
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/topdown"
+
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+)
+
+// ruleName returns the name of the rule in the sansshell package that
+// query refers to, or "" if it isn't a simple reference to such a rule.
+func ruleName(query string) string {
+	ref, err := ast.ParseRef(query)
+	if err != nil {
+		return ""
+	}
+	prefix := sansshellPackage.Path
+	if len(ref) != len(prefix)+1 || !ref.HasPrefix(prefix) {
+		return ""
+	}
+	name, ok := ref[len(ref)-1].Value.(ast.String)
+	if !ok {
+		return ""
+	}
+	return string(name)
+}
+
+// ruleProgress tracks how far evaluation of one rule body got.
+type ruleProgress struct {
+	rule    *ast.Rule
+	matched bool
+	// passed is the number of expressions which held in the furthest
+	// evaluation and failed is the expression after them, if any.
+	passed int
+	failed *ast.Expr
+}
+
+// exprIndex returns the index in body of the source expression which
+// contains loc. The compiler may rewrite one source expression into
+// several, but they all point back into the original.
+func exprIndex(body ast.Body, loc *ast.Location) int {
+	if loc == nil {
+		return -1
+	}
+	for i, e := range body {
+		l := e.Location
+		if l != nil && loc.Offset >= l.Offset && loc.Offset < l.Offset+len(l.Text) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Explain implements rpcauth.AuthzExplainer. It evaluates input with
+// tracing and reports, for every body of the allow rule, how many
+// expressions held and which expression failed along with the input
+// fields it refers to.
+func (q *opaAuthzPolicy) Explain(ctx context.Context, input *rpcauth.RPCAuthInput) (*rpcauth.Explanation, error) {
+	var evalInput rego.EvalOption
+	if input == nil {
+		evalInput = rego.EvalInput(nil)
+	} else {
+		evalInput = rego.EvalInput(input)
+	}
+	tracer := topdown.NewBufferTracer()
+	results, err := q.query.Eval(ctx, evalInput,
+		rego.EvalQueryTracer(tracer),
+		// Evaluate every body so that we can see how close each came.
+		rego.EvalRuleIndexing(false),
+		rego.EvalEarlyExit(false),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("authz policy evaluation error: %w", err)
+	}
+	explanation := &rpcauth.Explanation{Allowed: results.Allowed()}
+	if !explanation.Allowed {
+		explanation.DenialHints, err = q.DenialHints(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if q.allowRule == "" {
+		return explanation, nil
+	}
+
+	var rules []*ruleProgress
+	byLine := make(map[int]*ruleProgress)
+	for _, r := range q.module.Rules {
+		if r.Default || r.Head.Ref().String() != q.allowRule || r.Location == nil {
+			continue
+		}
+		p := &ruleProgress{rule: r}
+		rules = append(rules, p)
+		byLine[r.Location.Row] = p
+	}
+	queries := make(map[uint64]*ruleProgress)
+	for _, evt := range *tracer {
+		if r, ok := evt.Node.(*ast.Rule); ok {
+			if r.Location == nil || r.Location.File != q.module.Package.Location.File {
+				continue
+			}
+			p := byLine[r.Location.Row]
+			if p == nil || r.Head.Ref().String() != q.allowRule {
+				continue
+			}
+			switch evt.Op {
+			case topdown.EnterOp:
+				queries[evt.QueryID] = p
+			case topdown.ExitOp:
+				p.matched = true
+			}
+			continue
+		}
+		p := queries[evt.QueryID]
+		expr, ok := evt.Node.(*ast.Expr)
+		if p == nil || !ok {
+			continue
+		}
+		i := exprIndex(p.rule.Body, expr.Location)
+		if i < 0 {
+			continue
+		}
+		if evt.Op == topdown.FailOp && (p.failed == nil || i > p.passed) {
+			// Every expression before this one held.
+			p.passed, p.failed = i, p.rule.Body[i]
+		}
+	}
+
+	var inputDoc any
+	if input != nil {
+		b, err := json.Marshal(input)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &inputDoc); err != nil {
+			return nil, err
+		}
+	}
+	for _, p := range rules {
+		r := &rpcauth.RuleExplanation{
+			Line:        p.rule.Location.Row,
+			Matched:     p.matched,
+			Expressions: len(p.rule.Body),
+		}
+		switch {
+		case p.matched:
+			r.Passed = r.Expressions
+		case p.failed != nil:
+			r.Passed = p.passed
+			r.Failed = explainExpr(p.failed, inputDoc)
+		}
+		explanation.Rules = append(explanation.Rules, r)
+	}
+	sort.SliceStable(explanation.Rules, func(i, j int) bool {
+		a, b := explanation.Rules[i], explanation.Rules[j]
+		if a.Matched != b.Matched {
+			return a.Matched
+		}
+		return a.Passed > b.Passed
+	})
+	return explanation, nil
+}
+
+// explainExpr describes expr along with the input fields it refers to.
+func explainExpr(expr *ast.Expr, inputDoc any) *rpcauth.ExpressionExplanation {
+	e := &rpcauth.ExpressionExplanation{
+		Line: expr.Location.Row,
+		Text: strings.TrimSpace(string(expr.Location.Text)),
+	}
+	seen := make(map[string]bool)
+	ast.WalkRefs(expr, func(ref ast.Ref) bool {
+		if !ref.HasPrefix(ast.InputRootRef) {
+			return false
+		}
+		ref = ref.GroundPrefix()
+		path := ref.String()
+		if seen[path] {
+			return false
+		}
+		seen[path] = true
+		f := &rpcauth.InputFieldExplanation{Path: path}
+		if v, ok := lookupInput(inputDoc, ref[1:]); ok {
+			f.Value, _ = json.Marshal(v)
+		}
+		e.InputFields = append(e.InputFields, f)
+		return false
+	})
+	return e
+}
+
+// lookupInput returns the value at path in the input document.
+func lookupInput(doc any, path ast.Ref) (any, bool) {
+	for _, t := range path {
+		switch v := doc.(type) {
+		case map[string]any:
+			key, ok := t.Value.(ast.String)
+			if !ok {
+				return nil, false
+			}
+			if doc, ok = v[string(key)]; !ok {
+				return nil, false
+			}
+		case []any:
+			n, ok := t.Value.(ast.Number)
+			if !ok {
+				return nil, false
+			}
+			i, ok := n.Int()
+			if !ok || i < 0 || i >= len(v) {
+				return nil, false
+			}
+			doc = v[i]
+		default:
+			return nil, false
+		}
+	}
+	return doc, doc != nil
+}
@@ -0,0 +1,132 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package opa
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+	"github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+func TestExplain(t *testing.T) {
+	ctx := context.Background()
+	policy, err := NewOpaAuthzPolicy(ctx, `
+package sansshell.authz
+
+default allow = false
+
+allow {
+  input.method = "/LocalFile.LocalFile/Read"
+  input.message.file.filename = "/etc/hosts"
+}
+
+allow {
+  input.method = "/LocalFile.LocalFile/Read"
+  input.peer.principal.groups[_] = "admin"
+  not input.message.file.filename = "/etc/shadow"
+}
+
+allow {
+  x := input.peer.principal.id
+  startswith(x, "svc-")
+}
+
+denial_hints[msg] {
+  not allow
+  msg := "ask an admin"
+}
+`, WithDenialHintsQuery("data.sansshell.authz.denial_hints"))
+	testutil.FatalOnErr("NewOpaAuthzPolicy", err, t)
+
+	input := func(file, id string) *rpcauth.RPCAuthInput {
+		return &rpcauth.RPCAuthInput{
+			Method:  "/LocalFile.LocalFile/Read",
+			Message: []byte(`{"file":{"filename":"` + file + `"}}`),
+			Peer: &rpcauth.PeerAuthInput{
+				Principal: &rpcauth.PrincipalAuthInput{ID: id, Groups: []string{"admin"}},
+			},
+		}
+	}
+
+	t.Run("denied", func(t *testing.T) {
+		e, err := rpcauth.Explain(ctx, policy, input("/etc/shadow", "alice"))
+		testutil.FatalOnErr("Explain", err, t)
+		if e.Allowed {
+			t.Error("explanation says the request was allowed")
+		}
+		if strings.Join(e.DenialHints, ",") != "ask an admin" {
+			t.Errorf("got hints %v", e.DenialHints)
+		}
+		var lines []int
+		for _, r := range e.Rules {
+			lines = append(lines, r.Line)
+			if r.Matched || r.Failed == nil {
+				t.Errorf("rule at line %d unexpectedly matched", r.Line)
+			}
+		}
+		// The admin rule came closest, then the /etc/hosts rule which
+		// got as far as the svc- rule but is earlier in the policy.
+		if len(lines) != 3 || lines[0] != 11 || lines[1] != 6 || lines[2] != 17 {
+			t.Fatalf("got rules at lines %v, want [11 6 17]", lines)
+		}
+		closest := e.Rules[0]
+		if closest.Passed != 2 || closest.Expressions != 3 {
+			t.Errorf("closest rule passed %d of %d expressions, want 2 of 3", closest.Passed, closest.Expressions)
+		}
+		if closest.Failed.Line != 14 || closest.Failed.Text != `not input.message.file.filename = "/etc/shadow"` {
+			t.Errorf("closest rule failed at %+v", closest.Failed)
+		}
+		if f := closest.Failed.InputFields; len(f) != 1 || f[0].Path != "input.message.file.filename" || string(f[0].Value) != `"/etc/shadow"` {
+			t.Errorf("got input fields %+v", f)
+		}
+		if !strings.Contains(e.String(), "failed at line 14") {
+			t.Errorf("unexpected formatting:\n%s", e)
+		}
+	})
+
+	t.Run("allowed", func(t *testing.T) {
+		e, err := rpcauth.Explain(ctx, policy, input("/etc/hosts", "svc-foo"))
+		testutil.FatalOnErr("Explain", err, t)
+		if !e.Allowed || len(e.DenialHints) != 0 {
+			t.Errorf("got %+v, want allowed without hints", e)
+		}
+		for _, r := range e.Rules {
+			if !r.Matched || r.Passed != r.Expressions || r.Failed != nil {
+				t.Errorf("rule at line %d didn't match: %+v", r.Line, r)
+			}
+		}
+	})
+
+	t.Run("missing input", func(t *testing.T) {
+		e, err := rpcauth.Explain(ctx, policy, &rpcauth.RPCAuthInput{Method: "/LocalFile.LocalFile/Read"})
+		testutil.FatalOnErr("Explain", err, t)
+		f := e.Rules[0].Failed.InputFields
+		if len(f) != 1 || f[0].Value != nil {
+			t.Errorf("got input fields %+v, want one unset field", f)
+		}
+	})
+}
+
+func TestExplainUnsupported(t *testing.T) {
+	policy := rpcauth.NewMockAuthzPolicy(nil, nil)
+	if _, err := rpcauth.Explain(context.Background(), policy, &rpcauth.RPCAuthInput{}); err == nil {
+		t.Error("Explain unexpectedly succeeded for a policy that can't explain itself")
+	}
+}
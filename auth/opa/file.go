@@ -0,0 +1,134 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package opa
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+	"github.com/Snowflake-Labs/sansshell/telemetry/metrics"
+)
+
+// Metrics
+var (
+	policyReloadCounter = metrics.MetricDefinition{Name: "authz_policy_reload",
+		Description: "number of successful authz policy reloads"}
+	policyReloadFailureCounter = metrics.MetricDefinition{Name: "authz_policy_reload_failure",
+		Description: "number of authz policy reloads rejected because the new policy was invalid"}
+)
+
+// A FileAuthzPolicy is an OPA policy loaded from a file which can be
+// reloaded while in use. Reloading compiles the new policy first and only
+// swaps it in if that succeeds, so a bad edit keeps the old policy active.
+type FileAuthzPolicy struct {
+	*rpcauth.SwappableAuthzPolicy
+
+	path string
+	opts []Option
+
+	// mu serializes reloads and guards the fields below.
+	mu       sync.Mutex
+	contents []byte
+	// rejected and rejectErr remember the last invalid policy so that it
+	// is only reported once.
+	rejected  []byte
+	rejectErr error
+}
+
+// NewFileAuthzPolicy loads the policy in the file at path. Options are
+// applied on every load.
+func NewFileAuthzPolicy(ctx context.Context, path string, opts ...Option) (*FileAuthzPolicy, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read policy file: %v", err)
+	}
+	policy, err := NewOpaAuthzPolicy(ctx, string(contents), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &FileAuthzPolicy{
+		SwappableAuthzPolicy: rpcauth.NewSwappableAuthzPolicy(policy),
+		path:                 path,
+		opts:                 opts,
+		contents:             contents,
+	}, nil
+}
+
+// Reload re-reads the policy file and swaps in the new policy if it has
+// changed and is valid. Invalid policies are returned as errors, and are
+// logged and counted in the authz_policy_reload_failure metric the first
+// time they are seen.
+func (f *FileAuthzPolicy) Reload(ctx context.Context) error {
+	logger := logr.FromContextOrDiscard(ctx)
+	recorder := metrics.RecorderFromContextOrNoop(ctx)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	contents, err := os.ReadFile(f.path)
+	if err != nil {
+		err = fmt.Errorf("can't read policy file: %v", err)
+		logger.Error(err, "keeping current authz policy", "file", f.path)
+		recorder.CounterOrLog(ctx, policyReloadFailureCounter, 1)
+		return err
+	}
+	if bytes.Equal(contents, f.contents) {
+		return nil
+	}
+	if f.rejected != nil && bytes.Equal(contents, f.rejected) {
+		return f.rejectErr
+	}
+	policy, err := NewOpaAuthzPolicy(ctx, string(contents), f.opts...)
+	if err != nil {
+		logger.Error(err, "invalid authz policy, keeping current policy", "file", f.path)
+		recorder.CounterOrLog(ctx, policyReloadFailureCounter, 1)
+		f.rejected, f.rejectErr = contents, err
+		return err
+	}
+	f.Swap(policy)
+	f.contents = contents
+	logger.Info("reloaded authz policy", "file", f.path)
+	recorder.CounterOrLog(ctx, policyReloadCounter, 1)
+	return nil
+}
+
+// Watch re-reads the policy file every interval and reloads it when it
+// changes, until ctx is done. A missing file is ignored. Reload errors are
+// logged and counted by Reload and don't stop watching.
+func (f *FileAuthzPolicy) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := os.Stat(f.path); err != nil {
+				// The file may be in the middle of being replaced, so
+				// don't report it as a failed reload.
+				continue
+			}
+			_ = f.Reload(ctx)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package opa
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+	"github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+func policyAllowing(method string) string {
+	return `
+package sansshell.authz
+
+default allow = false
+
+allow {
+  input.method = "` + method + `"
+}
+`
+}
+
+func TestFileAuthzPolicyReload(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "policy.rego")
+	write := func(policy string) {
+		t.Helper()
+		testutil.FatalOnErr("WriteFile", os.WriteFile(path, []byte(policy), 0600), t)
+	}
+	allowed := func(p rpcauth.AuthzPolicy, method string) bool {
+		t.Helper()
+		ok, err := p.Eval(ctx, &rpcauth.RPCAuthInput{Method: method})
+		testutil.FatalOnErr("Eval", err, t)
+		return ok
+	}
+
+	write(policyAllowing("/Foo/Old"))
+	p, err := NewFileAuthzPolicy(ctx, path)
+	testutil.FatalOnErr("NewFileAuthzPolicy", err, t)
+	if !allowed(p, "/Foo/Old") || allowed(p, "/Foo/New") {
+		t.Fatal("initial policy not loaded")
+	}
+
+	// Reloading an unchanged file keeps the same policy.
+	current := p.Current()
+	testutil.FatalOnErr("Reload", p.Reload(ctx), t)
+	if p.Current() != current {
+		t.Error("unchanged policy was swapped")
+	}
+
+	for _, bad := range []string{"package sansshell.authz\nallow {", "package other\nallow = true"} {
+		write(bad)
+		if err := p.Reload(ctx); err == nil {
+			t.Errorf("Reload of %q succeeded", bad)
+		}
+		if !allowed(p, "/Foo/Old") {
+			t.Errorf("bad policy %q replaced the old one", bad)
+		}
+	}
+
+	write(policyAllowing("/Foo/New"))
+	testutil.FatalOnErr("Reload", p.Reload(ctx), t)
+	if allowed(p, "/Foo/Old") || !allowed(p, "/Foo/New") {
+		t.Error("new policy wasn't swapped in")
+	}
+
+	os.Remove(path)
+	if err := p.Reload(ctx); err == nil {
+		t.Error("Reload of missing file succeeded")
+	}
+	if !allowed(p, "/Foo/New") {
+		t.Error("missing file replaced the policy")
+	}
+
+	if _, err := NewFileAuthzPolicy(ctx, path); err == nil {
+		t.Error("NewFileAuthzPolicy succeeded for missing file")
+	}
+}
+
+func TestFileAuthzPolicyWatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	path := filepath.Join(t.TempDir(), "policy.rego")
+	testutil.FatalOnErr("WriteFile", os.WriteFile(path, []byte(policyAllowing("/Foo/Old")), 0600), t)
+	p, err := NewFileAuthzPolicy(ctx, path)
+	testutil.FatalOnErr("NewFileAuthzPolicy", err, t)
+	go p.Watch(ctx, 10*time.Millisecond)
+
+	testutil.FatalOnErr("WriteFile", os.WriteFile(path, []byte(policyAllowing("/Foo/Newer")), 0600), t)
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		ok, err := p.Eval(ctx, &rpcauth.RPCAuthInput{Method: "/Foo/Newer"})
+		testutil.FatalOnErr("Eval", err, t)
+		if ok {
+			return
+		}
+	}
+	t.Fatal("policy change wasn't picked up")
+}
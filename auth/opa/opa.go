@@ -54,6 +54,14 @@ type opaAuthzPolicy struct {
 	query            rego.PreparedEvalQuery
 	denialHintsQuery *rego.PreparedEvalQuery
 	b                *bytes.Buffer
+
+	// module and allowRule are used to explain decisions. allowRule is
+	// empty if the allow query isn't a rule in the policy.
+	module    *ast.Module
+	allowRule string
+
+	// usesHost is false if the policy can't look at input.host.
+	usesHost bool
 }
 
 type policyOptions struct {
@@ -149,9 +157,62 @@ func NewOpaAuthzPolicy(ctx context.Context, policy string, opts ...Option) (rpca
 		query:            prepared,
 		denialHintsQuery: denialHintsQuery,
 		b:                b,
+		module:           module,
+		allowRule:        ruleName(options.query),
+		usesHost:         usesHost(module, options.query, options.denialHintsQuery),
 	}, nil
 }
 
+// usesHost returns true if module, or any of queries, might read
+// input.host. Any use of input other than through a constant key, such as
+// input[k] or passing it whole to a function, counts as reading it.
+func usesHost(module *ast.Module, queries ...string) bool {
+	uses := false
+	var visit func(x any) bool
+	visit = func(x any) bool {
+		switch x := x.(type) {
+		case ast.Ref:
+			if x[0].Equal(ast.InputRootDocument) {
+				if len(x) < 2 {
+					uses = true
+				} else if key, ok := x[1].Value.(ast.String); !ok || key == "host" {
+					uses = true
+				}
+			} else {
+				ast.NewGenericVisitor(visit).Walk(x[0])
+			}
+			// The head has been dealt with, but the rest may refer to
+			// input too, as in data.hosts[input.host].
+			for _, t := range x[1:] {
+				ast.NewGenericVisitor(visit).Walk(t)
+			}
+			return true
+		case ast.Var:
+			if x.Equal(ast.InputRootDocument.Value) {
+				uses = true
+			}
+		}
+		return uses
+	}
+	ast.NewGenericVisitor(visit).Walk(module)
+	for _, q := range queries {
+		if q == "" {
+			continue
+		}
+		body, err := ast.ParseBody(q)
+		if err != nil {
+			return true
+		}
+		ast.NewGenericVisitor(visit).Walk(body)
+	}
+	return uses
+}
+
+// UsesHost implements rpcauth.HostAwarePolicy.
+func (q *opaAuthzPolicy) UsesHost() bool {
+	return q.usesHost
+}
+
 // Eval evaluates this policy using the provided input, returning 'true'
 // iff the evaulation was successful, and the operation represented by
 // `input` is permitted by the policy.
@@ -334,3 +334,58 @@ allow {
 		t.Error("didn't get error for empty policy")
 	}
 }
+
+func TestAuthzPolicyUsesHost(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name   string
+		policy string
+		opts   []Option
+		want   bool
+	}{
+		{
+			name:   "no host",
+			policy: "package sansshell.authz\nallow { input.method = \"/Foo\"; input.peer.principal.id = \"alice\" }",
+		},
+		{
+			name:   "host",
+			policy: "package sansshell.authz\nallow { input.host.net.address = \"10.0.0.1\" }",
+			want:   true,
+		},
+		{
+			name:   "host as an index",
+			policy: "package sansshell.authz\nhosts := {\"10.0.0.1\"}\nallow { hosts[input.host.net.address] }",
+			want:   true,
+		},
+		{
+			name:   "dynamic key",
+			policy: "package sansshell.authz\nallow { some k; input[k].net.address = \"10.0.0.1\" }",
+			want:   true,
+		},
+		{
+			name:   "whole input",
+			policy: "package sansshell.authz\nallow { x := input; x.method = \"/Foo\" }",
+			want:   true,
+		},
+		{
+			name:   "imported input",
+			policy: "package sansshell.authz\nimport input\nallow { input.method = \"/Foo\" }",
+			want:   true,
+		},
+		{
+			name:   "host in denial hints",
+			policy: "package sansshell.authz\nallow { input.method = \"/Foo\" }",
+			opts:   []Option{WithDenialHintsQuery("[input.host.net.address]")},
+			want:   true,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := NewOpaAuthzPolicy(ctx, tc.policy, tc.opts...)
+			testutil.FatalOnErr("NewOpaAuthzPolicy", err, t)
+			if got := policy.(rpcauth.HostAwarePolicy).UsesHost(); got != tc.want {
+				t.Errorf("UsesHost() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
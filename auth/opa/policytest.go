@@ -0,0 +1,225 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package opa
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+)
+
+// A PolicyTestCase describes a single request and the decision a policy is
+// expected to make for it. Test cases are stored as JSON, for example
+//
+//	{
+//	  "method": "/LocalFile.LocalFile/Read",
+//	  "type": "LocalFile.ReadActionRequest",
+//	  "request": {"file": {"filename": "/etc/hosts"}},
+//	  "peer": {"principal": {"id": "alice", "groups": ["sre"]}},
+//	  "expect": {"allowed": true}
+//	}
+type PolicyTestCase struct {
+	// Name identifies the case in results. It defaults to the name of the
+	// file the case was loaded from.
+	Name string `json:"name"`
+
+	// Method is the gRPC method being called, as '/Package.Service/Method'.
+	Method string `json:"method"`
+
+	// Type is the full name of the request message, such as
+	// 'LocalFile.ReadActionRequest'. It may be omitted if Request is empty.
+	Type string `json:"type"`
+
+	// Request is the request message in protojson.
+	Request json.RawMessage `json:"request"`
+
+	// Metadata is the incoming gRPC metadata for the request.
+	Metadata map[string][]string `json:"metadata"`
+
+	// Peer describes the caller, including any certificate information.
+	Peer *rpcauth.PeerAuthInput `json:"peer"`
+
+	// Host describes the host receiving the request.
+	Host *rpcauth.HostAuthInput `json:"host"`
+
+	// Approvers are any MPA approvers of the request.
+	Approvers []*rpcauth.PrincipalAuthInput `json:"approvers"`
+
+	// Environment describes the environment of the host, if any.
+	Environment *rpcauth.EnvironmentInput `json:"environment"`
+
+	// Extensions are passed through to the policy input as-is.
+	Extensions json.RawMessage `json:"extensions"`
+
+	// Expect is the expected decision.
+	Expect PolicyTestExpectation `json:"expect"`
+}
+
+// PolicyTestExpectation is the expected outcome of a PolicyTestCase.
+type PolicyTestExpectation struct {
+	// Allowed is whether the policy should permit the request.
+	Allowed bool `json:"allowed"`
+
+	// DenialHints, if set, must exactly match the policy's denial hints
+	// in any order. Leave unset to not check hints.
+	DenialHints []string `json:"denial_hints"`
+}
+
+// PolicyTestResult is the outcome of running a PolicyTestCase.
+type PolicyTestResult struct {
+	Case *PolicyTestCase
+
+	// Allowed and DenialHints are what the policy decided.
+	Allowed     bool
+	DenialHints []string
+
+	// Err is set if the case couldn't be evaluated.
+	Err error
+}
+
+// Passed returns true if the policy made the expected decision.
+func (r *PolicyTestResult) Passed() bool {
+	return r.Err == nil && len(r.Failures()) == 0
+}
+
+// Failures describes how the result differs from the expectation.
+func (r *PolicyTestResult) Failures() []string {
+	var out []string
+	if r.Err != nil {
+		out = append(out, r.Err.Error())
+		return out
+	}
+	if r.Allowed != r.Case.Expect.Allowed {
+		out = append(out, fmt.Sprintf("allowed = %v, want %v", r.Allowed, r.Case.Expect.Allowed))
+	}
+	if want := r.Case.Expect.DenialHints; want != nil {
+		got := append([]string{}, r.DenialHints...)
+		want = append([]string{}, want...)
+		sort.Strings(got)
+		sort.Strings(want)
+		if !reflect.DeepEqual(got, want) {
+			out = append(out, fmt.Sprintf("denial hints = %q, want %q", got, want))
+		}
+	}
+	return out
+}
+
+// LoadPolicyTestCases reads every *.json file in dir as a PolicyTestCase.
+// Cases are returned sorted by file name.
+func LoadPolicyTestCases(dir string) ([]*PolicyTestCase, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	var cases []*PolicyTestCase
+	for _, f := range files {
+		tc, err := LoadPolicyTestCase(f)
+		if err != nil {
+			return nil, err
+		}
+		cases = append(cases, tc)
+	}
+	return cases, nil
+}
+
+// LoadPolicyTestCase reads a single PolicyTestCase from the JSON file at path.
+func LoadPolicyTestCase(path string) (*PolicyTestCase, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tc := &PolicyTestCase{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(tc); err != nil {
+		return nil, fmt.Errorf("can't parse test case %s: %v", path, err)
+	}
+	if tc.Name == "" {
+		tc.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+	}
+	return tc, nil
+}
+
+// NewPolicyTestInput builds the policy input for a test case. It goes
+// through rpcauth.NewRPCAuthInput just like a server does so that the
+// request is rendered exactly as the policy would see it in production.
+func NewPolicyTestInput(ctx context.Context, tc *PolicyTestCase) (*rpcauth.RPCAuthInput, error) {
+	var req proto.Message
+	if tc.Type != "" {
+		mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(tc.Type))
+		if err != nil {
+			return nil, fmt.Errorf("unknown request type %q: %v", tc.Type, err)
+		}
+		req = mt.New().Interface()
+		if len(tc.Request) > 0 {
+			if err := protojson.Unmarshal(tc.Request, req); err != nil {
+				return nil, fmt.Errorf("can't parse request as %s: %v", tc.Type, err)
+			}
+		}
+	} else if len(tc.Request) > 0 {
+		return nil, fmt.Errorf("request given without a type")
+	}
+
+	if len(tc.Metadata) > 0 {
+		ctx = metadata.NewIncomingContext(ctx, metadata.MD(tc.Metadata).Copy())
+	}
+	ctx = rpcauth.AddPeerToContext(ctx, tc.Peer)
+	input, err := rpcauth.NewRPCAuthInput(ctx, tc.Method, req)
+	if err != nil {
+		return nil, err
+	}
+	input.Host = tc.Host
+	input.Approvers = tc.Approvers
+	input.Environment = tc.Environment
+	input.Extensions = tc.Extensions
+	return input, nil
+}
+
+// RunPolicyTests evaluates each case against policy.
+func RunPolicyTests(ctx context.Context, policy rpcauth.AuthzPolicy, cases []*PolicyTestCase) []*PolicyTestResult {
+	var results []*PolicyTestResult
+	for _, tc := range cases {
+		r := &PolicyTestResult{Case: tc}
+		results = append(results, r)
+		input, err := NewPolicyTestInput(ctx, tc)
+		if err != nil {
+			r.Err = err
+			continue
+		}
+		r.Allowed, r.Err = policy.Eval(ctx, input)
+		if r.Err != nil || r.Allowed {
+			continue
+		}
+		r.DenialHints, r.Err = policy.DenialHints(ctx, input)
+	}
+	return results
+}
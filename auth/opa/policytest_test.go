@@ -0,0 +1,162 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package opa
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+func TestRunPolicyTests(t *testing.T) {
+	ctx := context.Background()
+	policy, err := NewOpaAuthzPolicy(ctx, `
+package sansshell.authz
+
+allow {
+  input.method = "/Foo/Read"
+  input.type = "google.protobuf.StringValue"
+  input.message = "/etc/hosts"
+  input.peer.principal.groups[_] = "sre"
+}
+
+allow {
+  input.method = "/Foo/Write"
+  input.approvers[_].id = "bob"
+  input.metadata["sansshell-justification"][_] = "ticket"
+}
+
+denial_hints[msg] {
+  not allow
+  msg := "nope"
+}
+`, WithDenialHintsQuery("data.sansshell.authz.denial_hints"))
+	testutil.FatalOnErr("NewOpaAuthzPolicy", err, t)
+
+	dir := t.TempDir()
+	for name, contents := range map[string]string{
+		"read.json": `{
+  "method": "/Foo/Read",
+  "type": "google.protobuf.StringValue",
+  "request": "/etc/hosts",
+  "peer": {"principal": {"id": "alice", "groups": ["sre"]}},
+  "expect": {"allowed": true}
+}`,
+		"read-wrong-file.json": `{
+  "name": "wrong file",
+  "method": "/Foo/Read",
+  "type": "google.protobuf.StringValue",
+  "request": "/etc/shadow",
+  "peer": {"principal": {"id": "alice", "groups": ["sre"]}},
+  "expect": {"allowed": false, "denial_hints": ["nope"]}
+}`,
+		"write.json": `{
+  "method": "/Foo/Write",
+  "metadata": {"sansshell-justification": ["ticket"]},
+  "approvers": [{"id": "bob"}],
+  "expect": {"allowed": true}
+}`,
+		"write-bad-expectation.json": `{
+  "method": "/Foo/Write",
+  "approvers": [{"id": "bob"}],
+  "expect": {"allowed": true}
+}`,
+		"wrong-hints.json": `{
+  "method": "/Foo/Write",
+  "expect": {"allowed": false, "denial_hints": ["something else"]}
+}`,
+		"unknown-type.json": `{
+  "method": "/Foo/Read",
+  "type": "Foo.NoSuchMessage",
+  "expect": {"allowed": true}
+}`,
+		"ignored.txt": `not a test case`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases, err := LoadPolicyTestCases(dir)
+	testutil.FatalOnErr("LoadPolicyTestCases", err, t)
+	var names []string
+	for _, c := range cases {
+		names = append(names, c.Name)
+	}
+	if got, want := strings.Join(names, ","), "wrong file,read,unknown-type,write-bad-expectation,write,wrong-hints"; got != want {
+		t.Fatalf("loaded cases %s, want %s", got, want)
+	}
+
+	results := RunPolicyTests(ctx, policy, cases)
+	for _, r := range results {
+		var wantFailure string
+		switch r.Case.Name {
+		case "unknown-type":
+			wantFailure = "unknown request type"
+		case "write-bad-expectation":
+			wantFailure = "allowed = false, want true"
+		case "wrong-hints":
+			wantFailure = "denial hints"
+		}
+		if got := strings.Join(r.Failures(), "; "); !strings.Contains(got, wantFailure) || (wantFailure == "") != r.Passed() {
+			t.Errorf("%s: failures %q, want %q", r.Case.Name, got, wantFailure)
+		}
+	}
+}
+
+func TestLoadPolicyTestCasesErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		contents string
+	}{
+		{name: "bad json", contents: `{`},
+		{name: "unknown field", contents: `{"method": "/Foo/Read", "expected": {"allowed": true}}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "case.json"), []byte(tc.contents), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadPolicyTestCases(dir); err == nil {
+				t.Error("LoadPolicyTestCases unexpectedly succeeded")
+			}
+		})
+	}
+}
+
+func TestNewPolicyTestInputErrors(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name string
+		tc   *PolicyTestCase
+	}{
+		{name: "request without type", tc: &PolicyTestCase{Method: "/Foo/Read", Request: []byte(`"x"`)}},
+		{name: "request doesn't match type", tc: &PolicyTestCase{Method: "/Foo/Read", Type: "google.protobuf.StringValue", Request: []byte(`{"a": 1}`)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewPolicyTestInput(ctx, tc.tc); err == nil {
+				t.Error("NewPolicyTestInput unexpectedly succeeded")
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package rpcauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes a single authorization decision.
+type AuditRecord struct {
+	// Time is when the decision was made.
+	Time time.Time `json:"time"`
+
+	// Method is the gRPC method being authorized.
+	Method string `json:"method"`
+
+	// Allowed is true if the request was permitted.
+	Allowed bool `json:"allowed"`
+
+	// Error is set if the request was denied because a hook or the policy
+	// itself failed rather than by the policy's decision.
+	Error string `json:"error,omitempty"`
+
+	// DenialHints are the hints from the policy for denied requests.
+	DenialHints []string `json:"denial_hints,omitempty"`
+
+	// Justification is the justification passed along with the request, if any.
+	Justification string `json:"justification,omitempty"`
+
+	// Approvers are the MPA approvers of the request, if any.
+	Approvers []*PrincipalAuthInput `json:"approvers,omitempty"`
+
+	// Input is the policy input with sensitive fields redacted.
+	Input RPCAuthInput `json:"input"`
+}
+
+// An AuditSink receives a record of every authorization decision made
+// by an RPCAuthorizer.
+//
+// Write is called synchronously while authorizing, so implementations
+// should be fast and must be safe for concurrent use. A failed write is
+// logged but doesn't change the decision.
+type AuditSink interface {
+	Write(ctx context.Context, record *AuditRecord) error
+}
+
+// jsonAuditSink writes records as JSON lines to a writer.
+type jsonAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONAuditSink returns an AuditSink which writes one JSON object per
+// line to w.
+func NewJSONAuditSink(w io.Writer) AuditSink {
+	return &jsonAuditSink{w: w}
+}
+
+func (j *jsonAuditSink) Write(_ context.Context, record *AuditRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, err = j.w.Write(b)
+	return err
+}
+
+// FileAuditSinkOption configures a FileAuditSink.
+type FileAuditSinkOption func(*FileAuditSink)
+
+// WithAuditMaxSize rotates the audit log once writing a record would
+// make it larger than size bytes. Zero, the default, disables rotation.
+func WithAuditMaxSize(size int64) FileAuditSinkOption {
+	return func(f *FileAuditSink) {
+		f.maxSize = size
+	}
+}
+
+// WithAuditMaxBackups sets how many rotated audit logs to keep, named
+// <path>.1 (the newest) through <path>.<n>. Older logs are deleted.
+// The default is 5.
+func WithAuditMaxBackups(n int) FileAuditSinkOption {
+	return func(f *FileAuditSink) {
+		f.maxBackups = n
+	}
+}
+
+// FileAuditSink is an AuditSink which appends JSON lines to a file and
+// optionally rotates it by size.
+type FileAuditSink struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu     sync.Mutex
+	f      *os.File // nil if the file needs to be reopened
+	size   int64
+	closed bool
+}
+
+// NewFileAuditSink opens (or creates) the audit log at path for appending.
+func NewFileAuditSink(path string, opts ...FileAuditSinkOption) (*FileAuditSink, error) {
+	f := &FileAuditSink{
+		path:       path,
+		maxBackups: 5,
+	}
+	for _, o := range opts {
+		o(f)
+	}
+	if f.maxBackups < 0 {
+		return nil, fmt.Errorf("audit log max backups can't be negative: %d", f.maxBackups)
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *FileAuditSink) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("can't open audit log: %v", err)
+	}
+	st, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("can't stat audit log: %v", err)
+	}
+	f.f = file
+	f.size = st.Size()
+	return nil
+}
+
+func (f *FileAuditSink) backupName(n int) string {
+	return fmt.Sprintf("%s.%d", f.path, n)
+}
+
+// rotate moves the current log aside and opens a new one. f.mu must be held.
+func (f *FileAuditSink) rotate() error {
+	err := f.f.Close()
+	// Even if closing failed the file is unusable, so the next write
+	// will try to open it again.
+	f.f = nil
+	if err != nil {
+		return fmt.Errorf("can't close audit log: %v", err)
+	}
+	if f.maxBackups == 0 {
+		if err := os.Remove(f.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("can't remove audit log: %v", err)
+		}
+		return f.open()
+	}
+	if err := os.Remove(f.backupName(f.maxBackups)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("can't remove old audit log: %v", err)
+	}
+	for n := f.maxBackups - 1; n >= 1; n-- {
+		if err := os.Rename(f.backupName(n), f.backupName(n+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("can't rotate audit log: %v", err)
+		}
+	}
+	if err := os.Rename(f.path, f.backupName(1)); err != nil {
+		return fmt.Errorf("can't rotate audit log: %v", err)
+	}
+	return f.open()
+}
+
+// Write implements AuditSink.
+func (f *FileAuditSink) Write(_ context.Context, record *AuditRecord) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return fmt.Errorf("audit log %s is closed", f.path)
+	}
+	if f.f == nil {
+		if err := f.open(); err != nil {
+			return err
+		}
+	}
+	if f.maxSize > 0 && f.size > 0 && f.size+int64(len(b)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := f.f.Write(b)
+	f.size += int64(n)
+	return err
+}
+
+// Close closes the underlying file. Later writes fail.
+func (f *FileAuditSink) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed || f.f == nil {
+		f.closed = true
+		return nil
+	}
+	f.closed = true
+	err := f.f.Close()
+	f.f = nil
+	return err
+}
@@ -0,0 +1,200 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package rpcauth
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func readAuditRecords(t *testing.T, b []byte) []AuditRecord {
+	t.Helper()
+	var records []AuditRecord
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		var r AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("invalid audit line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func TestAuditSink(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	allow := true
+	policy := NewMockAuthzPolicy(
+		func(ctx context.Context, input *RPCAuthInput) (bool, error) {
+			if input.Method == "/Fail" {
+				return false, errors.New("policy broke")
+			}
+			return allow, nil
+		},
+		func(ctx context.Context, input *RPCAuthInput) ([]string, error) {
+			return []string{"not today"}, nil
+		},
+	)
+	approverHook := RPCAuthzHookFunc(func(ctx context.Context, input *RPCAuthInput) error {
+		if input.Method == "/Hook" {
+			return status.Error(codes.FailedPrecondition, "hook says no")
+		}
+		input.Approvers = []*PrincipalAuthInput{{ID: "approver"}}
+		return nil
+	})
+	authz := NewRPCAuthorizerWithAuditSink(policy, NewJSONAuditSink(&buf), approverHook)
+
+	input := func(method string) *RPCAuthInput {
+		return &RPCAuthInput{
+			Method: method,
+			Metadata: metadata.MD{
+				ReqJustKey:      []string{"ticket-1"},
+				"authorization": []string{"secret"},
+			},
+		}
+	}
+	if err := authz.Eval(ctx, input("/Allowed")); err != nil {
+		t.Fatal(err)
+	}
+	allow = false
+	if err := authz.Eval(ctx, input("/Denied")); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("got %v, want PermissionDenied", err)
+	}
+	if err := authz.Eval(ctx, input("/Fail")); status.Code(err) != codes.Internal {
+		t.Fatalf("got %v, want Internal", err)
+	}
+	if err := authz.Eval(ctx, input("/Hook")); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("got %v, want FailedPrecondition", err)
+	}
+
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("audit log contains redacted metadata: %v", buf.String())
+	}
+	records := readAuditRecords(t, buf.Bytes())
+	if len(records) != 4 {
+		t.Fatalf("got %d records, want 4:\n%v", len(records), buf.String())
+	}
+	for _, tc := range []struct {
+		method    string
+		allowed   bool
+		hints     []string
+		err       string
+		approvers bool
+	}{
+		{method: "/Allowed", allowed: true, approvers: true},
+		{method: "/Denied", hints: []string{"not today"}, approvers: true},
+		{method: "/Fail", err: "policy broke", approvers: true},
+		{method: "/Hook", err: "hook says no"},
+	} {
+		var r AuditRecord
+		for _, rec := range records {
+			if rec.Method == tc.method {
+				r = rec
+			}
+		}
+		if r.Method != tc.method || r.Input.Method != tc.method {
+			t.Errorf("%s: missing record", tc.method)
+			continue
+		}
+		if r.Allowed != tc.allowed {
+			t.Errorf("%s: allowed = %v, want %v", tc.method, r.Allowed, tc.allowed)
+		}
+		if strings.Join(r.DenialHints, ",") != strings.Join(tc.hints, ",") {
+			t.Errorf("%s: hints = %v, want %v", tc.method, r.DenialHints, tc.hints)
+		}
+		if !strings.Contains(r.Error, tc.err) || (tc.err == "") != (r.Error == "") {
+			t.Errorf("%s: error = %q, want %q", tc.method, r.Error, tc.err)
+		}
+		if r.Justification != "ticket-1" {
+			t.Errorf("%s: justification = %q", tc.method, r.Justification)
+		}
+		if gotApprovers := len(r.Approvers) == 1 && r.Approvers[0].ID == "approver"; gotApprovers != tc.approvers {
+			t.Errorf("%s: approvers = %+v", tc.method, r.Approvers)
+		}
+		if r.Time.IsZero() {
+			t.Errorf("%s: missing time", tc.method)
+		}
+	}
+}
+
+func TestFileAuditSinkRotation(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "audit.log")
+	record := &AuditRecord{Method: "/Foo"}
+	line, err := json.Marshal(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Room for two records per file.
+	sink, err := NewFileAuditSink(path, WithAuditMaxSize(int64(2*(len(line)+1))), WithAuditMaxBackups(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 7; i++ {
+		if err := sink.Write(ctx, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(ctx, record); err == nil {
+		t.Error("write after close unexpectedly succeeded")
+	}
+
+	for file, want := range map[string]int{path: 1, path + ".1": 2, path + ".2": 2} {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(readAuditRecords(t, b)); got != want {
+			t.Errorf("%s: got %d records, want %d", file, got, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only 2 backups, got %v", err)
+	}
+
+	// Reopening appends to the existing log.
+	sink, err = NewFileAuditSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+	sink.Close()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(readAuditRecords(t, b)); got != 2 {
+		t.Errorf("got %d records after reopening, want 2", got)
+	}
+}
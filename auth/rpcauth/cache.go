@@ -0,0 +1,313 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package rpcauth
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/Snowflake-Labs/sansshell/telemetry/metrics"
+)
+
+// Metrics
+var (
+	authzCacheHitCounter = metrics.MetricDefinition{Name: "authz_cache_hit",
+		Description: "number of authz decisions answered from the decision cache"}
+	authzCacheMissCounter = metrics.MetricDefinition{Name: "authz_cache_miss",
+		Description: "number of authz decisions which had to be evaluated by the policy"}
+)
+
+const (
+	// DefaultCacheTTL is how long decisions are cached if WithCacheTTL
+	// isn't used.
+	DefaultCacheTTL = 10 * time.Second
+	// DefaultCacheMaxEntries is how many decisions are cached if
+	// WithCacheMaxEntries isn't used.
+	DefaultCacheMaxEntries = 10000
+)
+
+// A CachingAuthzPolicy is an AuthzPolicy which remembers the decisions of
+// another policy for a short time. This avoids re-evaluating identical
+// input, such as when the proxy fans a request out to many targets or
+// when a stream sends many messages.
+//
+// Decisions are keyed on a hash of the input the policy sees: method,
+// message, metadata, peer, approvers, rejection, environment and
+// extensions. As hooks run before the policy, anything they add to the
+// input (such as MPA approvers fetched from a target) is part of the key
+// too.
+//
+// The host is left out so that one decision is shared by every target of
+// a proxied request. That's only safe if the policy never looks at
+// input.host, so unless the wrapped policy is a HostAwarePolicy which says
+// it doesn't, the host is kept in the key and each host gets its own
+// decision.
+//
+// Evaluation errors are never cached, and neither is any input which
+// can't be hashed. If the wrapped policy has a Generation method, such as
+// SwappableAuthzPolicy, cached decisions are discarded whenever the
+// generation changes so that a reloaded policy takes effect immediately.
+type CachingAuthzPolicy struct {
+	policy     AuthzPolicy
+	ttl        time.Duration
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	// lru holds *cacheEntry, most recently used first.
+	lru *list.List
+}
+
+type cacheEntry struct {
+	key        [sha256.Size]byte
+	generation uint64
+	expires    time.Time
+	allowed    bool
+	// hints are only valid if hasHints is set as they're fetched lazily.
+	hints    []string
+	hasHints bool
+}
+
+// CacheOption configures a CachingAuthzPolicy.
+type CacheOption func(*CachingAuthzPolicy)
+
+// WithCacheTTL sets how long a decision is remembered.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(c *CachingAuthzPolicy) {
+		c.ttl = ttl
+	}
+}
+
+// WithCacheMaxEntries bounds the number of decisions remembered. Once full
+// the least recently used decision is evicted.
+func WithCacheMaxEntries(n int) CacheOption {
+	return func(c *CachingAuthzPolicy) {
+		c.maxEntries = n
+	}
+}
+
+// A HostAwarePolicy is an AuthzPolicy which can tell whether its decisions
+// might depend on input.host.
+type HostAwarePolicy interface {
+	// UsesHost returns false only if the policy never looks at input.host.
+	UsesHost() bool
+}
+
+// policyUsesHost returns true unless policy is known to ignore input.host.
+func policyUsesHost(policy AuthzPolicy) bool {
+	h, ok := policy.(HostAwarePolicy)
+	return !ok || h.UsesHost()
+}
+
+// NewCachingAuthzPolicy returns a CachingAuthzPolicy caching the decisions
+// of policy.
+func NewCachingAuthzPolicy(policy AuthzPolicy, opts ...CacheOption) *CachingAuthzPolicy {
+	c := &CachingAuthzPolicy{
+		policy:     policy,
+		ttl:        DefaultCacheTTL,
+		maxEntries: DefaultCacheMaxEntries,
+		now:        time.Now,
+		entries:    make(map[[sha256.Size]byte]*list.Element),
+		lru:        list.New(),
+	}
+	for _, o := range opts {
+		o(c)
+	}
+	return c
+}
+
+// Policy returns the policy whose decisions are cached.
+func (c *CachingAuthzPolicy) Policy() AuthzPolicy {
+	return c.policy
+}
+
+// cacheKeyInput is the subset of RPCAuthInput which the policy sees. It's
+// a separate type so that host can be dropped without mutating the input.
+type cacheKeyInput struct {
+	Method      string                `json:"method"`
+	Message     json.RawMessage       `json:"message"`
+	MessageType string                `json:"type"`
+	Metadata    map[string][]string   `json:"metadata"`
+	Peer        *PeerAuthInput        `json:"peer"`
+	Host        *HostAuthInput        `json:"host"`
+	Approvers   []*PrincipalAuthInput `json:"approvers"`
+	Rejection   *RejectionAuthInput   `json:"rejection"`
+	Environment *EnvironmentInput     `json:"environment"`
+	Extensions  json.RawMessage       `json:"extensions"`
+}
+
+// key returns the cache key for input, or false if it can't be cached.
+func (c *CachingAuthzPolicy) key(input *RPCAuthInput) ([sha256.Size]byte, bool) {
+	if input == nil {
+		return [sha256.Size]byte{}, false
+	}
+	k := cacheKeyInput{
+		Method:      input.Method,
+		Message:     input.Message,
+		MessageType: input.MessageType,
+		Metadata:    input.Metadata,
+		Peer:        input.Peer,
+		Host:        input.Host,
+		Approvers:   input.Approvers,
+		Rejection:   input.Rejection,
+		Environment: input.Environment,
+		Extensions:  input.Extensions,
+	}
+	if !policyUsesHost(c.policy) {
+		k.Host = nil
+	}
+	// encoding/json sorts map keys and compacts raw messages, so equal
+	// input always produces the same bytes.
+	b, err := json.Marshal(k)
+	if err != nil {
+		return [sha256.Size]byte{}, false
+	}
+	return sha256.Sum256(b), true
+}
+
+func (c *CachingAuthzPolicy) generation() uint64 {
+	if g, ok := c.policy.(interface{ Generation() uint64 }); ok {
+		return g.Generation()
+	}
+	return 0
+}
+
+// lookup returns a copy of the live entry for key, if there is one.
+func (c *CachingAuthzPolicy) lookup(key [sha256.Size]byte, generation uint64) (cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[key]
+	if !ok {
+		return cacheEntry{}, false
+	}
+	e := elem.Value.(*cacheEntry)
+	if e.generation != generation || !c.now().Before(e.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		return cacheEntry{}, false
+	}
+	c.lru.MoveToFront(elem)
+	return *e, true
+}
+
+// store records a decision, along with hints if hasHints is set.
+func (c *CachingAuthzPolicy) store(key [sha256.Size]byte, generation uint64, allowed bool, hints []string, hasHints bool) {
+	if c.ttl <= 0 || c.maxEntries <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		e := elem.Value.(*cacheEntry)
+		if e.generation == generation && e.allowed == allowed {
+			// Just fill in the hints without extending the lifetime.
+			if hasHints {
+				e.hints, e.hasHints = hints, true
+			}
+			return
+		}
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+	}
+	for c.lru.Len() >= c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{
+		key:        key,
+		generation: generation,
+		expires:    c.now().Add(c.ttl),
+		allowed:    allowed,
+		hints:      hints,
+		hasHints:   hasHints,
+	})
+}
+
+// Len returns the number of cached decisions, including any which have
+// expired but not yet been evicted.
+func (c *CachingAuthzPolicy) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Purge discards all cached decisions.
+func (c *CachingAuthzPolicy) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[[sha256.Size]byte]*list.Element)
+	c.lru.Init()
+}
+
+func (c *CachingAuthzPolicy) recordLookup(ctx context.Context, input *RPCAuthInput, hit bool) {
+	metric := authzCacheMissCounter
+	if hit {
+		metric = authzCacheHitCounter
+	}
+	metrics.RecorderFromContextOrNoop(ctx).CounterOrLog(ctx, metric, 1, attribute.String("method", input.Method))
+}
+
+// Eval implements AuthzPolicy.
+func (c *CachingAuthzPolicy) Eval(ctx context.Context, input *RPCAuthInput) (bool, error) {
+	// The generation is read first so that if the policy is swapped while
+	// computing the key, such as for one which uses input.host, the
+	// decision is stored as stale.
+	generation := c.generation()
+	key, ok := c.key(input)
+	if !ok {
+		return c.policy.Eval(ctx, input)
+	}
+	if e, ok := c.lookup(key, generation); ok {
+		c.recordLookup(ctx, input, true)
+		return e.allowed, nil
+	}
+	c.recordLookup(ctx, input, false)
+	allowed, err := c.policy.Eval(ctx, input)
+	if err != nil {
+		return false, err
+	}
+	c.store(key, generation, allowed, nil, false)
+	return allowed, nil
+}
+
+// DenialHints implements AuthzPolicy. Hints are cached alongside the
+// decision they explain.
+func (c *CachingAuthzPolicy) DenialHints(ctx context.Context, input *RPCAuthInput) ([]string, error) {
+	generation := c.generation()
+	key, ok := c.key(input)
+	if !ok {
+		return c.policy.DenialHints(ctx, input)
+	}
+	e, cached := c.lookup(key, generation)
+	if cached && e.hasHints {
+		return e.hints, nil
+	}
+	hints, err := c.policy.DenialHints(ctx, input)
+	if err != nil || !cached {
+		return hints, err
+	}
+	c.store(key, generation, e.allowed, hints, true)
+	return hints, nil
+}
@@ -0,0 +1,214 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package rpcauth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/Snowflake-Labs/sansshell/telemetry/metrics"
+)
+
+// countingRecorder counts calls to CounterOrLog by metric name.
+type countingRecorder struct {
+	metrics.MetricsRecorder
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (c *countingRecorder) CounterOrLog(_ context.Context, m metrics.MetricDefinition, value int64, _ ...attribute.KeyValue) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[m.Name] += value
+}
+
+func (c *countingRecorder) GaugeOrLog(context.Context, metrics.MetricDefinition, metric.Int64Callback, ...attribute.KeyValue) {
+}
+
+func TestCachingAuthzPolicy(t *testing.T) {
+	recorder := &countingRecorder{counts: map[string]int64{}}
+	ctx := metrics.NewContextWithRecorder(context.Background(), recorder)
+
+	evals, hintEvals := 0, 0
+	inner := NewMockAuthzPolicy(
+		func(ctx context.Context, input *RPCAuthInput) (bool, error) {
+			evals++
+			if input.Method == "/Fail" {
+				return false, errors.New("broken")
+			}
+			return input.Method == "/Allowed", nil
+		},
+		func(ctx context.Context, input *RPCAuthInput) ([]string, error) {
+			hintEvals++
+			return []string{"no"}, nil
+		},
+	)
+	swappable := NewSwappableAuthzPolicy(inner)
+	now := time.Unix(1000, 0)
+	cache := NewCachingAuthzPolicy(swappable, WithCacheTTL(time.Minute), WithCacheMaxEntries(2))
+	cache.now = func() time.Time { return now }
+
+	input := func(method, host string) *RPCAuthInput {
+		in := &RPCAuthInput{
+			Method:   method,
+			Metadata: map[string][]string{"b": {"2"}, "a": {"1"}},
+			Peer:     &PeerAuthInput{Principal: &PrincipalAuthInput{ID: "alice"}},
+		}
+		if host != "" {
+			in.Host = &HostAuthInput{Net: &NetAuthInput{Address: host}}
+		}
+		return in
+	}
+	eval := func(in *RPCAuthInput, want bool) {
+		t.Helper()
+		got, err := cache.Eval(ctx, in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("%s: got %v, want %v", in.Method, got, want)
+		}
+	}
+	expectEvals := func(want int) {
+		t.Helper()
+		if evals != want {
+			t.Fatalf("policy evaluated %d times, want %d", evals, want)
+		}
+	}
+
+	eval(input("/Allowed", "h1"), true)
+	eval(input("/Allowed", "h1"), true)
+	expectEvals(1)
+
+	// Host is part of the key unless the policy says it doesn't use it.
+	eval(input("/Allowed", "h2"), true)
+	expectEvals(2)
+
+	// Denial hints are cached along with the decision.
+	for i := 0; i < 2; i++ {
+		eval(input("/Denied", ""), false)
+		hints, err := cache.DenialHints(ctx, input("/Denied", ""))
+		if err != nil || len(hints) != 1 || hints[0] != "no" {
+			t.Fatalf("got hints %v, %v", hints, err)
+		}
+	}
+	expectEvals(3)
+	if hintEvals != 1 {
+		t.Errorf("hints evaluated %d times, want 1", hintEvals)
+	}
+
+	// The cache is bounded, so the least recently used entry (h1) was evicted.
+	if got := cache.Len(); got != 2 {
+		t.Errorf("cache has %d entries, want 2", got)
+	}
+	eval(input("/Allowed", "h2"), true)
+	expectEvals(3)
+	eval(input("/Allowed", "h1"), true)
+	expectEvals(4)
+
+	// Errors aren't cached.
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Eval(ctx, input("/Fail", "")); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	expectEvals(6)
+
+	// Entries expire.
+	now = now.Add(time.Minute)
+	eval(input("/Allowed", "h1"), true)
+	expectEvals(7)
+
+	// Swapping the policy invalidates the cache.
+	swappable.Swap(inner)
+	eval(input("/Allowed", "h1"), true)
+	expectEvals(8)
+
+	if got, want := recorder.counts[authzCacheHitCounter.Name], int64(3); got != want {
+		t.Errorf("got %d hits, want %d", got, want)
+	}
+	if got, want := recorder.counts[authzCacheMissCounter.Name], int64(8); got != want {
+		t.Errorf("got %d misses, want %d", got, want)
+	}
+}
+
+// hostAwarePolicy is an AuthzPolicy which says whether it uses input.host.
+type hostAwarePolicy struct {
+	AuthzPolicy
+	usesHost bool
+}
+
+func (h hostAwarePolicy) UsesHost() bool { return h.usesHost }
+
+func TestCachingAuthzPolicyHost(t *testing.T) {
+	ctx := context.Background()
+	evals := 0
+	inner := NewMockAuthzPolicy(
+		func(ctx context.Context, input *RPCAuthInput) (bool, error) {
+			evals++
+			return true, nil
+		}, nil)
+	swappable := NewSwappableAuthzPolicy(hostAwarePolicy{inner, false})
+	cache := NewCachingAuthzPolicy(swappable)
+
+	// A policy which never looks at the host shares its decisions between
+	// hosts.
+	for _, host := range []string{"h1", "h2", "h3"} {
+		in := &RPCAuthInput{Method: "/Foo", Host: &HostAuthInput{Net: &NetAuthInput{Address: host}}}
+		if _, err := cache.Eval(ctx, in); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if evals != 1 {
+		t.Errorf("policy evaluated %d times, want 1", evals)
+	}
+
+	// Anything else the policy sees is still part of the key.
+	for _, in := range []*RPCAuthInput{
+		{Method: "/Foo", Approvers: []*PrincipalAuthInput{{ID: "bob"}}},
+		{Method: "/Foo", Extensions: []byte(`{"a":1}`)},
+		{Method: "/Foo", Environment: &EnvironmentInput{NonHostPolicyCheck: true}},
+		{Method: "/Foo", Metadata: map[string][]string{"a": {"1"}}},
+	} {
+		if _, err := cache.Eval(ctx, in); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if evals != 5 {
+		t.Errorf("policy evaluated %d times, want 5", evals)
+	}
+
+	// One which does gets a decision per host.
+	swappable.Swap(hostAwarePolicy{inner, true})
+	for i := 0; i < 2; i++ {
+		for _, host := range []string{"h1", "h2"} {
+			in := &RPCAuthInput{Method: "/Foo", Host: &HostAuthInput{Net: &NetAuthInput{Address: host}}}
+			if _, err := cache.Eval(ctx, in); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if evals != 7 {
+		t.Errorf("policy evaluated %d times, want 7", evals)
+	}
+}
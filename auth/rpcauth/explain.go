@@ -0,0 +1,150 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package rpcauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// An Explanation describes how a policy reached a decision, so that a
+// caller can understand a denial without reading the policy.
+type Explanation struct {
+	// Allowed is the decision the policy made.
+	Allowed bool `json:"allowed"`
+
+	// Rules describes each rule which could have allowed the request,
+	// starting with the ones which came closest.
+	Rules []*RuleExplanation `json:"rules"`
+
+	// DenialHints are the policy's denial hints, if the request was denied.
+	DenialHints []string `json:"denial_hints,omitempty"`
+}
+
+// A RuleExplanation describes the evaluation of a single rule body.
+type RuleExplanation struct {
+	// Line is where the rule starts in the policy.
+	Line int `json:"line"`
+
+	// Matched is true if every expression in the body held.
+	Matched bool `json:"matched"`
+
+	// Expressions is the number of expressions in the body and Passed how
+	// many of them held before evaluation stopped.
+	Expressions int `json:"expressions"`
+	Passed      int `json:"passed"`
+
+	// Failed is the expression which stopped evaluation, if the rule didn't
+	// match.
+	Failed *ExpressionExplanation `json:"failed,omitempty"`
+}
+
+// An ExpressionExplanation describes a single expression in a rule.
+type ExpressionExplanation struct {
+	// Line is where the expression is in the policy.
+	Line int `json:"line"`
+
+	// Text is the expression as written in the policy.
+	Text string `json:"text"`
+
+	// InputFields are the parts of the input the expression refers to.
+	InputFields []*InputFieldExplanation `json:"input_fields,omitempty"`
+}
+
+// An InputFieldExplanation is a field of the policy input, such as
+// input.message.filename, along with its value.
+type InputFieldExplanation struct {
+	Path string `json:"path"`
+
+	// Value is the JSON value of the field, or empty if the field isn't
+	// set in the input.
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// An AuthzExplainer can explain its authz decisions.
+type AuthzExplainer interface {
+	// Explain evaluates input and describes how the decision was reached.
+	Explain(ctx context.Context, input *RPCAuthInput) (*Explanation, error)
+}
+
+// Explain asks policy to explain its decision for input. It returns an
+// Unimplemented error if the policy can't explain itself.
+func Explain(ctx context.Context, policy AuthzPolicy, input *RPCAuthInput) (*Explanation, error) {
+	e, ok := policy.(AuthzExplainer)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "authz policy of type %T can't explain its decisions", policy)
+	}
+	return e.Explain(ctx, input)
+}
+
+// Explain implements AuthzExplainer if the current policy does.
+func (s *SwappableAuthzPolicy) Explain(ctx context.Context, input *RPCAuthInput) (*Explanation, error) {
+	return Explain(ctx, s.Current(), input)
+}
+
+// Explain implements AuthzExplainer if the wrapped policy does. Explanations
+// are never cached.
+func (c *CachingAuthzPolicy) Explain(ctx context.Context, input *RPCAuthInput) (*Explanation, error) {
+	return Explain(ctx, c.policy, input)
+}
+
+// Explain describes the policy decision for input. Unlike Eval it doesn't
+// run any hooks, so input should already have been through Eval.
+func (g *rpcAuthorizerImpl) Explain(ctx context.Context, input *RPCAuthInput) (*Explanation, error) {
+	return Explain(ctx, g.policy, input)
+}
+
+// String formats the explanation for people to read.
+func (e *Explanation) String() string {
+	var b strings.Builder
+	if e.Allowed {
+		b.WriteString("allowed")
+	} else {
+		b.WriteString("denied")
+	}
+	if len(e.DenialHints) > 0 {
+		fmt.Fprintf(&b, ": %s", strings.Join(e.DenialHints, ", "))
+	}
+	b.WriteString("\n")
+	if len(e.Rules) == 0 {
+		b.WriteString("  no rules could allow this request\n")
+	}
+	for _, r := range e.Rules {
+		if r.Matched {
+			fmt.Fprintf(&b, "  rule at line %d matched\n", r.Line)
+			continue
+		}
+		fmt.Fprintf(&b, "  rule at line %d: %d of %d expressions held\n", r.Line, r.Passed, r.Expressions)
+		if r.Failed == nil {
+			continue
+		}
+		fmt.Fprintf(&b, "    failed at line %d: %s\n", r.Failed.Line, r.Failed.Text)
+		for _, f := range r.Failed.InputFields {
+			v := "<unset>"
+			if len(f.Value) > 0 {
+				v = string(f.Value)
+			}
+			fmt.Fprintf(&b, "      %s = %s\n", f.Path, v)
+		}
+	}
+	return b.String()
+}
@@ -65,6 +65,14 @@ func (c *conditionalHook) Hook(ctx context.Context, input *RPCAuthInput) error {
 	return nil
 }
 
+// Allowed passes through to the wrapped hook if it's an RPCAuthzAllowedHook.
+func (c *conditionalHook) Allowed(ctx context.Context, input *RPCAuthInput) error {
+	if allowed, ok := c.hook.(RPCAuthzAllowedHook); ok && c.predicate(input) {
+		return allowed.Allowed(ctx, input)
+	}
+	return nil
+}
+
 // HostNetHook returns an RPCAuthzHook that sets host networking information.
 func HostNetHook(addr net.Addr) RPCAuthzHook {
 	return RPCAuthzHookFunc(func(_ context.Context, input *RPCAuthInput) error {
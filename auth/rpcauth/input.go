@@ -56,6 +56,10 @@ type RPCAuthInput struct {
 	// Information about approvers when using multi-party authentication.
 	Approvers []*PrincipalAuthInput `json:"approvers"`
 
+	// Information about the rejection of the request when using multi-party
+	// authentication, if it was rejected. Rejected requests have no approvers.
+	Rejection *RejectionAuthInput `json:"rejection"`
+
 	// Information about the environment in which the policy evaluation is
 	// happening.
 	Environment *EnvironmentInput `json:"environment"`
@@ -159,6 +163,16 @@ type PrincipalAuthInput struct {
 	Groups []string `json:"groups"`
 }
 
+// RejectionAuthInput contains policy-relevant information about the rejection
+// of a multi-party authentication request.
+type RejectionAuthInput struct {
+	// The principal that rejected the request.
+	Rejecter *PrincipalAuthInput `json:"rejecter"`
+
+	// The reason given for the rejection.
+	Reason string `json:"reason"`
+}
+
 // NewRPCAuthInput creates RpcAuthInput for the supplied method and request, deriving
 // other information (if available) from the context.
 func NewRPCAuthInput(ctx context.Context, method string, req proto.Message) (*RPCAuthInput, error) {
@@ -184,6 +198,21 @@ func NewRPCAuthInput(ctx context.Context, method string, req proto.Message) (*RP
 
 type peerInfoKey struct{}
 
+type streamIndexKey struct{}
+
+// WithStreamMessageIndex returns a context recording that authorization is
+// for message n, counting from zero, of a client stream.
+func WithStreamMessageIndex(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, streamIndexKey{}, n)
+}
+
+// StreamMessageIndex returns the index, counting from zero, of the client
+// stream message being authorized. It is zero for unary calls.
+func StreamMessageIndex(ctx context.Context) int {
+	n, _ := ctx.Value(streamIndexKey{}).(int)
+	return n
+}
+
 // AddPeerToContext adds a PeerAuthInput to the context. This is typically
 // added by the rpcauth grpc interceptors.
 func AddPeerToContext(ctx context.Context, p *PeerAuthInput) context.Context {
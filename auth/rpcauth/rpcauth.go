@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-logr/logr"
 	"go.opentelemetry.io/otel/attribute"
@@ -44,6 +45,8 @@ var (
 		Description: "number of authorization failure due to missing input"}
 	authzFailureEvalErrorCounter = metrics.MetricDefinition{Name: "authz_failure_eval_error",
 		Description: "number of authorization failure due to policy evaluation error"}
+	authzAuditWriteErrorCounter = metrics.MetricDefinition{Name: "authz_audit_write_error",
+		Description: "number of failures to write authorization audit records"}
 )
 
 type AuthzPolicy interface {
@@ -83,6 +86,9 @@ type rpcAuthorizerImpl struct {
 
 	// Additional authorization hooks invoked before policy evaluation.
 	hooks []RPCAuthzHook
+
+	// If non-nil, receives a record of every decision.
+	auditSink AuditSink
 }
 
 // A RPCAuthzHook is invoked on populated RpcAuthInput prior to policy
@@ -92,6 +98,15 @@ type RPCAuthzHook interface {
 	Hook(context.Context, *RPCAuthInput) error
 }
 
+// An RPCAuthzAllowedHook is an RPCAuthzHook which also wants to know when
+// policy evaluation permits a request, such as to record that something
+// populated by the hook was used. Allowed is only invoked once the policy
+// has allowed the request, and may still reject it by returning an error.
+type RPCAuthzAllowedHook interface {
+	RPCAuthzHook
+	Allowed(context.Context, *RPCAuthInput) error
+}
+
 // NewRPCAuthorizer creates a new Authorizer with AuthzPolicy. Any supplied authorization
 // hooks will be executed, in the order provided, on each policy evauluation.
 // NOTE: The policy is used for both client and server hooks below. If you need
@@ -104,6 +119,42 @@ func NewRPCAuthorizer(policy AuthzPolicy, authzHooks ...RPCAuthzHook) RPCAuthori
 	}
 }
 
+// NewRPCAuthorizerWithAuditSink is like NewRPCAuthorizer but additionally
+// writes a record of every authorization decision to sink.
+func NewRPCAuthorizerWithAuditSink(policy AuthzPolicy, sink AuditSink, authzHooks ...RPCAuthzHook) RPCAuthorizer {
+	return &rpcAuthorizerImpl{
+		policy:    policy,
+		hooks:     authzHooks,
+		auditSink: sink,
+	}
+}
+
+// audit writes a record of a decision to the audit sink, if there is one.
+// Failures are logged and counted but don't affect the decision.
+func (g *rpcAuthorizerImpl) audit(ctx context.Context, input *RPCAuthInput, redactedInput RPCAuthInput, allowed bool, evalErr error, hints []string) {
+	if g.auditSink == nil {
+		return
+	}
+	record := &AuditRecord{
+		Time:        time.Now(),
+		Method:      input.Method,
+		Allowed:     allowed,
+		DenialHints: hints,
+		Approvers:   input.Approvers,
+		Input:       redactedInput,
+	}
+	if evalErr != nil {
+		record.Error = evalErr.Error()
+	}
+	if j := input.Metadata[ReqJustKey]; len(j) > 0 {
+		record.Justification = j[0]
+	}
+	if err := g.auditSink.Write(ctx, record); err != nil {
+		logr.FromContextOrDiscard(ctx).Error(err, "failed to write authz audit record", "method", input.Method)
+		metrics.RecorderFromContextOrNoop(ctx).CounterOrLog(ctx, authzAuditWriteErrorCounter, 1, attribute.String("method", input.Method))
+	}
+}
+
 // Eval will evalulate the supplied input against the authorization policy, returning
 // nil iff policy evaulation was successful, and the request is permitted, or
 // an appropriate status.Error otherwise. Any input hooks will be executed
@@ -129,6 +180,7 @@ func (g *rpcAuthorizerImpl) Eval(ctx context.Context, input *RPCAuthInput) error
 	for _, hook := range g.hooks {
 		if err := hook.Hook(ctx, input); err != nil {
 			logger.V(1).Error(err, "authz hook error", "input", redactedInput)
+			g.audit(ctx, input, redactedInput, false, err, nil)
 			if _, ok := status.FromError(err); ok {
 				// error is already an appropriate status.Status
 				return err
@@ -145,8 +197,25 @@ func (g *rpcAuthorizerImpl) Eval(ctx context.Context, input *RPCAuthInput) error
 	if err != nil {
 		logger.V(1).Error(err, "failed to evaluate authz policy", "input", redactedInput)
 		recorder.CounterOrLog(ctx, authzFailureEvalErrorCounter, 1, attribute.String("method", input.Method))
+		g.audit(ctx, input, redactedInput, false, err, nil)
 		return status.Errorf(codes.Internal, "authz policy evaluation error: %v", err)
 	}
+	if result {
+		for _, hook := range g.hooks {
+			allowedHook, ok := hook.(RPCAuthzAllowedHook)
+			if !ok {
+				continue
+			}
+			if err := allowedHook.Allowed(ctx, input); err != nil {
+				logger.V(1).Error(err, "authz allowed hook error", "input", redactedInput)
+				g.audit(ctx, input, redactedInput, false, err, nil)
+				if _, ok := status.FromError(err); ok {
+					return err
+				}
+				return status.Errorf(codes.Internal, "authz hook error: %v", err)
+			}
+		}
+	}
 	var hints []string
 	if !result {
 		// We've failed so let's see if we can help tell the user what might have failed.
@@ -159,6 +228,7 @@ func (g *rpcAuthorizerImpl) Eval(ctx context.Context, input *RPCAuthInput) error
 		}
 	}
 	logger.Info("authz policy evaluation result", "authorizationResult", result, "input", redactedInput, "denialHints", hints)
+	g.audit(ctx, input, redactedInput, result, nil, hints)
 	if !result {
 		errRegister := recorder.Counter(ctx, authzDeniedPolicyCounter, 1, attribute.String("method", input.Method))
 		if errRegister != nil {
@@ -262,6 +332,9 @@ type wrappedStream struct {
 
 	peerMu            sync.Mutex
 	lastPeerAuthInput *PeerAuthInput
+
+	// received counts the messages received so far.
+	received int
 }
 
 func (e *wrappedStream) Context() context.Context {
@@ -285,6 +358,8 @@ func (e *wrappedStream) RecvMsg(req interface{}) error {
 	if !ok {
 		return status.Errorf(codes.Internal, "unable to authorize request of type %T which is not proto.Message", req)
 	}
+	ctx = WithStreamMessageIndex(ctx, e.received)
+	e.received++
 	authInput, err := NewRPCAuthInput(ctx, e.info.FullMethod, msg)
 	if err != nil {
 		return err
@@ -398,6 +398,67 @@ func TestAuthzHook(t *testing.T) {
 	}
 }
 
+type allowedHook struct {
+	allowed int
+	err     error
+}
+
+func (a *allowedHook) Hook(context.Context, *RPCAuthInput) error { return nil }
+
+func (a *allowedHook) Allowed(context.Context, *RPCAuthInput) error {
+	a.allowed++
+	return a.err
+}
+
+func TestAllowedHook(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		name        string
+		result      bool
+		hookErr     error
+		wantAllowed int
+		wantCode    codes.Code
+	}{
+		{
+			name:        "allowed",
+			result:      true,
+			wantAllowed: 2,
+			wantCode:    codes.OK,
+		},
+		{
+			name:     "denied",
+			result:   false,
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:        "allowed but hook rejects",
+			result:      true,
+			hookErr:     status.Error(codes.ResourceExhausted, "used up"),
+			wantAllowed: 1,
+			wantCode:    codes.ResourceExhausted,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			policy := NewMockAuthzPolicy(func(context.Context, *RPCAuthInput) (bool, error) {
+				return tc.result, nil
+			}, func(context.Context, *RPCAuthInput) ([]string, error) {
+				return nil, nil
+			})
+			hook := &allowedHook{err: tc.hookErr}
+			always := func(*RPCAuthInput) bool { return true }
+			err := NewRPCAuthorizer(policy, hook, HookIf(hook, always)).Eval(ctx, &RPCAuthInput{Method: "/Foo/Bar"})
+			if got := status.Code(err); got != tc.wantCode {
+				t.Errorf("got %v, want %v", err, tc.wantCode)
+			}
+			// A failing Allowed stops the remaining hooks from running.
+			if hook.allowed != tc.wantAllowed {
+				t.Errorf("Allowed called %d times, want %d", hook.allowed, tc.wantAllowed)
+			}
+		})
+	}
+}
+
 func TestAuthorize(t *testing.T) {
 	req := &emptypb.Empty{}
 	info := &grpc.UnaryServerInfo{
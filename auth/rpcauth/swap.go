@@ -0,0 +1,85 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package rpcauth
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// A ReloadableAuthzPolicy is an AuthzPolicy which can refresh itself
+// from its source, such as a policy file, while in use.
+type ReloadableAuthzPolicy interface {
+	AuthzPolicy
+
+	// Reload refreshes the policy. If the new policy can't be loaded an
+	// error is returned and the current policy stays in place.
+	Reload(ctx context.Context) error
+}
+
+// policyHolder lets us store an interface in an atomic.Pointer.
+type policyHolder struct {
+	AuthzPolicy
+}
+
+// A SwappableAuthzPolicy is an AuthzPolicy which delegates to another
+// policy that can be atomically replaced while requests are being
+// evaluated.
+type SwappableAuthzPolicy struct {
+	current    atomic.Pointer[policyHolder]
+	generation atomic.Uint64
+}
+
+// NewSwappableAuthzPolicy returns a SwappableAuthzPolicy which initially
+// delegates to policy.
+func NewSwappableAuthzPolicy(policy AuthzPolicy) *SwappableAuthzPolicy {
+	s := &SwappableAuthzPolicy{}
+	s.Swap(policy)
+	return s
+}
+
+// Swap replaces the policy used for all future evaluations.
+func (s *SwappableAuthzPolicy) Swap(policy AuthzPolicy) {
+	s.current.Store(&policyHolder{policy})
+	s.generation.Add(1)
+}
+
+// Generation returns a number which changes every time the policy is
+// swapped, allowing callers to notice when decisions may have changed.
+func (s *SwappableAuthzPolicy) Generation() uint64 {
+	return s.generation.Load()
+}
+
+// Current returns the policy currently in use.
+func (s *SwappableAuthzPolicy) Current() AuthzPolicy {
+	return s.current.Load().AuthzPolicy
+}
+
+// Eval implements AuthzPolicy.
+func (s *SwappableAuthzPolicy) Eval(ctx context.Context, input *RPCAuthInput) (bool, error) {
+	return s.Current().Eval(ctx, input)
+}
+
+// DenialHints implements AuthzPolicy.
+func (s *SwappableAuthzPolicy) DenialHints(ctx context.Context, input *RPCAuthInput) ([]string, error) {
+	return s.Current().DenialHints(ctx, input)
+}
+
+// UsesHost implements HostAwarePolicy for the current policy.
+func (s *SwappableAuthzPolicy) UsesHost() bool {
+	return policyUsesHost(s.Current())
+}
@@ -18,6 +18,13 @@ allow {
 	input.method = "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"
 }
 
+# Allow copying files between targets with the Transfer service, if the
+# proxy hosts it. The LocalFile calls it makes to targets are checked by
+# the rules below like any others.
+allow {
+	input.method = "/Transfer.Transfer/Copy"
+}
+
 ## Access control for targets
 
 # Allow proxying HTTP requests
@@ -24,22 +24,31 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/go-logr/stdr"
 	"go.opentelemetry.io/otel"
 	prometheus_exporter "go.opentelemetry.io/otel/exporters/prometheus"
 	otelmetricsdk "go.opentelemetry.io/otel/sdk/metric"
+	_ "gocloud.dev/blob/azureblob" // Pull in Azure blob support
+	_ "gocloud.dev/blob/fileblob"  // Pull in file blob support
+	_ "gocloud.dev/blob/gcsblob"   // Pull in GCS blob support
+	_ "gocloud.dev/blob/s3blob"    // Pull in S3 blob support
 	"google.golang.org/grpc"
 	channelz "google.golang.org/grpc/channelz/service"
 	"google.golang.org/grpc/reflection"
 
+	"github.com/Snowflake-Labs/sansshell/auth/jwt"
 	"github.com/Snowflake-Labs/sansshell/auth/mtls"
 	mtlsFlags "github.com/Snowflake-Labs/sansshell/auth/mtls/flags"
+	mtlsSpiffe "github.com/Snowflake-Labs/sansshell/auth/mtls/spiffe"
 	"github.com/Snowflake-Labs/sansshell/auth/opa"
 	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
 	"github.com/Snowflake-Labs/sansshell/cmd/proxy-server/server"
 	"github.com/Snowflake-Labs/sansshell/cmd/util"
+	"github.com/Snowflake-Labs/sansshell/server/quota"
+	certissuer "github.com/Snowflake-Labs/sansshell/services/certissuer/server"
 	"github.com/Snowflake-Labs/sansshell/services/mpa/mpahooks"
 	ss "github.com/Snowflake-Labs/sansshell/services/sansshell/server"
 	"github.com/Snowflake-Labs/sansshell/telemetry/metrics"
@@ -70,18 +79,41 @@ var (
 	//go:embed default-policy.rego
 	defaultPolicy string
 
-	policyFlag       = flag.String("policy", defaultPolicy, "Local OPA policy governing access.  If empty, use builtin policy.")
-	policyFile       = flag.String("policy-file", "", "Path to a file with an OPA policy.  If empty, uses --policy.")
-	clientPolicyFlag = flag.String("client-policy", "", "OPA policy for outbound client actions (i.e. connecting to sansshell servers). If empty no policy is applied.")
-	clientPolicyFile = flag.String("client-policy-file", "", "Path to a file with a client OPA.  If empty uses --client-policy")
-	hostport         = flag.String("hostport", "localhost:50043", "Where to listen for connections.")
-	debugport        = flag.String("debugport", "localhost:50045", "A separate port for http debug pages. Set to an empty string to disable.")
-	metricsport      = flag.String("metricsport", "localhost:50046", "Http endpoint for exposing metrics")
-	credSource       = flag.String("credential-source", mtlsFlags.Name(), fmt.Sprintf("Method used to obtain mTLS creds (one of [%s])", strings.Join(mtls.Loaders(), ",")))
-	verbosity        = flag.Int("v", 0, "Verbosity level. > 0 indicates more extensive logging")
-	validate         = flag.Bool("validate", false, "If true will evaluate the policy and then exit (non-zero on error)")
-	justification    = flag.Bool("justification", false, "If true then justification (which is logged and possibly validated) must be passed along in the client context Metadata with the key '"+rpcauth.ReqJustKey+"'")
-	version          bool
+	policyFlag           = flag.String("policy", defaultPolicy, "Local OPA policy governing access.  If empty, use builtin policy.")
+	policyFile           = flag.String("policy-file", "", "Path to a file with an OPA policy.  If empty, uses --policy.")
+	clientPolicyFlag     = flag.String("client-policy", "", "OPA policy for outbound client actions (i.e. connecting to sansshell servers). If empty no policy is applied.")
+	clientPolicyFile     = flag.String("client-policy-file", "", "Path to a file with a client OPA.  If empty uses --client-policy")
+	hostport             = flag.String("hostport", "localhost:50043", "Where to listen for connections.")
+	debugport            = flag.String("debugport", "localhost:50045", "A separate port for http debug pages. Set to an empty string to disable.")
+	metricsport          = flag.String("metricsport", "localhost:50046", "Http endpoint for exposing metrics")
+	credSource           = flag.String("credential-source", mtlsFlags.Name(), fmt.Sprintf("Method used to obtain mTLS creds (one of [%s])", strings.Join(mtls.Loaders(), ",")))
+	verbosity            = flag.Int("v", 0, "Verbosity level. > 0 indicates more extensive logging")
+	validate             = flag.Bool("validate", false, "If true will evaluate the policy and then exit (non-zero on error)")
+	justification        = flag.Bool("justification", false, "If true then justification (which is logged and possibly validated) must be passed along in the client context Metadata with the key '"+rpcauth.ReqJustKey+"'")
+	policyWatch          = flag.Duration("policy-watch-interval", 0, "If set along with --policy-file, how often to check the policy file for changes and reload it. The policy file is also reloaded on SIGHUP.")
+	auditLog             = flag.String("audit-log", "", "Path to a file receiving a JSON line for every authz decision. If empty, no audit log is written.")
+	auditLogMaxSize      = flag.Int64("audit-log-max-size", 100, "Size in megabytes at which the audit log is rotated. 0 disables rotation.")
+	auditLogMaxBackups   = flag.Int("audit-log-max-backups", 5, "Number of rotated audit logs to keep.")
+	authzCacheTTL        = flag.Duration("authz-cache-ttl", 0, "If non-zero, cache authz policy decisions for identical requests for this long.")
+	authzCacheSize       = flag.Int("authz-cache-size", rpcauth.DefaultCacheMaxEntries, "Maximum number of authz decisions to cache when --authz-cache-ttl is set.")
+	crlSources           = flag.String("crl", "", "Comma separated list of CRL files or blob URLs. Peers with a certificate revoked by any of them are rejected.")
+	crlRefresh           = flag.Duration("crl-refresh-interval", time.Hour, "How often to reload the CRLs given by --crl. 0 disables reloading.")
+	crlIssuers           = flag.String("crl-issuers", "", "PEM file of the CAs which sign the CRLs given by --crl. If set, a CRL signed by anyone else fails to load.")
+	ocspStapling         = flag.Bool("ocsp-stapling", false, "If true, reject targets whose stapled OCSP response is invalid, expired or says their certificate is revoked. Only targets whose credentials loader staples a response are checked.")
+	certIssuerCACert     = flag.String("cert-issuer-ca-cert", "", "If set along with --cert-issuer-ca-key, host the CertIssuer service and sign short-lived client certificates with this CA certificate, PEM format.")
+	certIssuerCAKey      = flag.String("cert-issuer-ca-key", "", "Path to the key of --cert-issuer-ca-cert.")
+	certIssuerLifetime   = flag.Duration("cert-issuer-max-lifetime", certissuer.DefaultMaxLifetime, "Longest lifetime of certificates issued by the CertIssuer service.")
+	certIssuerHostport   = flag.String("cert-issuer-hostport", "", "If set, also serve the CertIssuer service here with client certificates optional, so users with only an OIDC token can get a certificate.")
+	certIssuerJWKS       = flag.String("cert-issuer-jwks", "", "Path to a JSON Web Key Set. If set, the CertIssuer service accepts OIDC tokens signed by these keys as identities.")
+	certIssuerOIDCIssuer = flag.String("cert-issuer-oidc-issuer", "", "If set, OIDC tokens must have this issuer.")
+	certIssuerAudience   = flag.String("cert-issuer-oidc-audience", "", "If set, OIDC tokens must have this audience.")
+	jwks                 = flag.String("jwks", "", "Path to a JSON Web Key Set. If set, requests may carry an OIDC bearer token signed by one of these keys, whose subject and groups become the principal seen by the authz policy.")
+	jwtIssuer            = flag.String("jwt-issuer", "", "If set along with --jwks, bearer tokens must have this issuer.")
+	jwtAudience          = flag.String("jwt-audience", "", "If set along with --jwks, bearer tokens must have this audience.")
+	jwtGroupsClaim       = flag.String("jwt-groups-claim", jwt.DefaultGroupsClaim, "The bearer token claim holding the groups of the principal.")
+	quotaConfig          = flag.String("quota-config", "", "Path to a JSON file of per method and per principal rate and concurrency limits. If empty, requests are not limited.")
+	transferService      = flag.Bool("transfer", false, "If true, host the Transfer service so files can be copied between targets through the proxy. Each LocalFile call it makes is authorized by the policy as if the caller had made it.")
+	version              bool
 )
 
 func init() {
@@ -90,6 +122,7 @@ func init() {
 	flag.StringVar(&mtlsFlags.ServerCertFile, "server-cert", mtlsFlags.ServerCertFile, "Path to an x509 server cert, PEM format")
 	flag.StringVar(&mtlsFlags.ServerKeyFile, "server-key", mtlsFlags.ServerKeyFile, "Path to the server's TLS key")
 	flag.StringVar(&mtlsFlags.RootCAFile, "root-ca", mtlsFlags.RootCAFile, "The root of trust for remote identities, PEM format")
+	flag.StringVar(&mtlsSpiffe.SocketPath, "spiffe-socket", mtlsSpiffe.SocketPath, "Address of the SPIFFE Workload API used by the spiffe credential source. Defaults to $SPIFFE_ENDPOINT_SOCKET")
 
 	flag.BoolVar(&version, "version", false, "Returns the server built version from the sansshell server package")
 }
@@ -135,7 +168,14 @@ func main() {
 	}
 
 	// authz policy for inbound requests
-	parsedOpaAuthPolicy, err := opa.NewOpaAuthzPolicy(ctx, policy, opa.WithDenialHintsQuery("data.sansshell.authz.denial_hints"))
+	var parsedOpaAuthPolicy rpcauth.AuthzPolicy
+	var filePolicy *opa.FileAuthzPolicy
+	if *policyFile != "" {
+		filePolicy, err = opa.NewFileAuthzPolicy(ctx, *policyFile, opa.WithDenialHintsQuery("data.sansshell.authz.denial_hints"))
+		parsedOpaAuthPolicy = filePolicy
+	} else {
+		parsedOpaAuthPolicy, err = opa.NewOpaAuthzPolicy(ctx, policy, opa.WithDenialHintsQuery("data.sansshell.authz.denial_hints"))
+	}
 	if err != nil {
 		log.Fatalf("Invalid policy: %v\n", err)
 	}
@@ -144,23 +184,112 @@ func main() {
 		os.Exit(0)
 	}
 
+	auditSink, err := util.OpenAuditLog(*auditLog, *auditLogMaxSize, *auditLogMaxBackups)
+	if err != nil {
+		log.Fatalf("Unable to open audit log: %v\n", err)
+	}
+
 	// Create a an instance of logging/version for the proxy server itself.
 	srv := &ss.Server{}
 
-	server.Run(ctx,
+	opts := []server.Option{
 		server.WithLogger(logger),
 		server.WithAuthzPolicy(parsedOpaAuthPolicy),
 		server.WithClientAuthzPolicy(clientOpaAuthzPolicy),
+		server.WithAuditSink(auditSink),
 		server.WithCredSource(*credSource),
 		server.WithHostPort(*hostport),
 		server.WithJustification(*justification),
 		server.WithAuthzHook(rpcauth.PeerPrincipalFromCertHook()),
-		server.WithAuthzHook(mpahooks.ProxyMPAAuthzHook()),
 		server.WithRawServerOption(func(s *grpc.Server) { reflection.Register(s) }),
 		server.WithRawServerOption(func(s *grpc.Server) { channelz.RegisterChannelzServiceToServer(s) }),
 		server.WithRawServerOption(srv.Register),
 		server.WithDebugPort(*debugport),
 		server.WithMetricsPort(*metricsport),
 		server.WithMetricsRecorder(recorder),
-	)
+	}
+	if *jwks != "" {
+		verifier, err := jwt.NewVerifier(*jwks, jwt.WithIssuer(*jwtIssuer), jwt.WithAudience(*jwtAudience), jwt.WithGroupsClaim(*jwtGroupsClaim))
+		if err != nil {
+			log.Fatalf("Unable to load JWKS: %v\n", err)
+		}
+		opts = append(opts, server.WithBearerTokenVerifier(verifier))
+	}
+	// MPA hooks go after any hook setting the principal so they see the
+	// same identity as the policy.
+	opts = append(opts, server.WithAuthzHook(mpahooks.ProxyMPAAuthzHook()))
+	if filePolicy != nil {
+		opts = append(opts, server.WithReloadPolicyOnSIGHUP())
+		if *policyWatch > 0 {
+			go filePolicy.Watch(ctx, *policyWatch)
+		}
+	}
+	if *authzCacheTTL > 0 {
+		cacheOpts := []rpcauth.CacheOption{
+			rpcauth.WithCacheTTL(*authzCacheTTL),
+			rpcauth.WithCacheMaxEntries(*authzCacheSize),
+		}
+		opts = append(opts, server.WithAuthzDecisionCache(cacheOpts...))
+	}
+	if *crlSources != "" || *ocspStapling {
+		var revocationOpts []mtls.RevocationOption
+		if *crlSources != "" {
+			revocationOpts = append(revocationOpts, mtls.WithCRLSources(strings.Split(*crlSources, ",")...))
+		}
+		if *crlIssuers != "" {
+			issuers, err := mtls.LoadCertificates(*crlIssuers)
+			if err != nil {
+				log.Fatalf("Unable to load CRL issuers: %v\n", err)
+			}
+			revocationOpts = append(revocationOpts, mtls.WithCRLIssuers(issuers...))
+		}
+		if *ocspStapling {
+			revocationOpts = append(revocationOpts, mtls.WithOCSPStapling())
+		}
+		checker, err := mtls.NewRevocationChecker(ctx, revocationOpts...)
+		if err != nil {
+			log.Fatalf("Unable to load CRLs: %v\n", err)
+		}
+		if *crlRefresh > 0 {
+			go checker.Watch(ctx, *crlRefresh)
+		}
+		opts = append(opts, server.WithRevocationChecker(checker))
+	}
+	if *certIssuerCACert != "" || *certIssuerCAKey != "" {
+		caCert, caKey, err := certissuer.LoadCA(*certIssuerCACert, *certIssuerCAKey)
+		if err != nil {
+			log.Fatalf("Unable to load cert issuer CA: %v\n", err)
+		}
+		issuerOpts := []certissuer.Option{certissuer.WithMaxLifetime(*certIssuerLifetime)}
+		if *certIssuerJWKS != "" {
+			verifier, err := jwt.NewVerifier(*certIssuerJWKS, jwt.WithIssuer(*certIssuerOIDCIssuer), jwt.WithAudience(*certIssuerAudience))
+			if err != nil {
+				log.Fatalf("Unable to load cert issuer JWKS: %v\n", err)
+			}
+			issuerOpts = append(issuerOpts, certissuer.WithTokenVerifier(verifier))
+		}
+		issuer, err := certissuer.New(caCert, caKey, issuerOpts...)
+		if err != nil {
+			log.Fatalf("Unable to create cert issuer: %v\n", err)
+		}
+		opts = append(opts, server.WithCertIssuer(issuer, *certIssuerHostport))
+	}
+	if *quotaConfig != "" {
+		config, err := quota.LoadConfig(*quotaConfig)
+		if err != nil {
+			log.Fatalf("Unable to load quotas: %v\n", err)
+		}
+		limiter, err := quota.New(config)
+		if err != nil {
+			log.Fatalf("Invalid quota config: %v\n", err)
+		}
+		opts = append(opts,
+			server.WithUnaryInterceptor(limiter.UnaryServerInterceptor()),
+			server.WithStreamInterceptor(limiter.StreamServerInterceptor()),
+		)
+	}
+	if *transferService {
+		opts = append(opts, server.WithTransferService())
+	}
+	server.Run(ctx, opts...)
 }
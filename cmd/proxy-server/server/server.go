@@ -39,9 +39,13 @@ import (
 	"go.opentelemetry.io/otel/metric/noop"
 	"google.golang.org/grpc"
 
+	"github.com/Snowflake-Labs/sansshell/auth/jwt"
 	"github.com/Snowflake-Labs/sansshell/auth/mtls"
 	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+	"github.com/Snowflake-Labs/sansshell/cmd/util"
 	"github.com/Snowflake-Labs/sansshell/proxy/server"
+	certissuer "github.com/Snowflake-Labs/sansshell/services/certissuer/server"
+	transfer "github.com/Snowflake-Labs/sansshell/services/transfer/server"
 	"github.com/Snowflake-Labs/sansshell/telemetry"
 	"github.com/Snowflake-Labs/sansshell/telemetry/metrics"
 	"google.golang.org/grpc/credentials"
@@ -55,6 +59,7 @@ type runState struct {
 	logger                   logr.Logger
 	policy                   rpcauth.AuthzPolicy
 	clientPolicy             rpcauth.AuthzPolicy
+	auditSink                rpcauth.AuditSink
 	credSource               string
 	tlsConfig                *tls.Config
 	hostport                 string
@@ -73,6 +78,17 @@ type runState struct {
 	authzHooks               []rpcauth.RPCAuthzHook
 	services                 []func(*grpc.Server)
 	metricsRecorder          metrics.MetricsRecorder
+	reloadPolicyOnSIGHUP     bool
+
+	authzCache     bool
+	authzCacheOpts []rpcauth.CacheOption
+
+	revocationChecker *mtls.RevocationChecker
+
+	certIssuer         *certissuer.Server
+	certIssuerHostport string
+
+	transferService bool
 }
 
 type Option interface {
@@ -110,6 +126,16 @@ func WithClientAuthzPolicy(clientPolicy rpcauth.AuthzPolicy) Option {
 	})
 }
 
+// WithAuditSink writes a structured record of every authz decision made
+// for incoming RPC requests, including each proxied call to a target, to
+// the given sink. Decisions made by the client authz policy aren't recorded.
+func WithAuditSink(sink rpcauth.AuditSink) Option {
+	return optionFunc(func(_ context.Context, r *runState) error {
+		r.auditSink = sink
+		return nil
+	})
+}
+
 // WithTlsConfig applies a supplied tls.Config object to the gRPC server.
 func WithTlsConfig(tlsConfig *tls.Config) Option {
 	return optionFunc(func(_ context.Context, r *runState) error {
@@ -308,6 +334,102 @@ func WithOtelTracing(interceptorOpts ...otelgrpc.Option) Option {
 	})
 }
 
+// WithReloadPolicyOnSIGHUP will make the proxy reload its authz policy for
+// incoming requests when it receives a SIGHUP signal. The policy must implement
+// rpcauth.ReloadableAuthzPolicy, such as one returned by opa.NewFileAuthzPolicy.
+// A new policy that fails to compile is rejected and the old policy stays in use.
+func WithReloadPolicyOnSIGHUP() Option {
+	return optionFunc(func(_ context.Context, r *runState) error {
+		r.reloadPolicyOnSIGHUP = true
+		return nil
+	})
+}
+
+// WithAuthzDecisionCache makes the proxy remember the decisions of its authz
+// policy for incoming requests, so identical input isn't evaluated again.
+// See rpcauth.CachingAuthzPolicy for what is cached and for how long.
+func WithAuthzDecisionCache(opts ...rpcauth.CacheOption) Option {
+	return optionFunc(func(_ context.Context, r *runState) error {
+		r.authzCache = true
+		r.authzCacheOpts = opts
+		return nil
+	})
+}
+
+// WithRevocationChecker makes the proxy reject clients and servers whose
+// certificates the checker reports as revoked during the TLS handshake.
+func WithRevocationChecker(checker *mtls.RevocationChecker) Option {
+	return optionFunc(func(_ context.Context, r *runState) error {
+		r.revocationChecker = checker
+		return nil
+	})
+}
+
+// WithCertIssuer hosts the CertIssuer service on the proxy so users can
+// exchange their identity for a short-lived client certificate. Requests are
+// authorized by the proxy's policy like any other RPC, with the principal
+// taken from a bearer token when one is sent. If hostport is set the service
+// is also served there with client certificates optional, so users with
+// only a token can get their first certificate. Nothing else is served on
+// that port.
+func WithCertIssuer(issuer *certissuer.Server, hostport string) Option {
+	return optionFunc(func(_ context.Context, r *runState) error {
+		r.certIssuer = issuer
+		r.certIssuerHostport = hostport
+		r.services = append(r.services, issuer.Register)
+		r.authzHooks = append(r.authzHooks, issuer.AuthzHook())
+		return nil
+	})
+}
+
+// WithTransferService hosts the Transfer service on the proxy so files can
+// be copied between targets without passing through the client. The
+// service talks to targets just as the proxy does for forwarded requests,
+// so each LocalFile call it makes is authorized by the proxy's policy with
+// the caller's identity.
+func WithTransferService() Option {
+	return optionFunc(func(_ context.Context, r *runState) error {
+		r.transferService = true
+		return nil
+	})
+}
+
+// WithBearerTokenVerifier makes the proxy accept bearer tokens, such as OIDC
+// ID tokens, sent in the "authorization" metadata of requests. Tokens are
+// checked by the verifier before authorization and requests with invalid
+// tokens are rejected. A valid token's subject and groups replace the
+// principal the policy sees in input.peer.principal, so this should be
+// applied after any hook which sets the principal from the client
+// certificate and before any hook which uses the principal, such as the
+// MPA hooks. The token itself is never logged or shown to the policy.
+func WithBearerTokenVerifier(v *jwt.Verifier) Option {
+	return optionFunc(func(_ context.Context, r *runState) error {
+		r.unaryInterceptors = append(r.unaryInterceptors, v.UnaryServerInterceptor())
+		r.streamInterceptors = append(r.streamInterceptors, v.StreamServerInterceptor())
+		r.authzHooks = append(r.authzHooks, v.PeerPrincipalHook())
+		return nil
+	})
+}
+
+// credOptions returns the mtls options for the credentials in rs, followed
+// by extra.
+func credOptions(rs *runState, extra ...mtls.Option) []mtls.Option {
+	var opts []mtls.Option
+	if rs.revocationChecker != nil {
+		opts = append(opts, mtls.WithRevocationChecker(rs.revocationChecker))
+	}
+	return append(opts, extra...)
+}
+
+// tlsConfigWithOptions returns a copy of rs.tlsConfig with credOptions applied.
+func tlsConfigWithOptions(rs *runState, extra ...mtls.Option) *tls.Config {
+	config := rs.tlsConfig.Clone()
+	for _, o := range credOptions(rs, extra...) {
+		o(config)
+	}
+	return config
+}
+
 // Run takes the given context and RunState along with any authz hooks and starts up a sansshell proxy server
 // using the flags above to provide credentials. An address hook (based on the remote host) with always be added.
 // As this is intended to be called from main() it doesn't return errors and will instead exit on any errors.
@@ -322,6 +444,16 @@ func Run(ctx context.Context, opts ...Option) {
 		}
 	}
 
+	if rs.reloadPolicyOnSIGHUP {
+		if err := util.ReloadPolicyOnSIGHUP(ctx, rs.logger, rs.policy); err != nil {
+			rs.logger.Error(err, "unable to reload policy on SIGHUP")
+			os.Exit(1)
+		}
+	}
+	if rs.authzCache && rs.policy != nil {
+		rs.policy = rpcauth.NewCachingAuthzPolicy(rs.policy, rs.authzCacheOpts...)
+	}
+
 	// If there's a debug port, we want to start it early
 	if rs.debughandler != nil && rs.debugport != "" {
 		go func() {
@@ -366,7 +498,7 @@ func Run(ctx context.Context, opts ...Option) {
 
 	h := []rpcauth.RPCAuthzHook{addressHook, justificationHook}
 	h = append(h, rs.authzHooks...)
-	authz := rpcauth.NewRPCAuthorizer(rs.policy, h...)
+	authz := rpcauth.NewRPCAuthorizerWithAuditSink(rs.policy, rs.auditSink, h...)
 
 	var clientAuthz rpcauth.RPCAuthorizer
 	if rs.clientPolicy != nil {
@@ -418,23 +550,34 @@ func Run(ctx context.Context, opts ...Option) {
 		authz.AuthorizeStream,
 	)
 	serverOpts := []grpc.ServerOption{
-		grpc.Creds(serverCreds),
 		grpc.ChainUnaryInterceptor(unaryServer...),
 		grpc.ChainStreamInterceptor(streamServer...),
 	}
 	if rs.statsHandler != nil {
 		serverOpts = append(serverOpts, grpc.StatsHandler(rs.statsHandler))
 	}
-	g := grpc.NewServer(serverOpts...)
+	g := grpc.NewServer(append(serverOpts, grpc.Creds(serverCreds))...)
 
 	// We always register the proxy.
 	server.Register(g)
+	if rs.transferService {
+		transfer.New(targetDialer, authz).Register(g)
+	}
 
 	// Now loop over any other registered and call them.
 	for _, s := range rs.services {
 		s(g)
 	}
 
+	var issuerServer *grpc.Server
+	if rs.certIssuer != nil && rs.certIssuerHostport != "" {
+		issuerServer, err = startCertIssuerServer(ctx, rs, serverOpts)
+		if err != nil {
+			rs.logger.Error(err, "unable to start cert issuer server", "hostport", rs.certIssuerHostport)
+			os.Exit(1)
+		}
+	}
+
 	// React to interrupt signals by shutting down gracefully.
 	// This tends to improve the proxy behavior when running on platforms like Kubernetes.
 	// The proxy will continue to serve streaming RPCs during graceful shutdown.
@@ -444,6 +587,9 @@ func Run(ctx context.Context, opts ...Option) {
 		s := <-sigCh
 		signal.Stop(sigCh)
 		rs.logger.Info("beginning graceful shutdown", "signal", s)
+		if issuerServer != nil {
+			issuerServer.GracefulStop()
+		}
 		g.GracefulStop()
 	}()
 
@@ -456,6 +602,30 @@ func Run(ctx context.Context, opts ...Option) {
 	}
 }
 
+// startCertIssuerServer serves only the CertIssuer service on its own port,
+// where client certificates are optional. opts are the proxy's server
+// options other than credentials, so requests go through the same logging
+// and authz.
+func startCertIssuerServer(ctx context.Context, rs *runState, opts []grpc.ServerOption) (*grpc.Server, error) {
+	creds, err := extractServerTransportCredentialsFromRunState(ctx, rs, mtls.WithClientCertOptional())
+	if err != nil {
+		return nil, err
+	}
+	lis, err := net.Listen("tcp", rs.certIssuerHostport)
+	if err != nil {
+		return nil, err
+	}
+	rs.logger.Info("cert issuer listening", "hostport", rs.certIssuerHostport)
+	g := grpc.NewServer(append(opts, grpc.Creds(creds))...)
+	rs.certIssuer.Register(g)
+	go func() {
+		if err := g.Serve(lis); err != nil {
+			rs.logger.Error(err, "cert issuer grpcserver.Serve()")
+		}
+	}()
+	return g, nil
+}
+
 // extractClientTransportCredentialsFromRunState extracts transport credentials from runState. Will error if both credSource and tlsConfig are specified
 func extractClientTransportCredentialsFromRunState(ctx context.Context, rs *runState) (credentials.TransportCredentials, error) {
 	var creds credentials.TransportCredentials
@@ -464,30 +634,30 @@ func extractClientTransportCredentialsFromRunState(ctx context.Context, rs *runS
 		return nil, fmt.Errorf("both credSource and tlsConfig are defined for the client")
 	}
 	if rs.credSource != "" {
-		creds, err = mtls.LoadClientCredentials(ctx, rs.credSource)
+		creds, err = mtls.LoadClientCredentials(ctx, rs.credSource, credOptions(rs)...)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		creds = credentials.NewTLS(rs.tlsConfig)
+		creds = credentials.NewTLS(tlsConfigWithOptions(rs))
 	}
 	return creds, nil
 }
 
 // extractServerTransportCredentialsFromRunState extracts transport credentials from runState. Will error if both credSource and tlsConfig are specified
-func extractServerTransportCredentialsFromRunState(ctx context.Context, rs *runState) (credentials.TransportCredentials, error) {
+func extractServerTransportCredentialsFromRunState(ctx context.Context, rs *runState, extra ...mtls.Option) (credentials.TransportCredentials, error) {
 	var creds credentials.TransportCredentials
 	var err error
 	if rs.credSource != "" && rs.tlsConfig != nil {
 		return nil, fmt.Errorf("both credSource and tlsConfig are defined for the server")
 	}
 	if rs.credSource != "" {
-		creds, err = mtls.LoadServerCredentials(ctx, rs.credSource)
+		creds, err = mtls.LoadServerCredentials(ctx, rs.credSource, credOptions(rs, extra...)...)
 		if err != nil {
 			return nil, err
 		}
 	} else {
-		creds = credentials.NewTLS(rs.tlsConfig)
+		creds = credentials.NewTLS(tlsConfigWithOptions(rs, extra...))
 	}
 	return creds, nil
 }
@@ -0,0 +1,216 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+
+	cmdUtil "github.com/Snowflake-Labs/sansshell/cmd/util"
+)
+
+// callResult is the outcome of one call to one target.
+type callResult struct {
+	Method    string
+	Code      string
+	Message   string
+	Responses []proto.Message
+}
+
+// targetResult is the outcome of every call a command made to a target.
+type targetResult struct {
+	target string
+	index  int
+	calls  []callResult
+}
+
+// results returns the calls made to the target at index. Replies are
+// unpacked so they can be compared field by field.
+func (r *replyRecorder) results(index int) targetResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := targetResult{target: r.targets[index], index: index}
+	for _, c := range r.calls {
+		st := status.Convert(c.errs[index])
+		cr := callResult{Method: c.method, Code: st.Code().String(), Message: st.Message()}
+		for _, a := range c.responses[index] {
+			m, err := a.UnmarshalNew()
+			if err != nil {
+				// Without the type all we can compare is the encoded form.
+				m = a
+			}
+			cr.Responses = append(cr.Responses, m)
+		}
+		res.calls = append(res.calls, cr)
+	}
+	return res
+}
+
+// equal returns true if both targets got the same statuses and replies.
+func (t targetResult) equal(o targetResult) bool {
+	if len(t.calls) != len(o.calls) {
+		return false
+	}
+	for i, c := range t.calls {
+		oc := o.calls[i]
+		if c.Method != oc.Method || c.Code != oc.Code || c.Message != oc.Message || len(c.Responses) != len(oc.Responses) {
+			return false
+		}
+		for j := range c.Responses {
+			if !proto.Equal(c.Responses[j], oc.Responses[j]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// resultGroup is a set of targets which all got the same result.
+type resultGroup struct {
+	results []targetResult
+}
+
+// groupResults puts targets with equal results together. Groups are sorted
+// largest first, with ties in the order their first target was given.
+func groupResults(results []targetResult) []*resultGroup {
+	var groups []*resultGroup
+	for _, r := range results {
+		found := false
+		for _, g := range groups {
+			if g.results[0].equal(r) {
+				g.results = append(g.results, r)
+				found = true
+				break
+			}
+		}
+		if !found {
+			groups = append(groups, &resultGroup{results: []targetResult{r}})
+		}
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return len(groups[i].results) > len(groups[j].results)
+	})
+	return groups
+}
+
+// matchesTarget returns true if name refers to target (as
+// host[:port][;<duration>]), either exactly or by its host without a port.
+func matchesTarget(name, target string) bool {
+	target = cmdUtil.StripTimeout(target)
+	if name == target {
+		return true
+	}
+	host, _, err := net.SplitHostPort(target)
+	return err == nil && name == host
+}
+
+// findBaseline returns the index of the target matching baseline, or -1.
+func findBaseline(baseline string, targets []string) int {
+	for i, t := range targets {
+		if matchesTarget(baseline, t) {
+			return i
+		}
+	}
+	return -1
+}
+
+// writeAggregate writes results grouped by identical result. Every group but
+// the largest is marked as an outlier. If baseline is a valid index each
+// group not containing that target also gets a diff against it.
+func writeAggregate(w io.Writer, results []targetResult, baseline int) {
+	groups := groupResults(results)
+	var base *targetResult
+	for i := range results {
+		if results[i].index == baseline {
+			base = &results[i]
+		}
+	}
+	for i, g := range groups {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		var names []string
+		hasBase := false
+		for _, r := range g.results {
+			names = append(names, r.target)
+			hasBase = hasBase || (base != nil && r.index == base.index)
+		}
+		noun := "targets"
+		if len(names) == 1 {
+			noun = "target"
+		}
+		var tags []string
+		if i > 0 {
+			tags = append(tags, "outlier")
+		}
+		if hasBase {
+			tags = append(tags, "baseline")
+		}
+		tag := ""
+		if len(tags) > 0 {
+			tag = " (" + strings.Join(tags, ", ") + ")"
+		}
+		fmt.Fprintf(w, "%d %s%s: %s\n", len(names), noun, tag, strings.Join(names, ", "))
+		writeCalls(w, g.results[0].calls)
+		if base != nil && !hasBase {
+			fmt.Fprintf(w, "  Diff from baseline %s (-baseline +these):\n", base.target)
+			diff := cmp.Diff(base.calls, g.results[0].calls, protocmp.Transform())
+			fmt.Fprint(w, indent(diff, "    "))
+		}
+	}
+}
+
+// writeCalls writes the status and replies of each call.
+func writeCalls(w io.Writer, calls []callResult) {
+	if len(calls) == 0 {
+		fmt.Fprintln(w, "  No calls made")
+	}
+	opts := protojson.MarshalOptions{Multiline: true, Indent: "  "}
+	for _, c := range calls {
+		fmt.Fprintf(w, "  %s: %s", c.Method, c.Code)
+		if c.Message != "" {
+			fmt.Fprintf(w, " - %s", c.Message)
+		}
+		fmt.Fprintln(w)
+		for _, resp := range c.Responses {
+			b, err := opts.Marshal(resp)
+			if err != nil {
+				fmt.Fprintf(w, "    <can't render %T: %v>\n", resp, err)
+				continue
+			}
+			fmt.Fprint(w, indent(string(b), "    "))
+		}
+	}
+}
+
+// indent prefixes every line of s, ending it with a newline.
+func indent(s, prefix string) string {
+	s = strings.TrimRight(s, "\n")
+	if s == "" {
+		return ""
+	}
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix) + "\n"
+}
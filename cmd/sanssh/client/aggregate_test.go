@@ -0,0 +1,121 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func versionResult(target string, index int, version string) targetResult {
+	r := targetResult{target: target, index: index}
+	if version == "" {
+		r.calls = []callResult{{Method: "/Version.Version/Version", Code: "Unavailable", Message: "down"}}
+		return r
+	}
+	r.calls = []callResult{{Method: "/Version.Version/Version", Code: "OK", Responses: []proto.Message{wrapperspb.String(version)}}}
+	return r
+}
+
+func TestGroupResults(t *testing.T) {
+	results := []targetResult{
+		versionResult("host0:1", 0, "1.0"),
+		versionResult("host1:1", 1, "2.0"),
+		versionResult("host2:1", 2, "2.0"),
+		versionResult("host3:1", 3, ""),
+		versionResult("host4:1", 4, "2.0"),
+		{target: "host5:1", index: 5},
+	}
+	groups := groupResults(results)
+	var got [][]int
+	for _, g := range groups {
+		var idx []int
+		for _, r := range g.results {
+			idx = append(idx, r.index)
+		}
+		got = append(got, idx)
+	}
+	want := [][]int{{1, 2, 4}, {0}, {3}, {5}}
+	if len(got) != len(want) {
+		t.Fatalf("got groups %v, want %v", got, want)
+	}
+	for i := range want {
+		if len(got[i]) != len(want[i]) {
+			t.Fatalf("got groups %v, want %v", got, want)
+		}
+		for j := range want[i] {
+			if got[i][j] != want[i][j] {
+				t.Fatalf("got groups %v, want %v", got, want)
+			}
+		}
+	}
+}
+
+func TestWriteAggregate(t *testing.T) {
+	results := []targetResult{
+		versionResult("host0:1", 0, "1.0"),
+		versionResult("host1:1", 1, "2.0"),
+		versionResult("host2:1", 2, "2.0"),
+		versionResult("host3:1", 3, ""),
+	}
+	targets := []string{"host0:1;10s", "host1:1;10s", "host2:1;10s", "host3:1;10s"}
+	if got := findBaseline("host9", targets); got != -1 {
+		t.Errorf("findBaseline(host9) = %d, want -1", got)
+	}
+	baseline := findBaseline("host0", targets)
+	if baseline != 0 {
+		t.Fatalf("findBaseline(host0) = %d, want 0", baseline)
+	}
+
+	var buf bytes.Buffer
+	writeAggregate(&buf, results, -1)
+	out := buf.String()
+	for _, want := range []string{
+		"2 targets: host1:1, host2:1\n  /Version.Version/Version: OK\n    \"2.0\"\n",
+		"\n1 target (outlier): host0:1\n",
+		"\n1 target (outlier): host3:1\n  /Version.Version/Version: Unavailable - down\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Diff") {
+		t.Errorf("unexpected diff without a baseline:\n%s", out)
+	}
+
+	buf.Reset()
+	writeAggregate(&buf, results, baseline)
+	out = buf.String()
+	for _, want := range []string{
+		"1 target (outlier, baseline): host0:1\n",
+		"Diff from baseline host0:1 (-baseline +these):",
+		`"1.0"`,
+		`"2.0"`,
+		"Unavailable",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if n := strings.Count(out, "Diff from baseline"); n != 2 {
+		t.Errorf("got %d diffs, want 2:\n%s", n, out)
+	}
+}
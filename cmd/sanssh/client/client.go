@@ -21,12 +21,14 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"google.golang.org/grpc/credentials"
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/subcommands"
@@ -34,6 +36,7 @@ import (
 
 	"github.com/Snowflake-Labs/sansshell/auth/mtls"
 	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+	proxypb "github.com/Snowflake-Labs/sansshell/proxy"
 	"github.com/Snowflake-Labs/sansshell/proxy/proxy"
 
 	cmdUtil "github.com/Snowflake-Labs/sansshell/cmd/util"
@@ -80,6 +83,34 @@ type RunState struct {
 	EnableMPA bool
 	// If true, the command is authz dry run and real action should not be executed
 	AuthzDryRun bool
+	// If true along with AuthzDryRun, explain the proxy's authz decision for each target.
+	AuthzExplain bool
+	// Canary if non-zero runs the command against the first Canary targets on
+	// their own before any others. If any of them fail the remaining targets
+	// are skipped.
+	Canary int
+	// BatchPause is how long to wait between batches.
+	BatchPause time.Duration
+	// MaxFailures if non-zero stops starting new batches once this many
+	// targets have failed.
+	MaxFailures int
+	// MaxFailurePercent if non-zero stops starting new batches once this
+	// percentage of all targets have failed.
+	MaxFailurePercent float64
+	// HealthCheck if set is a command (e.g. "healthcheck validate") run
+	// against each batch once the main command completes. If it fails for
+	// any target the remaining targets are skipped.
+	HealthCheck []string
+	// Format if set is one of Formats(). Instead of each command's own
+	// output, an Envelope holding the status and replies of each call the
+	// command makes is written for each target.
+	Format string
+	// Aggregate if true replaces each command's own output with the targets
+	// grouped by identical result, so outliers stand out.
+	Aggregate bool
+	// Baseline optionally names a target (as host[:port]) which the other
+	// groups are diffed against when aggregating.
+	Baseline string
 
 	credentials.PerRPCCredentials
 }
@@ -88,6 +119,17 @@ const (
 	defaultOutput = "-"
 )
 
+// localCommands are top level commands which run entirely on the local
+// machine and so don't need targets, a proxy or credentials.
+var localCommands = map[string]bool{}
+
+// RegisterLocalCommand marks the top level command name as one which runs
+// locally. Run will execute it directly without connecting anywhere and
+// output goes to stdout/stderr.
+func RegisterLocalCommand(name string) {
+	localCommands[name] = true
+}
+
 // UnaryClientTimeoutInterceptor returns a grpc.UnaryClientInterceptor that adds a deadline to every request
 func UnaryClientTimeoutInterceptor(timeout time.Duration) grpc.UnaryClientInterceptor {
 	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
@@ -189,6 +231,13 @@ func Run(ctx context.Context, rs RunState) {
 		// to invoke the tool and not trying to run the command.
 		os.Exit(int(subcommands.Execute(ctx, &util.ExecuteState{})))
 	}
+	if localCommands[flag.Arg(0)] {
+		state := &util.ExecuteState{
+			Out: []io.Writer{os.Stdout},
+			Err: []io.Writer{os.Stderr},
+		}
+		os.Exit(int(subcommands.Execute(ctx, state)))
+	}
 
 	// Bunch of flag sanity checking
 	if len(rs.Targets) == 0 && rs.Proxy == "" {
@@ -199,6 +248,32 @@ func Run(ctx context.Context, rs RunState) {
 		fmt.Fprintln(os.Stderr, "Can't set targets to multiple entries without a proxy")
 		os.Exit(1)
 	}
+	if rs.Format != "" {
+		if !validFormat(rs.Format) {
+			fmt.Fprintf(os.Stderr, "Unknown output format %q, must be one of %v\n", rs.Format, Formats())
+			os.Exit(1)
+		}
+		if rs.PrefixOutput {
+			fmt.Fprintln(os.Stderr, "Can't prefix output when using an output format")
+			os.Exit(1)
+		}
+	}
+	if rs.Aggregate && (rs.Format != "" || rs.PrefixOutput) {
+		fmt.Fprintln(os.Stderr, "Can't aggregate output when using an output format or prefixing output")
+		os.Exit(1)
+	}
+	baseline := -1
+	if rs.Baseline != "" {
+		if !rs.Aggregate {
+			fmt.Fprintln(os.Stderr, "A baseline can only be used when aggregating output")
+			os.Exit(1)
+		}
+		if baseline = findBaseline(rs.Baseline, rs.Targets); baseline == -1 {
+			fmt.Fprintf(os.Stderr, "Baseline %q isn't one of the targets\n", rs.Baseline)
+			os.Exit(1)
+		}
+	}
+	recording := rs.Format != "" || rs.Aggregate
 
 	// Process combinations of outputs/output-dir that are valid and in the end
 	// make sure outputsFlag has the correct relevant entries.
@@ -287,6 +362,18 @@ func Run(ctx context.Context, rs RunState) {
 		unaryInterceptors = append(unaryInterceptors, mpahooks.UnaryClientIntercepter())
 		streamInterceptors = append(streamInterceptors, mpahooks.StreamClientIntercepter())
 	}
+	// Calls which don't go through the proxy are recorded by gRPC interceptors.
+	// There's only ever one batch in that case so one recorder will do.
+	var directRecorder *replyRecorder
+	if recording && (rs.Proxy == "" || len(rs.Targets) == 0) {
+		name := rs.Proxy
+		if rs.Proxy == "" {
+			name = rs.Targets[0]
+		}
+		directRecorder = newReplyRecorder([]string{name})
+		streamInterceptors = append([]grpc.StreamClientInterceptor{directRecorder.grpcStreamInterceptor}, streamInterceptors...)
+		unaryInterceptors = append([]grpc.UnaryClientInterceptor{directRecorder.grpcUnaryInterceptor}, unaryInterceptors...)
+	}
 	// timeout interceptor should be the last item in ops so that it's executed first.
 	streamInterceptors = append(streamInterceptors, StreamClientTimeoutInterceptor(rs.IdleTimeout))
 	unaryInterceptors = append(unaryInterceptors, UnaryClientTimeoutInterceptor(rs.IdleTimeout))
@@ -338,23 +425,24 @@ func Run(ctx context.Context, rs RunState) {
 
 	exitCode := subcommands.ExitSuccess
 
-	// If there's no batch size set then it'll be the whole thing so we can use one loop below.
-	if rs.BatchSize == 0 {
-		rs.BatchSize = len(rs.Targets)
-	}
 	// Save original lists since we'll replace rs with sub slices
 	output := state.Out
 	errors := state.Err
 
-	batchCnt := 1
-	if len(rs.Targets) > 0 {
-		// How many batches? Integer math truncates so we have to do one more for remainder.
-		batchCnt = (len(rs.Targets)-1)/rs.BatchSize + 1
-	}
-	for i := 0; i < batchCnt; i++ {
-		start, end := i*rs.BatchSize, rs.BatchSize*(i+1)
-		if end > len(rs.Targets) {
-			end = len(rs.Targets)
+	batches := planBatches(len(rs.Targets), rs.Canary, rs.BatchSize)
+	limits := failureLimits{max: rs.MaxFailures, maxPercent: rs.MaxFailurePercent, total: len(rs.Targets)}
+	failed := 0
+	var results []targetResult
+	for i, b := range batches {
+		start, end := b.start, b.end
+		if i > 0 && rs.BatchPause > 0 {
+			fmt.Fprintf(os.Stderr, "Pausing %v before batch %d\n", rs.BatchPause, i)
+			select {
+			case <-ctx.Done():
+				fmt.Fprintf(os.Stderr, "Rollout interrupted: %v\n", ctx.Err())
+				os.Exit(1)
+			case <-time.After(rs.BatchPause):
+			}
 		}
 		// Set up a connection to the sansshell-server (possibly via proxy).
 		conn, err := proxy.DialContext(ctx, rs.Proxy, rs.Targets[start:end], ops...)
@@ -364,27 +452,145 @@ func Run(ctx context.Context, rs RunState) {
 		}
 
 		conn.AuthzDryRun = rs.AuthzDryRun
+		conn.AuthzExplain = rs.AuthzExplain
+		conn.AuthzExplanationHandler = printAuthzExplanation
 
 		if rs.EnableMPA {
 			conn.UnaryInterceptors = []proxy.UnaryInterceptor{mpahooks.ProxyClientUnaryInterceptor(state)}
 			conn.StreamInterceptors = []proxy.StreamInterceptor{mpahooks.ProxyClientStreamInterceptor(state)}
 		}
 		state.Conn = conn
-		state.Out = output[start:end]
-		state.Err = errors[start:end]
+		batchOutput := output[start:end]
+		batchErrors := errors[start:end]
 		if len(rs.Targets) == 0 {
 			// Special case - if we're talking directly to the proxy, we have an output of size 1
-			state.Out = output[:1]
-			state.Err = errors[:1]
+			batchOutput = output[:1]
+			batchErrors = errors[:1]
 		}
-		if subcommands.Execute(ctx, state) != subcommands.ExitSuccess {
-			exitCode = subcommands.ExitFailure
+		state.Out = batchOutput
+
+		// Calls through the proxy are always recorded so that failures can
+		// be told apart per target. With an output format or aggregation
+		// the replies are written out instead of the command's own output.
+		recorder := directRecorder
+		if recorder == nil && rs.Proxy != "" && len(rs.Targets) > 0 {
+			recorder = newReplyRecorder(rs.Targets[start:end])
+			recorder.errorsOnly = !recording
+			conn.UnaryInterceptors = append([]proxy.UnaryInterceptor{recorder.unaryInterceptor}, conn.UnaryInterceptors...)
+			conn.StreamInterceptors = append([]proxy.StreamInterceptor{recorder.streamInterceptor}, conn.StreamInterceptors...)
+		}
+		if recording {
+			state.Out = make([]io.Writer, len(batchOutput))
+			for j := range state.Out {
+				state.Out[j] = io.Discard
+			}
+		}
+		state.Err = batchErrors
+		batchFailed := failedTargets(subcommands.Execute(ctx, state), recorder, 0, len(batchErrors))
+
+		// Only run the health check if there's something to check.
+		healthFailed := false
+		if len(rs.HealthCheck) > 0 && len(rs.Targets) > 0 {
+			from := 0
+			if recorder != nil {
+				from = recorder.numCalls()
+			}
+			for j, f := range failedTargets(executeArgs(ctx, rs.HealthCheck, state), recorder, from, len(batchErrors)) {
+				if f {
+					fmt.Fprintf(batchErrors[j], "Health check %q failed\n", strings.Join(rs.HealthCheck, " "))
+					batchFailed[j] = true
+					healthFailed = true
+				}
+			}
 		}
 		if err := conn.Close(); err != nil {
 			fmt.Fprintf(os.Stderr, "error closing connection - %v\n", err)
 		}
+		if rs.Aggregate {
+			for j := range recorder.targets {
+				r := recorder.results(j)
+				r.index += start
+				results = append(results, r)
+			}
+		}
+		if rs.Format != "" {
+			if err := recorder.write(rs.Format, start, batchOutput); err != nil {
+				fmt.Fprintf(os.Stderr, "Can't write %s output - %v\n", rs.Format, err)
+				exitCode = subcommands.ExitFailure
+			}
+		}
+
+		cnt := 0
+		for _, f := range batchFailed {
+			if f {
+				cnt++
+			}
+		}
+		if cnt > 0 {
+			exitCode = subcommands.ExitFailure
+		}
+		failed += cnt
+		if i == len(batches)-1 {
+			break
+		}
+
+		reason := limits.exceeded(failed)
+		switch {
+		case healthFailed:
+			reason = fmt.Sprintf("health check failed for %d target(s) in batch %d", cnt, i)
+		case i == 0 && rs.Canary > 0 && cnt > 0:
+			reason = fmt.Sprintf("%d canary target(s) failed", cnt)
+		}
+		if reason != "" {
+			fmt.Fprintf(os.Stderr, "Aborting rollout: %s\n", reason)
+			for j := end; j < len(rs.Targets); j++ {
+				fmt.Fprintf(errors[j], "Skipped: rollout aborted: %s\n", reason)
+			}
+			exitCode = subcommands.ExitFailure
+			break
+		}
+	}
+
+	if rs.Aggregate {
+		writeAggregate(os.Stdout, results, baseline)
 	}
 
 	// Invoke the subcommand, passing the dialed connection object
 	os.Exit(int(exitCode))
 }
+
+// printAuthzExplanation prints the proxy's explanation of its authz
+// decision for target.
+func printAuthzExplanation(target string, explanation *proxypb.AuthzExplanation) {
+	fmt.Printf("%s: Authz explanation: %s", target, explanationFromProto(explanation))
+}
+
+func explanationFromProto(p *proxypb.AuthzExplanation) *rpcauth.Explanation {
+	e := &rpcauth.Explanation{
+		Allowed:     p.Allowed,
+		DenialHints: p.DenialHints,
+	}
+	for _, r := range p.Rules {
+		rule := &rpcauth.RuleExplanation{
+			Line:        int(r.Line),
+			Matched:     r.Matched,
+			Expressions: int(r.Expressions),
+			Passed:      int(r.Passed),
+		}
+		if f := r.GetFailed(); f != nil {
+			rule.Failed = &rpcauth.ExpressionExplanation{
+				Line: int(f.Line),
+				Text: f.Text,
+			}
+			for _, in := range f.InputFields {
+				field := &rpcauth.InputFieldExplanation{Path: in.Path}
+				if in.Value != "" {
+					field.Value = json.RawMessage(in.Value)
+				}
+				rule.Failed.InputFields = append(rule.Failed.InputFields, field)
+			}
+		}
+		e.Rules = append(e.Rules, rule)
+	}
+	return e
+}
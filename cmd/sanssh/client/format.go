@@ -0,0 +1,397 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"gopkg.in/yaml.v3"
+
+	cmdUtil "github.com/Snowflake-Labs/sansshell/cmd/util"
+	"github.com/Snowflake-Labs/sansshell/proxy/proxy"
+)
+
+// Output formats for RunState.Format. The default (empty) format is each
+// command's own text output.
+const (
+	// FormatJSON emits each Envelope as an indented JSON object.
+	FormatJSON = "json"
+	// FormatJSONL emits each Envelope as a JSON object on a single line.
+	FormatJSONL = "jsonl"
+	// FormatYAML emits each Envelope as a YAML document.
+	FormatYAML = "yaml"
+)
+
+// Formats returns the supported output formats, other than the default.
+func Formats() []string {
+	return []string{FormatJSON, FormatJSONL, FormatYAML}
+}
+
+func validFormat(format string) bool {
+	for _, f := range Formats() {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Envelope is the machine readable output for one RPC made by a command
+// to one target.
+type Envelope struct {
+	// Target is the target as given to sanssh.
+	Target string `json:"target"`
+	// Index is the index of the target in the target list.
+	Index int `json:"index"`
+	// Method is the full gRPC method called (e.g. /Process.Process/List).
+	Method string `json:"method"`
+	// Status is the status the call finished with for this target.
+	Status EnvelopeStatus `json:"status"`
+	// Attempts is how many times the call was made to the target, if the
+	// method is retried. See proxy.RetryPolicy.
+	Attempts int `json:"attempts,omitempty"`
+	// Responses holds each reply received from the target as protojson.
+	// Messages include an @type field naming their type.
+	Responses []json.RawMessage `json:"responses"`
+}
+
+// EnvelopeStatus is the gRPC status of a call.
+type EnvelopeStatus struct {
+	// Code is the name of the status code (e.g. OK or NotFound).
+	Code string `json:"code"`
+	// Message is the error message, if any.
+	Message string `json:"message,omitempty"`
+}
+
+// recordedCall is a single RPC made to a set of targets.
+type recordedCall struct {
+	method    string
+	responses [][]*anypb.Any
+	errs      []error
+	// done is set once a target's stream has closed cleanly.
+	done []bool
+	// attempts is how many times the call was made to each target.
+	attempts []int
+}
+
+// replyRecorder captures the replies to every call made by a command so
+// they can be written out as Envelopes instead of the command's own text.
+// It's installed as interceptors on the proxy.Conn for calls through the
+// proxy, and as gRPC interceptors for calls made directly to a target or
+// the proxy itself.
+type replyRecorder struct {
+	targets []string
+	// errorsOnly drops responses, for when only the errors are needed.
+	errorsOnly bool
+
+	mu    sync.Mutex
+	calls []*recordedCall
+}
+
+// newReplyRecorder returns a recorder for calls to targets, which are given
+// as host[:port][;<duration>].
+func newReplyRecorder(targets []string) *replyRecorder {
+	r := &replyRecorder{}
+	for _, t := range targets {
+		r.targets = append(r.targets, cmdUtil.StripTimeout(t))
+	}
+	return r
+}
+
+func (r *replyRecorder) newCall(method string) *recordedCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := &recordedCall{
+		method:    method,
+		responses: make([][]*anypb.Any, len(r.targets)),
+		errs:      make([]error, len(r.targets)),
+		done:      make([]bool, len(r.targets)),
+		attempts:  make([]int, len(r.targets)),
+	}
+	r.calls = append(r.calls, c)
+	return c
+}
+
+// add records a reply or error for the target at index. A nil resp and
+// err records nothing.
+func (r *replyRecorder) add(c *recordedCall, index int, resp *anypb.Any, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if index < 0 || index >= len(r.targets) {
+		return
+	}
+	if errors.Is(err, io.EOF) {
+		c.done[index] = true
+		return
+	}
+	if err != nil {
+		c.errs[index] = err
+		return
+	}
+	if resp != nil && !r.errorsOnly {
+		c.responses[index] = append(c.responses[index], resp)
+	}
+}
+
+// numCalls returns the number of calls recorded so far.
+func (r *replyRecorder) numCalls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.calls)
+}
+
+// failed returns true if any call from the from'th on ended with an error
+// for the target at index.
+func (r *replyRecorder) failed(from, index int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if index < 0 || index >= len(r.targets) {
+		return false
+	}
+	for _, c := range r.calls[min(from, len(r.calls)):] {
+		if status.Code(c.errs[index]) != codes.OK {
+			return true
+		}
+	}
+	return false
+}
+
+// addMessage records msg as a reply from the target at index.
+func (r *replyRecorder) addMessage(c *recordedCall, index int, msg any) {
+	m, ok := msg.(proto.Message)
+	if !ok {
+		return
+	}
+	resp, err := anypb.New(m)
+	r.add(c, index, resp, err)
+}
+
+// addRet records a reply or error from the proxy.
+func (r *replyRecorder) addRet(c *recordedCall, ret *proxy.Ret) {
+	r.add(c, ret.Index, ret.Resp, ret.Error)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if ret.Index >= 0 && ret.Index < len(r.targets) && ret.Attempts > c.attempts[ret.Index] {
+		c.attempts[ret.Index] = ret.Attempts
+	}
+}
+
+// failAll records err for every target which hasn't already finished.
+func (r *replyRecorder) failAll(c *recordedCall, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range c.errs {
+		if c.errs[i] == nil && !c.done[i] {
+			c.errs[i] = err
+		}
+	}
+}
+
+// unaryInterceptor records calls made with proxy.Conn.InvokeOneMany.
+func (r *replyRecorder) unaryInterceptor(ctx context.Context, conn *proxy.Conn, method string, args any, invoker proxy.UnaryInvoker, opts ...grpc.CallOption) (<-chan *proxy.Ret, error) {
+	c := r.newCall(method)
+	ch, err := invoker(ctx, method, args, opts...)
+	if err != nil {
+		r.failAll(c, err)
+		return nil, err
+	}
+	out := make(chan *proxy.Ret)
+	go func() {
+		for ret := range ch {
+			r.addRet(c, ret)
+			out <- ret
+		}
+		close(out)
+	}()
+	return out, nil
+}
+
+// streamInterceptor records calls made with proxy.Conn.NewStream.
+func (r *replyRecorder) streamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *proxy.Conn, method string, streamer proxy.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	c := r.newCall(method)
+	stream, err := streamer(ctx, desc, method, opts...)
+	if err != nil {
+		r.failAll(c, err)
+		return nil, err
+	}
+	return &recordingStream{ClientStream: stream, recorder: r, call: c}, nil
+}
+
+// grpcUnaryInterceptor records calls to a single target (or the proxy)
+// which don't go through the proxy.
+func (r *replyRecorder) grpcUnaryInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	c := r.newCall(method)
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	if err != nil {
+		r.add(c, 0, nil, err)
+		return err
+	}
+	r.addMessage(c, 0, reply)
+	return nil
+}
+
+// grpcStreamInterceptor is the streaming version of grpcUnaryInterceptor.
+func (r *replyRecorder) grpcStreamInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	c := r.newCall(method)
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		r.add(c, 0, nil, err)
+		return nil, err
+	}
+	return &recordingStream{ClientStream: stream, recorder: r, call: c}, nil
+}
+
+// recordingStream records every message received on a stream.
+type recordingStream struct {
+	grpc.ClientStream
+	recorder *replyRecorder
+	call     *recordedCall
+}
+
+func (s *recordingStream) RecvMsg(m any) error {
+	rets, many := m.(*[]*proxy.Ret)
+	before := 0
+	if many {
+		before = len(*rets)
+	}
+	err := s.ClientStream.RecvMsg(m)
+	if many {
+		for _, ret := range (*rets)[before:] {
+			s.recorder.addRet(s.call, ret)
+		}
+	} else if err == nil {
+		s.recorder.addMessage(s.call, 0, m)
+	}
+	switch {
+	case err == nil || errors.Is(err, io.EOF):
+	case many:
+		// An error for the whole stream rather than any one target.
+		s.recorder.failAll(s.call, err)
+	default:
+		s.recorder.add(s.call, 0, nil, err)
+	}
+	return err
+}
+
+// envelopes returns the Envelopes for the target at index, in the order
+// calls were made.
+func (r *replyRecorder) envelopes(index int) ([]*Envelope, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []*Envelope
+	for _, c := range r.calls {
+		st := status.Convert(c.errs[index])
+		e := &Envelope{
+			Target:    r.targets[index],
+			Index:     index,
+			Method:    c.method,
+			Status:    EnvelopeStatus{Code: st.Code().String(), Message: st.Message()},
+			Attempts:  c.attempts[index],
+			Responses: []json.RawMessage{},
+		}
+		for _, resp := range c.responses[index] {
+			b, err := protojson.Marshal(resp)
+			if err != nil {
+				return nil, fmt.Errorf("can't marshal %s response from %s: %v", c.method, e.Target, err)
+			}
+			e.Responses = append(e.Responses, b)
+		}
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// write writes the Envelopes for each target to the matching writer in
+// outs using format. Index in the Envelopes is offset by base so it refers
+// to the full target list rather than this batch.
+func (r *replyRecorder) write(format string, base int, outs []io.Writer) error {
+	for i := range r.targets {
+		envs, err := r.envelopes(i)
+		if err != nil {
+			return err
+		}
+		for _, e := range envs {
+			e.Index += base
+			if err := writeEnvelope(format, outs[i], e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeEnvelope writes e to w in format.
+func writeEnvelope(format string, w io.Writer, e *Envelope) error {
+	var b []byte
+	var err error
+	switch format {
+	case FormatJSON:
+		b, err = json.MarshalIndent(e, "", "  ")
+		b = append(b, '\n')
+	case FormatJSONL:
+		b, err = json.Marshal(e)
+		b = append(b, '\n')
+	case FormatYAML:
+		b, err = envelopeYAML(e)
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// envelopeYAML renders e as a YAML document. It goes through JSON so that
+// responses keep their protojson field names and order.
+func envelopeYAML(e *Envelope) ([]byte, error) {
+	j, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	// JSON is YAML, but in flow style. Clear the styles so it's written
+	// out in the usual block style.
+	var node yaml.Node
+	if err := yaml.Unmarshal(j, &node); err != nil {
+		return nil, err
+	}
+	clearYAMLStyle(&node)
+	y, err := yaml.Marshal(&node)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("---\n"), y...), nil
+}
+
+func clearYAMLStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		clearYAMLStyle(c)
+	}
+}
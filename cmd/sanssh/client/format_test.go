@@ -0,0 +1,223 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/Snowflake-Labs/sansshell/proxy/proxy"
+	"github.com/Snowflake-Labs/sansshell/proxy/server"
+	tdpb "github.com/Snowflake-Labs/sansshell/proxy/testdata"
+	proxytestutil "github.com/Snowflake-Labs/sansshell/proxy/testutil"
+	"github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+func mustAny(t *testing.T, m proto.Message) *anypb.Any {
+	t.Helper()
+	a, err := anypb.New(m)
+	testutil.FatalOnErr("anypb.New", err, t)
+	return a
+}
+
+// fakeStream returns each batch of rets from RecvMsg in turn, then err.
+type fakeStream struct {
+	grpc.ClientStream
+	rets [][]*proxy.Ret
+	err  error
+}
+
+func (f *fakeStream) RecvMsg(m any) error {
+	if len(f.rets) == 0 {
+		return f.err
+	}
+	p := m.(*[]*proxy.Ret)
+	*p = append(*p, f.rets[0]...)
+	f.rets = f.rets[1:]
+	return nil
+}
+
+func TestReplyRecorder(t *testing.T) {
+	ctx := context.Background()
+	rec := newReplyRecorder([]string{"host1", "host2"})
+
+	// A unary call where one target fails.
+	invoker := func(ctx context.Context, method string, args any, opts ...grpc.CallOption) (<-chan *proxy.Ret, error) {
+		ch := make(chan *proxy.Ret, 2)
+		ch <- &proxy.Ret{Target: "host1", Index: 0, Resp: mustAny(t, wrapperspb.String("one"))}
+		ch <- &proxy.Ret{Target: "host2", Index: 1, Error: status.Error(codes.NotFound, "no such thing")}
+		close(ch)
+		return ch, nil
+	}
+	ch, err := rec.unaryInterceptor(ctx, nil, "/Test.Test/Unary", nil, invoker)
+	testutil.FatalOnErr("unaryInterceptor", err, t)
+	cnt := 0
+	for range ch {
+		cnt++
+	}
+	if cnt != 2 {
+		t.Fatalf("got %d replies from interceptor, want 2", cnt)
+	}
+
+	// A stream where host1 sends two replies and then the whole stream fails.
+	streamer := func(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &fakeStream{
+			rets: [][]*proxy.Ret{
+				{{Target: "host1", Index: 0, Resp: mustAny(t, wrapperspb.Int64(1))}},
+				{{Target: "host1", Index: 0, Resp: mustAny(t, wrapperspb.Int64(2))}, {Target: "host1", Index: 0, Error: io.EOF}},
+			},
+			err: status.Error(codes.Unavailable, "stream broke"),
+		}, nil
+	}
+	stream, err := rec.streamInterceptor(ctx, &grpc.StreamDesc{}, nil, "/Test.Test/Stream", streamer)
+	testutil.FatalOnErr("streamInterceptor", err, t)
+	for {
+		var rets []*proxy.Ret
+		if err := stream.RecvMsg(&rets); err != nil {
+			break
+		}
+	}
+
+	var out [2]bytes.Buffer
+	testutil.FatalOnErr("write", rec.write(FormatJSONL, 10, []io.Writer{&out[0], &out[1]}), t)
+
+	want := [2][]string{
+		{
+			`{"target":"host1","index":10,"method":"/Test.Test/Unary","status":{"code":"OK"},"responses":[{"@type":"type.googleapis.com/google.protobuf.StringValue","value":"one"}]}`,
+			`{"target":"host1","index":10,"method":"/Test.Test/Stream","status":{"code":"OK"},"responses":[{"@type":"type.googleapis.com/google.protobuf.Int64Value","value":"1"},{"@type":"type.googleapis.com/google.protobuf.Int64Value","value":"2"}]}`,
+		},
+		{
+			`{"target":"host2","index":11,"method":"/Test.Test/Unary","status":{"code":"NotFound","message":"no such thing"},"responses":[]}`,
+			`{"target":"host2","index":11,"method":"/Test.Test/Stream","status":{"code":"Unavailable","message":"stream broke"},"responses":[]}`,
+		},
+	}
+	for i := range out {
+		got := strings.Split(strings.TrimSpace(out[i].String()), "\n")
+		if len(got) != len(want[i]) {
+			t.Fatalf("target %d: got %d lines, want %d:\n%s", i, len(got), len(want[i]), out[i].String())
+		}
+		for j := range got {
+			if got[j] != want[i][j] {
+				t.Errorf("target %d line %d:\ngot  %s\nwant %s", i, j, got[j], want[i][j])
+			}
+		}
+	}
+}
+
+func TestWriteEnvelope(t *testing.T) {
+	e := &Envelope{
+		Target:    "host1",
+		Index:     0,
+		Method:    "/Test.Test/Unary",
+		Status:    EnvelopeStatus{Code: "OK"},
+		Responses: []json.RawMessage{json.RawMessage(`{"@type":"type.googleapis.com/google.protobuf.StringValue","value":"true"}`)},
+	}
+
+	var buf bytes.Buffer
+	testutil.FatalOnErr("json", writeEnvelope(FormatJSON, &buf, e), t)
+	got := &Envelope{}
+	testutil.FatalOnErr("unmarshal", json.Unmarshal(buf.Bytes(), got), t)
+	if got.Target != e.Target || got.Method != e.Method || len(got.Responses) != 1 {
+		t.Errorf("json round trip got %+v, want %+v", got, e)
+	}
+	if !strings.Contains(buf.String(), "\n  \"target\"") {
+		t.Errorf("json output isn't indented:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	testutil.FatalOnErr("yaml", writeEnvelope(FormatYAML, &buf, e), t)
+	wantYAML := `---
+target: host1
+index: 0
+method: /Test.Test/Unary
+status:
+    code: OK
+responses:
+    - '@type': type.googleapis.com/google.protobuf.StringValue
+      value: "true"
+`
+	if buf.String() != wantYAML {
+		t.Errorf("yaml got:\n%s\nwant:\n%s", buf.String(), wantYAML)
+	}
+
+	if err := writeEnvelope("xml", &buf, e); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
+
+func TestReplyRecorderThroughProxy(t *testing.T) {
+	ctx := context.Background()
+	targets := proxytestutil.StartTestDataServers(t, "foo:123", "bar:123")
+	lis := bufconn.Listen(proxytestutil.BufSize)
+	authz := proxytestutil.NewAllowAllRPCAuthorizer(ctx, t)
+	s := grpc.NewServer(grpc.StreamInterceptor(authz.AuthorizeStream))
+	server.New(server.NewDialer(proxytestutil.WithBufDialer(targets), grpc.WithTransportCredentials(insecure.NewCredentials())), authz).Register(s)
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	names := []string{"foo:123", "bar:123"}
+	conn, err := proxy.DialContext(ctx, "proxy", names,
+		proxytestutil.WithBufDialer(map[string]*bufconn.Listener{"proxy": lis}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	testutil.FatalOnErr("DialContext", err, t)
+	t.Cleanup(func() { conn.Close() })
+
+	rec := newReplyRecorder(names)
+	conn.UnaryInterceptors = []proxy.UnaryInterceptor{rec.unaryInterceptor}
+	conn.StreamInterceptors = []proxy.StreamInterceptor{rec.streamInterceptor}
+
+	ts := tdpb.NewTestServiceClientProxy(conn)
+	resps, err := ts.TestUnaryOneMany(ctx, &tdpb.TestRequest{Input: "input"})
+	testutil.FatalOnErr("TestUnaryOneMany", err, t)
+	for range resps {
+	}
+	stream, err := ts.TestServerStreamOneMany(ctx, &tdpb.TestRequest{Input: "error"})
+	testutil.FatalOnErr("TestServerStreamOneMany", err, t)
+	for {
+		if _, err := stream.Recv(); err != nil {
+			break
+		}
+	}
+
+	for i, name := range names {
+		envs, err := rec.envelopes(i)
+		testutil.FatalOnErr("envelopes", err, t)
+		if len(envs) != 2 {
+			t.Fatalf("%s: got %d envelopes, want 2", name, len(envs))
+		}
+		unary, stream := envs[0], envs[1]
+		if unary.Status.Code != "OK" || len(unary.Responses) != 1 || !strings.Contains(string(unary.Responses[0]), name+" input") {
+			t.Errorf("%s: unexpected unary envelope %+v", name, unary)
+		}
+		if stream.Method != "/Testdata.TestService/TestServerStream" || stream.Status.Code == "OK" || len(stream.Responses) != 0 {
+			t.Errorf("%s: unexpected stream envelope %+v", name, stream)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A Selector picks targets by label. A target matches if it has every
+// label in the selector with the same value.
+type Selector map[string]string
+
+// ParseSelector parses a selector of the form key=value[,key=value...].
+// An empty string is an empty selector which matches everything.
+func ParseSelector(s string) (Selector, error) {
+	sel := Selector{}
+	for _, kv := range strings.Split(s, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(kv, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid selector %q, must be key=value", kv)
+		}
+		if _, dup := sel[k]; dup {
+			return nil, fmt.Errorf("label %q selected more than once", k)
+		}
+		sel[k] = strings.TrimSpace(v)
+	}
+	return sel, nil
+}
+
+// Matches returns true if labels has every label in the selector.
+func (s Selector) Matches(labels map[string]string) bool {
+	for k, v := range s {
+		if l, ok := labels[k]; !ok || l != v {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the selector as key=value pairs sorted by key.
+func (s Selector) String() string {
+	return strings.Join(s.pairs(), ",")
+}
+
+// pairs returns the selector as key=value strings sorted by key.
+func (s Selector) pairs() []string {
+	var kvs []string
+	for k, v := range s {
+		kvs = append(kvs, k+"="+v)
+	}
+	sort.Strings(kvs)
+	return kvs
+}
+
+// A TargetResolver finds the targets (as host[:port]) matching a selector.
+// Source is resolver specific, such as the path of an inventory file.
+type TargetResolver interface {
+	Resolve(ctx context.Context, source string, sel Selector) ([]string, error)
+}
+
+var (
+	resolverMu sync.RWMutex
+	resolvers  = make(map[string]TargetResolver)
+)
+
+// RegisterTargetResolver associates a name with a TargetResolver.
+// Implementations will typically call RegisterTargetResolver during init().
+func RegisterTargetResolver(name string, resolver TargetResolver) error {
+	resolverMu.Lock()
+	defer resolverMu.Unlock()
+	if resolver == nil {
+		return errors.New("resolver cannot be nil")
+	}
+	if _, exists := resolvers[name]; exists {
+		return errors.New("duplicate registration of target resolver with name: " + name)
+	}
+	resolvers[name] = resolver
+	return nil
+}
+
+// TargetResolvers returns the names of all registered TargetResolvers as a
+// sorted list.
+func TargetResolvers() []string {
+	resolverMu.RLock()
+	defer resolverMu.RUnlock()
+	var out []string
+	for r := range resolvers {
+		out = append(out, r)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ResolveTargets resolves sel using spec, which names a registered resolver
+// and its source as name:source (e.g. inventory:/etc/sansshell/hosts.yaml).
+func ResolveTargets(ctx context.Context, spec string, sel Selector) ([]string, error) {
+	name, source, _ := strings.Cut(spec, ":")
+	resolverMu.RLock()
+	resolver, ok := resolvers[name]
+	resolverMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown target resolver %q (must be one of [%s])", name, strings.Join(TargetResolvers(), ","))
+	}
+	if source == "" {
+		return nil, fmt.Errorf("target resolver %q needs a source, as %s:<source>", name, name)
+	}
+	return resolver.Resolve(ctx, source, sel)
+}
+
+func init() {
+	for name, resolver := range map[string]TargetResolver{
+		"inventory": inventoryResolver{},
+		"srv":       srvResolver{},
+		"exec":      execResolver{},
+	} {
+		if err := RegisterTargetResolver(name, resolver); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Inventory is the format of the files read by the inventory resolver.
+// It can be written as YAML or JSON:
+//
+//	hosts:
+//	  - target: db1.example.com:50042
+//	    labels: {role: fdb, az: us-east-1a}
+type Inventory struct {
+	Hosts []InventoryHost `yaml:"hosts" json:"hosts"`
+}
+
+// InventoryHost is a single target in an Inventory.
+type InventoryHost struct {
+	Target string            `yaml:"target" json:"target"`
+	Labels map[string]string `yaml:"labels" json:"labels"`
+}
+
+// inventoryResolver returns the hosts in an inventory file whose labels
+// match the selector.
+type inventoryResolver struct{}
+
+func (inventoryResolver) Resolve(_ context.Context, source string, sel Selector) ([]string, error) {
+	b, err := os.ReadFile(source)
+	if err != nil {
+		return nil, fmt.Errorf("can't read inventory: %v", err)
+	}
+	// JSON is a subset of YAML so one decoder handles both.
+	inv := &Inventory{}
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	dec.KnownFields(true)
+	if err := dec.Decode(inv); err != nil {
+		return nil, fmt.Errorf("can't parse inventory %s: %v", source, err)
+	}
+	var out []string
+	for i, h := range inv.Hosts {
+		if h.Target == "" {
+			return nil, fmt.Errorf("inventory %s: host %d has no target", source, i)
+		}
+		if sel.Matches(h.Labels) {
+			out = append(out, h.Target)
+		}
+	}
+	return out, nil
+}
+
+// lookupSRV is net.DefaultResolver.LookupSRV, replaceable for testing.
+var lookupSRV = net.DefaultResolver.LookupSRV
+
+// srvResolver returns the targets in the DNS SRV records for the source
+// name (e.g. _sansshell._tcp.example.com). SRV records don't carry labels
+// so selectors aren't supported.
+type srvResolver struct{}
+
+func (srvResolver) Resolve(ctx context.Context, source string, sel Selector) ([]string, error) {
+	if len(sel) > 0 {
+		return nil, fmt.Errorf("srv target resolver doesn't support selectors")
+	}
+	_, addrs, err := lookupSRV(ctx, "", "", source)
+	if err != nil {
+		return nil, fmt.Errorf("can't look up SRV records for %s: %v", source, err)
+	}
+	var out []string
+	for _, a := range addrs {
+		out = append(out, net.JoinHostPort(strings.TrimSuffix(a.Target, "."), strconv.Itoa(int(a.Port))))
+	}
+	return out, nil
+}
+
+// execResolver runs the command in source (split on whitespace) with the
+// selector as key=value arguments, sorted by key, and returns each non-blank
+// line of its output as a target. Lines starting with # are ignored.
+type execResolver struct{}
+
+func (execResolver) Resolve(ctx context.Context, source string, sel Selector) ([]string, error) {
+	args := strings.Fields(source)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("exec target resolver needs a command")
+	}
+	args = append(args, sel.pairs()...)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	b, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("running %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	var out []string
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return out, scanner.Err()
+}
@@ -0,0 +1,155 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+func TestParseSelector(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		want    Selector
+		wantErr bool
+	}{
+		{in: "", want: Selector{}},
+		{in: "role=fdb", want: Selector{"role": "fdb"}},
+		{in: "role=fdb, az=us-east-1a", want: Selector{"role": "fdb", "az": "us-east-1a"}},
+		{in: "role=", want: Selector{"role": ""}},
+		{in: "role", wantErr: true},
+		{in: "=fdb", wantErr: true},
+		{in: "role=fdb,role=web", wantErr: true},
+	} {
+		got, err := ParseSelector(tc.in)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("ParseSelector(%q) error = %v, want error %v", tc.in, err, tc.wantErr)
+			continue
+		}
+		if !tc.wantErr && !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ParseSelector(%q) = %v, want %v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestInventoryResolver(t *testing.T) {
+	dir := t.TempDir()
+	yml := filepath.Join(dir, "hosts.yaml")
+	testutil.FatalOnErr("write", os.WriteFile(yml, []byte(`
+hosts:
+  - target: fdb1:50042
+    labels: {role: fdb, az: us-east-1a}
+  - target: fdb2:50042
+    labels: {role: fdb, az: us-east-1b}
+  - target: web1
+    labels: {role: web, az: us-east-1a}
+`), 0o644), t)
+	js := filepath.Join(dir, "hosts.json")
+	testutil.FatalOnErr("write", os.WriteFile(js, []byte(`{"hosts": [
+  {"target": "fdb1:50042", "labels": {"role": "fdb", "az": "us-east-1a"}},
+  {"target": "web1", "labels": {"role": "web"}}
+]}`), 0o644), t)
+	bad := filepath.Join(dir, "bad.yaml")
+	testutil.FatalOnErr("write", os.WriteFile(bad, []byte("hosts:\n  - name: fdb1\n"), 0o644), t)
+
+	for _, tc := range []struct {
+		name    string
+		spec    string
+		sel     Selector
+		want    []string
+		wantErr bool
+	}{
+		{name: "all", spec: "inventory:" + yml, sel: Selector{}, want: []string{"fdb1:50042", "fdb2:50042", "web1"}},
+		{name: "one label", spec: "inventory:" + yml, sel: Selector{"role": "fdb"}, want: []string{"fdb1:50042", "fdb2:50042"}},
+		{name: "two labels", spec: "inventory:" + yml, sel: Selector{"role": "fdb", "az": "us-east-1b"}, want: []string{"fdb2:50042"}},
+		{name: "no match", spec: "inventory:" + yml, sel: Selector{"role": "db"}},
+		{name: "json", spec: "inventory:" + js, sel: Selector{"role": "web"}, want: []string{"web1"}},
+		{name: "unknown field", spec: "inventory:" + bad, wantErr: true},
+		{name: "missing file", spec: "inventory:" + filepath.Join(dir, "missing"), wantErr: true},
+		{name: "no source", spec: "inventory", wantErr: true},
+		{name: "unknown resolver", spec: "consul:" + yml, wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ResolveTargets(context.Background(), tc.spec, tc.sel)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ResolveTargets(%q) error = %v, want error %v", tc.spec, err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("ResolveTargets(%q, %v) = %v, want %v", tc.spec, tc.sel, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSRVResolver(t *testing.T) {
+	orig := lookupSRV
+	t.Cleanup(func() { lookupSRV = orig })
+	lookupSRV = func(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+		if name != "_sansshell._tcp.example.com" {
+			return "", nil, errors.New("no such host")
+		}
+		return name, []*net.SRV{
+			{Target: "host1.example.com.", Port: 50042},
+			{Target: "host2.example.com.", Port: 50043},
+		}, nil
+	}
+
+	got, err := ResolveTargets(context.Background(), "srv:_sansshell._tcp.example.com", nil)
+	testutil.FatalOnErr("ResolveTargets", err, t)
+	want := []string{"host1.example.com:50042", "host2.example.com:50043"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := ResolveTargets(context.Background(), "srv:_other._tcp.example.com", nil); err == nil {
+		t.Error("expected error for failed lookup")
+	}
+	if _, err := ResolveTargets(context.Background(), "srv:_sansshell._tcp.example.com", Selector{"role": "fdb"}); err == nil {
+		t.Error("expected error for selector")
+	}
+}
+
+func TestExecResolver(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "hosts.sh")
+	testutil.FatalOnErr("write", os.WriteFile(script, []byte(`#!/bin/sh
+if [ "$1" = "fail" ]; then
+  echo "bad args" >&2
+  exit 1
+fi
+echo "# hosts for $*"
+echo host1
+echo
+echo "$1"
+`), 0o755), t)
+
+	got, err := ResolveTargets(context.Background(), "exec:"+script, Selector{"role": "fdb", "az": "b"})
+	testutil.FatalOnErr("ResolveTargets", err, t)
+	// Selector arguments are sorted by key.
+	want := []string{"host1", "az=b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := ResolveTargets(context.Background(), "exec:"+script+" fail", nil); err == nil {
+		t.Error("expected error when command fails")
+	}
+}
@@ -0,0 +1,126 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/google/subcommands"
+)
+
+// batch is a range [start, end) of targets which are worked on together.
+type batch struct {
+	start, end int
+}
+
+// planBatches splits n targets into the batches a rollout goes through.
+// If canary is non-zero the first canary targets are done on their own
+// and the rest are split into batches of batchSize (or all at once if
+// batchSize is zero). There's always at least one batch so that commands
+// sent directly to the proxy (no targets) still run.
+func planBatches(n, canary, batchSize int) []batch {
+	if n == 0 {
+		return []batch{{0, 0}}
+	}
+	var batches []batch
+	start := 0
+	if canary > 0 {
+		if canary > n {
+			canary = n
+		}
+		batches = append(batches, batch{0, canary})
+		start = canary
+	}
+	if batchSize <= 0 {
+		batchSize = n - start
+	}
+	for ; start < n; start += batchSize {
+		end := start + batchSize
+		if end > n {
+			end = n
+		}
+		batches = append(batches, batch{start, end})
+	}
+	return batches
+}
+
+// failureLimits decides when a rollout has seen enough failures to
+// stop. Zero values disable the corresponding limit.
+type failureLimits struct {
+	// max is the number of failed targets at which to stop.
+	max int
+	// maxPercent is the percentage of all targets which, once failed,
+	// stops the rollout.
+	maxPercent float64
+	// total is the number of targets in the rollout.
+	total int
+}
+
+// exceeded returns a description of the limit reached by failed targets,
+// or an empty string if the rollout can continue.
+func (l failureLimits) exceeded(failed int) string {
+	if l.max > 0 && failed >= l.max {
+		return fmt.Sprintf("%d target(s) failed, limit is %d", failed, l.max)
+	}
+	if l.maxPercent > 0 && l.total > 0 && float64(failed)*100 >= l.maxPercent*float64(l.total) {
+		return fmt.Sprintf("%d of %d target(s) failed, limit is %g%%", failed, l.total, l.maxPercent)
+	}
+	return ""
+}
+
+// failedTargets returns which of n targets failed in a run that finished
+// with status, going by the calls r recorded from the from'th on. A target
+// fails if the run failed and one of its calls returned an error. If the
+// run failed without an error from any target, or there's no recorder,
+// all of them are considered failed since there's no way to tell them
+// apart.
+func failedTargets(status subcommands.ExitStatus, r *replyRecorder, from, n int) []bool {
+	failed := make([]bool, n)
+	if status == subcommands.ExitSuccess {
+		return failed
+	}
+	reported := false
+	if r != nil {
+		for i := range failed {
+			failed[i] = r.failed(from, i)
+			reported = reported || failed[i]
+		}
+	}
+	if !reported {
+		for i := range failed {
+			failed[i] = true
+		}
+	}
+	return failed
+}
+
+// executeArgs runs the registered top level command given by args (e.g.
+// "healthcheck validate") instead of the one on the command line.
+func executeArgs(ctx context.Context, args []string, state any) subcommands.ExitStatus {
+	f := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	if err := f.Parse(args); err != nil {
+		return subcommands.ExitUsageError
+	}
+	cdr := subcommands.NewCommander(f, os.Args[0])
+	subcommands.DefaultCommander.VisitCommands(func(g *subcommands.CommandGroup, c subcommands.Command) {
+		cdr.Register(c, g.Name())
+	})
+	return cdr.Execute(ctx, state)
+}
@@ -0,0 +1,111 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package client
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/subcommands"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/Snowflake-Labs/sansshell/proxy/proxy"
+)
+
+func TestPlanBatches(t *testing.T) {
+	for _, tc := range []struct {
+		name                 string
+		n, canary, batchSize int
+		want                 []batch
+	}{
+		{name: "no targets", n: 0, batchSize: 2, want: []batch{{0, 0}}},
+		{name: "all at once", n: 5, want: []batch{{0, 5}}},
+		{name: "batches", n: 5, batchSize: 2, want: []batch{{0, 2}, {2, 4}, {4, 5}}},
+		{name: "canary then rest", n: 5, canary: 1, want: []batch{{0, 1}, {1, 5}}},
+		{name: "canary then batches", n: 6, canary: 2, batchSize: 3, want: []batch{{0, 2}, {2, 5}, {5, 6}}},
+		{name: "canary covers everything", n: 2, canary: 5, batchSize: 1, want: []batch{{0, 2}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := planBatches(tc.n, tc.canary, tc.batchSize)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("planBatches(%d, %d, %d) = %v, want %v", tc.n, tc.canary, tc.batchSize, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFailureLimits(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		limits   failureLimits
+		failed   int
+		exceeded bool
+	}{
+		{name: "no limits", limits: failureLimits{total: 10}, failed: 10},
+		{name: "under max", limits: failureLimits{max: 3, total: 10}, failed: 2},
+		{name: "at max", limits: failureLimits{max: 3, total: 10}, failed: 3, exceeded: true},
+		{name: "under percent", limits: failureLimits{maxPercent: 25, total: 10}, failed: 2},
+		{name: "at percent", limits: failureLimits{maxPercent: 25, total: 8}, failed: 2, exceeded: true},
+		{name: "either limit", limits: failureLimits{max: 5, maxPercent: 10, total: 10}, failed: 1, exceeded: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.limits.exceeded(tc.failed)
+			if (got != "") != tc.exceeded {
+				t.Errorf("exceeded(%d) = %q, want exceeded %v", tc.failed, got, tc.exceeded)
+			}
+		})
+	}
+}
+
+func TestFailedTargets(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status subcommands.ExitStatus
+		errs   []error
+		from   int
+		noRec  bool
+		want   []bool
+	}{
+		{name: "success ignores errors", status: subcommands.ExitSuccess, errs: []error{status.Error(codes.Internal, "boom"), nil}, want: []bool{false, false}},
+		{name: "failure with errors", status: subcommands.ExitFailure, errs: []error{nil, status.Error(codes.Unavailable, "down"), nil}, want: []bool{false, true, false}},
+		{name: "failure without errors", status: subcommands.ExitFailure, errs: []error{nil, nil}, want: []bool{true, true}},
+		{name: "ok status isn't an error", status: subcommands.ExitFailure, errs: []error{status.Error(codes.OK, ""), status.Error(codes.NotFound, "gone")}, want: []bool{false, true}},
+		{name: "earlier calls are ignored", status: subcommands.ExitFailure, errs: []error{status.Error(codes.Internal, "boom"), nil}, from: 1, want: []bool{true, true}},
+		{name: "no recorder", status: subcommands.ExitFailure, noRec: true, errs: []error{nil, nil}, want: []bool{true, true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var r *replyRecorder
+			if !tc.noRec {
+				r = newReplyRecorder(make([]string, len(tc.errs)))
+				r.errorsOnly = true
+				c := r.newCall("/Foo/Bar")
+				for i, err := range tc.errs {
+					// Output written for a target doesn't make it fail.
+					r.addRet(c, &proxy.Ret{Index: i, Resp: mustAny(t, wrapperspb.String("out")), Error: err})
+				}
+				if got := len(c.responses[0]); got != 0 {
+					t.Errorf("errors only recorder kept %d responses", got)
+				}
+			}
+			if got := failedTargets(tc.status, r, tc.from, len(tc.errs)); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("failedTargets() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -32,15 +32,20 @@ import (
 	"github.com/google/subcommands"
 	"google.golang.org/grpc/metadata"
 
+	"github.com/Snowflake-Labs/sansshell/auth/jwt"
 	"github.com/Snowflake-Labs/sansshell/auth/mtls"
+	mtlsIssuer "github.com/Snowflake-Labs/sansshell/auth/mtls/certissuer"
 	mtlsFlags "github.com/Snowflake-Labs/sansshell/auth/mtls/flags"
+	mtlsSpiffe "github.com/Snowflake-Labs/sansshell/auth/mtls/spiffe"
 	"github.com/Snowflake-Labs/sansshell/auth/opa"
 	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
 	"github.com/Snowflake-Labs/sansshell/cmd/sanssh/client"
 	cmdUtil "github.com/Snowflake-Labs/sansshell/cmd/util"
+	"github.com/Snowflake-Labs/sansshell/services/mpa/mpahooks"
 	"github.com/Snowflake-Labs/sansshell/services/util"
 
 	// Import services here to make them accessible for CLI
+	_ "github.com/Snowflake-Labs/sansshell/cmd/sanssh/policy"
 	_ "github.com/Snowflake-Labs/sansshell/services/ansible/client"
 	_ "github.com/Snowflake-Labs/sansshell/services/dns/client"
 	_ "github.com/Snowflake-Labs/sansshell/services/exec/client"
@@ -57,6 +62,7 @@ import (
 	_ "github.com/Snowflake-Labs/sansshell/services/sansshell/client"
 	_ "github.com/Snowflake-Labs/sansshell/services/service/client"
 	_ "github.com/Snowflake-Labs/sansshell/services/sysinfo/client"
+	_ "github.com/Snowflake-Labs/sansshell/services/terminal/client"
 	_ "github.com/Snowflake-Labs/sansshell/services/tlsinfo/client"
 )
 
@@ -83,13 +89,26 @@ If port is blank the default of %d will be used`, proxyEnv, defaultProxyPort))
 	outputsDir       = flag.String("output-dir", "", "If set defines a directory to emit output/errors from commands. Files will be generated based on target as destination/0 destination/0.error, etc.")
 	justification    = flag.String("justification", "", "If non-empty will add the key '"+rpcauth.ReqJustKey+"' to the outgoing context Metadata to be passed along to the server for possible validation and logging.")
 	targetsFile      = flag.String("targets-file", "", "If set read the targets list line by line (as host[:port]) from the indicated file instead of using --targets (error if both flags are used). A blank port acts the same as --targets")
+	resolverFlag     = flag.String("resolver", "", fmt.Sprintf("If set, find targets with this resolver instead of using --targets, as NAME:SOURCE (e.g. inventory:hosts.yaml). NAME is one of [%s]", strings.Join(client.TargetResolvers(), ",")))
+	selectFlag       = flag.String("select", "", "Labels targets found by --resolver must have, as key=value[,key=value...] (e.g. role=fdb,az=us-east-1a)")
 	clientPolicyFlag = flag.String("client-policy", "", "OPA policy for outbound client actions.  If empty no policy is applied.")
 	clientPolicyFile = flag.String("client-policy-file", "", "Path to a file with a client OPA.  If empty uses --client-policy")
 	verbosity        = flag.Int("v", -1, "Verbosity level. > 0 indicates more extensive logging")
+	format           = flag.String("format", "", fmt.Sprintf("If set, emit the status and replies of each call to each target in this machine readable format (one of [%s]) instead of the command's usual output", strings.Join(client.Formats(), ",")))
+	aggregate        = flag.Bool("aggregate", false, "If true, instead of each target's output print the targets grouped by identical replies, with the largest group first and the rest marked as outliers")
+	baseline         = flag.String("baseline", "", "With --aggregate, show how each group differs from the replies of this target (host[:port])")
 	prefixHeader     = flag.Bool("h", false, "If true prefix each line of output with '<index>-<target>: '")
 	batchSize        = flag.Int("batch-size", 0, "If non-zero will perform the proxy->target work in batches of this size (with any remainder done at the end).")
+	canary           = flag.Int("canary", 0, "If non-zero run the command against this many targets first, as their own batch. If any of them fail the remaining targets are skipped.")
+	batchPause       = flag.Duration("batch-pause", 0, "How long to wait between batches (see --batch-size and --canary).")
+	maxFailures      = flag.Int("max-failures", 0, "If non-zero, stop starting new batches once this many targets have failed.")
+	maxFailurePct    = flag.Float64("max-failure-percent", 0, "If non-zero, stop starting new batches once this percentage of all targets have failed.")
+	healthCheck      = flag.String("health-check", "", "If set, a sanssh command (e.g. 'healthcheck validate') run against each batch after the main command. If it fails for any target the remaining targets are skipped.")
 	mpa              = flag.Bool("mpa", false, "Request multi-party approval for commands. This will create an MPA request, wait for approval, and then execute the command.")
+	mpaApprovals     = flag.String("mpa-approvals", "", "Approvals required before an MPA request counts as approved, as a comma separated list of COUNT[:GROUP] (e.g. 2:sre or 1:sre,1:owners). Requires --mpa. If empty, one approval from anybody is required.")
 	authzDryRun      = flag.Bool("authz-dry-run", false, "If true, the client will send a request to the server to check if the user has the permission to run the command. The server will respond with a success or failure message.")
+	tokenFile        = flag.String("token-file", "", "If set, send the OIDC token in this file as a bearer token with every request so the proxy or server can authorize you by your SSO identity and groups. The file is re-read for each request.")
+	authzExplain     = flag.Bool("explain", false, "With --authz-dry-run, print an explanation of the proxy's authz decision for each target: how close each allow rule came to matching, which expression failed and the input fields it looked at.")
 
 	// targets will be bound to --targets for sending a single request to N nodes.
 	targetsFlag util.StringSliceCommaOrWhitespaceFlag
@@ -104,6 +123,10 @@ func init() {
 	flag.StringVar(&mtlsFlags.ServerCertFile, "server-cert", mtlsFlags.ServerCertFile, "Path to an x509 server cert, PEM format")
 	flag.StringVar(&mtlsFlags.ServerKeyFile, "server-key", mtlsFlags.ServerKeyFile, "Path to the server's TLS key")
 	flag.StringVar(&mtlsFlags.RootCAFile, "root-ca", mtlsFlags.RootCAFile, "The root of trust for remote identities, PEM format")
+	flag.StringVar(&mtlsSpiffe.SocketPath, "spiffe-socket", mtlsSpiffe.SocketPath, "Address of the SPIFFE Workload API used by the spiffe credential source. Defaults to $SPIFFE_ENDPOINT_SOCKET")
+	flag.StringVar(&mtlsIssuer.IssuerAddr, "cert-issuer", mtlsIssuer.IssuerAddr, "Address of the CertIssuer service used by the certissuer credential source. Defaults to --proxy")
+	flag.StringVar(&mtlsIssuer.TokenFile, "cert-issuer-token-file", mtlsIssuer.TokenFile, "File holding an OIDC token to exchange for a client certificate. If empty the certificate from --client-cert is used instead")
+	flag.DurationVar(&mtlsIssuer.Lifetime, "cert-issuer-lifetime", mtlsIssuer.Lifetime, "How long certificates from the CertIssuer service should last. If zero the issuer's default is used")
 
 	// Setup an empty slice so it can be deref'd below regardless of user input.
 	outputsFlag.Target = &[]string{}
@@ -121,9 +144,12 @@ func init() {
 	subcommands.ImportantFlag("outputs")
 	subcommands.ImportantFlag("output-dir")
 	subcommands.ImportantFlag("targets-file")
+	subcommands.ImportantFlag("resolver")
+	subcommands.ImportantFlag("select")
 	subcommands.ImportantFlag("justification")
 	subcommands.ImportantFlag("client-policy")
 	subcommands.ImportantFlag("client-policy-file")
+	subcommands.ImportantFlag("format")
 	subcommands.ImportantFlag("mpa")
 	subcommands.ImportantFlag("v")
 }
@@ -174,6 +200,30 @@ func main() {
 		}
 	}
 
+	if *selectFlag != "" && *resolverFlag == "" {
+		log.Fatal("--select requires --resolver")
+	}
+	if *resolverFlag != "" {
+		if len(*targetsFlag.Target) > 0 {
+			log.Fatal("can't set --resolver with --targets or --targets-file")
+		}
+		sel, err := client.ParseSelector(*selectFlag)
+		if err != nil {
+			log.Fatalf("invalid --select: %v", err)
+		}
+		targets, err := client.ResolveTargets(context.Background(), *resolverFlag, sel)
+		if err != nil {
+			log.Fatalf("can't resolve targets: %v", err)
+		}
+		if len(targets) == 0 {
+			log.Fatalf("no targets found by %s matching %q", *resolverFlag, sel)
+		}
+		*targetsFlag.Target = targets
+	}
+
+	if mtlsIssuer.IssuerAddr == "" && *proxyAddr != "" {
+		mtlsIssuer.IssuerAddr = cmdUtil.ValidateAndAddPortAndTimeout(*proxyAddr, defaultProxyPort, 0)
+	}
 	// Validate and add the default proxy port (if needed).
 	if *proxyAddr != "" {
 		*proxyAddr = cmdUtil.ValidateAndAddPortAndTimeout(*proxyAddr, defaultProxyPort, *dialTimeout)
@@ -204,17 +254,42 @@ func main() {
 		Targets:           *targetsFlag.Target,
 		Outputs:           *outputsFlag.Target,
 		AuthzDryRun:       *authzDryRun,
+		AuthzExplain:      *authzExplain,
 		OutputsDir:        *outputsDir,
 		CredSource:        *credSource,
 		IdleTimeout:       *idleTimeout,
 		ClientAuthzPolicy: clientPolicy,
 		PrefixOutput:      *prefixHeader,
+		Format:            *format,
+		Aggregate:         *aggregate,
+		Baseline:          *baseline,
 		BatchSize:         *batchSize,
+		Canary:            *canary,
+		BatchPause:        *batchPause,
+		MaxFailures:       *maxFailures,
+		MaxFailurePercent: *maxFailurePct,
+		HealthCheck:       strings.Fields(*healthCheck),
 		EnableMPA:         *mpa,
 	}
 
+	if *tokenFile != "" {
+		rs.PerRPCCredentials = jwt.NewTokenFileCredentials(*tokenFile)
+	}
 	if *justification != "" {
 		ctx = metadata.AppendToOutgoingContext(ctx, rpcauth.ReqJustKey, *justification)
 	}
+	if *authzExplain && !*authzDryRun {
+		log.Fatal("--explain requires --authz-dry-run")
+	}
+	if *mpaApprovals != "" {
+		if !*mpa {
+			log.Fatal("--mpa-approvals requires --mpa")
+		}
+		spec, err := mpahooks.ParseApprovalSpec(*mpaApprovals)
+		if err != nil {
+			log.Fatalf("invalid --mpa-approvals: %v", err)
+		}
+		ctx = mpahooks.WithRequiredApprovals(ctx, spec)
+	}
 	client.Run(ctx, rs)
 }
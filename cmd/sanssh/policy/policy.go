@@ -0,0 +1,226 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+// Package policy provides the 'policy' sanssh commands for working with
+// OPA authz policies locally, without connecting to any targets.
+package policy
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/google/subcommands"
+
+	"github.com/Snowflake-Labs/sansshell/auth/opa"
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+	"github.com/Snowflake-Labs/sansshell/client"
+	sansshellClient "github.com/Snowflake-Labs/sansshell/cmd/sanssh/client"
+	"github.com/Snowflake-Labs/sansshell/services/util"
+)
+
+const subPackage = "policy"
+
+func init() {
+	subcommands.Register(&policyCmd{}, subPackage)
+	sansshellClient.RegisterLocalCommand(subPackage)
+}
+
+func (*policyCmd) GetSubpackage(f *flag.FlagSet) *subcommands.Commander {
+	c := client.SetupSubpackage(subPackage, f)
+	c.Register(&testCmd{}, "")
+	c.Register(&explainCmd{}, "")
+	return c
+}
+
+type policyCmd struct{}
+
+func (*policyCmd) Name() string { return subPackage }
+func (p *policyCmd) Synopsis() string {
+	return client.GenerateSynopsis(p.GetSubpackage(flag.NewFlagSet("", flag.ContinueOnError)), 2)
+}
+func (p *policyCmd) Usage() string {
+	return client.GenerateUsage(subPackage, p.Synopsis())
+}
+func (*policyCmd) SetFlags(f *flag.FlagSet) {}
+
+func (p *policyCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	c := p.GetSubpackage(f)
+	return c.Execute(ctx, args...)
+}
+
+// policyFlags are the flags used to load a policy.
+type policyFlags struct {
+	policyFile       string
+	allowQuery       string
+	denialHintsQuery string
+}
+
+func (p *policyFlags) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&p.policyFile, "policy-file", "", "Path to the OPA policy")
+	f.StringVar(&p.allowQuery, "allow-query", opa.DefaultAuthzQuery, "Query used to decide if a request is allowed")
+	f.StringVar(&p.denialHintsQuery, "denial-hints-query", "data.sansshell.authz.denial_hints", "Query used to fetch denial hints. If empty, denial hints aren't used.")
+}
+
+func (p *policyFlags) load(ctx context.Context) (rpcauth.AuthzPolicy, error) {
+	policy, err := os.ReadFile(p.policyFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't read policy: %v", err)
+	}
+	opts := []opa.Option{opa.WithAllowQuery(p.allowQuery)}
+	if p.denialHintsQuery != "" {
+		opts = append(opts, opa.WithDenialHintsQuery(p.denialHintsQuery))
+	}
+	authzPolicy, err := opa.NewOpaAuthzPolicy(ctx, string(policy), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("can't load policy: %v", err)
+	}
+	return authzPolicy, nil
+}
+
+type testCmd struct {
+	policyFlags
+	verbose bool
+}
+
+func (*testCmd) Name() string     { return "test" }
+func (*testCmd) Synopsis() string { return "Runs test cases against an OPA policy" }
+func (*testCmd) Usage() string {
+	return `test --policy-file=<file> <dir> [<dir>...]:
+  Evaluates every *.json test case in each directory against the policy and
+  reports which cases didn't get the expected decision. Each case is a JSON
+  object giving the method, request type and request along with the caller
+  and the expected decision, for example
+
+    {
+      "method": "/LocalFile.LocalFile/Read",
+      "type": "LocalFile.ReadActionRequest",
+      "request": {"file": {"filename": "/etc/hosts"}},
+      "peer": {"principal": {"id": "alice", "groups": ["sre"]}},
+      "expect": {"allowed": false, "denial_hints": ["sre may not read /etc/hosts"]}
+    }
+
+  Requests are built the same way the server builds policy input, so the
+  policy sees exactly what it would see in production.
+
+`
+}
+
+func (p *testCmd) SetFlags(f *flag.FlagSet) {
+	p.policyFlags.SetFlags(f)
+	f.BoolVar(&p.verbose, "v", false, "Print passing cases as well as failing ones")
+}
+
+func (p *testCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	state := args[0].(*util.ExecuteState)
+	if p.policyFile == "" || f.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "--policy-file and at least one test case directory are required")
+		return subcommands.ExitUsageError
+	}
+	authzPolicy, err := p.load(ctx)
+	if err != nil {
+		fmt.Fprintln(state.Err[0], err)
+		return subcommands.ExitFailure
+	}
+
+	var cases []*opa.PolicyTestCase
+	for _, dir := range f.Args() {
+		c, err := opa.LoadPolicyTestCases(dir)
+		if err != nil {
+			fmt.Fprintf(state.Err[0], "can't load test cases: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		if len(c) == 0 {
+			fmt.Fprintf(state.Err[0], "no test cases found in %s\n", dir)
+			return subcommands.ExitFailure
+		}
+		cases = append(cases, c...)
+	}
+
+	failed := 0
+	for _, r := range opa.RunPolicyTests(ctx, authzPolicy, cases) {
+		if r.Passed() {
+			if p.verbose {
+				fmt.Fprintf(state.Out[0], "PASS %s\n", r.Case.Name)
+			}
+			continue
+		}
+		failed++
+		fmt.Fprintf(state.Out[0], "FAIL %s: %s\n", r.Case.Name, strings.Join(r.Failures(), "; "))
+	}
+	fmt.Fprintf(state.Out[0], "%d passed, %d failed\n", len(cases)-failed, failed)
+	if failed > 0 {
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+type explainCmd struct {
+	policyFlags
+}
+
+func (*explainCmd) Name() string     { return "explain" }
+func (*explainCmd) Synopsis() string { return "Explains an OPA policy's decision for a request" }
+func (*explainCmd) Usage() string {
+	return `explain --policy-file=<file> <case.json> [<case.json>...]:
+  Evaluates each request against the policy and shows how close each allow
+  rule came to matching, which expression stopped it and the input fields
+  that expression looked at. Requests are given in the same format as the
+  test cases for 'policy test', and any expectation is ignored.
+
+  To explain the proxy's decision for a live request use
+  'sanssh --authz-dry-run --explain' instead.
+
+`
+}
+
+func (p *explainCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	state := args[0].(*util.ExecuteState)
+	if p.policyFile == "" || f.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "--policy-file and at least one request are required")
+		return subcommands.ExitUsageError
+	}
+	authzPolicy, err := p.load(ctx)
+	if err != nil {
+		fmt.Fprintln(state.Err[0], err)
+		return subcommands.ExitFailure
+	}
+	retCode := subcommands.ExitSuccess
+	for _, file := range f.Args() {
+		tc, err := opa.LoadPolicyTestCase(file)
+		if err != nil {
+			fmt.Fprintln(state.Err[0], err)
+			retCode = subcommands.ExitFailure
+			continue
+		}
+		input, err := opa.NewPolicyTestInput(ctx, tc)
+		if err != nil {
+			fmt.Fprintf(state.Err[0], "%s: %v\n", tc.Name, err)
+			retCode = subcommands.ExitFailure
+			continue
+		}
+		explanation, err := rpcauth.Explain(ctx, authzPolicy, input)
+		if err != nil {
+			fmt.Fprintf(state.Err[0], "%s: %v\n", tc.Name, err)
+			retCode = subcommands.ExitFailure
+			continue
+		}
+		fmt.Fprintf(state.Out[0], "%s: %s", tc.Name, explanation)
+	}
+	return retCode
+}
@@ -28,6 +28,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/go-logr/stdr"
@@ -43,12 +44,15 @@ import (
 	_ "gocloud.dev/blob/gcsblob"   // Pull in GCS blob support
 	_ "gocloud.dev/blob/s3blob"    // Pull in S3 blob support
 
+	"github.com/Snowflake-Labs/sansshell/auth/jwt"
 	"github.com/Snowflake-Labs/sansshell/auth/mtls"
 	mtlsFlags "github.com/Snowflake-Labs/sansshell/auth/mtls/flags"
+	mtlsSpiffe "github.com/Snowflake-Labs/sansshell/auth/mtls/spiffe"
 	"github.com/Snowflake-Labs/sansshell/auth/opa"
 	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
 	"github.com/Snowflake-Labs/sansshell/cmd/sansshell-server/server"
 	"github.com/Snowflake-Labs/sansshell/cmd/util"
+	"github.com/Snowflake-Labs/sansshell/server/quota"
 	"github.com/Snowflake-Labs/sansshell/services"
 	ssutil "github.com/Snowflake-Labs/sansshell/services/util"
 	"github.com/Snowflake-Labs/sansshell/telemetry/metrics"
@@ -58,7 +62,7 @@ import (
 	// Ansible needs a real import to bind flags.
 	ansible "github.com/Snowflake-Labs/sansshell/services/ansible/server"
 	_ "github.com/Snowflake-Labs/sansshell/services/dns/server"
-	_ "github.com/Snowflake-Labs/sansshell/services/exec/server"
+	exec "github.com/Snowflake-Labs/sansshell/services/exec/server"
 	_ "github.com/Snowflake-Labs/sansshell/services/httpoverrpc/server"
 	_ "github.com/Snowflake-Labs/sansshell/services/tlsinfo/server"
 
@@ -77,26 +81,44 @@ import (
 	ssserver "github.com/Snowflake-Labs/sansshell/services/sansshell/server"
 	_ "github.com/Snowflake-Labs/sansshell/services/service/server"
 	_ "github.com/Snowflake-Labs/sansshell/services/sysinfo/server"
+	// Terminal needs a real import to bind flags.
+	terminal "github.com/Snowflake-Labs/sansshell/services/terminal/server"
 )
 
 var (
 	//go:embed default-policy.rego
 	defaultPolicy string
 
-	policyFlag    = flag.String("policy", defaultPolicy, "Local OPA policy governing access.  If empty, use builtin policy.")
-	policyFile    = flag.String("policy-file", "", "Path to a file with an OPA policy.  If empty, uses --policy.")
-	apiVersion    = flag.String("api-version", "1.0.0", "Version of the Sansshell services API accepted by the server. Policy set in proxy and server must be verified before upgrading against API extensions to avoid unintentional side effects.")
-	hostport      = flag.String("hostport", "localhost:50042", "Where to listen for connections.")
-	debugport     = flag.String("debugport", "localhost:50044", "A separate port for http debug pages. Set to an empty string to disable.")
-	metricsport   = flag.String("metricsport", "localhost:50047", "A separate port for http debug pages. Set to an empty string to disable.")
-	unixSocket    = flag.String("unix-socket", "", "Path to a Unix socket to listen on in addition to hostport. The socket supports plaintext (non-TLS) communication. Set to an empty string to disable.")
-	credSource    = flag.String("credential-source", mtlsFlags.Name(), fmt.Sprintf("Method used to obtain mTLS credentials (one of [%s])", strings.Join(mtls.Loaders(), ",")))
-	verbosity     = flag.Int("v", 0, "Verbosity level. > 0 indicates more extensive logging")
-	validate      = flag.Bool("validate", false, "If true will evaluate the policy and then exit (non-zero on error)")
-	justification = flag.Bool("justification", false, "If true then justification (which is logged and possibly validated) must be passed along in the client context Metadata with the key '"+rpcauth.ReqJustKey+"'")
-	version       bool
-
-	fdbCLIEnvList ssutil.StringSliceFlag
+	policyFlag         = flag.String("policy", defaultPolicy, "Local OPA policy governing access.  If empty, use builtin policy.")
+	policyFile         = flag.String("policy-file", "", "Path to a file with an OPA policy.  If empty, uses --policy.")
+	apiVersion         = flag.String("api-version", "1.0.0", "Version of the Sansshell services API accepted by the server. Policy set in proxy and server must be verified before upgrading against API extensions to avoid unintentional side effects.")
+	hostport           = flag.String("hostport", "localhost:50042", "Where to listen for connections.")
+	debugport          = flag.String("debugport", "localhost:50044", "A separate port for http debug pages. Set to an empty string to disable.")
+	metricsport        = flag.String("metricsport", "localhost:50047", "A separate port for http debug pages. Set to an empty string to disable.")
+	unixSocket         = flag.String("unix-socket", "", "Path to a Unix socket to listen on in addition to hostport. The socket supports plaintext (non-TLS) communication. Set to an empty string to disable.")
+	credSource         = flag.String("credential-source", mtlsFlags.Name(), fmt.Sprintf("Method used to obtain mTLS credentials (one of [%s])", strings.Join(mtls.Loaders(), ",")))
+	verbosity          = flag.Int("v", 0, "Verbosity level. > 0 indicates more extensive logging")
+	validate           = flag.Bool("validate", false, "If true will evaluate the policy and then exit (non-zero on error)")
+	justification      = flag.Bool("justification", false, "If true then justification (which is logged and possibly validated) must be passed along in the client context Metadata with the key '"+rpcauth.ReqJustKey+"'")
+	mpaStoreDir        = flag.String("mpa-store-dir", "", "Directory used to persist MPA requests and approvals across restarts. If empty, they are only kept in memory.")
+	policyWatch        = flag.Duration("policy-watch-interval", 0, "If set along with --policy-file, how often to check the policy file for changes and reload it. The policy file is also reloaded on SIGHUP.")
+	auditLog           = flag.String("audit-log", "", "Path to a file receiving a JSON line for every authz decision. If empty, no audit log is written.")
+	auditLogMaxSize    = flag.Int64("audit-log-max-size", 100, "Size in megabytes at which the audit log is rotated. 0 disables rotation.")
+	auditLogMaxBackups = flag.Int("audit-log-max-backups", 5, "Number of rotated audit logs to keep.")
+	authzCacheTTL      = flag.Duration("authz-cache-ttl", 0, "If non-zero, cache authz policy decisions for identical requests for this long.")
+	authzCacheSize     = flag.Int("authz-cache-size", rpcauth.DefaultCacheMaxEntries, "Maximum number of authz decisions to cache when --authz-cache-ttl is set.")
+	crlSources         = flag.String("crl", "", "Comma separated list of CRL files or blob URLs. Peers with a certificate revoked by any of them are rejected.")
+	crlRefresh         = flag.Duration("crl-refresh-interval", time.Hour, "How often to reload the CRLs given by --crl. 0 disables reloading.")
+	crlIssuers         = flag.String("crl-issuers", "", "PEM file of the CAs which sign the CRLs given by --crl. If set, a CRL signed by anyone else fails to load.")
+	jwks               = flag.String("jwks", "", "Path to a JSON Web Key Set. If set, requests may carry an OIDC bearer token signed by one of these keys, whose subject and groups become the principal seen by the authz policy.")
+	jwtIssuer          = flag.String("jwt-issuer", "", "If set along with --jwks, bearer tokens must have this issuer.")
+	jwtAudience        = flag.String("jwt-audience", "", "If set along with --jwks, bearer tokens must have this audience.")
+	jwtGroupsClaim     = flag.String("jwt-groups-claim", jwt.DefaultGroupsClaim, "The bearer token claim holding the groups of the principal.")
+	quotaConfig        = flag.String("quota-config", "", "Path to a JSON file of per method and per principal rate and concurrency limits. If empty, requests are not limited.")
+	version            bool
+
+	fdbCLIEnvList    ssutil.StringSliceFlag
+	execEnvAllowList ssutil.StringSliceFlag
 )
 
 func init() {
@@ -114,9 +136,22 @@ func init() {
 	flag.StringVar(&mtlsFlags.ServerCertFile, "server-cert", mtlsFlags.ServerCertFile, "Path to an x509 server cert, PEM format")
 	flag.StringVar(&mtlsFlags.ServerKeyFile, "server-key", mtlsFlags.ServerKeyFile, "Path to the server's TLS key")
 	flag.StringVar(&mtlsFlags.RootCAFile, "root-ca", mtlsFlags.RootCAFile, "The root of trust for remote identities, PEM format")
+	flag.StringVar(&mtlsSpiffe.SocketPath, "spiffe-socket", mtlsSpiffe.SocketPath, "Address of the SPIFFE Workload API used by the spiffe credential source. Defaults to $SPIFFE_ENDPOINT_SOCKET")
 
 	flag.StringVar(&ansible.AnsiblePlaybookBin, "ansible_playbook_bin", ansible.AnsiblePlaybookBin, "Path to ansible-playbook binary")
 
+	execEnvAllowList.Target = &exec.EnvAllowList
+	flag.Var(&execEnvAllowList, "exec-env-allow-list", "List of environment variable names (separated by comma) exec requests may set. A name ending in * allows any variable with that prefix.")
+	flag.DurationVar(&exec.DefaultKillGrace, "exec-kill-grace", exec.DefaultKillGrace, "How long a command which reached its timeout has to exit after SIGTERM before it's killed, if the request doesn't say")
+	flag.StringVar(&ssutil.CgroupParent, "exec-cgroup-parent", ssutil.CgroupParent, "cgroup v2, relative to /sys/fs/cgroup, under which commands with resource limits run. If empty, the server's own cgroup, which must be delegated to it.")
+	flag.StringVar(&terminal.RecordingDir, "terminal-recording-dir", terminal.RecordingDir, "Directory where recordings of terminal sessions are written")
+	flag.StringVar(&terminal.RecordingBucket, "terminal-recording-bucket", terminal.RecordingBucket, "If set, a blob bucket URL (as for localfile uploads) that terminal session recordings are uploaded to when the session ends")
+	flag.StringVar(&exec.ScriptDir, "exec-script-dir", exec.ScriptDir, "Directory scripts pushed with RunScript are written to. It must not be mounted noexec. If empty, the system temporary directory.")
+	flag.Int64Var(&exec.ScriptMaxSize, "exec-script-max-size", exec.ScriptMaxSize, "The largest script, in bytes, RunScript accepts")
+	flag.StringVar(&exec.JobsDir, "jobs-dir", exec.JobsDir, "Directory where the output of background jobs is kept")
+	flag.Int64Var(&exec.JobOutputMax, "job-output-max", exec.JobOutputMax, "Bytes of stdout and of stderr kept for each background job")
+	flag.DurationVar(&exec.JobRetention, "job-retention", exec.JobRetention, "How long a background job and its output are kept after it exits")
+
 	flag.StringVar(&packages.YumBin, "yum-bin", packages.YumBin, "Path to yum binary")
 
 	flag.StringVar(&process.JstackBin, "jstack-bin", process.JstackBin, "Path to the jstack binary")
@@ -161,7 +196,14 @@ func main() {
 	ctx := logr.NewContext(context.Background(), logger)
 	ctx = metrics.NewContextWithRecorder(ctx, recorder)
 
-	authzPolicy, err := opa.NewOpaAuthzPolicy(ctx, policy, opa.WithDenialHintsQuery("data.sansshell.authz.denial_hints"))
+	var authzPolicy rpcauth.AuthzPolicy
+	var filePolicy *opa.FileAuthzPolicy
+	if *policyFile != "" {
+		filePolicy, err = opa.NewFileAuthzPolicy(ctx, *policyFile, opa.WithDenialHintsQuery("data.sansshell.authz.denial_hints"))
+		authzPolicy = filePolicy
+	} else {
+		authzPolicy, err = opa.NewOpaAuthzPolicy(ctx, policy, opa.WithDenialHintsQuery("data.sansshell.authz.denial_hints"))
+	}
 	if err != nil {
 		log.Fatalf("Invalid policy: %v\n", err)
 	}
@@ -171,20 +213,89 @@ func main() {
 		os.Exit(0)
 	}
 
-	server.Run(ctx,
+	if *mpaStoreDir != "" {
+		store, err := mpa.NewFileStore(*mpaStoreDir)
+		if err != nil {
+			log.Fatalf("Unable to open MPA store: %v\n", err)
+		}
+		mpa.SetStore(store)
+	}
+
+	auditSink, err := util.OpenAuditLog(*auditLog, *auditLogMaxSize, *auditLogMaxBackups)
+	if err != nil {
+		log.Fatalf("Unable to open audit log: %v\n", err)
+	}
+
+	opts := []server.Option{
 		server.WithLogger(logger),
 		server.WithCredSource(*credSource),
 		server.WithHostPort(*hostport),
 		server.WithUnixSocket(*unixSocket),
 		server.WithAuthzPolicy(authzPolicy),
+		server.WithAuditSink(auditSink),
 		server.WithJustification(*justification),
 		server.WithAuthzHook(rpcauth.PeerPrincipalFromCertHook()),
-		server.WithAuthzHook(mpa.ServerMPAAuthzHook()),
 		server.WithRawServerOption(func(s *grpc.Server) { reflection.Register(s) }),
 		server.WithRawServerOption(func(s *grpc.Server) { channelz.RegisterChannelzServiceToServer(s) }),
 		server.WithDebugPort(*debugport),
 		server.WithMetricsPort(*metricsport),
 		server.WithMetricsRecorder(recorder),
 		server.WithRefreshCredsOnSIGHUP(),
-	)
+	}
+	if *jwks != "" {
+		verifier, err := jwt.NewVerifier(*jwks, jwt.WithIssuer(*jwtIssuer), jwt.WithAudience(*jwtAudience), jwt.WithGroupsClaim(*jwtGroupsClaim))
+		if err != nil {
+			log.Fatalf("Unable to load JWKS: %v\n", err)
+		}
+		opts = append(opts, server.WithBearerTokenVerifier(verifier))
+	}
+	// MPA hooks go after any hook setting the principal so they see the
+	// same identity as the policy.
+	opts = append(opts, server.WithAuthzHook(mpa.ServerMPAAuthzHook()))
+	if filePolicy != nil {
+		opts = append(opts, server.WithReloadPolicyOnSIGHUP())
+		if *policyWatch > 0 {
+			go filePolicy.Watch(ctx, *policyWatch)
+		}
+	}
+	if *authzCacheTTL > 0 {
+		cacheOpts := []rpcauth.CacheOption{
+			rpcauth.WithCacheTTL(*authzCacheTTL),
+			rpcauth.WithCacheMaxEntries(*authzCacheSize),
+		}
+		opts = append(opts, server.WithAuthzDecisionCache(cacheOpts...))
+	}
+	if *crlSources != "" {
+		revocationOpts := []mtls.RevocationOption{mtls.WithCRLSources(strings.Split(*crlSources, ",")...)}
+		if *crlIssuers != "" {
+			issuers, err := mtls.LoadCertificates(*crlIssuers)
+			if err != nil {
+				log.Fatalf("Unable to load CRL issuers: %v\n", err)
+			}
+			revocationOpts = append(revocationOpts, mtls.WithCRLIssuers(issuers...))
+		}
+		checker, err := mtls.NewRevocationChecker(ctx, revocationOpts...)
+		if err != nil {
+			log.Fatalf("Unable to load CRLs: %v\n", err)
+		}
+		if *crlRefresh > 0 {
+			go checker.Watch(ctx, *crlRefresh)
+		}
+		opts = append(opts, server.WithRevocationChecker(checker))
+	}
+	if *quotaConfig != "" {
+		config, err := quota.LoadConfig(*quotaConfig)
+		if err != nil {
+			log.Fatalf("Unable to load quotas: %v\n", err)
+		}
+		limiter, err := quota.New(config)
+		if err != nil {
+			log.Fatalf("Invalid quota config: %v\n", err)
+		}
+		opts = append(opts,
+			server.WithUnaryInterceptor(limiter.UnaryServerInterceptor()),
+			server.WithStreamInterceptor(limiter.StreamServerInterceptor()),
+		)
+	}
+	server.Run(ctx, opts...)
 }
@@ -38,8 +38,10 @@ import (
 	"go.opentelemetry.io/otel/metric/noop"
 	"google.golang.org/grpc"
 
+	"github.com/Snowflake-Labs/sansshell/auth/jwt"
 	"github.com/Snowflake-Labs/sansshell/auth/mtls"
 	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+	"github.com/Snowflake-Labs/sansshell/cmd/util"
 	"github.com/Snowflake-Labs/sansshell/server"
 	"github.com/Snowflake-Labs/sansshell/telemetry/metrics"
 	"google.golang.org/grpc/credentials"
@@ -62,6 +64,7 @@ type runState struct {
 	unixSocket           string
 	unixSocketConfigHook func(string) error
 	policy               rpcauth.AuthzPolicy
+	auditSink            rpcauth.AuditSink
 	justification        bool
 	justificationFunc    func(string) error
 	unaryInterceptors    []grpc.UnaryServerInterceptor
@@ -71,6 +74,12 @@ type runState struct {
 	services             []func(*grpc.Server)
 
 	refreshCredsOnSIGHUP bool
+	reloadPolicyOnSIGHUP bool
+
+	authzCache     bool
+	authzCacheOpts []rpcauth.CacheOption
+
+	revocationChecker *mtls.RevocationChecker
 }
 
 type Option interface {
@@ -100,6 +109,15 @@ func WithAuthzPolicy(policy rpcauth.AuthzPolicy) Option {
 	})
 }
 
+// WithAuditSink writes a structured record of every authz decision
+// made for incoming RPC requests to the given sink.
+func WithAuditSink(sink rpcauth.AuditSink) Option {
+	return optionFunc(func(_ context.Context, r *runState) error {
+		r.auditSink = sink
+		return nil
+	})
+}
+
 // WithTlsConfig applies a supplied tls.Config object to the gRPC server.
 func WithTlsConfig(tlsConfig *tls.Config) Option {
 	return optionFunc(func(_ context.Context, r *runState) error {
@@ -295,6 +313,71 @@ func WithRefreshCredsOnSIGHUP() Option {
 	})
 }
 
+// WithReloadPolicyOnSIGHUP will make sansshell-server reload its authz policy
+// when it receives a SIGHUP signal. The policy must implement
+// rpcauth.ReloadableAuthzPolicy, such as one returned by opa.NewFileAuthzPolicy.
+// A new policy that fails to compile is rejected and the old policy stays in use.
+func WithReloadPolicyOnSIGHUP() Option {
+	return optionFunc(func(_ context.Context, r *runState) error {
+		r.reloadPolicyOnSIGHUP = true
+		return nil
+	})
+}
+
+// WithAuthzDecisionCache makes sansshell-server remember the decisions of its authz
+// policy for incoming requests, so identical input isn't evaluated again.
+// See rpcauth.CachingAuthzPolicy for what is cached and for how long.
+func WithAuthzDecisionCache(opts ...rpcauth.CacheOption) Option {
+	return optionFunc(func(_ context.Context, r *runState) error {
+		r.authzCache = true
+		r.authzCacheOpts = opts
+		return nil
+	})
+}
+
+// WithRevocationChecker makes sansshell-server reject peers whose certificates
+// the checker reports as revoked during the TLS handshake.
+func WithRevocationChecker(checker *mtls.RevocationChecker) Option {
+	return optionFunc(func(_ context.Context, r *runState) error {
+		r.revocationChecker = checker
+		return nil
+	})
+}
+
+// WithBearerTokenVerifier makes sansshell-server accept bearer tokens, such as OIDC
+// ID tokens, sent in the "authorization" metadata of requests. Tokens are
+// checked by the verifier before authorization and requests with invalid
+// tokens are rejected. A valid token's subject and groups replace the
+// principal the policy sees in input.peer.principal, so this should be
+// applied after any hook which sets the principal from the client
+// certificate and before any hook which uses the principal, such as the
+// MPA hooks. The token itself is never logged or shown to the policy.
+func WithBearerTokenVerifier(v *jwt.Verifier) Option {
+	return optionFunc(func(_ context.Context, r *runState) error {
+		r.unaryInterceptors = append(r.unaryInterceptors, v.UnaryServerInterceptor())
+		r.streamInterceptors = append(r.streamInterceptors, v.StreamServerInterceptor())
+		r.authzHooks = append(r.authzHooks, v.PeerPrincipalHook())
+		return nil
+	})
+}
+
+// credOptions returns the mtls options for the credentials in rs.
+func credOptions(rs *runState) []mtls.Option {
+	if rs.revocationChecker == nil {
+		return nil
+	}
+	return []mtls.Option{mtls.WithRevocationChecker(rs.revocationChecker)}
+}
+
+// tlsConfigWithOptions returns a copy of rs.tlsConfig with credOptions applied.
+func tlsConfigWithOptions(rs *runState) *tls.Config {
+	config := rs.tlsConfig.Clone()
+	for _, o := range credOptions(rs) {
+		o(config)
+	}
+	return config
+}
+
 // Run takes the given context and RunState and starts up a sansshell server.
 // As this is intended to be called from main() it doesn't return errors and will instead exit on any errors.
 func Run(ctx context.Context, opts ...Option) {
@@ -308,6 +391,16 @@ func Run(ctx context.Context, opts ...Option) {
 		}
 	}
 
+	if rs.reloadPolicyOnSIGHUP {
+		if err := util.ReloadPolicyOnSIGHUP(ctx, rs.logger, rs.policy); err != nil {
+			rs.logger.Error(err, "unable to reload policy on SIGHUP")
+			os.Exit(1)
+		}
+	}
+	if rs.authzCache && rs.policy != nil {
+		rs.policy = rpcauth.NewCachingAuthzPolicy(rs.policy, rs.authzCacheOpts...)
+	}
+
 	// If there's a debug port, we want to start it early
 	if rs.debughandler != nil && rs.debugport != "" {
 		go func() {
@@ -353,7 +446,7 @@ func extractTransportCredentialsFromRunState(ctx context.Context, rs *runState)
 	}
 	if rs.credSource != "" {
 		var refreshCreds func() error
-		creds, refreshCreds, err = mtls.LoadServerCredentialsWithForceRefresh(ctx, rs.credSource)
+		creds, refreshCreds, err = mtls.LoadServerCredentialsWithForceRefresh(ctx, rs.credSource, credOptions(rs)...)
 		if err != nil {
 			return nil, err
 		}
@@ -370,7 +463,7 @@ func extractTransportCredentialsFromRunState(ctx context.Context, rs *runState)
 			}()
 		}
 	} else {
-		creds = credentials.NewTLS(rs.tlsConfig)
+		creds = credentials.NewTLS(tlsConfigWithOptions(rs))
 	}
 	return creds, nil
 }
@@ -401,6 +494,9 @@ func extractCommonOptionsFromRunState(rs *runState) []server.Option {
 
 	var serverOpts []server.Option
 	serverOpts = append(serverOpts, server.WithAuthzPolicy(rs.policy))
+	if rs.auditSink != nil {
+		serverOpts = append(serverOpts, server.WithAuditSink(rs.auditSink))
+	}
 	serverOpts = append(serverOpts, server.WithLogger(rs.logger))
 	serverOpts = append(serverOpts, server.WithAuthzHook(justificationHook))
 	for _, a := range rs.authzHooks {
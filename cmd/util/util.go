@@ -18,14 +18,19 @@
 package util
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/go-logr/logr"
+
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
 )
 
 // ChoosePolicy selects an OPA policy based on the flags, or calls log.Fatal if
@@ -56,6 +61,45 @@ func ChoosePolicy(logger logr.Logger, defaultPolicy string, policyFlag string, p
 	return policy
 }
 
+// ReloadPolicyOnSIGHUP reloads policy whenever the process receives a
+// SIGHUP until ctx is done. It returns an error if policy can't be reloaded.
+// A policy that fails to reload is logged and the previous one stays in use.
+func ReloadPolicyOnSIGHUP(ctx context.Context, logger logr.Logger, policy rpcauth.AuthzPolicy) error {
+	reloadable, ok := policy.(rpcauth.ReloadableAuthzPolicy)
+	if !ok {
+		return fmt.Errorf("authz policy of type %T can't be reloaded, use a policy file", policy)
+	}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(c)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-c:
+				logger.Info("got SIGHUP, reloading authz policy")
+				if err := reloadable.Reload(ctx); err != nil {
+					logger.Error(err, "unable to reload authz policy")
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// OpenAuditLog returns an audit sink appending to the file at path which is
+// rotated once it grows beyond maxSizeMB megabytes, keeping maxBackups old
+// logs. It returns a nil sink if path is empty.
+func OpenAuditLog(path string, maxSizeMB int64, maxBackups int) (rpcauth.AuditSink, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return rpcauth.NewFileAuditSink(path,
+		rpcauth.WithAuditMaxSize(maxSizeMB*1024*1024),
+		rpcauth.WithAuditMaxBackups(maxBackups))
+}
+
 // hasPort returns true if the provided address does not include a port number.
 func hasPort(s string) bool {
 	return strings.LastIndex(s, "]") < strings.LastIndex(s, ":")
@@ -17,10 +17,16 @@
 package util
 
 import (
+	"context"
 	"log"
+	"os"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/go-logr/logr"
+
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
 	"github.com/Snowflake-Labs/sansshell/testing/testutil"
 )
 
@@ -162,3 +168,31 @@ func TestStripTime(t *testing.T) {
 		}
 	}
 }
+
+type reloadCounter struct {
+	rpcauth.AuthzPolicy
+	reloads chan struct{}
+}
+
+func (r *reloadCounter) Reload(context.Context) error {
+	r.reloads <- struct{}{}
+	return nil
+}
+
+func TestReloadPolicyOnSIGHUP(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := ReloadPolicyOnSIGHUP(ctx, logr.Discard(), rpcauth.NewMockAuthzPolicy(nil, nil)); err == nil {
+		t.Error("expected error for policy which can't be reloaded")
+	}
+
+	p := &reloadCounter{reloads: make(chan struct{}, 1)}
+	testutil.FatalOnErr("ReloadPolicyOnSIGHUP", ReloadPolicyOnSIGHUP(ctx, logr.Discard(), p), t)
+	testutil.FatalOnErr("Kill", syscall.Kill(os.Getpid(), syscall.SIGHUP), t)
+	select {
+	case <-p.reloads:
+	case <-time.After(10 * time.Second):
+		t.Fatal("policy wasn't reloaded")
+	}
+}
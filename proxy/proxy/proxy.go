@@ -79,6 +79,15 @@ type Conn struct {
 	// Perform authz dry run instead of actual execution
 	AuthzDryRun bool
 
+	// When performing an authz dry run, ask the proxy to explain its
+	// authz decision for each target. Explanations are passed to
+	// AuthzExplanationHandler.
+	AuthzExplain bool
+
+	// AuthzExplanationHandler, if set, is called with the explanation of
+	// the proxy's authz decision for a target when AuthzExplain is set.
+	AuthzExplanationHandler func(target string, explanation *proxypb.AuthzExplanation)
+
 	// UnaryInterceptors allow intercepting Invoke and InvokeOneMany calls
 	// that go through a proxy.
 	// It is unsafe to modify Intercepters while calls are in progress.
@@ -97,6 +106,9 @@ type Ret struct {
 	Index int
 	Resp  *anypb.Any
 	Error error
+	// Attempts is how many times the call was made to the target. It's
+	// only set for methods with a RetryPolicy.
+	Attempts int
 }
 
 // Direct indicates whether the proxy is in use or a direct connection is being made.
@@ -114,13 +126,14 @@ func (p *Conn) Proxy() *grpc.ClientConn {
 // proxyStream provides all the context for send/receive in a grpc stream sense then translated to the streaming connection
 // we hold to the proxy. It also implements a fully functional grpc.ClientStream interface.
 type proxyStream struct {
-	method      string
-	stream      proxypb.Proxy_ProxyClient
-	ids         map[uint64]*Ret
-	authzDryRun bool
-	errors      []*Ret
-	sentErrors  bool
-	sendClosed  bool
+	method                  string
+	stream                  proxypb.Proxy_ProxyClient
+	ids                     map[uint64]*Ret
+	authzDryRun             bool
+	authzExplanationHandler func(string, *proxypb.AuthzExplanation)
+	errors                  []*Ret
+	sentErrors              bool
+	sendClosed              bool
 }
 
 // Invoke - see grpc.ClientConnInterface
@@ -182,7 +195,7 @@ func (p *Conn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method stri
 		// TODO(jchacon): Add V1 style logging indicating pass through in use.
 		return p.cc.NewStream(ctx, desc, method, opts...)
 	}
-	stream := p.newStream
+	stream := p.newStreamWithRetry
 	for i := len(p.StreamInterceptors) - 1; i >= 0; i-- {
 		intercept := p.StreamInterceptors[i]
 		inner := stream
@@ -200,11 +213,12 @@ func (p *Conn) newStream(ctx context.Context, desc *grpc.StreamDesc, method stri
 	}
 
 	s := &proxyStream{
-		method:      method,
-		stream:      stream,
-		ids:         streamIds,
-		errors:      errors,
-		authzDryRun: p.AuthzDryRun,
+		method:                  method,
+		stream:                  stream,
+		ids:                     streamIds,
+		errors:                  errors,
+		authzDryRun:             p.AuthzDryRun,
+		authzExplanationHandler: p.AuthzExplanationHandler,
 	}
 
 	return s, nil
@@ -401,6 +415,7 @@ func (p *proxyStream) RecvMsg(m any) error {
 			}
 			msg = "Aborted by proxy"
 		}
+		p.handleAuthzExplanations(cl)
 
 		// See if it's normal close. We can ignore those except to remove tracking.
 		// Otherwise send errors back for each target and then remove.
@@ -428,6 +443,23 @@ func (p *proxyStream) RecvMsg(m any) error {
 	return nil
 }
 
+// handleAuthzExplanations passes any authz explanations attached to cl to
+// the explanation handler. All stream ids in cl must be known.
+func (p *proxyStream) handleAuthzExplanations(cl *proxypb.ServerClose) {
+	if p.authzExplanationHandler == nil {
+		return
+	}
+	for _, d := range cl.GetStatus().GetDetails() {
+		e := &proxypb.AuthzExplanation{}
+		if !d.MessageIs(e) || d.UnmarshalTo(e) != nil {
+			continue
+		}
+		for _, id := range cl.StreamIds {
+			p.authzExplanationHandler(p.ids[id].Target, e)
+		}
+	}
+}
+
 // createStreams is a helper which does the heavy lifting of creating N tracked streams to the proxy
 // for later RPCs to flow across. It returns a proxy stream object (for clients), and a map of stream ids to prefilled ProxyRet
 // objects. If any of the targets had an error connecting these will be collected and returned as a slice. This way later calls
@@ -455,10 +487,11 @@ func (p *Conn) createStreams(ctx context.Context, method string) (proxypb.Proxy_
 			req := &proxypb.ProxyRequest{
 				Request: &proxypb.ProxyRequest_StartStream{
 					StartStream: &proxypb.StartStream{
-						Target:      t,
-						MethodName:  method,
-						Nonce:       uint32(i),
-						AuthzDryRun: p.AuthzDryRun,
+						Target:       t,
+						MethodName:   method,
+						Nonce:        uint32(i),
+						AuthzDryRun:  p.AuthzDryRun,
+						AuthzExplain: p.AuthzExplain,
 					},
 				},
 			}
@@ -581,7 +614,7 @@ func (p *Conn) createStreams(ctx context.Context, method string) (proxypb.Proxy_
 //
 // NOTE: The returned channel must be read until it closes in order to avoid leaking goroutines.
 func (p *Conn) InvokeOneMany(ctx context.Context, method string, args any, opts ...grpc.CallOption) (<-chan *Ret, error) {
-	invoke := p.invokeOneMany
+	invoke := p.invokeWithRetry
 	for i := len(p.UnaryInterceptors) - 1; i >= 0; i-- {
 		intercept := p.UnaryInterceptors[i]
 		inner := invoke
@@ -604,10 +637,11 @@ func (p *Conn) invokeOneMany(ctx context.Context, method string, args any, opts
 	}
 
 	s := &proxyStream{
-		method:      method,
-		stream:      stream,
-		ids:         streamIds,
-		authzDryRun: p.AuthzDryRun,
+		method:                  method,
+		stream:                  stream,
+		ids:                     streamIds,
+		authzDryRun:             p.AuthzDryRun,
+		authzExplanationHandler: p.AuthzExplanationHandler,
 	}
 	if err := s.send(requestMsg); err != nil {
 		return nil, err
@@ -668,6 +702,7 @@ func (p *Conn) invokeOneMany(ctx context.Context, method string, args any, opts
 						break processing
 					}
 				}
+				s.handleAuthzExplanations(cl)
 
 				// See if it's normal close. We can ignore those except to remove tracking.
 				// Otherwise send errors back for each target and then remove.
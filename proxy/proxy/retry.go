@@ -0,0 +1,358 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A RetryPolicy describes how a method is retried for targets which fail
+// with a transient error. Only idempotent methods should have a policy, as
+// a retried call may already have taken effect on the target. Methods
+// without one (such as Exec.Run) are never retried.
+//
+// Unary and server streaming methods can be retried. A streaming call is
+// only retried for a target if it failed before sending any responses, and
+// retries start once every other target has finished.
+type RetryPolicy struct {
+	// MaxAttempts is the most times the call is made to a target,
+	// including the first. Must be at least 2.
+	MaxAttempts int
+	// InitialBackoff is how long to wait before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between retries.
+	MaxBackoff time.Duration
+	// BackoffMultiplier is applied to the wait after each retry. Must be at
+	// least 1.
+	BackoffMultiplier float64
+	// RetryableCodes are the status codes which are retried. If empty
+	// Unavailable and DeadlineExceeded are retried.
+	RetryableCodes []codes.Code
+}
+
+// DefaultRetryPolicy is a reasonable RetryPolicy for most idempotent methods.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:       3,
+	InitialBackoff:    time.Second,
+	MaxBackoff:        10 * time.Second,
+	BackoffMultiplier: 2,
+}
+
+var (
+	retryMu       sync.RWMutex
+	retryPolicies = make(map[string]RetryPolicy)
+)
+
+// RegisterRetryPolicy sets the RetryPolicy for method (as
+// /Package.Service/Method), replacing any existing one. Client packages
+// typically call this during init() for their idempotent methods.
+func RegisterRetryPolicy(method string, policy RetryPolicy) error {
+	switch {
+	case policy.MaxAttempts < 2:
+		return fmt.Errorf("retry policy for %s must allow at least 2 attempts", method)
+	case policy.InitialBackoff <= 0:
+		return fmt.Errorf("retry policy for %s must have a positive initial backoff", method)
+	case policy.MaxBackoff < policy.InitialBackoff:
+		return fmt.Errorf("retry policy for %s has a max backoff less than the initial backoff", method)
+	case policy.BackoffMultiplier < 1:
+		return fmt.Errorf("retry policy for %s must have a backoff multiplier of at least 1", method)
+	}
+	retryMu.Lock()
+	defer retryMu.Unlock()
+	retryPolicies[method] = policy
+	return nil
+}
+
+// UnregisterRetryPolicy removes any RetryPolicy for method so it's no
+// longer retried.
+func UnregisterRetryPolicy(method string) {
+	retryMu.Lock()
+	defer retryMu.Unlock()
+	delete(retryPolicies, method)
+}
+
+// RetryPolicyFor returns the RetryPolicy registered for method, if any.
+func RetryPolicyFor(method string) (RetryPolicy, bool) {
+	retryMu.RLock()
+	defer retryMu.RUnlock()
+	p, ok := retryPolicies[method]
+	return p, ok
+}
+
+// retryPolicy returns the policy to use for method on this Conn.
+func (p *Conn) retryPolicy(method string) (RetryPolicy, bool) {
+	// A dry run never reaches the target so there's nothing to retry.
+	if p.AuthzDryRun {
+		return RetryPolicy{}, false
+	}
+	return RetryPolicyFor(method)
+}
+
+// retryable returns true if a target which failed with err on the given
+// attempt should be tried again.
+func (r RetryPolicy) retryable(err error, attempt int) bool {
+	if err == nil || errors.Is(err, io.EOF) || attempt >= r.MaxAttempts {
+		return false
+	}
+	want := r.RetryableCodes
+	if len(want) == 0 {
+		want = []codes.Code{codes.Unavailable, codes.DeadlineExceeded}
+	}
+	code := status.Code(err)
+	for _, c := range want {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns how long to wait after the given attempt failed.
+func (r RetryPolicy) backoff(attempt int) time.Duration {
+	d := float64(r.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		d *= r.BackoffMultiplier
+		if d >= float64(r.MaxBackoff) {
+			return r.MaxBackoff
+		}
+	}
+	return time.Duration(d)
+}
+
+// wait sleeps for the backoff after attempt, returning early with an error
+// if ctx is done.
+func (r RetryPolicy) wait(ctx context.Context, attempt int) error {
+	t := time.NewTimer(r.backoff(attempt))
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// withAttempts adds the number of attempts made to a target's final error.
+func withAttempts(err error, attempts int) error {
+	if err == nil || attempts < 2 || errors.Is(err, io.EOF) {
+		return err
+	}
+	st := status.Convert(err)
+	return status.Errorf(st.Code(), "%s (after %d attempts)", st.Message(), attempts)
+}
+
+// subConn returns a Conn for the subset of targets at indexes which shares
+// this Conn's connection to the proxy.
+func (p *Conn) subConn(indexes []int) *Conn {
+	sub := &Conn{
+		cc:                      p.cc,
+		AuthzDryRun:             p.AuthzDryRun,
+		AuthzExplain:            p.AuthzExplain,
+		AuthzExplanationHandler: p.AuthzExplanationHandler,
+	}
+	for _, i := range indexes {
+		sub.Targets = append(sub.Targets, p.Targets[i])
+		sub.dialTimeouts = append(sub.dialTimeouts, p.dialTimeouts[i])
+	}
+	return sub
+}
+
+// invokeWithRetry is invokeOneMany, retrying targets according to the
+// method's RetryPolicy.
+func (p *Conn) invokeWithRetry(ctx context.Context, method string, args any, opts ...grpc.CallOption) (<-chan *Ret, error) {
+	policy, ok := p.retryPolicy(method)
+	if !ok {
+		return p.invokeOneMany(ctx, method, args, opts...)
+	}
+	ch, err := p.invokeOneMany(ctx, method, args, opts...)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan *Ret)
+	go func() {
+		defer close(out)
+		// indexes maps the targets of the current attempt back to ours.
+		var indexes []int
+		for attempt := 1; ; attempt++ {
+			var retry []int
+			for r := range ch {
+				ret := &Ret{Target: r.Target, Index: r.Index, Resp: r.Resp, Error: r.Error, Attempts: attempt}
+				if indexes != nil {
+					ret.Index = indexes[r.Index]
+				}
+				if policy.retryable(ret.Error, attempt) {
+					retry = append(retry, ret.Index)
+					continue
+				}
+				ret.Error = withAttempts(ret.Error, attempt)
+				out <- ret
+			}
+			if len(retry) == 0 {
+				return
+			}
+			err := policy.wait(ctx, attempt)
+			if err == nil {
+				ch, err = p.subConn(retry).invokeOneMany(ctx, method, args, opts...)
+			}
+			if err != nil {
+				for _, i := range retry {
+					out <- &Ret{Target: p.Targets[i], Index: i, Error: withAttempts(err, attempt+1), Attempts: attempt + 1}
+				}
+				return
+			}
+			indexes = retry
+		}
+	}()
+	return out, nil
+}
+
+// newStreamWithRetry is newStream, retrying targets of server streaming
+// methods according to the method's RetryPolicy.
+func (p *Conn) newStreamWithRetry(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	policy, ok := p.retryPolicy(method)
+	if !ok || desc.ClientStreams || !desc.ServerStreams {
+		return p.newStream(ctx, desc, method, opts...)
+	}
+	s, err := p.newStream(ctx, desc, method, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &retryStream{
+		ClientStream: s,
+		conn:         p,
+		ctx:          ctx,
+		desc:         desc,
+		method:       method,
+		opts:         opts,
+		policy:       policy,
+		attempt:      1,
+		gotData:      make(map[int]bool),
+	}, nil
+}
+
+// retryStream is a server streaming call to N targets which restarts the
+// call for targets that fail with a retryable error before sending any
+// responses. It records what was sent so it can be replayed.
+type retryStream struct {
+	grpc.ClientStream
+
+	conn    *Conn
+	ctx     context.Context
+	desc    *grpc.StreamDesc
+	method  string
+	opts    []grpc.CallOption
+	policy  RetryPolicy
+	attempt int
+
+	// indexes maps the targets of the current stream back to conn's. nil
+	// if the current stream is to all of conn's targets.
+	indexes []int
+	sent    []any
+	closed  bool
+	gotData map[int]bool
+	pending []int
+}
+
+// SendMsg - see grpc.ClientStream
+func (r *retryStream) SendMsg(m any) error {
+	r.sent = append(r.sent, m)
+	return r.ClientStream.SendMsg(m)
+}
+
+// CloseSend - see grpc.ClientStream
+func (r *retryStream) CloseSend() error {
+	r.closed = true
+	return r.ClientStream.CloseSend()
+}
+
+// RecvMsg - see grpc.ClientStream
+func (r *retryStream) RecvMsg(m any) error {
+	rets, ok := m.(*[]*Ret)
+	if !ok {
+		return r.ClientStream.RecvMsg(m)
+	}
+	before := len(*rets)
+	for {
+		var got []*Ret
+		err := r.ClientStream.RecvMsg(&got)
+		for _, g := range got {
+			ret := &Ret{Target: g.Target, Index: g.Index, Resp: g.Resp, Error: g.Error, Attempts: r.attempt}
+			if r.indexes != nil {
+				ret.Index = r.indexes[g.Index]
+			}
+			if ret.Error == nil {
+				r.gotData[ret.Index] = true
+			} else if !r.gotData[ret.Index] && r.policy.retryable(ret.Error, r.attempt) {
+				r.pending = append(r.pending, ret.Index)
+				continue
+			}
+			ret.Error = withAttempts(ret.Error, r.attempt)
+			*rets = append(*rets, ret)
+		}
+		if errors.Is(err, io.EOF) && len(r.pending) > 0 {
+			if err := r.retry(); err != nil {
+				for _, i := range r.pending {
+					*rets = append(*rets, &Ret{Target: r.conn.Targets[i], Index: i, Error: withAttempts(err, r.attempt+1), Attempts: r.attempt + 1})
+				}
+				r.pending = nil
+				return nil
+			}
+		} else if err != nil {
+			return err
+		}
+		// Keep going if everything received was held back for a retry.
+		if len(*rets) > before || len(got) == 0 && len(r.pending) == 0 {
+			return nil
+		}
+	}
+}
+
+// retry waits out the backoff and then starts a new stream to the pending
+// targets, replaying everything sent so far.
+func (r *retryStream) retry() error {
+	if err := r.policy.wait(r.ctx, r.attempt); err != nil {
+		return err
+	}
+	s, err := r.conn.subConn(r.pending).newStream(r.ctx, r.desc, r.method, r.opts...)
+	if err != nil {
+		return err
+	}
+	for _, m := range r.sent {
+		if err := s.SendMsg(m); err != nil {
+			return err
+		}
+	}
+	if r.closed {
+		if err := s.CloseSend(); err != nil {
+			return err
+		}
+	}
+	r.ClientStream = s
+	r.indexes = r.pending
+	r.pending = nil
+	r.attempt++
+	return nil
+}
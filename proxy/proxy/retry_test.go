@@ -0,0 +1,248 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package proxy_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/Snowflake-Labs/sansshell/proxy/proxy"
+	tdpb "github.com/Snowflake-Labs/sansshell/proxy/testdata"
+	"github.com/Snowflake-Labs/sansshell/proxy/testutil"
+	tu "github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+// flakyServer fails the first failures calls with Unavailable.
+type flakyServer struct {
+	tdpb.UnimplementedTestServiceServer
+	name     string
+	failures int
+	// sendFirst makes streams send a response before failing.
+	sendFirst bool
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *flakyServer) fail() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	return f.calls <= f.failures
+}
+
+func (f *flakyServer) TestUnary(ctx context.Context, req *tdpb.TestRequest) (*tdpb.TestResponse, error) {
+	if f.fail() {
+		return nil, status.Error(codes.Unavailable, "try again")
+	}
+	return &tdpb.TestResponse{Output: f.name + " " + req.Input}, nil
+}
+
+func (f *flakyServer) TestServerStream(req *tdpb.TestRequest, stream tdpb.TestService_TestServerStreamServer) error {
+	fail := f.fail()
+	if fail && f.sendFirst {
+		if err := stream.Send(&tdpb.TestResponse{Output: f.name + " partial"}); err != nil {
+			return err
+		}
+	}
+	if fail {
+		return status.Error(codes.Unavailable, "try again")
+	}
+	for i := 0; i < 2; i++ {
+		if err := stream.Send(&tdpb.TestResponse{Output: fmt.Sprintf("%s %d %s", f.name, i, req.Input)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startFlakyServers(t *testing.T, servers ...*flakyServer) map[string]*bufconn.Listener {
+	t.Helper()
+	out := map[string]*bufconn.Listener{}
+	for _, f := range servers {
+		lis := bufconn.Listen(testutil.BufSize)
+		s := grpc.NewServer()
+		tdpb.RegisterTestServiceServer(s, f)
+		go func() { _ = s.Serve(lis) }()
+		t.Cleanup(s.Stop)
+		out[f.name] = lis
+	}
+	return out
+}
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	policy := proxy.RetryPolicy{
+		MaxAttempts:       3,
+		InitialBackoff:    time.Millisecond,
+		MaxBackoff:        5 * time.Millisecond,
+		BackoffMultiplier: 2,
+	}
+	for _, m := range []string{"/Testdata.TestService/TestUnary", "/Testdata.TestService/TestServerStream"} {
+		tu.FatalOnErr("RegisterRetryPolicy", proxy.RegisterRetryPolicy(m, policy), t)
+		m := m
+		t.Cleanup(func() { proxy.UnregisterRetryPolicy(m) })
+	}
+	if err := proxy.RegisterRetryPolicy("/Testdata.TestService/TestBidiStream", proxy.RetryPolicy{MaxAttempts: 1}); err == nil {
+		t.Error("expected error registering policy with 1 attempt")
+	}
+
+	type result struct {
+		outputs  []string
+		code     codes.Code
+		attempts int
+	}
+	for _, tc := range []struct {
+		name    string
+		stream  bool
+		noRetry bool
+		servers []*flakyServer
+		want    map[string]result
+	}{
+		{
+			name: "unary",
+			servers: []*flakyServer{
+				{name: "ok:1"},
+				{name: "flaky:1", failures: 1},
+				{name: "down:1", failures: 100},
+			},
+			want: map[string]result{
+				"ok:1":    {outputs: []string{"ok:1 in"}, attempts: 1},
+				"flaky:1": {outputs: []string{"flaky:1 in"}, attempts: 2},
+				"down:1":  {code: codes.Unavailable, attempts: 3},
+			},
+		},
+		{
+			name:    "unary without policy",
+			noRetry: true,
+			servers: []*flakyServer{
+				{name: "ok:1"},
+				{name: "flaky:1", failures: 1},
+			},
+			want: map[string]result{
+				"ok:1":    {outputs: []string{"ok:1 in"}},
+				"flaky:1": {code: codes.Unavailable},
+			},
+		},
+		{
+			name:   "stream",
+			stream: true,
+			servers: []*flakyServer{
+				{name: "ok:1"},
+				{name: "flaky:1", failures: 2},
+				{name: "partial:1", failures: 1, sendFirst: true},
+				{name: "down:1", failures: 100},
+			},
+			want: map[string]result{
+				"ok:1":      {outputs: []string{"ok:1 0 in", "ok:1 1 in"}, attempts: 1},
+				"flaky:1":   {outputs: []string{"flaky:1 0 in", "flaky:1 1 in"}, attempts: 3},
+				"partial:1": {outputs: []string{"partial:1 partial"}, code: codes.Unavailable, attempts: 1},
+				"down:1":    {code: codes.Unavailable, attempts: 3},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.noRetry {
+				proxy.UnregisterRetryPolicy("/Testdata.TestService/TestUnary")
+				t.Cleanup(func() {
+					tu.FatalOnErr("RegisterRetryPolicy", proxy.RegisterRetryPolicy("/Testdata.TestService/TestUnary", policy), t)
+				})
+			}
+			var targets []string
+			for _, s := range tc.servers {
+				targets = append(targets, s.name)
+			}
+			bufMap := startTestProxy(ctx, t, startFlakyServers(t, tc.servers...))
+			conn, err := proxy.DialContext(ctx, "proxy", targets, testutil.WithBufDialer(bufMap), grpc.WithTransportCredentials(insecure.NewCredentials()))
+			tu.FatalOnErr("DialContext", err, t)
+			t.Cleanup(func() { conn.Close() })
+
+			got := map[string]result{}
+			record := func(index int, output string, err error, attempts int) {
+				r := got[targets[index]]
+				if err == nil {
+					r.outputs = append(r.outputs, output)
+				} else {
+					r.code = status.Code(err)
+					if attempts > 1 && !strings.Contains(err.Error(), fmt.Sprintf("after %d attempts", attempts)) {
+						t.Errorf("%s: error %v doesn't mention %d attempts", targets[index], err, attempts)
+					}
+				}
+				r.attempts = attempts
+				got[targets[index]] = r
+			}
+
+			if tc.stream {
+				stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true}, "/Testdata.TestService/TestServerStream")
+				tu.FatalOnErr("NewStream", err, t)
+				tu.FatalOnErr("SendMsg", stream.SendMsg(&tdpb.TestRequest{Input: "in"}), t)
+				tu.FatalOnErr("CloseSend", stream.CloseSend(), t)
+				for {
+					var rets []*proxy.Ret
+					err := stream.RecvMsg(&rets)
+					if err == io.EOF {
+						break
+					}
+					tu.FatalOnErr("RecvMsg", err, t)
+					for _, r := range rets {
+						if r.Error == io.EOF {
+							continue
+						}
+						resp := &tdpb.TestResponse{}
+						if r.Error == nil {
+							tu.FatalOnErr("UnmarshalTo", r.Resp.UnmarshalTo(resp), t)
+						}
+						record(r.Index, resp.Output, r.Error, r.Attempts)
+					}
+				}
+			} else {
+				ch, err := conn.InvokeOneMany(ctx, "/Testdata.TestService/TestUnary", &tdpb.TestRequest{Input: "in"})
+				tu.FatalOnErr("InvokeOneMany", err, t)
+				for r := range ch {
+					resp := &tdpb.TestResponse{}
+					if r.Error == nil {
+						tu.FatalOnErr("UnmarshalTo", r.Resp.UnmarshalTo(resp), t)
+					}
+					record(r.Index, resp.Output, r.Error, r.Attempts)
+				}
+			}
+
+			for name, want := range tc.want {
+				g := got[name]
+				if strings.Join(g.outputs, ",") != strings.Join(want.outputs, ",") || g.code != want.code || g.attempts != want.attempts {
+					t.Errorf("%s: got %+v, want %+v", name, g, want)
+				}
+			}
+			for _, s := range tc.servers {
+				if want := tc.want[s.name].attempts; want > 0 && s.calls != want {
+					t.Errorf("%s: server got %d calls, want %d", s.name, s.calls, want)
+				}
+			}
+		})
+	}
+}
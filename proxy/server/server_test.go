@@ -711,3 +711,76 @@ allow {
 		}
 	}
 }
+
+func TestProxyServerAuthzExplain(t *testing.T) {
+	ctx := context.Background()
+	policy := `
+package sansshell.authz
+
+default allow = false
+
+allow {
+  input.method = "/Proxy.Proxy/Proxy"
+}
+
+allow {
+  input.method = "/Testdata.TestService/TestUnary"
+  input.message.input = "allowed"
+}
+`
+	authz := testutil.NewOpaRPCAuthorizer(ctx, t, policy)
+	testServerMap := testutil.StartTestDataServers(t, "foo:123")
+
+	for _, tc := range []struct {
+		name    string
+		input   string
+		code    codes.Code
+		allowed bool
+	}{
+		{name: "allowed", input: "allowed", code: codes.Aborted, allowed: true},
+		{name: "denied", input: "denied", code: codes.PermissionDenied},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			proxyStream := startTestProxyWithAuthz(ctx, t, testServerMap, authz)
+			reply := testutil.Exchange(t, proxyStream, &pb.ProxyRequest{
+				Request: &pb.ProxyRequest_StartStream{
+					StartStream: &pb.StartStream{
+						Target:       "foo:123",
+						MethodName:   "/Testdata.TestService/TestUnary",
+						AuthzDryRun:  true,
+						AuthzExplain: true,
+					},
+				},
+			})
+			streamid := reply.GetStartStreamReply().GetStreamId()
+			got := testutil.Exchange(t, proxyStream, testutil.PackStreamData(t, &tdpb.TestRequest{Input: tc.input}, streamid))
+			st := got.GetServerClose().GetStatus()
+			if codes.Code(st.GetCode()) != tc.code {
+				t.Fatalf("got status %v, want code %v", st, tc.code)
+			}
+			if len(st.GetDetails()) != 1 {
+				t.Fatalf("got details %v, want one explanation", st.GetDetails())
+			}
+			explanation := &pb.AuthzExplanation{}
+			tu.FatalOnErr("UnmarshalTo", st.GetDetails()[0].UnmarshalTo(explanation), t)
+			if explanation.Allowed != tc.allowed {
+				t.Errorf("explanation allowed = %v, want %v", explanation.Allowed, tc.allowed)
+			}
+			if len(explanation.Rules) != 2 {
+				t.Fatalf("got rules %v, want 2", explanation.Rules)
+			}
+			closest := explanation.Rules[0]
+			if closest.Line != 10 || closest.Matched != tc.allowed {
+				t.Errorf("closest rule = %v, want the TestUnary rule", closest)
+			}
+			if !tc.allowed {
+				failed := closest.GetFailed()
+				if closest.Passed != 1 || failed.GetText() != `input.message.input = "allowed"` ||
+					len(failed.GetInputFields()) != 1 || failed.GetInputFields()[0].GetValue() != `"denied"` {
+					t.Errorf("unexpected explanation of failure: %v", closest)
+				}
+			}
+		})
+	}
+}
@@ -103,6 +103,10 @@ type TargetStream struct {
 
 	// If true, the stream will not send requests to the target. It will execute only authz checks.
 	authzDryRun bool
+
+	// If true along with authzDryRun, the status the stream is closed with
+	// explains the authz decision.
+	authzExplain bool
 }
 
 func (s *TargetStream) getStream() grpc.ClientStream {
@@ -257,7 +261,7 @@ func (s *TargetStream) Run(nonce uint32, replyChan chan *pb.ProxyReply) {
 		})
 		// read from the incoming request channel, and write
 		// to the stream.
-		for {
+		for received := 0; ; received++ {
 			var req proto.Message
 			var ok bool
 			select {
@@ -279,6 +283,7 @@ func (s *TargetStream) Run(nonce uint32, replyChan chan *pb.ProxyReply) {
 				}
 			}
 
+			ctx := rpcauth.WithStreamMessageIndex(ctx, received)
 			authinput, err := rpcauth.NewRPCAuthInput(ctx, s.Method(), req)
 			if err != nil {
 				err = status.Errorf(codes.Internal, "error creating authz input %v", err)
@@ -303,6 +308,9 @@ func (s *TargetStream) Run(nonce uint32, replyChan chan *pb.ProxyReply) {
 
 			// If authz fails, close immediately with an error
 			if err := s.authorizer.Eval(ctx, authinput); err != nil {
+				if s.authzExplain && status.Code(err) == codes.PermissionDenied {
+					err = s.explain(ctx, authinput, status.Convert(err)).Err()
+				}
 				s.CloseWith(err)
 				return err
 			}
@@ -310,6 +318,9 @@ func (s *TargetStream) Run(nonce uint32, replyChan chan *pb.ProxyReply) {
 			if s.authzDryRun {
 				// TODO: make authz dry run request to server
 				st := status.Newf(codes.Aborted, "authz dry run passed. Proxy: Ok, Server: Unknown. Aborting \"%s\" futher execution", s.Method())
+				if s.authzExplain {
+					st = s.explain(ctx, authinput, st)
+				}
 				s.CloseWith(st.Err())
 				return nil
 			}
@@ -494,6 +505,7 @@ func (t *TargetStreamSet) Add(ctx context.Context, req *pb.StartStream, replyCha
 		sendReply(reply)
 		return nil
 	}
+	stream.authzExplain = req.GetAuthzDryRun() && req.GetAuthzExplain()
 	streamID := stream.StreamID()
 	t.streams[streamID] = stream
 	t.noncePairs[targetNonce] = true
@@ -625,6 +637,27 @@ func (t *TargetStreamSet) Send(ctx context.Context, req *pb.StreamData) error {
 	return nil
 }
 
+// explain attaches an explanation of the authz decision for input to st.
+// If the decision can't be explained st is returned unchanged.
+func (s *TargetStream) explain(ctx context.Context, input *rpcauth.RPCAuthInput, st *status.Status) *status.Status {
+	explainer, ok := s.authorizer.(rpcauth.AuthzExplainer)
+	if !ok {
+		s.logger.Info("authz policy can't explain its decisions")
+		return st
+	}
+	explanation, err := explainer.Explain(ctx, input)
+	if err != nil {
+		s.logger.Error(err, "unable to explain authz decision")
+		return st
+	}
+	withExplanation, err := st.WithDetails(explanationToProto(explanation))
+	if err != nil {
+		s.logger.Error(err, "unable to attach authz explanation")
+		return st
+	}
+	return withExplanation
+}
+
 func convertStatus(s *status.Status) *pb.Status {
 	if s == nil {
 		return nil
@@ -679,3 +712,32 @@ func (u *unconnectedClientStream) SendMsg(interface{}) error {
 func (u *unconnectedClientStream) RecvMsg(interface{}) error {
 	return fmt.Errorf("%w: RecvMsg", errUnconnectedClient)
 }
+
+func explanationToProto(e *rpcauth.Explanation) *pb.AuthzExplanation {
+	p := &pb.AuthzExplanation{
+		Allowed:     e.Allowed,
+		DenialHints: e.DenialHints,
+	}
+	for _, r := range e.Rules {
+		rule := &pb.AuthzRuleExplanation{
+			Line:        int32(r.Line),
+			Matched:     r.Matched,
+			Expressions: int32(r.Expressions),
+			Passed:      int32(r.Passed),
+		}
+		if r.Failed != nil {
+			rule.Failed = &pb.AuthzExpressionExplanation{
+				Line: int32(r.Failed.Line),
+				Text: r.Failed.Text,
+			}
+			for _, f := range r.Failed.InputFields {
+				rule.Failed.InputFields = append(rule.Failed.InputFields, &pb.AuthzInputField{
+					Path:  f.Path,
+					Value: string(f.Value),
+				})
+			}
+		}
+		p.Rules = append(p.Rules, rule)
+	}
+	return p
+}
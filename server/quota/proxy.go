@@ -0,0 +1,174 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package quota
+
+import (
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Snowflake-Labs/sansshell/proxy"
+)
+
+// proxyMethod is the method of the proxy's streaming RPC.
+const proxyMethod = "/Proxy.Proxy/Proxy"
+
+// pendingKey identifies a StartStream request waiting for its reply.
+type pendingKey struct {
+	target string
+	nonce  uint32
+}
+
+// proxyStream wraps a Proxy stream to apply quotas to each target call
+// made through it. A call holds its quota from StartStream until the
+// proxy sends its ServerClose.
+type proxyStream struct {
+	grpc.ServerStream
+	l         *Limiter
+	principal string
+
+	// sendMu serializes sends, as rejections are sent from RecvMsg while
+	// the proxy sends from its own goroutine.
+	sendMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[pendingKey]func()
+	streams map[uint64]func()
+}
+
+func newProxyStream(ss grpc.ServerStream, l *Limiter, principal string) *proxyStream {
+	return &proxyStream{
+		ServerStream: ss,
+		l:            l,
+		principal:    principal,
+		pending:      make(map[pendingKey]func()),
+		streams:      make(map[uint64]func()),
+	}
+}
+
+// see: grpc.ServerStream.RecvMsg
+func (p *proxyStream) RecvMsg(m interface{}) error {
+	for {
+		if err := p.ServerStream.RecvMsg(m); err != nil {
+			return err
+		}
+		req, ok := m.(*pb.ProxyRequest)
+		if !ok {
+			return nil
+		}
+		start := req.GetStartStream()
+		// Dry runs don't execute anything so aren't limited.
+		if start == nil || start.AuthzDryRun {
+			return nil
+		}
+		release, err := p.l.Acquire(p.Context(), start.MethodName, p.principal)
+		if err == nil {
+			key := pendingKey{target: start.Target, nonce: start.Nonce}
+			p.mu.Lock()
+			if old := p.pending[key]; old != nil {
+				old()
+			}
+			p.pending[key] = release
+			p.mu.Unlock()
+			return nil
+		}
+		// Answer the request ourselves and wait for the next one so the
+		// proxy never sees it.
+		reply := &pb.ProxyReply{
+			Reply: &pb.ProxyReply_StartStreamReply{
+				StartStreamReply: &pb.StartStreamReply{
+					Target: start.Target,
+					Nonce:  start.Nonce,
+					Reply: &pb.StartStreamReply_ErrorStatus{
+						ErrorStatus: convertStatus(status.Convert(err)),
+					},
+				},
+			},
+		}
+		if err := p.send(reply); err != nil {
+			return err
+		}
+	}
+}
+
+// see: grpc.ServerStream.SendMsg
+func (p *proxyStream) SendMsg(m interface{}) error {
+	if reply, ok := m.(*pb.ProxyReply); ok {
+		p.track(reply)
+	}
+	return p.send(m)
+}
+
+func (p *proxyStream) send(m interface{}) error {
+	p.sendMu.Lock()
+	defer p.sendMu.Unlock()
+	return p.ServerStream.SendMsg(m)
+}
+
+// track moves quota held by pending calls to their streams once started,
+// and releases it when calls fail to start or finish.
+func (p *proxyStream) track(reply *pb.ProxyReply) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if start := reply.GetStartStreamReply(); start != nil {
+		key := pendingKey{target: start.Target, nonce: start.Nonce}
+		release := p.pending[key]
+		if release == nil {
+			return
+		}
+		delete(p.pending, key)
+		if start.GetErrorStatus() != nil {
+			release()
+			return
+		}
+		p.streams[start.GetStreamId()] = release
+	}
+	if closed := reply.GetServerClose(); closed != nil {
+		for _, id := range closed.StreamIds {
+			if release := p.streams[id]; release != nil {
+				release()
+				delete(p.streams, id)
+			}
+		}
+	}
+}
+
+// releaseAll releases the quota of every call still outstanding when the
+// Proxy stream ends.
+func (p *proxyStream) releaseAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for k, release := range p.pending {
+		release()
+		delete(p.pending, k)
+	}
+	for id, release := range p.streams {
+		release()
+		delete(p.streams, id)
+	}
+}
+
+// convertStatus converts a gRPC status into its proxy representation.
+func convertStatus(s *status.Status) *pb.Status {
+	sp := s.Proto()
+	return &pb.Status{
+		Code:    sp.Code,
+		Message: sp.Message,
+		Details: sp.Details,
+	}
+}
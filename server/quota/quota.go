@@ -0,0 +1,360 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+// Package quota limits how fast and how many requests at once each
+// principal can make to a server or proxy. Limits are configured per
+// method and applied by gRPC interceptors, rejecting requests over quota
+// with codes.ResourceExhausted and a hint of when to retry.
+package quota
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/time/rate"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/Snowflake-Labs/sansshell/auth/jwt"
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+	"github.com/Snowflake-Labs/sansshell/proxy/auth/proxiedidentity"
+	"github.com/Snowflake-Labs/sansshell/telemetry/metrics"
+)
+
+// Metrics
+var (
+	quotaRejectedCounter = metrics.MetricDefinition{Name: "quota_rejected",
+		Description: "number of requests rejected for exceeding a rate or concurrency quota"}
+)
+
+const (
+	reasonRate        = "rate"
+	reasonConcurrency = "concurrency"
+
+	// concurrencyRetryDelay is the retry hint given when a principal has
+	// too many requests in flight. Unlike rate limits there's no way to know
+	// when a slot frees up.
+	concurrencyRetryDelay = time.Second
+
+	// idleBucketTimeout is how long quota state for a principal is kept
+	// after its last request.
+	idleBucketTimeout = 10 * time.Minute
+)
+
+// A Rule limits the requests each principal can make to matching methods.
+type Rule struct {
+	// Method is a full method name such as '/Exec.Exec/Run', a prefix
+	// ending in '*' such as '/Exec.Exec/*', or '*' for every method.
+	Method string `json:"method"`
+
+	// Principal restricts the rule to a single principal. If empty the
+	// rule applies to everyone, with each principal getting its own quota.
+	Principal string `json:"principal"`
+
+	// Rate is the sustained number of requests per second allowed. Zero
+	// means no rate limit.
+	Rate float64 `json:"rate"`
+
+	// Burst is how many requests can be made at once before Rate applies.
+	// It defaults to Rate rounded up.
+	Burst int `json:"burst"`
+
+	// MaxInFlight is the number of requests which may run at the same
+	// time. Zero means no limit.
+	MaxInFlight int `json:"max_in_flight"`
+}
+
+// matches returns true if the rule applies to method and principal.
+func (r *Rule) matches(method, principal string) bool {
+	if r.Principal != "" && r.Principal != principal {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(r.Method, "*"); ok {
+		return strings.HasPrefix(method, prefix)
+	}
+	return r.Method == method
+}
+
+// Config is a set of quota rules. For each request the first rule matching
+// its method and principal applies, so rules for specific principals
+// should come before general ones. Requests matching no rule are not
+// limited.
+type Config struct {
+	Rules []Rule `json:"rules"`
+
+	// TrustedProxies are the principals of proxies whose callers should be
+	// limited rather than the proxy itself. Requests from them are counted
+	// against the identity the proxy forwards, if there is one.
+	TrustedProxies []string `json:"trusted_proxies"`
+}
+
+// LoadConfig reads a Config from the JSON file at path, for example
+//
+//	{
+//	  "rules": [
+//	    {"method": "/Exec.Exec/*", "principal": "deploy-bot", "rate": 50, "max_in_flight": 100},
+//	    {"method": "/Exec.Exec/*", "rate": 1, "burst": 5, "max_in_flight": 4},
+//	    {"method": "/Process.Process/GetMemoryDump", "max_in_flight": 1}
+//	  ],
+//	  "trusted_proxies": ["proxy"]
+//	}
+func LoadConfig(path string) (*Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read quota config: %v", err)
+	}
+	c := &Config{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return nil, fmt.Errorf("can't parse quota config %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// bucketKey identifies the quota state for a principal under a rule.
+type bucketKey struct {
+	rule      int
+	principal string
+}
+
+// bucket is the quota state for a principal under a rule.
+type bucket struct {
+	limiter  *rate.Limiter
+	inFlight int
+	lastUsed time.Time
+}
+
+// A Limiter enforces a Config.
+type Limiter struct {
+	rules     []Rule
+	principal func(context.Context) string
+	now       func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	lastSweep time.Time
+}
+
+// An Option configures a Limiter.
+type Option func(*Limiter)
+
+// WithPrincipalFunc sets how the principal making a request is found.
+// The default is DefaultPrincipal, or ProxiedPrincipal if the config has
+// trusted proxies.
+func WithPrincipalFunc(f func(context.Context) string) Option {
+	return func(l *Limiter) {
+		l.principal = f
+	}
+}
+
+// New returns a Limiter enforcing the rules in config.
+func New(config *Config, opts ...Option) (*Limiter, error) {
+	l := &Limiter{
+		principal: DefaultPrincipal,
+		now:       time.Now,
+		buckets:   make(map[bucketKey]*bucket),
+	}
+	for i, r := range config.Rules {
+		switch {
+		case r.Method == "":
+			return nil, fmt.Errorf("quota rule %d has no method", i)
+		case r.Rate < 0 || r.Burst < 0 || r.MaxInFlight < 0:
+			return nil, fmt.Errorf("quota rule %d for %s has a negative limit", i, r.Method)
+		case r.Rate == 0 && r.MaxInFlight == 0:
+			return nil, fmt.Errorf("quota rule %d for %s sets neither rate nor max_in_flight", i, r.Method)
+		}
+		if r.Rate > 0 && r.Burst == 0 {
+			r.Burst = int(math.Ceil(r.Rate))
+		}
+		l.rules = append(l.rules, r)
+	}
+	if len(config.TrustedProxies) > 0 {
+		l.principal = ProxiedPrincipal(config.TrustedProxies...)
+	}
+	for _, o := range opts {
+		o(l)
+	}
+	return l, nil
+}
+
+// DefaultPrincipal identifies the caller of a request before authorization
+// has run. It uses, in order, the subject of a verified bearer token, the
+// common name of the client certificate, the unix user of a socket peer
+// and the peer's network address.
+func DefaultPrincipal(ctx context.Context) string {
+	if p := jwt.PrincipalFromContext(ctx); p != nil {
+		return p.ID
+	}
+	peer := rpcauth.PeerInputFromContext(ctx)
+	switch {
+	case peer == nil:
+		return ""
+	case peer.Principal != nil && peer.Principal.ID != "":
+		return peer.Principal.ID
+	case peer.Cert != nil && peer.Cert.Subject.CommonName != "":
+		return peer.Cert.Subject.CommonName
+	case peer.Unix != nil:
+		return peer.Unix.UserName
+	case peer.Net != nil:
+		return peer.Net.Address
+	}
+	return ""
+}
+
+// ProxiedPrincipal returns a function identifying callers like
+// DefaultPrincipal, except that requests from one of trustedProxies are
+// identified by the caller identity the proxy forwarded with them. Only
+// proxies can be trusted to forward an identity, anybody else could claim
+// to be anyone.
+func ProxiedPrincipal(trustedProxies ...string) func(context.Context) string {
+	return func(ctx context.Context) string {
+		principal := DefaultPrincipal(ctx)
+		if !slices.Contains(trustedProxies, principal) {
+			return principal
+		}
+		if p := proxiedidentity.FromContext(ctx); p != nil && p.ID != "" {
+			return p.ID
+		}
+		return principal
+	}
+}
+
+// Acquire checks whether principal may call method now. If so the returned
+// function must be called once the request completes. Otherwise a
+// codes.ResourceExhausted error is returned carrying an
+// errdetails.RetryInfo with how long to wait before retrying.
+func (l *Limiter) Acquire(ctx context.Context, method, principal string) (func(), error) {
+	idx := -1
+	for i := range l.rules {
+		if l.rules[i].matches(method, principal) {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return func() {}, nil
+	}
+	r := &l.rules[idx]
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+	key := bucketKey{rule: idx, principal: principal}
+	b := l.buckets[key]
+	if b == nil {
+		b = &bucket{}
+		if r.Rate > 0 {
+			b.limiter = rate.NewLimiter(rate.Limit(r.Rate), r.Burst)
+		}
+		l.buckets[key] = b
+	}
+	b.lastUsed = now
+
+	if r.MaxInFlight > 0 && b.inFlight >= r.MaxInFlight {
+		return nil, rejected(ctx, method, principal, reasonConcurrency, concurrencyRetryDelay)
+	}
+	if b.limiter != nil {
+		res := b.limiter.ReserveN(now, 1)
+		if delay := res.DelayFrom(now); delay > 0 {
+			res.CancelAt(now)
+			return nil, rejected(ctx, method, principal, reasonRate, delay)
+		}
+	}
+	b.inFlight++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			b.inFlight--
+			b.lastUsed = l.now()
+		})
+	}, nil
+}
+
+// sweep drops quota state for principals which have been idle long enough
+// that their rate limit would be full again. Callers must hold l.mu.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < idleBucketTimeout {
+		return
+	}
+	l.lastSweep = now
+	for k, b := range l.buckets {
+		if b.inFlight == 0 && now.Sub(b.lastUsed) >= idleBucketTimeout {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// rejected records a rejection and returns the error for it.
+func rejected(ctx context.Context, method, principal, reason string, retry time.Duration) error {
+	recorder := metrics.RecorderFromContextOrNoop(ctx)
+	recorder.CounterOrLog(ctx, quotaRejectedCounter, 1, attribute.String("method", method), attribute.String("reason", reason))
+	st := status.Newf(codes.ResourceExhausted, "%s quota for %s exceeded by %q, retry after %v", reason, method, principal, retry.Round(time.Millisecond))
+	if withRetry, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(retry)}); err == nil {
+		st = withRetry
+	}
+	return st.Err()
+}
+
+// UnaryServerInterceptor returns an interceptor enforcing the quotas. It
+// should run after any interceptor which establishes the caller's
+// identity, such as jwt.Verifier.UnaryServerInterceptor.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		release, err := l.Acquire(ctx, info.FullMethod, l.principal(ctx))
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the streaming version of UnaryServerInterceptor.
+// A stream is in flight until its handler returns. On a proxy each target
+// call within a Proxy stream is also checked against the quota for its
+// method, and calls over quota are rejected individually.
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		principal := l.principal(ss.Context())
+		release, err := l.Acquire(ss.Context(), info.FullMethod, principal)
+		if err != nil {
+			return err
+		}
+		defer release()
+		if info.FullMethod != proxyMethod {
+			return handler(srv, ss)
+		}
+		ps := newProxyStream(ss, l, principal)
+		defer ps.releaseAll()
+		return handler(srv, ps)
+	}
+}
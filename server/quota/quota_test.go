@@ -0,0 +1,300 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package quota
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+	pb "github.com/Snowflake-Labs/sansshell/proxy"
+	"github.com/Snowflake-Labs/sansshell/proxy/auth/proxiedidentity"
+	"github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+// retryDelay returns the RetryInfo hint in err.
+func retryDelay(t *testing.T, err error) time.Duration {
+	t.Helper()
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("err = %v, want ResourceExhausted", err)
+	}
+	for _, d := range status.Convert(err).Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok {
+			return ri.RetryDelay.AsDuration()
+		}
+	}
+	t.Fatalf("no RetryInfo in %v", err)
+	return 0
+}
+
+func TestAcquire(t *testing.T) {
+	ctx := context.Background()
+	l, err := New(&Config{Rules: []Rule{
+		{Method: "/Exec.Exec/*", Principal: "bot", MaxInFlight: 1},
+		{Method: "/Exec.Exec/*", Rate: 1, Burst: 2},
+		{Method: "/Process.Process/GetMemoryDump", MaxInFlight: 1},
+	}})
+	testutil.FatalOnErr("New", err, t)
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	// Burst of 2 then limited to 1/s, separately for each principal.
+	for i := 0; i < 2; i++ {
+		_, err := l.Acquire(ctx, "/Exec.Exec/Run", "alice")
+		testutil.FatalOnErr("Acquire within burst", err, t)
+	}
+	_, err = l.Acquire(ctx, "/Exec.Exec/Run", "alice")
+	if d := retryDelay(t, err); d <= 0 || d > time.Second {
+		t.Errorf("retry delay = %v, want (0, 1s]", d)
+	}
+	_, err = l.Acquire(ctx, "/Exec.Exec/Run", "bob")
+	testutil.FatalOnErr("Acquire as other principal", err, t)
+	now = now.Add(time.Second)
+	_, err = l.Acquire(ctx, "/Exec.Exec/Run", "alice")
+	testutil.FatalOnErr("Acquire after refill", err, t)
+
+	// The principal specific rule takes precedence and has no rate limit.
+	release, err := l.Acquire(ctx, "/Exec.Exec/Run", "bot")
+	testutil.FatalOnErr("Acquire bot", err, t)
+	_, err = l.Acquire(ctx, "/Exec.Exec/Run", "bot")
+	if d := retryDelay(t, err); d != concurrencyRetryDelay {
+		t.Errorf("retry delay = %v, want %v", d, concurrencyRetryDelay)
+	}
+	release()
+	release()
+	for i := 0; i < 5; i++ {
+		release, err := l.Acquire(ctx, "/Exec.Exec/Run", "bot")
+		testutil.FatalOnErr("Acquire bot after release", err, t)
+		release()
+	}
+
+	// Unmatched methods aren't limited.
+	for i := 0; i < 5; i++ {
+		_, err := l.Acquire(ctx, "/Process.Process/List", "alice")
+		testutil.FatalOnErr("Acquire unlimited", err, t)
+	}
+
+	// Idle state is eventually dropped, except for alice and bob who
+	// still have requests in flight.
+	now = now.Add(2 * idleBucketTimeout)
+	_, err = l.Acquire(ctx, "/Process.Process/GetMemoryDump", "alice")
+	testutil.FatalOnErr("Acquire after idle", err, t)
+	if got := len(l.buckets); got != 3 {
+		t.Errorf("%d buckets after sweep, want 3", got)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		rule Rule
+	}{
+		{name: "no method", rule: Rule{Rate: 1}},
+		{name: "negative", rule: Rule{Method: "*", Rate: -1}},
+		{name: "no limits", rule: Rule{Method: "*"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := New(&Config{Rules: []Rule{tc.rule}}); err == nil {
+				t.Error("New didn't return an error")
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.json")
+	testutil.FatalOnErr("WriteFile", os.WriteFile(good, []byte(`{"rules": [{"method": "*", "rate": 2.5, "max_in_flight": 3}]}`), 0644), t)
+	c, err := LoadConfig(good)
+	testutil.FatalOnErr("LoadConfig", err, t)
+	l, err := New(c)
+	testutil.FatalOnErr("New", err, t)
+	if got, want := l.rules[0], (Rule{Method: "*", Rate: 2.5, Burst: 3, MaxInFlight: 3}); got != want {
+		t.Errorf("rule = %+v, want %+v", got, want)
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	testutil.FatalOnErr("WriteFile", os.WriteFile(bad, []byte(`{"rules": [{"methd": "*"}]}`), 0644), t)
+	if _, err := LoadConfig(bad); err == nil {
+		t.Error("LoadConfig accepted an unknown field")
+	}
+	if _, err := LoadConfig(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("LoadConfig of missing file didn't error")
+	}
+}
+
+func TestProxiedPrincipal(t *testing.T) {
+	// peerCtx returns a context for a request from peer which forwards
+	// the identity of proxied, if set.
+	peerCtx := func(peer, proxied string) context.Context {
+		ctx := rpcauth.AddPeerToContext(context.Background(), &rpcauth.PeerAuthInput{
+			Principal: &rpcauth.PrincipalAuthInput{ID: peer},
+		})
+		if proxied != "" {
+			ctx = proxiedidentity.AppendToMetadataInOutgoingContext(ctx, &rpcauth.PrincipalAuthInput{ID: proxied})
+			md, _ := metadata.FromOutgoingContext(ctx)
+			ctx = metadata.NewIncomingContext(ctx, md)
+		}
+		return ctx
+	}
+	l, err := New(&Config{
+		Rules:          []Rule{{Method: "*", MaxInFlight: 1}},
+		TrustedProxies: []string{"proxy"},
+	})
+	testutil.FatalOnErr("New", err, t)
+	for _, tc := range []struct {
+		name    string
+		ctx     context.Context
+		want    string
+		wantDef string
+	}{
+		{name: "trusted proxy", ctx: peerCtx("proxy", "alice"), want: "alice", wantDef: "proxy"},
+		{name: "trusted proxy without identity", ctx: peerCtx("proxy", ""), want: "proxy", wantDef: "proxy"},
+		{name: "untrusted peer", ctx: peerCtx("mallory", "alice"), want: "mallory", wantDef: "mallory"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := l.principal(tc.ctx); got != tc.want {
+				t.Errorf("principal = %q, want %q", got, tc.want)
+			}
+			if got := DefaultPrincipal(tc.ctx); got != tc.wantDef {
+				t.Errorf("DefaultPrincipal = %q, want %q", got, tc.wantDef)
+			}
+		})
+	}
+
+	// Users behind the same proxy get their own quota.
+	release, err := l.Acquire(context.Background(), "/Foo/Bar", l.principal(peerCtx("proxy", "alice")))
+	testutil.FatalOnErr("Acquire alice", err, t)
+	defer release()
+	release, err = l.Acquire(context.Background(), "/Foo/Bar", l.principal(peerCtx("proxy", "bob")))
+	testutil.FatalOnErr("Acquire bob", err, t)
+	defer release()
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	l, err := New(&Config{Rules: []Rule{{Method: "*", MaxInFlight: 1}}}, WithPrincipalFunc(func(context.Context) string { return "alice" }))
+	testutil.FatalOnErr("New", err, t)
+	info := &grpc.UnaryServerInfo{FullMethod: "/Exec.Exec/Run"}
+	interceptor := l.UnaryServerInterceptor()
+
+	var inner error
+	_, err = interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		_, inner = interceptor(ctx, nil, info, func(context.Context, interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		return nil, nil
+	})
+	testutil.FatalOnErr("outer call", err, t)
+	if status.Code(inner) != codes.ResourceExhausted {
+		t.Errorf("concurrent call err = %v, want ResourceExhausted", inner)
+	}
+	_, err = interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	testutil.FatalOnErr("call after release", err, t)
+}
+
+// fakeProxyStream replays requests and records replies.
+type fakeProxyStream struct {
+	grpc.ServerStream
+	requests []*pb.ProxyRequest
+	replies  []*pb.ProxyReply
+}
+
+func (f *fakeProxyStream) Context() context.Context {
+	return context.Background()
+}
+
+func (f *fakeProxyStream) SetHeader(metadata.MD) error {
+	return nil
+}
+
+func (f *fakeProxyStream) RecvMsg(m interface{}) error {
+	if len(f.requests) == 0 {
+		return context.Canceled
+	}
+	m.(*pb.ProxyRequest).Reset()
+	*m.(*pb.ProxyRequest) = pb.ProxyRequest{Request: f.requests[0].Request}
+	f.requests = f.requests[1:]
+	return nil
+}
+
+func (f *fakeProxyStream) SendMsg(m interface{}) error {
+	f.replies = append(f.replies, m.(*pb.ProxyReply))
+	return nil
+}
+
+func startStream(target string, nonce uint32) *pb.ProxyRequest {
+	return &pb.ProxyRequest{Request: &pb.ProxyRequest_StartStream{StartStream: &pb.StartStream{
+		Target:     target,
+		MethodName: "/Exec.Exec/Run",
+		Nonce:      nonce,
+	}}}
+}
+
+func TestProxyStream(t *testing.T) {
+	l, err := New(&Config{Rules: []Rule{{Method: "/Exec.Exec/Run", MaxInFlight: 1}}}, WithPrincipalFunc(func(context.Context) string { return "alice" }))
+	testutil.FatalOnErr("New", err, t)
+	fake := &fakeProxyStream{requests: []*pb.ProxyRequest{
+		startStream("a", 1),
+		startStream("b", 2),
+		{Request: &pb.ProxyRequest_ClientClose{ClientClose: &pb.ClientClose{StreamIds: []uint64{7}}}},
+	}}
+
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		req := &pb.ProxyRequest{}
+		testutil.FatalOnErr("RecvMsg", ss.RecvMsg(req), t)
+		if got := req.GetStartStream().GetTarget(); got != "a" {
+			t.Fatalf("first request for %q, want a", got)
+		}
+		testutil.FatalOnErr("SendMsg", ss.SendMsg(&pb.ProxyReply{Reply: &pb.ProxyReply_StartStreamReply{StartStreamReply: &pb.StartStreamReply{
+			Target: "a", Nonce: 1, Reply: &pb.StartStreamReply_StreamId{StreamId: 7},
+		}}}), t)
+
+		// The second target is over quota so is answered without the
+		// handler seeing it.
+		testutil.FatalOnErr("RecvMsg", ss.RecvMsg(req), t)
+		if req.GetClientClose() == nil {
+			t.Fatalf("got %v, want the ClientClose", req)
+		}
+		if len(fake.replies) != 2 {
+			t.Fatalf("got %d replies, want 2", len(fake.replies))
+		}
+		reject := fake.replies[1].GetStartStreamReply()
+		if reject.GetTarget() != "b" || codes.Code(reject.GetErrorStatus().GetCode()) != codes.ResourceExhausted {
+			t.Fatalf("reply for rejected target = %v", reject)
+		}
+
+		// Once the first call finishes the quota is available again.
+		testutil.FatalOnErr("SendMsg", ss.SendMsg(&pb.ProxyReply{Reply: &pb.ProxyReply_ServerClose{ServerClose: &pb.ServerClose{StreamIds: []uint64{7}}}}), t)
+		release, err := l.Acquire(context.Background(), "/Exec.Exec/Run", "alice")
+		testutil.FatalOnErr("Acquire after ServerClose", err, t)
+		release()
+		return nil
+	}
+	err = l.StreamServerInterceptor()(nil, fake, &grpc.StreamServerInfo{FullMethod: proxyMethod}, handler)
+	testutil.FatalOnErr("interceptor", err, t)
+}
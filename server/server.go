@@ -40,6 +40,7 @@ import (
 type serveSetup struct {
 	creds              credentials.TransportCredentials
 	policy             rpcauth.AuthzPolicy
+	auditSink          rpcauth.AuditSink
 	logger             logr.Logger
 	authzHooks         []rpcauth.RPCAuthzHook
 	unaryInterceptors  []grpc.UnaryServerInterceptor
@@ -89,6 +90,14 @@ func WithLogger(l logr.Logger) Option {
 	})
 }
 
+// WithAuditSink writes a record of every authz decision to the given sink.
+func WithAuditSink(sink rpcauth.AuditSink) Option {
+	return optionFunc(func(_ context.Context, s *serveSetup) error {
+		s.auditSink = sink
+		return nil
+	})
+}
+
 // WithAuthzHook adds an authz hook which is checked by the installed authorizer.
 func WithAuthzHook(hook rpcauth.RPCAuthzHook) Option {
 	return optionFunc(func(_ context.Context, s *serveSetup) error {
@@ -211,7 +220,7 @@ func BuildServer(opts ...Option) (*grpc.Server, error) {
 		return nil, fmt.Errorf("rpc authorizer was not provided")
 	}
 
-	authz := rpcauth.NewRPCAuthorizer(ss.policy, ss.authzHooks...)
+	authz := rpcauth.NewRPCAuthorizerWithAuditSink(ss.policy, ss.auditSink, ss.authzHooks...)
 
 	unary := ss.unaryInterceptors
 	unary = append(unary,
@@ -0,0 +1,148 @@
+// Copyright (c) 2025 Snowflake Inc. All rights reserved.
+//
+//Licensed under the Apache License, Version 2.0 (the
+//"License"); you may not use this file except in compliance
+//with the License.  You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing,
+//software distributed under the License is distributed on an
+//"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+//KIND, either express or implied.  See the License for the
+//specific language governing permissions and limitations
+//under the License.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: certissuer.proto
+
+package certissuer
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	CertIssuer_IssueCertificate_FullMethodName = "/CertIssuer.CertIssuer/IssueCertificate"
+)
+
+// CertIssuerClient is the client API for CertIssuer service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// The CertIssuer service is hosted by a proxy rather than by targets. It
+// exchanges an identity the proxy can verify for a short-lived client
+// certificate that can be used to talk to the proxy and to targets.
+type CertIssuerClient interface {
+	// IssueCertificate signs a client certificate for the caller. The caller
+	// is identified by its client certificate or by an OIDC token passed in
+	// the 'authorization' metadata as 'Bearer <token>'.
+	IssueCertificate(ctx context.Context, in *IssueCertificateRequest, opts ...grpc.CallOption) (*IssueCertificateResponse, error)
+}
+
+type certIssuerClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCertIssuerClient(cc grpc.ClientConnInterface) CertIssuerClient {
+	return &certIssuerClient{cc}
+}
+
+func (c *certIssuerClient) IssueCertificate(ctx context.Context, in *IssueCertificateRequest, opts ...grpc.CallOption) (*IssueCertificateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(IssueCertificateResponse)
+	err := c.cc.Invoke(ctx, CertIssuer_IssueCertificate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CertIssuerServer is the server API for CertIssuer service.
+// All implementations should embed UnimplementedCertIssuerServer
+// for forward compatibility.
+//
+// The CertIssuer service is hosted by a proxy rather than by targets. It
+// exchanges an identity the proxy can verify for a short-lived client
+// certificate that can be used to talk to the proxy and to targets.
+type CertIssuerServer interface {
+	// IssueCertificate signs a client certificate for the caller. The caller
+	// is identified by its client certificate or by an OIDC token passed in
+	// the 'authorization' metadata as 'Bearer <token>'.
+	IssueCertificate(context.Context, *IssueCertificateRequest) (*IssueCertificateResponse, error)
+}
+
+// UnimplementedCertIssuerServer should be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCertIssuerServer struct{}
+
+func (UnimplementedCertIssuerServer) IssueCertificate(context.Context, *IssueCertificateRequest) (*IssueCertificateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IssueCertificate not implemented")
+}
+func (UnimplementedCertIssuerServer) testEmbeddedByValue() {}
+
+// UnsafeCertIssuerServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CertIssuerServer will
+// result in compilation errors.
+type UnsafeCertIssuerServer interface {
+	mustEmbedUnimplementedCertIssuerServer()
+}
+
+func RegisterCertIssuerServer(s grpc.ServiceRegistrar, srv CertIssuerServer) {
+	// If the following call pancis, it indicates UnimplementedCertIssuerServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&CertIssuer_ServiceDesc, srv)
+}
+
+func _CertIssuer_IssueCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IssueCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CertIssuerServer).IssueCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CertIssuer_IssueCertificate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CertIssuerServer).IssueCertificate(ctx, req.(*IssueCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// CertIssuer_ServiceDesc is the grpc.ServiceDesc for CertIssuer service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var CertIssuer_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "CertIssuer.CertIssuer",
+	HandlerType: (*CertIssuerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "IssueCertificate",
+			Handler:    _CertIssuer_IssueCertificate_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "certissuer.proto",
+}
@@ -0,0 +1,296 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+// Package server implements the sansshell 'CertIssuer' service.
+//
+// Unlike other services it doesn't register itself when imported, as it is
+// meant to be hosted by a proxy that holds a CA key. Create one with New and
+// register it with the proxy's gRPC server.
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/Snowflake-Labs/sansshell/auth/jwt"
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+	pb "github.com/Snowflake-Labs/sansshell/services/certissuer"
+	"github.com/Snowflake-Labs/sansshell/telemetry/metrics"
+)
+
+// Metrics
+var (
+	certIssuerIssueCounter = metrics.MetricDefinition{Name: "actions_certissuer_issue",
+		Description: "number of client certificates issued"}
+	certIssuerIssueFailureCounter = metrics.MetricDefinition{Name: "actions_certissuer_issue_failure",
+		Description: "number of failures when issuing client certificates"}
+)
+
+const (
+	// DefaultLifetime is how long issued certificates are valid for if the
+	// request doesn't say.
+	DefaultLifetime = 10 * time.Minute
+
+	// DefaultMaxLifetime is the longest lifetime a request can ask for
+	// unless WithMaxLifetime is used.
+	DefaultMaxLifetime = time.Hour
+
+	// AuthorizationKey is the metadata key holding a bearer token.
+	AuthorizationKey = jwt.AuthorizationKey
+
+	methodPrefix = "/CertIssuer.CertIssuer/"
+)
+
+// Server implements the CertIssuer gRPC service.
+type Server struct {
+	caCert          *x509.Certificate
+	caKey           crypto.Signer
+	verifier        *jwt.Verifier
+	defaultLifetime time.Duration
+	maxLifetime     time.Duration
+	now             func() time.Time
+}
+
+// An Option configures a Server.
+type Option func(*Server)
+
+// WithTokenVerifier lets callers identify themselves with a token in the
+// 'authorization' metadata instead of a client certificate.
+func WithTokenVerifier(v *jwt.Verifier) Option {
+	return func(s *Server) {
+		s.verifier = v
+	}
+}
+
+// WithDefaultLifetime sets how long certificates are valid for when the
+// request doesn't specify a lifetime.
+func WithDefaultLifetime(d time.Duration) Option {
+	return func(s *Server) {
+		s.defaultLifetime = d
+	}
+}
+
+// WithMaxLifetime caps the lifetime of issued certificates.
+func WithMaxLifetime(d time.Duration) Option {
+	return func(s *Server) {
+		s.maxLifetime = d
+	}
+}
+
+// New returns a Server which signs certificates with caKey, the key of
+// caCert.
+func New(caCert *x509.Certificate, caKey crypto.Signer, opts ...Option) (*Server, error) {
+	// Version 1 certificates, which have no basic constraints, can still
+	// be roots.
+	if caCert.BasicConstraintsValid && !caCert.IsCA {
+		return nil, errors.New("issuing certificate is not a CA")
+	}
+	s := &Server{
+		caCert:          caCert,
+		caKey:           caKey,
+		defaultLifetime: DefaultLifetime,
+		maxLifetime:     DefaultMaxLifetime,
+		now:             time.Now,
+	}
+	for _, o := range opts {
+		o(s)
+	}
+	if s.defaultLifetime > s.maxLifetime {
+		s.defaultLifetime = s.maxLifetime
+	}
+	return s, nil
+}
+
+// LoadCA reads a PEM encoded CA certificate and its private key from disk.
+func LoadCA(certFile, keyFile string) (*x509.Certificate, crypto.Signer, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't load issuing CA: %v", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't parse issuing CA: %v", err)
+	}
+	signer, ok := pair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, nil, errors.New("issuing CA key can't sign")
+	}
+	return cert, signer, nil
+}
+
+// Register is called to expose this handler to the gRPC server
+func (s *Server) Register(gs *grpc.Server) {
+	pb.RegisterCertIssuerServer(gs, s)
+}
+
+// tokenPrincipal verifies the bearer token in md, if there is one.
+func (s *Server) tokenPrincipal(md metadata.MD) (*rpcauth.PrincipalAuthInput, bool, error) {
+	token, ok := jwt.BearerToken(md)
+	if !ok {
+		return nil, false, nil
+	}
+	if s.verifier == nil {
+		return nil, true, status.Error(codes.Unauthenticated, "bearer tokens are not accepted")
+	}
+	p, err := s.verifier.Verify(token)
+	if err != nil {
+		return nil, true, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return p, true, nil
+}
+
+// AuthzHook returns a hook which, for CertIssuer requests carrying a bearer
+// token, verifies the token and sets input.peer.principal from its claims so
+// the proxy's policy can decide whether to issue a certificate. The token
+// itself is removed from the input so it never reaches the policy or audit
+// logs. It should run after rpcauth.PeerPrincipalFromCertHook.
+func (s *Server) AuthzHook() rpcauth.RPCAuthzHook {
+	return rpcauth.RPCAuthzHookFunc(func(_ context.Context, input *rpcauth.RPCAuthInput) error {
+		if !strings.HasPrefix(input.Method, methodPrefix) {
+			return nil
+		}
+		p, ok, err := s.tokenPrincipal(input.Metadata)
+		if !ok {
+			return nil
+		}
+		input.Metadata = input.Metadata.Copy()
+		delete(input.Metadata, AuthorizationKey)
+		if err != nil {
+			return err
+		}
+		if input.Peer == nil {
+			input.Peer = &rpcauth.PeerAuthInput{}
+		}
+		input.Peer.Principal = p
+		return nil
+	})
+}
+
+// principal returns who the certificate should be issued to. That is the
+// principal the authz policy saw, if hooks set one, so the certificate
+// matches what was authorized. Otherwise it comes from a bearer token or
+// the client certificate, in that order.
+func (s *Server) principal(ctx context.Context) (*rpcauth.PrincipalAuthInput, error) {
+	peer := rpcauth.PeerInputFromContext(ctx)
+	if peer != nil && peer.Principal != nil && peer.Principal.ID != "" {
+		return peer.Principal, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	p, ok, err := s.tokenPrincipal(md)
+	if ok {
+		return p, err
+	}
+	if peer == nil || peer.Cert == nil || peer.Cert.Subject.CommonName == "" {
+		return nil, status.Error(codes.Unauthenticated, "a client certificate or bearer token is required")
+	}
+	return &rpcauth.PrincipalAuthInput{
+		ID:     peer.Cert.Subject.CommonName,
+		Groups: peer.Cert.Subject.OrganizationalUnit,
+	}, nil
+}
+
+// IssueCertificate implements CertIssuerServer.
+func (s *Server) IssueCertificate(ctx context.Context, req *pb.IssueCertificateRequest) (*pb.IssueCertificateResponse, error) {
+	logger := logr.FromContextOrDiscard(ctx)
+	recorder := metrics.RecorderFromContextOrNoop(ctx)
+
+	p, err := s.principal(ctx)
+	if err != nil {
+		recorder.CounterOrLog(ctx, certIssuerIssueFailureCounter, 1, attribute.String("reason", "unauthenticated"))
+		return nil, err
+	}
+	block, _ := pem.Decode(req.Csr)
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		recorder.CounterOrLog(ctx, certIssuerIssueFailureCounter, 1, attribute.String("reason", "invalid_csr"))
+		return nil, status.Error(codes.InvalidArgument, "csr must be a PEM encoded CERTIFICATE REQUEST")
+	}
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err == nil {
+		err = csr.CheckSignature()
+	}
+	if err != nil {
+		recorder.CounterOrLog(ctx, certIssuerIssueFailureCounter, 1, attribute.String("reason", "invalid_csr"))
+		return nil, status.Errorf(codes.InvalidArgument, "invalid csr: %v", err)
+	}
+
+	lifetime := s.defaultLifetime
+	if req.Lifetime != nil {
+		lifetime = req.Lifetime.AsDuration()
+		if lifetime <= 0 {
+			recorder.CounterOrLog(ctx, certIssuerIssueFailureCounter, 1, attribute.String("reason", "invalid_lifetime"))
+			return nil, status.Errorf(codes.InvalidArgument, "lifetime must be positive, got %s", lifetime)
+		}
+	}
+	if lifetime > s.maxLifetime {
+		lifetime = s.maxLifetime
+	}
+	now := s.now()
+	notAfter := now.Add(lifetime)
+	if notAfter.After(s.caCert.NotAfter) {
+		notAfter = s.caCert.NotAfter
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		recorder.CounterOrLog(ctx, certIssuerIssueFailureCounter, 1, attribute.String("reason", "serial_err"))
+		return nil, status.Errorf(codes.Internal, "can't generate serial number: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:         p.ID,
+			OrganizationalUnit: p.Groups,
+		},
+		// Allow for some clock skew between the proxy and targets.
+		NotBefore:   now.Add(-time.Minute),
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, s.caCert, csr.PublicKey, s.caKey)
+	if err != nil {
+		recorder.CounterOrLog(ctx, certIssuerIssueFailureCounter, 1, attribute.String("reason", "sign_err"))
+		return nil, status.Errorf(codes.Internal, "can't sign certificate: %v", err)
+	}
+
+	var chain bytes.Buffer
+	_ = pem.Encode(&chain, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+	_ = pem.Encode(&chain, &pem.Block{Type: "CERTIFICATE", Bytes: s.caCert.Raw})
+	logger.Info("issued client certificate", "principal", p.ID, "groups", p.Groups, "serial", serial.String(), "notAfter", notAfter)
+	recorder.CounterOrLog(ctx, certIssuerIssueCounter, 1)
+	return &pb.IssueCertificateResponse{
+		CertificateChain: chain.Bytes(),
+		NotAfter:         timestamppb.New(notAfter),
+	}, nil
+}
@@ -0,0 +1,227 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	josejwt "github.com/go-jose/go-jose/v4/jwt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/Snowflake-Labs/sansshell/auth/jwt"
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+	pb "github.com/Snowflake-Labs/sansshell/services/certissuer"
+	"github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+func newTestServer(t *testing.T, opts ...Option) *Server {
+	t.Helper()
+	caCert, caKey, err := LoadCA("../../../auth/mtls/testdata/root.pem", "../../../auth/mtls/testdata/root.key")
+	testutil.FatalOnErr("LoadCA", err, t)
+	s, err := New(caCert, caKey, opts...)
+	testutil.FatalOnErr("New", err, t)
+	return s
+}
+
+func newCSR(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testutil.FatalOnErr("GenerateKey", err, t)
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{Subject: pkix.Name{CommonName: "ignored"}}, key)
+	testutil.FatalOnErr("CreateCertificateRequest", err, t)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})
+}
+
+func parseLeaf(t *testing.T, resp *pb.IssueCertificateResponse) *x509.Certificate {
+	t.Helper()
+	block, _ := pem.Decode(resp.CertificateChain)
+	if block == nil {
+		t.Fatal("no certificate in response")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	testutil.FatalOnErr("ParseCertificate", err, t)
+	return cert
+}
+
+func certPeerContext(cn string, ou ...string) context.Context {
+	return rpcauth.AddPeerToContext(context.Background(), &rpcauth.PeerAuthInput{
+		Cert: &rpcauth.CertAuthInput{Subject: pkix.Name{CommonName: cn, OrganizationalUnit: ou}},
+	})
+}
+
+func TestIssueCertificate(t *testing.T) {
+	s := newTestServer(t)
+	now := time.Now().Truncate(time.Second)
+	s.now = func() time.Time { return now }
+
+	for _, tc := range []struct {
+		name     string
+		ctx      context.Context
+		req      *pb.IssueCertificateRequest
+		wantCode codes.Code
+		wantCN   string
+		wantOU   []string
+		wantLife time.Duration
+	}{
+		{
+			name:     "default lifetime",
+			ctx:      certPeerContext("alice", "sre"),
+			req:      &pb.IssueCertificateRequest{Csr: newCSR(t)},
+			wantCN:   "alice",
+			wantOU:   []string{"sre"},
+			wantLife: DefaultLifetime,
+		},
+		{
+			name:     "capped lifetime",
+			ctx:      certPeerContext("alice"),
+			req:      &pb.IssueCertificateRequest{Csr: newCSR(t), Lifetime: durationpb.New(24 * time.Hour)},
+			wantCN:   "alice",
+			wantLife: DefaultMaxLifetime,
+		},
+		{
+			name: "principal from hooks",
+			ctx: rpcauth.AddPeerToContext(context.Background(), &rpcauth.PeerAuthInput{
+				Cert:      &rpcauth.CertAuthInput{Subject: pkix.Name{CommonName: "alice"}},
+				Principal: &rpcauth.PrincipalAuthInput{ID: "bob", Groups: []string{"dba"}},
+			}),
+			req:      &pb.IssueCertificateRequest{Csr: newCSR(t), Lifetime: durationpb.New(time.Minute)},
+			wantCN:   "bob",
+			wantOU:   []string{"dba"},
+			wantLife: time.Minute,
+		},
+		{
+			name:     "no identity",
+			ctx:      context.Background(),
+			req:      &pb.IssueCertificateRequest{Csr: newCSR(t)},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "token without verifier",
+			ctx:      metadata.NewIncomingContext(context.Background(), metadata.Pairs(AuthorizationKey, "Bearer foo")),
+			req:      &pb.IssueCertificateRequest{Csr: newCSR(t)},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name:     "bad csr",
+			ctx:      certPeerContext("alice"),
+			req:      &pb.IssueCertificateRequest{Csr: []byte("garbage")},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "negative lifetime",
+			ctx:      certPeerContext("alice"),
+			req:      &pb.IssueCertificateRequest{Csr: newCSR(t), Lifetime: durationpb.New(-time.Minute)},
+			wantCode: codes.InvalidArgument,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := s.IssueCertificate(tc.ctx, tc.req)
+			if got := status.Code(err); got != tc.wantCode {
+				t.Fatalf("IssueCertificate error %v, want code %v", err, tc.wantCode)
+			}
+			if err != nil {
+				return
+			}
+			leaf := parseLeaf(t, resp)
+			if leaf.Subject.CommonName != tc.wantCN || !reflect.DeepEqual(leaf.Subject.OrganizationalUnit, tc.wantOU) {
+				t.Errorf("subject = %v, want CN=%s OU=%v", leaf.Subject, tc.wantCN, tc.wantOU)
+			}
+			if got := leaf.NotAfter.Sub(now); got != tc.wantLife {
+				t.Errorf("lifetime = %v, want %v", got, tc.wantLife)
+			}
+			if !resp.NotAfter.AsTime().Equal(leaf.NotAfter) {
+				t.Errorf("not_after = %v, want %v", resp.NotAfter.AsTime(), leaf.NotAfter)
+			}
+			roots := x509.NewCertPool()
+			roots.AddCert(s.caCert)
+			if _, err := leaf.Verify(x509.VerifyOptions{Roots: roots, CurrentTime: now, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+				t.Errorf("issued certificate doesn't verify: %v", err)
+			}
+		})
+	}
+}
+
+func TestIssueCertificateWithToken(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testutil.FatalOnErr("GenerateKey", err, t)
+	jwks, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "k", Algorithm: string(jose.ES256)}}})
+	testutil.FatalOnErr("Marshal", err, t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	testutil.FatalOnErr("WriteFile", os.WriteFile(path, jwks, 0644), t)
+	verifier, err := jwt.NewVerifier(path)
+	testutil.FatalOnErr("NewVerifier", err, t)
+	s := newTestServer(t, WithTokenVerifier(verifier))
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: jose.JSONWebKey{Key: key, KeyID: "k"}}, nil)
+	testutil.FatalOnErr("NewSigner", err, t)
+	token, err := josejwt.Signed(signer).Claims(map[string]any{
+		"sub":    "carol",
+		"groups": []string{"oncall"},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+	}).Serialize()
+	testutil.FatalOnErr("Serialize", err, t)
+	md := metadata.Pairs(AuthorizationKey, "Bearer "+token, "other", "kept")
+
+	// The hook sets the principal and strips the token.
+	input := &rpcauth.RPCAuthInput{Method: "/CertIssuer.CertIssuer/IssueCertificate", Metadata: md}
+	testutil.FatalOnErr("hook", s.AuthzHook().Hook(context.Background(), input), t)
+	want := &rpcauth.PrincipalAuthInput{ID: "carol", Groups: []string{"oncall"}}
+	if input.Peer == nil || !reflect.DeepEqual(input.Peer.Principal, want) {
+		t.Errorf("principal = %+v, want %+v", input.Peer, want)
+	}
+	if _, ok := input.Metadata[AuthorizationKey]; ok {
+		t.Error("token left in policy input")
+	}
+	if len(md.Get(AuthorizationKey)) != 1 || len(input.Metadata.Get("other")) != 1 {
+		t.Error("hook changed the caller's metadata or dropped other keys")
+	}
+
+	// Other services are left alone.
+	input = &rpcauth.RPCAuthInput{Method: "/Exec.Exec/Run", Metadata: md}
+	testutil.FatalOnErr("hook", s.AuthzHook().Hook(context.Background(), input), t)
+	if input.Peer != nil {
+		t.Errorf("hook set peer %+v for another service", input.Peer)
+	}
+
+	// Bad tokens are rejected by the hook.
+	input = &rpcauth.RPCAuthInput{Method: "/CertIssuer.CertIssuer/IssueCertificate", Metadata: metadata.Pairs(AuthorizationKey, "Bearer junk")}
+	if err := s.AuthzHook().Hook(context.Background(), input); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("hook with bad token: got %v, want Unauthenticated", err)
+	}
+
+	resp, err := s.IssueCertificate(metadata.NewIncomingContext(context.Background(), md), &pb.IssueCertificateRequest{Csr: newCSR(t)})
+	testutil.FatalOnErr("IssueCertificate", err, t)
+	if leaf := parseLeaf(t, resp); leaf.Subject.CommonName != "carol" {
+		t.Errorf("issued to %q, want carol", leaf.Subject.CommonName)
+	}
+}
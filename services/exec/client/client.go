@@ -23,8 +23,11 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"time"
 
 	"github.com/google/subcommands"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	"github.com/Snowflake-Labs/sansshell/client"
 	pb "github.com/Snowflake-Labs/sansshell/services/exec"
@@ -46,6 +49,11 @@ func init() {
 func (*execCmd) GetSubpackage(f *flag.FlagSet) *subcommands.Commander {
 	c := client.SetupSubpackage(subPackage, f)
 	c.Register(&runCmd{}, "")
+	c.Register(&scriptCmd{}, "")
+	c.Register(&startCmd{}, "")
+	c.Register(&statusCmd{}, "")
+	c.Register(&attachCmd{}, "")
+	c.Register(&killCmd{}, "")
 	return c
 }
 
@@ -68,6 +76,12 @@ func (p *execCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interfac
 type runCmd struct {
 	streaming bool
 	user      string
+	stdin     string
+	env       []string
+	cwd       string
+	timeout   time.Duration
+	killGrace time.Duration
+	limits    pb.ResourceLimits
 
 	// returnCode internally keeps track of the final status to return
 	returnCode subcommands.ExitStatus
@@ -76,24 +90,51 @@ type runCmd struct {
 func (*runCmd) Name() string     { return "run" }
 func (*runCmd) Synopsis() string { return "Run provided command and return a response." }
 func (*runCmd) Usage() string {
-	return `run [--stream] [--user=user] <command> [<args>...]:
+	return `run [--stream] [--user=user] [--stdin=file] [--env=NAME=value...] [--cwd=dir] [--cmd-timeout=duration [--kill-grace=duration]] [--cpu-millis=N] [--memory-bytes=N] [--pids=N] [--io-read-bps=N] [--io-write-bps=N] <command> [<args>...]:
   Run a command remotely and return the response
 
 	Note: This is not optimized for large output or long running commands.  If
 	the output doesn't fit in memory in a single proto message or if it doesn't
-	complete within the timeout, you'll have a bad time.
+	complete within the timeout, you'll have a bad time. Use start instead to
+	run such commands in the background.
 
 	The --stream flag can be used to stream back command output as the command
 	runs. It doesn't affect the timeout.
 
 	--user flag allows to specify a user for running command, equivalent of
 	sudo -u <user> <command> ...
+
+	--stdin sends the contents of a file, or sanssh's own stdin if it's -,
+	to the command's stdin. With --stream it's streamed as it's read.
+
+	--env may be given more than once. The server only allows variables on
+	its allow-list.
+
+	--cmd-timeout has the server send the command SIGTERM once it has run
+	that long, and SIGKILL if it still hasn't exited after --kill-grace.
+
+	--cpu-millis, --memory-bytes, --pids, --io-read-bps and --io-write-bps
+	run the command in its own cgroup with those limits. The peak memory and
+	CPU time it used are then printed to stderr. Targets need cgroup v2.
 `
 }
 
 func (p *runCmd) SetFlags(f *flag.FlagSet) {
 	f.BoolVar(&p.streaming, "stream", DefaultStreaming, "If true, stream back stdout and stdin during the command instead of sending it all at the end.")
 	f.StringVar(&p.user, "user", "", "If specified, allows to run a command as a specified user. Equivalent of sudo -u <user> <command> ... .")
+	f.StringVar(&p.stdin, "stdin", "", "If specified, a file to send to the command's stdin, or - for this program's stdin.")
+	f.Func("env", "An environment variable to set for the command, as NAME=value. May be repeated.", func(s string) error {
+		p.env = append(p.env, s)
+		return nil
+	})
+	f.StringVar(&p.cwd, "cwd", "", "If specified, the absolute path of the directory to run the command in.")
+	f.DurationVar(&p.timeout, "cmd-timeout", 0, "If nonzero, how long the command may run before the server stops it.")
+	f.DurationVar(&p.killGrace, "kill-grace", 0, "How long the command has to exit after SIGTERM before it's killed, once it reaches --cmd-timeout. If zero, the server's default is used.")
+	f.Int64Var(&p.limits.CpuMillis, "cpu-millis", 0, "If nonzero, the CPU time the command may use per second, in thousandths of a CPU.")
+	f.Int64Var(&p.limits.MemoryBytes, "memory-bytes", 0, "If nonzero, the most memory the command may use.")
+	f.Int64Var(&p.limits.Pids, "pids", 0, "If nonzero, the most processes and threads the command may have.")
+	f.Int64Var(&p.limits.IoReadBps, "io-read-bps", 0, "If nonzero, the bytes per second the command may read from each block device.")
+	f.Int64Var(&p.limits.IoWriteBps, "io-write-bps", 0, "If nonzero, the bytes per second the command may write to each block device.")
 }
 
 func (p *runCmd) printCommandOutput(state *util.ExecuteState, idx int, resp *pb.ExecResponse, err error) {
@@ -113,7 +154,13 @@ func (p *runCmd) printCommandOutput(state *util.ExecuteState, idx int, resp *pb.
 		fmt.Fprintf(state.Err[idx], "%s", resp.Stderr)
 	}
 	fmt.Fprintf(state.Out[idx], "%s", resp.Stdout)
-	if resp.RetCode != 0 {
+	if resp.TimedOut {
+		fmt.Fprintf(state.Err[idx], "Command timed out\n")
+	}
+	if u := resp.Usage; u != nil {
+		fmt.Fprintf(state.Err[idx], "Peak memory: %d bytes, CPU time: %v\n", u.PeakMemoryBytes, u.CpuTime.AsDuration())
+	}
+	if resp.RetCode != 0 || resp.TimedOut {
 		p.returnCode = subcommands.ExitFailure
 	}
 }
@@ -126,7 +173,48 @@ func (p *runCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interface
 	}
 
 	c := pb.NewExecClientProxy(state.Conn)
-	req := &pb.ExecRequest{Command: f.Args()[0], Args: f.Args()[1:], User: p.user}
+	req := &pb.ExecRequest{
+		Command: f.Args()[0],
+		Args:    f.Args()[1:],
+		User:    p.user,
+		Env:     p.env,
+		Cwd:     p.cwd,
+	}
+	if proto.Size(&p.limits) > 0 {
+		req.Limits = &p.limits
+	}
+	if p.timeout != 0 {
+		req.Timeout = durationpb.New(p.timeout)
+		if p.killGrace != 0 {
+			req.KillGrace = durationpb.New(p.killGrace)
+		}
+	}
+
+	var stdin io.Reader
+	if p.stdin != "" {
+		stdin = os.Stdin
+		if p.stdin != "-" {
+			f, err := os.Open(p.stdin)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Can't open stdin file: %v\n", err)
+				return subcommands.ExitFailure
+			}
+			defer f.Close()
+			stdin = f
+		}
+	}
+
+	if p.streaming && stdin != nil {
+		return p.streamWithInput(ctx, state, c, req, stdin)
+	}
+	if stdin != nil {
+		b, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't read stdin: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		req.Stdin = b
+	}
 
 	if p.streaming {
 		resp, err := c.StreamingRunOneMany(ctx, req)
@@ -167,3 +255,53 @@ func (p *runCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interface
 	}
 	return p.returnCode
 }
+
+// streamWithInput runs req with StreamingRunWithInput, sending stdin to the
+// command as it's read.
+func (p *runCmd) streamWithInput(ctx context.Context, state *util.ExecuteState, c pb.ExecClientProxy, req *pb.ExecRequest, stdin io.Reader) subcommands.ExitStatus {
+	stream, err := c.StreamingRunWithInputOneMany(ctx)
+	if err == nil {
+		err = stream.Send(&pb.ExecInput{Input: &pb.ExecInput_Request{Request: req}})
+	}
+	if err != nil {
+		// Emit this to every error file as it's not specific to a given target.
+		for _, e := range state.Err {
+			fmt.Fprintf(e, "All targets - could not execute: %v\n", err)
+		}
+		return subcommands.ExitFailure
+	}
+
+	go func() {
+		buf := make([]byte, util.StreamingChunkSize)
+		for {
+			n, err := stdin.Read(buf)
+			if n > 0 {
+				if err := stream.Send(&pb.ExecInput{Input: &pb.ExecInput_Stdin{Stdin: buf[:n]}}); err != nil {
+					// The error will be returned by Recv.
+					return
+				}
+			}
+			if err != nil {
+				if err != io.EOF {
+					fmt.Fprintf(os.Stderr, "Can't read stdin: %v\n", err)
+				}
+				stream.CloseSend()
+				return
+			}
+		}
+	}()
+
+	for {
+		rs, err := stream.Recv()
+		if err != nil {
+			if err == io.EOF {
+				return p.returnCode
+			}
+			fmt.Fprintf(os.Stderr, "Stream failure: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		for _, r := range rs {
+			p.printCommandOutput(state, r.Index, r.Resp, r.Error)
+		}
+	}
+}
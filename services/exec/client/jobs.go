@@ -0,0 +1,299 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/google/subcommands"
+
+	pb "github.com/Snowflake-Labs/sansshell/services/exec"
+	"github.com/Snowflake-Labs/sansshell/services/util"
+)
+
+type startCmd struct {
+	user string
+	id   string
+}
+
+func (*startCmd) Name() string     { return "start" }
+func (*startCmd) Synopsis() string { return "Start a command in the background and print its job ID." }
+func (*startCmd) Usage() string {
+	return `start [--user=user] [--id=id] <command> [<args>...]:
+  Start a command running in the background on each target and print the job
+  ID. Unlike run, the command keeps running if sanssh exits and its output is
+  kept on the target, so this suits long running commands or large output.
+  Use status, attach and kill with the job ID to follow it.
+
+  The same job ID is used on every target. One is generated unless --id is
+  given.
+`
+}
+
+func (p *startCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&p.user, "user", "", "If specified, allows to run a command as a specified user. Equivalent of sudo -u <user> <command> ... .")
+	f.StringVar(&p.id, "id", "", "The job ID to use. Must be unique on each target.")
+}
+
+func (p *startCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	state := args[0].(*util.ExecuteState)
+	if f.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "Please specify a command to execute.\n")
+		return subcommands.ExitUsageError
+	}
+	id := p.id
+	if id == "" {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			fmt.Fprintf(os.Stderr, "Could not generate job ID: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		id = hex.EncodeToString(b)
+	}
+
+	c := pb.NewJobsClientProxy(state.Conn)
+	req := &pb.StartJobRequest{
+		Request: &pb.ExecRequest{Command: f.Args()[0], Args: f.Args()[1:], User: p.user},
+		Id:      id,
+	}
+	resp, err := c.StartOneMany(ctx, req)
+	if err != nil {
+		// Emit this to every error file as it's not specific to a given target.
+		for _, e := range state.Err {
+			fmt.Fprintf(e, "All targets - could not start job: %v\n", err)
+		}
+		return subcommands.ExitFailure
+	}
+	retCode := subcommands.ExitSuccess
+	for r := range resp {
+		if r.Error != nil {
+			fmt.Fprintf(state.Err[r.Index], "Could not start job: %v\n", r.Error)
+			retCode = subcommands.ExitFailure
+			continue
+		}
+		fmt.Fprintln(state.Out[r.Index], r.Resp.Id)
+	}
+	return retCode
+}
+
+type statusCmd struct{}
+
+func (*statusCmd) Name() string     { return "status" }
+func (*statusCmd) Synopsis() string { return "Print the status of a background job." }
+func (*statusCmd) Usage() string {
+	return `status <id>:
+  Print the state of a job started with start, including its exit code once
+  it has finished.
+`
+}
+
+func (*statusCmd) SetFlags(f *flag.FlagSet) {}
+
+// printJobStatus writes a human readable description of st to out.
+func printJobStatus(out io.Writer, st *pb.JobStatus) {
+	fmt.Fprintf(out, "Job:     %s\n", st.Id)
+	if st.Request != nil {
+		fmt.Fprintf(out, "Command: %s %q\n", st.Request.Command, st.Request.Args)
+	}
+	switch st.State {
+	case pb.JobState_JOB_STATE_RUNNING:
+		fmt.Fprintln(out, "State:   running")
+	case pb.JobState_JOB_STATE_EXITED:
+		if st.Signal != 0 {
+			fmt.Fprintf(out, "State:   killed by signal %d\n", st.Signal)
+		} else {
+			fmt.Fprintf(out, "State:   exited with code %d\n", st.RetCode)
+		}
+	default:
+		fmt.Fprintf(out, "State:   failed: %s\n", st.Error)
+	}
+	fmt.Fprintf(out, "Started: %s\n", st.StartTime.AsTime().Local().Format(time.RFC3339))
+	if st.EndTime != nil {
+		fmt.Fprintf(out, "Ended:   %s\n", st.EndTime.AsTime().Local().Format(time.RFC3339))
+	}
+	truncated := func(t bool) string {
+		if t {
+			return " (truncated)"
+		}
+		return ""
+	}
+	fmt.Fprintf(out, "Stdout:  %d bytes%s\n", st.StdoutSize, truncated(st.StdoutTruncated))
+	fmt.Fprintf(out, "Stderr:  %d bytes%s\n", st.StderrSize, truncated(st.StderrTruncated))
+}
+
+// jobFailed returns true if a job didn't run to completion successfully.
+func jobFailed(st *pb.JobStatus) bool {
+	return st.State == pb.JobState_JOB_STATE_FAILED || (st.State == pb.JobState_JOB_STATE_EXITED && st.RetCode != 0)
+}
+
+func (p *statusCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	state := args[0].(*util.ExecuteState)
+	if f.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Please specify a job ID.\n")
+		return subcommands.ExitUsageError
+	}
+
+	c := pb.NewJobsClientProxy(state.Conn)
+	resp, err := c.StatusOneMany(ctx, &pb.JobStatusRequest{Id: f.Arg(0)})
+	if err != nil {
+		// Emit this to every error file as it's not specific to a given target.
+		for _, e := range state.Err {
+			fmt.Fprintf(e, "All targets - could not get job status: %v\n", err)
+		}
+		return subcommands.ExitFailure
+	}
+	retCode := subcommands.ExitSuccess
+	for r := range resp {
+		if r.Error != nil {
+			fmt.Fprintf(state.Err[r.Index], "Could not get job status: %v\n", r.Error)
+			retCode = subcommands.ExitFailure
+			continue
+		}
+		printJobStatus(state.Out[r.Index], r.Resp)
+		if jobFailed(r.Resp) {
+			retCode = subcommands.ExitFailure
+		}
+	}
+	return retCode
+}
+
+type attachCmd struct {
+	follow       bool
+	stdoutOffset int64
+	stderrOffset int64
+}
+
+func (*attachCmd) Name() string     { return "attach" }
+func (*attachCmd) Synopsis() string { return "Stream the output of a background job." }
+func (*attachCmd) Usage() string {
+	return `attach [--follow=false] [--stdout-offset=N] [--stderr-offset=N] <id>:
+  Print the output of a job started with start. By default the output is
+  followed until the job exits, and the exit status is that of the job.
+  Interrupting attach leaves the job running, and it can be attached to
+  again. The offsets skip output which has already been seen.
+`
+}
+
+func (p *attachCmd) SetFlags(f *flag.FlagSet) {
+	f.BoolVar(&p.follow, "follow", true, "If true, keep printing output until the job exits. Otherwise only print the output so far.")
+	f.Int64Var(&p.stdoutOffset, "stdout-offset", 0, "Byte offset in the job's stdout to start printing from.")
+	f.Int64Var(&p.stderrOffset, "stderr-offset", 0, "Byte offset in the job's stderr to start printing from.")
+}
+
+func (p *attachCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	state := args[0].(*util.ExecuteState)
+	if f.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Please specify a job ID.\n")
+		return subcommands.ExitUsageError
+	}
+
+	c := pb.NewJobsClientProxy(state.Conn)
+	req := &pb.AttachJobRequest{
+		Id:           f.Arg(0),
+		StdoutOffset: p.stdoutOffset,
+		StderrOffset: p.stderrOffset,
+		Follow:       p.follow,
+	}
+	stream, err := c.AttachOneMany(ctx, req)
+	if err != nil {
+		// Emit this to every error file as it's not specific to a given target.
+		for _, e := range state.Err {
+			fmt.Fprintf(e, "All targets - could not attach to job: %v\n", err)
+		}
+		return subcommands.ExitFailure
+	}
+	retCode := subcommands.ExitSuccess
+	for {
+		rs, err := stream.Recv()
+		if err == io.EOF {
+			return retCode
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Stream failure: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		for _, r := range rs {
+			if r.Error == io.EOF {
+				continue
+			}
+			if r.Error != nil {
+				fmt.Fprintf(state.Err[r.Index], "Could not attach to job: %v\n", r.Error)
+				retCode = subcommands.ExitFailure
+				continue
+			}
+			state.Out[r.Index].Write(r.Resp.Stdout)
+			state.Err[r.Index].Write(r.Resp.Stderr)
+			if st := r.Resp.Status; st != nil {
+				if st.State == pb.JobState_JOB_STATE_FAILED {
+					fmt.Fprintf(state.Err[r.Index], "Job failed: %s\n", st.Error)
+				}
+				if jobFailed(st) {
+					retCode = subcommands.ExitFailure
+				}
+			}
+		}
+	}
+}
+
+type killCmd struct {
+	signal int
+}
+
+func (*killCmd) Name() string     { return "kill" }
+func (*killCmd) Synopsis() string { return "Send a signal to a background job." }
+func (*killCmd) Usage() string {
+	return `kill [--signal=N] <id>:
+  Send a signal, SIGTERM by default, to a job started with start.
+`
+}
+
+func (p *killCmd) SetFlags(f *flag.FlagSet) {
+	f.IntVar(&p.signal, "signal", 0, "Signal number to send. If unset, SIGTERM is sent.")
+}
+
+func (p *killCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	state := args[0].(*util.ExecuteState)
+	if f.NArg() != 1 {
+		fmt.Fprintf(os.Stderr, "Please specify a job ID.\n")
+		return subcommands.ExitUsageError
+	}
+
+	c := pb.NewJobsClientProxy(state.Conn)
+	resp, err := c.SignalOneMany(ctx, &pb.SignalJobRequest{Id: f.Arg(0), Signal: int32(p.signal)})
+	if err != nil {
+		// Emit this to every error file as it's not specific to a given target.
+		for _, e := range state.Err {
+			fmt.Fprintf(e, "All targets - could not signal job: %v\n", err)
+		}
+		return subcommands.ExitFailure
+	}
+	retCode := subcommands.ExitSuccess
+	for r := range resp {
+		if r.Error != nil {
+			fmt.Fprintf(state.Err[r.Index], "Could not signal job: %v\n", r.Error)
+			retCode = subcommands.ExitFailure
+		}
+	}
+	return retCode
+}
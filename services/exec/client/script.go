@@ -0,0 +1,170 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/google/subcommands"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	pb "github.com/Snowflake-Labs/sansshell/services/exec"
+	"github.com/Snowflake-Labs/sansshell/services/util"
+)
+
+type scriptCmd struct {
+	bucket    string
+	sha256    string
+	user      string
+	stdin     string
+	env       []string
+	cwd       string
+	timeout   time.Duration
+	killGrace time.Duration
+
+	// output prints responses as run does and tracks the exit status.
+	output runCmd
+}
+
+func (*scriptCmd) Name() string     { return "script" }
+func (*scriptCmd) Synopsis() string { return "Push a script to the targets, run it and remove it." }
+func (*scriptCmd) Usage() string {
+	return `script [--bucket=url --sha256=hash] [--user=user] [--stdin=file] [--env=NAME=value...] [--cwd=dir] [--cmd-timeout=duration [--kill-grace=duration]] <script> [<args>...]:
+  Copy a script to a private temporary directory on each target, run it
+  with the given args, stream back its output and then remove it. The
+  script must start with a #! line.
+
+  The script is read from a local file, unless --bucket is given in which
+  case each target fetches the script from the bucket with <script> as the
+  key. As for localfile cp the bucket is a URL such as
+  s3://bucket?region=us-west-2.
+
+  Targets only run a script whose SHA-256 matches the one sent, so policy
+  may allow specific scripts by their hash. It's computed for a local file
+  and must be given with --sha256 for a bucket. If both are present they
+  must match.
+
+  The other flags are as for run.
+`
+}
+
+func (p *scriptCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&p.bucket, "bucket", "", "If set, the bucket targets fetch the script from, with the script argument as the key.")
+	f.StringVar(&p.sha256, "sha256", "", "The hex encoded SHA-256 of the script. Required with --bucket.")
+	f.StringVar(&p.user, "user", "", "If specified, allows to run the script as a specified user. Equivalent of sudo -u <user> <script> ... .")
+	f.StringVar(&p.stdin, "stdin", "", "If specified, a file to send to the script's stdin, or - for this program's stdin.")
+	f.Func("env", "An environment variable to set for the script, as NAME=value. May be repeated.", func(s string) error {
+		p.env = append(p.env, s)
+		return nil
+	})
+	f.StringVar(&p.cwd, "cwd", "", "If specified, the absolute path of the directory to run the script in.")
+	f.DurationVar(&p.timeout, "cmd-timeout", 0, "If nonzero, how long the script may run before the server stops it.")
+	f.DurationVar(&p.killGrace, "kill-grace", 0, "How long the script has to exit after SIGTERM before it's killed, once it reaches --cmd-timeout. If zero, the server's default is used.")
+}
+
+func (p *scriptCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	state := args[0].(*util.ExecuteState)
+	if f.NArg() == 0 {
+		fmt.Fprintf(os.Stderr, "Please specify a script to run.\n")
+		return subcommands.ExitUsageError
+	}
+
+	req := &pb.RunScriptRequest{
+		Sha256: p.sha256,
+		Request: &pb.ExecRequest{
+			Args: f.Args()[1:],
+			User: p.user,
+			Env:  p.env,
+			Cwd:  p.cwd,
+		},
+	}
+	if p.bucket != "" {
+		if p.sha256 == "" {
+			fmt.Fprintf(os.Stderr, "--sha256 is required with --bucket.\n")
+			return subcommands.ExitUsageError
+		}
+		req.Script = &pb.RunScriptRequest_Blob{Blob: &pb.ScriptBlob{Bucket: p.bucket, Key: f.Arg(0)}}
+	} else {
+		content, err := os.ReadFile(f.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't read script: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		sum := sha256.Sum256(content)
+		hash := hex.EncodeToString(sum[:])
+		if p.sha256 != "" && p.sha256 != hash {
+			fmt.Fprintf(os.Stderr, "%s has sha256 %s, not %s\n", f.Arg(0), hash, p.sha256)
+			return subcommands.ExitFailure
+		}
+		req.Script = &pb.RunScriptRequest_Content{Content: content}
+		req.Sha256 = hash
+	}
+	if p.timeout != 0 {
+		req.Request.Timeout = durationpb.New(p.timeout)
+		if p.killGrace != 0 {
+			req.Request.KillGrace = durationpb.New(p.killGrace)
+		}
+	}
+	if p.stdin != "" {
+		var in io.Reader = os.Stdin
+		if p.stdin != "-" {
+			f, err := os.Open(p.stdin)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Can't open stdin file: %v\n", err)
+				return subcommands.ExitFailure
+			}
+			defer f.Close()
+			in = f
+		}
+		b, err := io.ReadAll(in)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't read stdin: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		req.Request.Stdin = b
+	}
+
+	c := pb.NewExecClientProxy(state.Conn)
+	resp, err := c.RunScriptOneMany(ctx, req)
+	if err != nil {
+		// Emit this to every error file as it's not specific to a given target.
+		for _, e := range state.Err {
+			fmt.Fprintf(e, "All targets - could not run script: %v\n", err)
+		}
+		return subcommands.ExitFailure
+	}
+	for {
+		rs, err := resp.Recv()
+		if err != nil {
+			if err == io.EOF {
+				return p.output.returnCode
+			}
+			fmt.Fprintf(os.Stderr, "Stream failure: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		for _, r := range rs {
+			p.output.printCommandOutput(state, r.Index, r.Resp, r.Error)
+		}
+	}
+}
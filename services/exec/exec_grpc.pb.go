@@ -34,8 +34,10 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Exec_Run_FullMethodName          = "/Exec.Exec/Run"
-	Exec_StreamingRun_FullMethodName = "/Exec.Exec/StreamingRun"
+	Exec_Run_FullMethodName                   = "/Exec.Exec/Run"
+	Exec_StreamingRun_FullMethodName          = "/Exec.Exec/StreamingRun"
+	Exec_StreamingRunWithInput_FullMethodName = "/Exec.Exec/StreamingRunWithInput"
+	Exec_RunScript_FullMethodName             = "/Exec.Exec/RunScript"
 )
 
 // ExecClient is the client API for Exec service.
@@ -51,6 +53,16 @@ type ExecClient interface {
 	// A nonzero return code, if any, will be in the final response. Intermediate
 	// responses may contain stdout and/or stderr.
 	StreamingRun(ctx context.Context, in *ExecRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExecResponse], error)
+	// StreamingRunWithInput is StreamingRun with the command's stdin streamed
+	// from the client. The first message must contain the request and later
+	// ones stdin. Stdin is closed when the client closes its side of the
+	// stream.
+	StreamingRunWithInput(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ExecInput, ExecResponse], error)
+	// RunScript writes a script to a private temporary directory on the
+	// target, runs it and streams back its output as StreamingRun does. The
+	// script is only run if its SHA-256 matches the one in the request, and
+	// is always removed afterwards.
+	RunScript(ctx context.Context, in *RunScriptRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExecResponse], error)
 }
 
 type execClient struct {
@@ -90,6 +102,38 @@ func (c *execClient) StreamingRun(ctx context.Context, in *ExecRequest, opts ...
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Exec_StreamingRunClient = grpc.ServerStreamingClient[ExecResponse]
 
+func (c *execClient) StreamingRunWithInput(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ExecInput, ExecResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Exec_ServiceDesc.Streams[1], Exec_StreamingRunWithInput_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ExecInput, ExecResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Exec_StreamingRunWithInputClient = grpc.BidiStreamingClient[ExecInput, ExecResponse]
+
+func (c *execClient) RunScript(ctx context.Context, in *RunScriptRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ExecResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Exec_ServiceDesc.Streams[2], Exec_RunScript_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RunScriptRequest, ExecResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Exec_RunScriptClient = grpc.ServerStreamingClient[ExecResponse]
+
 // ExecServer is the server API for Exec service.
 // All implementations should embed UnimplementedExecServer
 // for forward compatibility.
@@ -103,6 +147,16 @@ type ExecServer interface {
 	// A nonzero return code, if any, will be in the final response. Intermediate
 	// responses may contain stdout and/or stderr.
 	StreamingRun(*ExecRequest, grpc.ServerStreamingServer[ExecResponse]) error
+	// StreamingRunWithInput is StreamingRun with the command's stdin streamed
+	// from the client. The first message must contain the request and later
+	// ones stdin. Stdin is closed when the client closes its side of the
+	// stream.
+	StreamingRunWithInput(grpc.BidiStreamingServer[ExecInput, ExecResponse]) error
+	// RunScript writes a script to a private temporary directory on the
+	// target, runs it and streams back its output as StreamingRun does. The
+	// script is only run if its SHA-256 matches the one in the request, and
+	// is always removed afterwards.
+	RunScript(*RunScriptRequest, grpc.ServerStreamingServer[ExecResponse]) error
 }
 
 // UnimplementedExecServer should be embedded to have
@@ -118,6 +172,12 @@ func (UnimplementedExecServer) Run(context.Context, *ExecRequest) (*ExecResponse
 func (UnimplementedExecServer) StreamingRun(*ExecRequest, grpc.ServerStreamingServer[ExecResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method StreamingRun not implemented")
 }
+func (UnimplementedExecServer) StreamingRunWithInput(grpc.BidiStreamingServer[ExecInput, ExecResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamingRunWithInput not implemented")
+}
+func (UnimplementedExecServer) RunScript(*RunScriptRequest, grpc.ServerStreamingServer[ExecResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method RunScript not implemented")
+}
 func (UnimplementedExecServer) testEmbeddedByValue() {}
 
 // UnsafeExecServer may be embedded to opt out of forward compatibility for this service.
@@ -167,6 +227,24 @@ func _Exec_StreamingRun_Handler(srv interface{}, stream grpc.ServerStream) error
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Exec_StreamingRunServer = grpc.ServerStreamingServer[ExecResponse]
 
+func _Exec_StreamingRunWithInput_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ExecServer).StreamingRunWithInput(&grpc.GenericServerStream[ExecInput, ExecResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Exec_StreamingRunWithInputServer = grpc.BidiStreamingServer[ExecInput, ExecResponse]
+
+func _Exec_RunScript_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RunScriptRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ExecServer).RunScript(m, &grpc.GenericServerStream[RunScriptRequest, ExecResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Exec_RunScriptServer = grpc.ServerStreamingServer[ExecResponse]
+
 // Exec_ServiceDesc is the grpc.ServiceDesc for Exec service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -185,6 +263,259 @@ var Exec_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Exec_StreamingRun_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "StreamingRunWithInput",
+			Handler:       _Exec_StreamingRunWithInput_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "RunScript",
+			Handler:       _Exec_RunScript_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "exec.proto",
+}
+
+const (
+	Jobs_Start_FullMethodName  = "/Exec.Jobs/Start"
+	Jobs_Status_FullMethodName = "/Exec.Jobs/Status"
+	Jobs_Attach_FullMethodName = "/Exec.Jobs/Attach"
+	Jobs_Signal_FullMethodName = "/Exec.Jobs/Signal"
+)
+
+// JobsClient is the client API for Jobs service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// The Jobs service runs commands in the background, independently of the
+// RPC which started them. This suits long running commands or ones with
+// large output, which Exec handles poorly. Output is spooled to disk on the
+// target and can be read back, from any offset, while the job runs and for
+// a while after it exits.
+type JobsClient interface {
+	// Start runs a command in the background and returns the job's ID.
+	Start(ctx context.Context, in *StartJobRequest, opts ...grpc.CallOption) (*StartJobResponse, error)
+	// Status returns the current state of a job.
+	Status(ctx context.Context, in *JobStatusRequest, opts ...grpc.CallOption) (*JobStatus, error)
+	// Attach streams a job's output starting at the given offsets. If follow
+	// is set the stream continues until the job exits and the final response
+	// contains its status.
+	Attach(ctx context.Context, in *AttachJobRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AttachJobResponse], error)
+	// Signal sends a signal to a running job.
+	Signal(ctx context.Context, in *SignalJobRequest, opts ...grpc.CallOption) (*SignalJobResponse, error)
+}
+
+type jobsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewJobsClient(cc grpc.ClientConnInterface) JobsClient {
+	return &jobsClient{cc}
+}
+
+func (c *jobsClient) Start(ctx context.Context, in *StartJobRequest, opts ...grpc.CallOption) (*StartJobResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StartJobResponse)
+	err := c.cc.Invoke(ctx, Jobs_Start_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobsClient) Status(ctx context.Context, in *JobStatusRequest, opts ...grpc.CallOption) (*JobStatus, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JobStatus)
+	err := c.cc.Invoke(ctx, Jobs_Status_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jobsClient) Attach(ctx context.Context, in *AttachJobRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[AttachJobResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Jobs_ServiceDesc.Streams[0], Jobs_Attach_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[AttachJobRequest, AttachJobResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Jobs_AttachClient = grpc.ServerStreamingClient[AttachJobResponse]
+
+func (c *jobsClient) Signal(ctx context.Context, in *SignalJobRequest, opts ...grpc.CallOption) (*SignalJobResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SignalJobResponse)
+	err := c.cc.Invoke(ctx, Jobs_Signal_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// JobsServer is the server API for Jobs service.
+// All implementations should embed UnimplementedJobsServer
+// for forward compatibility.
+//
+// The Jobs service runs commands in the background, independently of the
+// RPC which started them. This suits long running commands or ones with
+// large output, which Exec handles poorly. Output is spooled to disk on the
+// target and can be read back, from any offset, while the job runs and for
+// a while after it exits.
+type JobsServer interface {
+	// Start runs a command in the background and returns the job's ID.
+	Start(context.Context, *StartJobRequest) (*StartJobResponse, error)
+	// Status returns the current state of a job.
+	Status(context.Context, *JobStatusRequest) (*JobStatus, error)
+	// Attach streams a job's output starting at the given offsets. If follow
+	// is set the stream continues until the job exits and the final response
+	// contains its status.
+	Attach(*AttachJobRequest, grpc.ServerStreamingServer[AttachJobResponse]) error
+	// Signal sends a signal to a running job.
+	Signal(context.Context, *SignalJobRequest) (*SignalJobResponse, error)
+}
+
+// UnimplementedJobsServer should be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedJobsServer struct{}
+
+func (UnimplementedJobsServer) Start(context.Context, *StartJobRequest) (*StartJobResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Start not implemented")
+}
+func (UnimplementedJobsServer) Status(context.Context, *JobStatusRequest) (*JobStatus, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Status not implemented")
+}
+func (UnimplementedJobsServer) Attach(*AttachJobRequest, grpc.ServerStreamingServer[AttachJobResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method Attach not implemented")
+}
+func (UnimplementedJobsServer) Signal(context.Context, *SignalJobRequest) (*SignalJobResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Signal not implemented")
+}
+func (UnimplementedJobsServer) testEmbeddedByValue() {}
+
+// UnsafeJobsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to JobsServer will
+// result in compilation errors.
+type UnsafeJobsServer interface {
+	mustEmbedUnimplementedJobsServer()
+}
+
+func RegisterJobsServer(s grpc.ServiceRegistrar, srv JobsServer) {
+	// If the following call pancis, it indicates UnimplementedJobsServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Jobs_ServiceDesc, srv)
+}
+
+func _Jobs_Start_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobsServer).Start(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Jobs_Start_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobsServer).Start(ctx, req.(*StartJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Jobs_Status_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JobStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobsServer).Status(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Jobs_Status_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobsServer).Status(ctx, req.(*JobStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Jobs_Attach_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(AttachJobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(JobsServer).Attach(m, &grpc.GenericServerStream[AttachJobRequest, AttachJobResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Jobs_AttachServer = grpc.ServerStreamingServer[AttachJobResponse]
+
+func _Jobs_Signal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SignalJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JobsServer).Signal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Jobs_Signal_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JobsServer).Signal(ctx, req.(*SignalJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Jobs_ServiceDesc is the grpc.ServiceDesc for Jobs service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Jobs_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "Exec.Jobs",
+	HandlerType: (*JobsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Start",
+			Handler:    _Jobs_Start_Handler,
+		},
+		{
+			MethodName: "Status",
+			Handler:    _Jobs_Status_Handler,
+		},
+		{
+			MethodName: "Signal",
+			Handler:    _Jobs_Signal_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Attach",
+			Handler:       _Jobs_Attach_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "exec.proto",
 }
@@ -21,6 +21,8 @@ type ExecClientProxy interface {
 	ExecClient
 	RunOneMany(ctx context.Context, in *ExecRequest, opts ...grpc.CallOption) (<-chan *RunManyResponse, error)
 	StreamingRunOneMany(ctx context.Context, in *ExecRequest, opts ...grpc.CallOption) (Exec_StreamingRunClientProxy, error)
+	StreamingRunWithInputOneMany(ctx context.Context, opts ...grpc.CallOption) (Exec_StreamingRunWithInputClientProxy, error)
+	RunScriptOneMany(ctx context.Context, in *RunScriptRequest, opts ...grpc.CallOption) (Exec_RunScriptClientProxy, error)
 }
 
 // Embed the original client inside of this so we get the other generated methods automatically.
@@ -188,3 +190,487 @@ func (c *execClientProxy) StreamingRunOneMany(ctx context.Context, in *ExecReque
 	}
 	return x, nil
 }
+
+// StreamingRunWithInputManyResponse encapsulates a proxy data packet.
+// It includes the target, index, response and possible error returned.
+type StreamingRunWithInputManyResponse struct {
+	Target string
+	// As targets can be duplicated this is the index into the slice passed to proxy.Conn.
+	Index int
+	Resp  *ExecResponse
+	Error error
+}
+
+type Exec_StreamingRunWithInputClientProxy interface {
+	Send(*ExecInput) error
+	Recv() ([]*StreamingRunWithInputManyResponse, error)
+	grpc.ClientStream
+}
+
+type execClientStreamingRunWithInputClientProxy struct {
+	cc         *proxy.Conn
+	directDone bool
+	grpc.ClientStream
+}
+
+func (x *execClientStreamingRunWithInputClientProxy) Send(m *ExecInput) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *execClientStreamingRunWithInputClientProxy) Recv() ([]*StreamingRunWithInputManyResponse, error) {
+	var ret []*StreamingRunWithInputManyResponse
+	// If this is a direct connection the RecvMsg call is to a standard grpc.ClientStream
+	// and not our proxy based one. This means we need to receive a typed response and
+	// convert it into a single slice entry return. This ensures the OneMany style calls
+	// can be used by proxy with 1:N targets and non proxy with 1 target without client changes.
+	if x.cc.Direct() {
+		// Check if we're done. Just return EOF now. Any real error was already sent inside
+		// of a ManyResponse.
+		if x.directDone {
+			return nil, io.EOF
+		}
+		m := &ExecResponse{}
+		err := x.ClientStream.RecvMsg(m)
+		ret = append(ret, &StreamingRunWithInputManyResponse{
+			Resp:   m,
+			Error:  err,
+			Target: x.cc.Targets[0],
+			Index:  0,
+		})
+		// An error means we're done so set things so a later call now gets an EOF.
+		if err != nil {
+			x.directDone = true
+		}
+		return ret, nil
+	}
+
+	m := []*proxy.Ret{}
+	if err := x.ClientStream.RecvMsg(&m); err != nil {
+		return nil, err
+	}
+	for _, r := range m {
+		typedResp := &StreamingRunWithInputManyResponse{
+			Resp: &ExecResponse{},
+		}
+		typedResp.Target = r.Target
+		typedResp.Index = r.Index
+		typedResp.Error = r.Error
+		if r.Error == nil {
+			if err := r.Resp.UnmarshalTo(typedResp.Resp); err != nil {
+				typedResp.Error = fmt.Errorf("can't decode any response - %v. Original Error - %v", err, r.Error)
+			}
+		}
+		ret = append(ret, typedResp)
+	}
+	return ret, nil
+}
+
+// StreamingRunWithInputOneMany provides the same API as StreamingRunWithInput but sends the same request to N destinations at once.
+// N can be a single destination.
+//
+// NOTE: The returned channel must be read until it closes in order to avoid leaking goroutines.
+func (c *execClientProxy) StreamingRunWithInputOneMany(ctx context.Context, opts ...grpc.CallOption) (Exec_StreamingRunWithInputClientProxy, error) {
+	stream, err := c.cc.NewStream(ctx, &Exec_ServiceDesc.Streams[1], "/Exec.Exec/StreamingRunWithInput", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &execClientStreamingRunWithInputClientProxy{c.cc.(*proxy.Conn), false, stream}
+	return x, nil
+}
+
+// RunScriptManyResponse encapsulates a proxy data packet.
+// It includes the target, index, response and possible error returned.
+type RunScriptManyResponse struct {
+	Target string
+	// As targets can be duplicated this is the index into the slice passed to proxy.Conn.
+	Index int
+	Resp  *ExecResponse
+	Error error
+}
+
+type Exec_RunScriptClientProxy interface {
+	Recv() ([]*RunScriptManyResponse, error)
+	grpc.ClientStream
+}
+
+type execClientRunScriptClientProxy struct {
+	cc         *proxy.Conn
+	directDone bool
+	grpc.ClientStream
+}
+
+func (x *execClientRunScriptClientProxy) Recv() ([]*RunScriptManyResponse, error) {
+	var ret []*RunScriptManyResponse
+	// If this is a direct connection the RecvMsg call is to a standard grpc.ClientStream
+	// and not our proxy based one. This means we need to receive a typed response and
+	// convert it into a single slice entry return. This ensures the OneMany style calls
+	// can be used by proxy with 1:N targets and non proxy with 1 target without client changes.
+	if x.cc.Direct() {
+		// Check if we're done. Just return EOF now. Any real error was already sent inside
+		// of a ManyResponse.
+		if x.directDone {
+			return nil, io.EOF
+		}
+		m := &ExecResponse{}
+		err := x.ClientStream.RecvMsg(m)
+		ret = append(ret, &RunScriptManyResponse{
+			Resp:   m,
+			Error:  err,
+			Target: x.cc.Targets[0],
+			Index:  0,
+		})
+		// An error means we're done so set things so a later call now gets an EOF.
+		if err != nil {
+			x.directDone = true
+		}
+		return ret, nil
+	}
+
+	m := []*proxy.Ret{}
+	if err := x.ClientStream.RecvMsg(&m); err != nil {
+		return nil, err
+	}
+	for _, r := range m {
+		typedResp := &RunScriptManyResponse{
+			Resp: &ExecResponse{},
+		}
+		typedResp.Target = r.Target
+		typedResp.Index = r.Index
+		typedResp.Error = r.Error
+		if r.Error == nil {
+			if err := r.Resp.UnmarshalTo(typedResp.Resp); err != nil {
+				typedResp.Error = fmt.Errorf("can't decode any response - %v. Original Error - %v", err, r.Error)
+			}
+		}
+		ret = append(ret, typedResp)
+	}
+	return ret, nil
+}
+
+// RunScriptOneMany provides the same API as RunScript but sends the same request to N destinations at once.
+// N can be a single destination.
+//
+// NOTE: The returned channel must be read until it closes in order to avoid leaking goroutines.
+func (c *execClientProxy) RunScriptOneMany(ctx context.Context, in *RunScriptRequest, opts ...grpc.CallOption) (Exec_RunScriptClientProxy, error) {
+	stream, err := c.cc.NewStream(ctx, &Exec_ServiceDesc.Streams[2], "/Exec.Exec/RunScript", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &execClientRunScriptClientProxy{c.cc.(*proxy.Conn), false, stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// JobsClientProxy is the superset of JobsClient which additionally includes the OneMany proxy methods
+type JobsClientProxy interface {
+	JobsClient
+	StartOneMany(ctx context.Context, in *StartJobRequest, opts ...grpc.CallOption) (<-chan *StartManyResponse, error)
+	StatusOneMany(ctx context.Context, in *JobStatusRequest, opts ...grpc.CallOption) (<-chan *StatusManyResponse, error)
+	AttachOneMany(ctx context.Context, in *AttachJobRequest, opts ...grpc.CallOption) (Jobs_AttachClientProxy, error)
+	SignalOneMany(ctx context.Context, in *SignalJobRequest, opts ...grpc.CallOption) (<-chan *SignalManyResponse, error)
+}
+
+// Embed the original client inside of this so we get the other generated methods automatically.
+type jobsClientProxy struct {
+	*jobsClient
+}
+
+// NewJobsClientProxy creates a JobsClientProxy for use in proxied connections.
+// NOTE: This takes a proxy.Conn instead of a generic ClientConnInterface as the methods here are only valid in proxy.Conn contexts.
+func NewJobsClientProxy(cc *proxy.Conn) JobsClientProxy {
+	return &jobsClientProxy{NewJobsClient(cc).(*jobsClient)}
+}
+
+// StartManyResponse encapsulates a proxy data packet.
+// It includes the target, index, response and possible error returned.
+type StartManyResponse struct {
+	Target string
+	// As targets can be duplicated this is the index into the slice passed to proxy.Conn.
+	Index int
+	Resp  *StartJobResponse
+	Error error
+}
+
+// StartOneMany provides the same API as Start but sends the same request to N destinations at once.
+// N can be a single destination.
+//
+// NOTE: The returned channel must be read until it closes in order to avoid leaking goroutines.
+func (c *jobsClientProxy) StartOneMany(ctx context.Context, in *StartJobRequest, opts ...grpc.CallOption) (<-chan *StartManyResponse, error) {
+	conn := c.cc.(*proxy.Conn)
+	ret := make(chan *StartManyResponse)
+	// If this is a single case we can just use Invoke and marshal it onto the channel once and be done.
+	if len(conn.Targets) == 1 {
+		go func() {
+			out := &StartManyResponse{
+				Target: conn.Targets[0],
+				Index:  0,
+				Resp:   &StartJobResponse{},
+			}
+			err := conn.Invoke(ctx, "/Exec.Jobs/Start", in, out.Resp, opts...)
+			if err != nil {
+				out.Error = err
+			}
+			// Send and close.
+			ret <- out
+			close(ret)
+		}()
+		return ret, nil
+	}
+	manyRet, err := conn.InvokeOneMany(ctx, "/Exec.Jobs/Start", in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// A goroutine to retrive untyped responses and convert them to typed ones.
+	go func() {
+		for {
+			typedResp := &StartManyResponse{
+				Resp: &StartJobResponse{},
+			}
+
+			resp, ok := <-manyRet
+			if !ok {
+				// All done so we can shut down.
+				close(ret)
+				return
+			}
+			typedResp.Target = resp.Target
+			typedResp.Index = resp.Index
+			typedResp.Error = resp.Error
+			if resp.Error == nil {
+				if err := resp.Resp.UnmarshalTo(typedResp.Resp); err != nil {
+					typedResp.Error = fmt.Errorf("can't decode any response - %v. Original Error - %v", err, resp.Error)
+				}
+			}
+			ret <- typedResp
+		}
+	}()
+
+	return ret, nil
+}
+
+// StatusManyResponse encapsulates a proxy data packet.
+// It includes the target, index, response and possible error returned.
+type StatusManyResponse struct {
+	Target string
+	// As targets can be duplicated this is the index into the slice passed to proxy.Conn.
+	Index int
+	Resp  *JobStatus
+	Error error
+}
+
+// StatusOneMany provides the same API as Status but sends the same request to N destinations at once.
+// N can be a single destination.
+//
+// NOTE: The returned channel must be read until it closes in order to avoid leaking goroutines.
+func (c *jobsClientProxy) StatusOneMany(ctx context.Context, in *JobStatusRequest, opts ...grpc.CallOption) (<-chan *StatusManyResponse, error) {
+	conn := c.cc.(*proxy.Conn)
+	ret := make(chan *StatusManyResponse)
+	// If this is a single case we can just use Invoke and marshal it onto the channel once and be done.
+	if len(conn.Targets) == 1 {
+		go func() {
+			out := &StatusManyResponse{
+				Target: conn.Targets[0],
+				Index:  0,
+				Resp:   &JobStatus{},
+			}
+			err := conn.Invoke(ctx, "/Exec.Jobs/Status", in, out.Resp, opts...)
+			if err != nil {
+				out.Error = err
+			}
+			// Send and close.
+			ret <- out
+			close(ret)
+		}()
+		return ret, nil
+	}
+	manyRet, err := conn.InvokeOneMany(ctx, "/Exec.Jobs/Status", in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// A goroutine to retrive untyped responses and convert them to typed ones.
+	go func() {
+		for {
+			typedResp := &StatusManyResponse{
+				Resp: &JobStatus{},
+			}
+
+			resp, ok := <-manyRet
+			if !ok {
+				// All done so we can shut down.
+				close(ret)
+				return
+			}
+			typedResp.Target = resp.Target
+			typedResp.Index = resp.Index
+			typedResp.Error = resp.Error
+			if resp.Error == nil {
+				if err := resp.Resp.UnmarshalTo(typedResp.Resp); err != nil {
+					typedResp.Error = fmt.Errorf("can't decode any response - %v. Original Error - %v", err, resp.Error)
+				}
+			}
+			ret <- typedResp
+		}
+	}()
+
+	return ret, nil
+}
+
+// AttachManyResponse encapsulates a proxy data packet.
+// It includes the target, index, response and possible error returned.
+type AttachManyResponse struct {
+	Target string
+	// As targets can be duplicated this is the index into the slice passed to proxy.Conn.
+	Index int
+	Resp  *AttachJobResponse
+	Error error
+}
+
+type Jobs_AttachClientProxy interface {
+	Recv() ([]*AttachManyResponse, error)
+	grpc.ClientStream
+}
+
+type jobsClientAttachClientProxy struct {
+	cc         *proxy.Conn
+	directDone bool
+	grpc.ClientStream
+}
+
+func (x *jobsClientAttachClientProxy) Recv() ([]*AttachManyResponse, error) {
+	var ret []*AttachManyResponse
+	// If this is a direct connection the RecvMsg call is to a standard grpc.ClientStream
+	// and not our proxy based one. This means we need to receive a typed response and
+	// convert it into a single slice entry return. This ensures the OneMany style calls
+	// can be used by proxy with 1:N targets and non proxy with 1 target without client changes.
+	if x.cc.Direct() {
+		// Check if we're done. Just return EOF now. Any real error was already sent inside
+		// of a ManyResponse.
+		if x.directDone {
+			return nil, io.EOF
+		}
+		m := &AttachJobResponse{}
+		err := x.ClientStream.RecvMsg(m)
+		ret = append(ret, &AttachManyResponse{
+			Resp:   m,
+			Error:  err,
+			Target: x.cc.Targets[0],
+			Index:  0,
+		})
+		// An error means we're done so set things so a later call now gets an EOF.
+		if err != nil {
+			x.directDone = true
+		}
+		return ret, nil
+	}
+
+	m := []*proxy.Ret{}
+	if err := x.ClientStream.RecvMsg(&m); err != nil {
+		return nil, err
+	}
+	for _, r := range m {
+		typedResp := &AttachManyResponse{
+			Resp: &AttachJobResponse{},
+		}
+		typedResp.Target = r.Target
+		typedResp.Index = r.Index
+		typedResp.Error = r.Error
+		if r.Error == nil {
+			if err := r.Resp.UnmarshalTo(typedResp.Resp); err != nil {
+				typedResp.Error = fmt.Errorf("can't decode any response - %v. Original Error - %v", err, r.Error)
+			}
+		}
+		ret = append(ret, typedResp)
+	}
+	return ret, nil
+}
+
+// AttachOneMany provides the same API as Attach but sends the same request to N destinations at once.
+// N can be a single destination.
+//
+// NOTE: The returned channel must be read until it closes in order to avoid leaking goroutines.
+func (c *jobsClientProxy) AttachOneMany(ctx context.Context, in *AttachJobRequest, opts ...grpc.CallOption) (Jobs_AttachClientProxy, error) {
+	stream, err := c.cc.NewStream(ctx, &Jobs_ServiceDesc.Streams[0], "/Exec.Jobs/Attach", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &jobsClientAttachClientProxy{c.cc.(*proxy.Conn), false, stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// SignalManyResponse encapsulates a proxy data packet.
+// It includes the target, index, response and possible error returned.
+type SignalManyResponse struct {
+	Target string
+	// As targets can be duplicated this is the index into the slice passed to proxy.Conn.
+	Index int
+	Resp  *SignalJobResponse
+	Error error
+}
+
+// SignalOneMany provides the same API as Signal but sends the same request to N destinations at once.
+// N can be a single destination.
+//
+// NOTE: The returned channel must be read until it closes in order to avoid leaking goroutines.
+func (c *jobsClientProxy) SignalOneMany(ctx context.Context, in *SignalJobRequest, opts ...grpc.CallOption) (<-chan *SignalManyResponse, error) {
+	conn := c.cc.(*proxy.Conn)
+	ret := make(chan *SignalManyResponse)
+	// If this is a single case we can just use Invoke and marshal it onto the channel once and be done.
+	if len(conn.Targets) == 1 {
+		go func() {
+			out := &SignalManyResponse{
+				Target: conn.Targets[0],
+				Index:  0,
+				Resp:   &SignalJobResponse{},
+			}
+			err := conn.Invoke(ctx, "/Exec.Jobs/Signal", in, out.Resp, opts...)
+			if err != nil {
+				out.Error = err
+			}
+			// Send and close.
+			ret <- out
+			close(ret)
+		}()
+		return ret, nil
+	}
+	manyRet, err := conn.InvokeOneMany(ctx, "/Exec.Jobs/Signal", in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// A goroutine to retrive untyped responses and convert them to typed ones.
+	go func() {
+		for {
+			typedResp := &SignalManyResponse{
+				Resp: &SignalJobResponse{},
+			}
+
+			resp, ok := <-manyRet
+			if !ok {
+				// All done so we can shut down.
+				close(ret)
+				return
+			}
+			typedResp.Target = resp.Target
+			typedResp.Index = resp.Index
+			typedResp.Error = resp.Error
+			if resp.Error == nil {
+				if err := resp.Resp.UnmarshalTo(typedResp.Resp); err != nil {
+					typedResp.Error = fmt.Errorf("can't decode any response - %v. Original Error - %v", err, resp.Error)
+				}
+			}
+			ret <- typedResp
+		}
+	}()
+
+	return ret, nil
+}
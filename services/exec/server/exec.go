@@ -18,18 +18,22 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"io"
 	"os"
 	"os/exec"
 	"os/user"
-	"path/filepath"
 	"strconv"
-	"syscall"
+	"strings"
+	"sync"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	"github.com/Snowflake-Labs/sansshell/services"
 	pb "github.com/Snowflake-Labs/sansshell/services/exec"
@@ -43,6 +47,18 @@ var (
 		Description: "number of failures when performing exec.Run"}
 )
 
+var (
+	// EnvAllowList is the environment variables a request may set for its
+	// command. A name ending in '*' allows any variable starting with the
+	// rest of it.
+	EnvAllowList = []string{"LANG", "LC_*", "TZ", "TERM"}
+
+	// DefaultKillGrace is how long a command which reached its timeout has
+	// to exit after SIGTERM before it's sent SIGKILL, if the request doesn't
+	// say.
+	DefaultKillGrace = 10 * time.Second
+)
+
 // server is used to implement the gRPC server
 type server struct{}
 
@@ -50,14 +66,9 @@ type server struct{}
 func (s *server) Run(ctx context.Context, req *pb.ExecRequest) (res *pb.ExecResponse, err error) {
 	recorder := metrics.RecorderFromContextOrNoop(ctx)
 
-	var opts []util.Option
-	if req.User != "" {
-		uid, gid, err := resolveUser(req.User)
-		if err != nil {
-			return nil, err
-		}
-		opts = append(opts, util.CommandUser(uint32(uid)))
-		opts = append(opts, util.CommandGroup(uint32(gid)))
+	opts, err := commandOptions(req)
+	if err != nil {
+		return nil, err
 	}
 	run, err := util.RunCommand(ctx, req.Command, req.Args, opts...)
 	if err != nil {
@@ -69,7 +80,7 @@ func (s *server) Run(ctx context.Context, req *pb.ExecRequest) (res *pb.ExecResp
 		recorder.CounterOrLog(ctx, execRunFailureCounter, 1)
 		return nil, run.Error
 	}
-	return &pb.ExecResponse{Stderr: run.Stderr.Bytes(), Stdout: run.Stdout.Bytes(), RetCode: int32(run.ExitCode)}, nil
+	return &pb.ExecResponse{Stderr: run.Stderr.Bytes(), Stdout: run.Stdout.Bytes(), RetCode: int32(run.ExitCode), TimedOut: run.TimedOut, Usage: usageProto(run.Usage)}, nil
 }
 
 // StreamingRun executes command and returns a stream of results
@@ -77,83 +88,232 @@ func (s *server) StreamingRun(req *pb.ExecRequest, stream pb.Exec_StreamingRunSe
 	ctx := stream.Context()
 	recorder := metrics.RecorderFromContextOrNoop(ctx)
 
-	// We can't use util.RunCommand because it runs the command synchronously, so we
-	// need to do input validation normally performed by it.
-	if !filepath.IsAbs(req.Command) {
-		return status.Errorf(codes.InvalidArgument, "%s is not an absolute path", req.Command)
+	cmd, err := startStreaming(ctx, req, stream)
+	if err != nil {
+		recorder.CounterOrLog(ctx, execRunFailureCounter, 1)
+		return err
 	}
-	if req.Command != filepath.Clean(req.Command) {
-		return status.Errorf(codes.InvalidArgument, "%s is not a clean path", req.Command)
+	resp, err := exitResponse(cmd, cmd.Wait())
+	if err != nil {
+		recorder.CounterOrLog(ctx, execRunFailureCounter, 1)
+		return err
+	}
+	if resp != nil {
+		return stream.Send(resp)
 	}
+	return nil
+}
 
-	cmd := exec.CommandContext(ctx, req.Command, req.Args...)
-	if req.User != "" {
-		uid, gid, err := resolveUser(req.User)
-		if err != nil {
-			return err
-		}
+// StreamingRunWithInput is StreamingRun with stdin streamed from the client.
+func (s *server) StreamingRunWithInput(stream pb.Exec_StreamingRunWithInputServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	recorder := metrics.RecorderFromContextOrNoop(ctx)
 
-		// Set uid/gid if needed for the sub-process to run under.
-		// Only do this if it's different than our current ones since
-		// attempting to setuid/gid() to even your current values is EPERM.
-		if uid != uint32(os.Geteuid()) || gid != uint32(os.Getgid()) {
-			cmd.SysProcAttr = &syscall.SysProcAttr{
-				Credential: &syscall.Credential{
-					Uid: uid,
-					Gid: gid,
-				},
-			}
-		}
+	in, err := stream.Recv()
+	if err == io.EOF {
+		return status.Error(codes.InvalidArgument, "no request sent")
 	}
-	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return err
 	}
-	stderr, err := cmd.StderrPipe()
+	req := in.GetRequest()
+	if req == nil {
+		return status.Error(codes.InvalidArgument, "the first message must contain the request")
+	}
+
+	// Use a pipe rather than an io.Reader so that Wait doesn't block on
+	// copying stdin if the command exits before the client is done.
+	stdin, stdinWriter, err := os.Pipe()
 	if err != nil {
-		return err
+		return status.Errorf(codes.Internal, "can't create stdin pipe: %v", err)
 	}
-	cmd.Env = []string{}
-	if err := cmd.Start(); err != nil {
+	cmd, err := startStreaming(ctx, req, stream, util.CommandStdin(stdin))
+	stdin.Close()
+	if err != nil {
+		stdinWriter.Close()
+		recorder.CounterOrLog(ctx, execRunFailureCounter, 1)
 		return err
 	}
 
-	// Send stderr asynchronously
+	inputErr := make(chan error, 1)
 	go func() {
+		defer stdinWriter.Close()
+		if _, err := stdinWriter.Write(req.Stdin); err != nil {
+			return
+		}
 		for {
-			buf := make([]byte, 1024)
-			n, err := stderr.Read(buf)
+			in, err := stream.Recv()
 			if err != nil {
+				// EOF is the client closing stdin. Anything else means
+				// the stream is done and the command will be killed.
+				return
+			}
+			if in.GetRequest() != nil {
+				inputErr <- status.Error(codes.InvalidArgument, "only the first message may contain a request")
+				cancel()
 				return
 			}
-			if err := stream.Send(&pb.ExecResponse{Stderr: buf[:n]}); err != nil {
-				recorder.CounterOrLog(ctx, execRunFailureCounter, 1)
+			if _, err := stdinWriter.Write(in.GetStdin()); err != nil {
+				// The command closed stdin, so the rest is discarded.
 				return
 			}
 		}
 	}()
 
-	// Send stdout synchronously
-	for {
-		buf := make([]byte, 1024)
-		n, err := stdout.Read(buf)
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			recorder.CounterOrLog(ctx, execRunFailureCounter, 1)
-			return err
+	resp, err := exitResponse(cmd, cmd.Wait())
+	select {
+	case err := <-inputErr:
+		return err
+	default:
+	}
+	if err != nil {
+		recorder.CounterOrLog(ctx, execRunFailureCounter, 1)
+		return err
+	}
+	if resp != nil {
+		return stream.Send(resp)
+	}
+	return nil
+}
+
+// responseSender is the part of the Exec server streams used to send
+// output.
+type responseSender interface {
+	Send(*pb.ExecResponse) error
+}
+
+// streamWriter sends everything written to it on a stream as either stdout
+// or stderr. Writers for a stream share mu so that sends don't overlap.
+type streamWriter struct {
+	mu     *sync.Mutex
+	stream responseSender
+	stderr bool
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for n := 0; n < len(p); {
+		chunk := p[n:min(len(p), n+util.StreamingChunkSize)]
+		resp := &pb.ExecResponse{Stdout: chunk}
+		if w.stderr {
+			resp = &pb.ExecResponse{Stderr: chunk}
+		}
+		if err := w.stream.Send(resp); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+	}
+	return len(p), nil
+}
+
+// startStreaming starts the command in req with its output sent on stream.
+// Any opts are applied after those from the request.
+func startStreaming(ctx context.Context, req *pb.ExecRequest, stream responseSender, opts ...util.Option) (*util.Command, error) {
+	reqOpts, err := commandOptions(req)
+	if err != nil {
+		return nil, err
+	}
+	mu := &sync.Mutex{}
+	stdout := &streamWriter{mu: mu, stream: stream}
+	stderr := &streamWriter{mu: mu, stream: stream, stderr: true}
+	return util.StartCommand(ctx, req.Command, req.Args, stdout, stderr, append(reqOpts, opts...)...)
+}
+
+// exitResponse turns the result of waiting for a streamed command into the
+// final response to send, if any. Exiting non-zero or reaching the timeout
+// isn't an error, as both are reported in the response.
+func exitResponse(cmd *util.Command, err error) (*pb.ExecResponse, error) {
+	timedOut := cmd.TimedOut()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) && !timedOut {
+		return nil, err
+	}
+	resp := &pb.ExecResponse{RetCode: int32(cmd.ProcessState.ExitCode()), TimedOut: timedOut, Usage: usageProto(cmd.Usage())}
+	if resp.RetCode == 0 && !resp.TimedOut && resp.Usage == nil {
+		// Nothing to report.
+		return nil, nil
+	}
+	return resp, nil
+}
+
+// usageProto converts u for a response.
+func usageProto(u *util.ResourceUsage) *pb.ResourceUsage {
+	if u == nil {
+		return nil
+	}
+	return &pb.ResourceUsage{PeakMemoryBytes: u.PeakMemoryBytes, CpuTime: durationpb.New(u.CPUTime)}
+}
+
+// commandOptions returns the util.RunCommand options needed to run req.
+func commandOptions(req *pb.ExecRequest) ([]util.Option, error) {
+	var opts []util.Option
+	if req.User != "" {
+		uid, gid, err := resolveUser(req.User)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, util.CommandUser(uint32(uid)))
+		opts = append(opts, util.CommandGroup(uint32(gid)))
+	}
+	for _, e := range req.Env {
+		name, _, ok := strings.Cut(e, "=")
+		if !ok || name == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "environment variable %q must be of the form NAME=value", e)
+		}
+		if !envAllowed(name) {
+			return nil, status.Errorf(codes.PermissionDenied, "environment variable %s is not allowed", name)
 		}
-		if err := stream.Send(&pb.ExecResponse{Stdout: buf[:n]}); err != nil {
-			return err
+		opts = append(opts, util.EnvVar(e))
+	}
+	if req.Cwd != "" {
+		if err := util.ValidPath(req.Cwd); err != nil {
+			return nil, err
 		}
+		opts = append(opts, util.CommandDir(req.Cwd))
+	}
+	if len(req.Stdin) > 0 {
+		opts = append(opts, util.CommandStdin(bytes.NewReader(req.Stdin)))
 	}
+	if req.Timeout != nil {
+		if err := req.Timeout.CheckValid(); err != nil || req.Timeout.AsDuration() <= 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid timeout %v", req.Timeout)
+		}
+		killGrace := DefaultKillGrace
+		if req.KillGrace != nil {
+			if err := req.KillGrace.CheckValid(); err != nil || req.KillGrace.AsDuration() < 0 {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid kill_grace %v", req.KillGrace)
+			}
+			killGrace = req.KillGrace.AsDuration()
+		}
+		opts = append(opts, util.CommandTimeout(req.Timeout.AsDuration(), killGrace))
+	}
+	if l := req.Limits; l != nil {
+		opts = append(opts, util.CommandLimits(util.ResourceLimits{
+			CPUMillis:   l.CpuMillis,
+			MemoryBytes: l.MemoryBytes,
+			Pids:        l.Pids,
+			IOReadBPS:   l.IoReadBps,
+			IOWriteBPS:  l.IoWriteBps,
+		}))
+	}
+	return opts, nil
+}
 
-	// If we've gotten here, stdout has been closed and the command is over
-	err = cmd.Wait()
-	if exitErr, ok := err.(*exec.ExitError); ok {
-		return stream.Send(&pb.ExecResponse{RetCode: int32(exitErr.ExitCode())})
+// envAllowed returns true if EnvAllowList allows setting the environment
+// variable name.
+func envAllowed(name string) bool {
+	for _, allowed := range EnvAllowList {
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == allowed {
+			return true
+		}
 	}
-	return err
+	return false
 }
 
 // resolveUser retruns uid and gid of provided username.
@@ -181,4 +341,5 @@ func (s *server) Register(gs *grpc.Server) {
 
 func init() {
 	services.RegisterSansShellService(&server{})
+	services.RegisterSansShellService(newJobsServer())
 }
@@ -23,10 +23,14 @@ import (
 	"net"
 	"os"
 	"testing"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	pb "github.com/Snowflake-Labs/sansshell/services/exec"
 	"github.com/Snowflake-Labs/sansshell/testing/testutil"
@@ -47,6 +51,13 @@ func TestMain(m *testing.M) {
 	s := grpc.NewServer()
 	lfs := &server{}
 	lfs.Register(s)
+	dir, err := os.MkdirTemp("", "jobs")
+	if err != nil {
+		log.Fatalf("can't create jobs dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	JobsDir = dir
+	newJobsServer().Register(s)
 	go func() {
 		if err := s.Serve(lis); err != nil {
 			log.Fatalf("Server exited with error: %v", err)
@@ -57,7 +68,9 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
-func collect(c pb.Exec_StreamingRunClient) (*pb.ExecResponse, error) {
+func collect(c interface {
+	Recv() (*pb.ExecResponse, error)
+}) (*pb.ExecResponse, error) {
 	collected := &pb.ExecResponse{}
 	for {
 		resp, err := c.Recv()
@@ -70,6 +83,7 @@ func collect(c pb.Exec_StreamingRunClient) (*pb.ExecResponse, error) {
 		collected.Stdout = append(collected.Stdout, resp.Stdout...)
 		collected.Stderr = append(collected.Stderr, resp.Stderr...)
 		collected.RetCode = resp.RetCode
+		collected.TimedOut = resp.TimedOut
 	}
 }
 
@@ -167,3 +181,162 @@ func TestExec(t *testing.T) {
 		})
 	}
 }
+
+func TestExecOptions(t *testing.T) {
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	testutil.FatalOnErr("Failed to dial bufnet", err, t)
+	t.Cleanup(func() { conn.Close() })
+	client := pb.NewExecClient(conn)
+
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name         string
+		req          *pb.ExecRequest
+		wantCode     codes.Code
+		stdout       string
+		wantTimedOut bool
+	}{
+		{
+			name:   "stdin",
+			req:    &pb.ExecRequest{Command: testutil.ResolvePath(t, "cat"), Stdin: []byte("hello")},
+			stdout: "hello",
+		},
+		{
+			name: "allowed env",
+			req: &pb.ExecRequest{
+				Command: testutil.ResolvePath(t, "env"),
+				Env:     []string{"LANG=C", "LC_ALL=C"},
+			},
+			stdout: "LANG=C\nLC_ALL=C\n",
+		},
+		{
+			name: "env not on allow-list",
+			req: &pb.ExecRequest{
+				Command: testutil.ResolvePath(t, "env"),
+				Env:     []string{"LD_PRELOAD=/tmp/evil.so"},
+			},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name: "malformed env",
+			req: &pb.ExecRequest{
+				Command: testutil.ResolvePath(t, "env"),
+				Env:     []string{"LANG"},
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:   "cwd",
+			req:    &pb.ExecRequest{Command: testutil.ResolvePath(t, "pwd"), Cwd: dir},
+			stdout: dir + "\n",
+		},
+		{
+			name:     "relative cwd",
+			req:      &pb.ExecRequest{Command: testutil.ResolvePath(t, "pwd"), Cwd: "tmp"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "timeout",
+			req: &pb.ExecRequest{
+				Command:   testutil.ResolvePath(t, "sleep"),
+				Args:      []string{"60"},
+				Timeout:   durationpb.New(100 * time.Millisecond),
+				KillGrace: durationpb.New(time.Second),
+			},
+			wantTimedOut: true,
+		},
+		{
+			name: "negative timeout",
+			req: &pb.ExecRequest{
+				Command: testutil.ResolvePath(t, "true"),
+				Timeout: durationpb.New(-time.Second),
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "invalid limits",
+			req: &pb.ExecRequest{
+				Command: testutil.ResolvePath(t, "true"),
+				Limits:  &pb.ResourceLimits{MemoryBytes: -1},
+			},
+			wantCode: codes.InvalidArgument,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			check := func(kind string, resp *pb.ExecResponse, err error) {
+				t.Helper()
+				if got := status.Code(err); got != tc.wantCode {
+					t.Fatalf("%s: got code %v (%v), want %v", kind, got, err, tc.wantCode)
+				}
+				if err != nil {
+					return
+				}
+				if got, want := string(resp.Stdout), tc.stdout; got != want {
+					t.Fatalf("%s: stdout doesn't match. Want %q Got %q", kind, want, got)
+				}
+				if got, want := resp.TimedOut, tc.wantTimedOut; got != want {
+					t.Fatalf("%s: TimedOut = %v, want %v", kind, got, want)
+				}
+			}
+			resp, err := client.Run(ctx, tc.req)
+			check("Run", resp, err)
+
+			stream, err := client.StreamingRun(ctx, tc.req)
+			testutil.FatalOnErr("StreamingRun", err, t)
+			resp, err = collect(stream)
+			check("StreamingRun", resp, err)
+		})
+	}
+}
+
+func TestStreamingRunWithInput(t *testing.T) {
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	testutil.FatalOnErr("Failed to dial bufnet", err, t)
+	t.Cleanup(func() { conn.Close() })
+	client := pb.NewExecClient(conn)
+
+	stream, err := client.StreamingRunWithInput(ctx)
+	testutil.FatalOnErr("StreamingRunWithInput", err, t)
+	err = stream.Send(&pb.ExecInput{Input: &pb.ExecInput_Request{Request: &pb.ExecRequest{
+		Command: testutil.ResolvePath(t, "sh"),
+		Args:    []string{"-c", "cat; exit 3"},
+		Stdin:   []byte("one "),
+	}}})
+	testutil.FatalOnErr("Send request", err, t)
+	for _, in := range []string{"two ", "three"} {
+		err := stream.Send(&pb.ExecInput{Input: &pb.ExecInput_Stdin{Stdin: []byte(in)}})
+		testutil.FatalOnErr("Send stdin", err, t)
+	}
+	testutil.FatalOnErr("CloseSend", stream.CloseSend(), t)
+	resp, err := collect(stream)
+	testutil.FatalOnErr("collect", err, t)
+	if got, want := string(resp.Stdout), "one two three"; got != want {
+		t.Fatalf("stdout doesn't match. Want %q Got %q", want, got)
+	}
+	if got, want := resp.RetCode, int32(3); got != want {
+		t.Fatalf("RetCode = %d, want %d", got, want)
+	}
+
+	// Only the first message may be a request.
+	stream, err = client.StreamingRunWithInput(ctx)
+	testutil.FatalOnErr("StreamingRunWithInput", err, t)
+	req := &pb.ExecInput{Input: &pb.ExecInput_Request{Request: &pb.ExecRequest{Command: testutil.ResolvePath(t, "cat")}}}
+	testutil.FatalOnErr("Send request", stream.Send(req), t)
+	testutil.FatalOnErr("Send request", stream.Send(req), t)
+	_, err = collect(stream)
+	if got, want := status.Code(err), codes.InvalidArgument; got != want {
+		t.Fatalf("second request: got code %v (%v), want %v", got, err, want)
+	}
+
+	// As must the first.
+	stream, err = client.StreamingRunWithInput(ctx)
+	testutil.FatalOnErr("StreamingRunWithInput", err, t)
+	testutil.FatalOnErr("Send stdin", stream.Send(&pb.ExecInput{Input: &pb.ExecInput_Stdin{Stdin: []byte("x")}}), t)
+	_, err = collect(stream)
+	if got, want := status.Code(err), codes.InvalidArgument; got != want {
+		t.Fatalf("stdin first: got code %v (%v), want %v", got, err, want)
+	}
+}
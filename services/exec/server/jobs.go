@@ -0,0 +1,428 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/Snowflake-Labs/sansshell/services/exec"
+	"github.com/Snowflake-Labs/sansshell/services/util"
+	"github.com/Snowflake-Labs/sansshell/telemetry/metrics"
+)
+
+var (
+	// JobsDir is where the output of background jobs is spooled. Each job
+	// gets its own directory beneath it.
+	JobsDir = filepath.Join(os.TempDir(), "sansshell-jobs")
+
+	// JobOutputMax is the number of bytes each of a job's stdout and stderr
+	// may spool before further output is discarded.
+	JobOutputMax int64 = 100 * 1024 * 1024
+
+	// JobRetention is how long a job, and its output, is kept after it
+	// exits.
+	JobRetention = 24 * time.Hour
+
+	// MaxRunningJobs is the number of jobs which may run at once.
+	MaxRunningJobs = 100
+)
+
+// Metrics
+var (
+	jobStartFailureCounter = metrics.MetricDefinition{Name: "actions_exec_job_start_failure",
+		Description: "number of failures when starting a background job"}
+)
+
+var jobIDRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// spool is a size limited file holding one of a job's output streams.
+type spool struct {
+	f       *os.File
+	max     int64
+	changed func()
+
+	mu        sync.Mutex
+	size      int64
+	truncated bool
+}
+
+// Write implements io.Writer. Output past the size limit is dropped
+// without error so the job isn't disturbed.
+func (s *spool) Write(p []byte) (int, error) {
+	n := len(p)
+	s.mu.Lock()
+	if room := s.max - s.size; int64(len(p)) > room {
+		p = p[:max(room, 0)]
+		s.truncated = true
+	}
+	var err error
+	if len(p) > 0 {
+		var w int
+		w, err = s.f.WriteAt(p, s.size)
+		s.size += int64(w)
+	}
+	s.mu.Unlock()
+	s.changed()
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// state returns the amount spooled and whether any output was dropped.
+func (s *spool) state() (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size, s.truncated
+}
+
+// read returns up to util.StreamingChunkSize bytes starting at off, not
+// going beyond end.
+func (s *spool) read(off, end int64) ([]byte, error) {
+	n := min(end-off, int64(util.StreamingChunkSize))
+	if n <= 0 {
+		return nil, nil
+	}
+	buf := make([]byte, n)
+	got, err := s.f.ReadAt(buf, off)
+	if err != nil && got < len(buf) {
+		return nil, status.Errorf(codes.Internal, "can't read job output: %v", err)
+	}
+	return buf, nil
+}
+
+// job is a command running, or which has run, in the background.
+type job struct {
+	id             string
+	req            *pb.ExecRequest
+	dir            string
+	stdout, stderr *spool
+
+	mu sync.Mutex
+	// cmd is nil, and start zero, until the job's process has started.
+	cmd      *util.Command
+	start    time.Time
+	state    pb.JobState
+	end      time.Time
+	retCode  int32
+	signal   int32
+	timedOut bool
+	usage    *pb.ResourceUsage
+	err      string
+	// changed is closed, and replaced, whenever output is written or the
+	// job exits.
+	changed chan struct{}
+}
+
+// notify wakes up anyone waiting for the job to change.
+func (j *job) notify() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// status returns the job's current status, along with a channel which is
+// closed when it next changes.
+func (j *job) status() (*pb.JobStatus, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	st := &pb.JobStatus{
+		Id:       j.id,
+		Request:  j.req,
+		State:    j.state,
+		RetCode:  j.retCode,
+		Signal:   j.signal,
+		Error:    j.err,
+		TimedOut: j.timedOut,
+		Usage:    j.usage,
+	}
+	if !j.start.IsZero() {
+		st.StartTime = timestamppb.New(j.start)
+	}
+	if !j.end.IsZero() {
+		st.EndTime = timestamppb.New(j.end)
+	}
+	st.StdoutSize, st.StdoutTruncated = j.stdout.state()
+	st.StderrSize, st.StderrTruncated = j.stderr.state()
+	return st, j.changed
+}
+
+// wait reaps the job's process and records how it exited.
+func (j *job) wait(ctx context.Context) {
+	err := j.cmd.Wait()
+	j.mu.Lock()
+	j.end = time.Now()
+	j.state = pb.JobState_JOB_STATE_EXITED
+	j.retCode = int32(j.cmd.ProcessState.ExitCode())
+	if ws, ok := j.cmd.ProcessState.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		j.signal = int32(ws.Signal())
+	}
+	j.timedOut = j.cmd.TimedOut()
+	j.usage = usageProto(j.cmd.Usage())
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) && !j.timedOut {
+		j.state = pb.JobState_JOB_STATE_FAILED
+		j.err = err.Error()
+	}
+	j.mu.Unlock()
+	logr.FromContextOrDiscard(ctx).Info("background job exited", "id", j.id, "code", j.retCode, "signal", j.signal, "err", err)
+	j.notify()
+}
+
+// close releases the job's files and removes its output.
+func (j *job) close() {
+	j.stdout.f.Close()
+	j.stderr.f.Close()
+	os.RemoveAll(j.dir)
+}
+
+// jobsServer implements the Jobs gRPC service.
+type jobsServer struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+func newJobsServer() *jobsServer {
+	return &jobsServer{jobs: make(map[string]*job)}
+}
+
+// lookup returns the job with the given ID.
+func (s *jobsServer) lookup(id string) (*job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[id]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no job %q", id)
+	}
+	return j, nil
+}
+
+// reserve claims id for a new job, checking it's free and that not too
+// many jobs are running.
+func (s *jobsServer) reserve(j *job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[j.id]; ok {
+		return status.Errorf(codes.AlreadyExists, "job %q already exists", j.id)
+	}
+	running := 0
+	for _, other := range s.jobs {
+		other.mu.Lock()
+		if other.state == pb.JobState_JOB_STATE_RUNNING {
+			running++
+		}
+		other.mu.Unlock()
+	}
+	if running >= MaxRunningJobs {
+		return status.Errorf(codes.ResourceExhausted, "%d jobs are already running", running)
+	}
+	s.jobs[j.id] = j
+	return nil
+}
+
+// remove forgets a job and deletes its output.
+func (s *jobsServer) remove(j *job) {
+	s.mu.Lock()
+	if s.jobs[j.id] == j {
+		delete(s.jobs, j.id)
+	}
+	s.mu.Unlock()
+	j.close()
+}
+
+// newJobID returns a random job ID.
+func newJobID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", status.Errorf(codes.Internal, "can't generate job ID: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Start implements JobsServer.
+func (s *jobsServer) Start(ctx context.Context, req *pb.StartJobRequest) (_ *pb.StartJobResponse, err error) {
+	recorder := metrics.RecorderFromContextOrNoop(ctx)
+	defer func() {
+		if err != nil {
+			recorder.CounterOrLog(ctx, jobStartFailureCounter, 1)
+		}
+	}()
+
+	if req.Request == nil {
+		return nil, status.Error(codes.InvalidArgument, "a request must be given")
+	}
+	id := req.Id
+	if id == "" {
+		if id, err = newJobID(); err != nil {
+			return nil, err
+		}
+	}
+	if !jobIDRegexp.MatchString(id) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid job ID %q", id)
+	}
+	opts, err := commandOptions(req.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &job{
+		id:      id,
+		req:     req.Request,
+		dir:     filepath.Join(JobsDir, id),
+		state:   pb.JobState_JOB_STATE_RUNNING,
+		changed: make(chan struct{}),
+	}
+	j.stdout = &spool{max: JobOutputMax, changed: j.notify}
+	j.stderr = &spool{max: JobOutputMax, changed: j.notify}
+	if err := s.reserve(j); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			s.remove(j)
+		}
+	}()
+
+	// Anything left over from an earlier server with the same ID is stale.
+	if err := os.RemoveAll(j.dir); err != nil {
+		return nil, status.Errorf(codes.Internal, "can't clean up job directory: %v", err)
+	}
+	if err := os.MkdirAll(j.dir, 0700); err != nil {
+		return nil, status.Errorf(codes.Internal, "can't create job directory: %v", err)
+	}
+	if j.stdout.f, err = os.OpenFile(filepath.Join(j.dir, "stdout"), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600); err != nil {
+		return nil, status.Errorf(codes.Internal, "can't create job output: %v", err)
+	}
+	if j.stderr.f, err = os.OpenFile(filepath.Join(j.dir, "stderr"), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600); err != nil {
+		return nil, status.Errorf(codes.Internal, "can't create job output: %v", err)
+	}
+
+	// The job must outlive this RPC so it can't use its context.
+	jobCtx := logr.NewContext(context.Background(), logr.FromContextOrDiscard(ctx).WithValues("job", id))
+	start := time.Now()
+	cmd, err := util.StartCommand(jobCtx, req.Request.Command, req.Request.Args, j.stdout, j.stderr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	j.mu.Lock()
+	j.cmd, j.start = cmd, start
+	j.mu.Unlock()
+	go func() {
+		j.wait(jobCtx)
+		time.AfterFunc(JobRetention, func() { s.remove(j) })
+	}()
+	return &pb.StartJobResponse{Id: id}, nil
+}
+
+// Status implements JobsServer.
+func (s *jobsServer) Status(ctx context.Context, req *pb.JobStatusRequest) (*pb.JobStatus, error) {
+	j, err := s.lookup(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	st, _ := j.status()
+	return st, nil
+}
+
+// Attach implements JobsServer.
+func (s *jobsServer) Attach(req *pb.AttachJobRequest, stream pb.Jobs_AttachServer) error {
+	ctx := stream.Context()
+	if req.StdoutOffset < 0 || req.StderrOffset < 0 {
+		return status.Error(codes.InvalidArgument, "offsets can't be negative")
+	}
+	j, err := s.lookup(req.Id)
+	if err != nil {
+		return err
+	}
+	outOff, errOff := req.StdoutOffset, req.StderrOffset
+	for {
+		st, changed := j.status()
+		for outOff < st.StdoutSize || errOff < st.StderrSize {
+			resp := &pb.AttachJobResponse{StdoutOffset: outOff, StderrOffset: errOff}
+			if resp.Stdout, err = j.stdout.read(outOff, st.StdoutSize); err != nil {
+				return err
+			}
+			if resp.Stderr, err = j.stderr.read(errOff, st.StderrSize); err != nil {
+				return err
+			}
+			outOff += int64(len(resp.Stdout))
+			errOff += int64(len(resp.Stderr))
+			if err := stream.Send(resp); err != nil {
+				return err
+			}
+		}
+		// Output is complete once the job has exited, as its process
+		// has been reaped and the output copied by then.
+		if !req.Follow || st.State != pb.JobState_JOB_STATE_RUNNING {
+			return stream.Send(&pb.AttachJobResponse{StdoutOffset: outOff, StderrOffset: errOff, Status: st})
+		}
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-changed:
+		}
+	}
+}
+
+// Signal implements JobsServer.
+func (s *jobsServer) Signal(ctx context.Context, req *pb.SignalJobRequest) (*pb.SignalJobResponse, error) {
+	sig := syscall.SIGTERM
+	if req.Signal != 0 {
+		sig = syscall.Signal(req.Signal)
+	}
+	if sig < 1 || sig > 64 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid signal %d", req.Signal)
+	}
+	j, err := s.lookup(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.state != pb.JobState_JOB_STATE_RUNNING {
+		return nil, status.Errorf(codes.FailedPrecondition, "job %q isn't running", req.Id)
+	}
+	if j.cmd == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "job %q is still starting", req.Id)
+	}
+	if err := j.cmd.Process.Signal(sig); err != nil {
+		return nil, status.Errorf(codes.Internal, "can't signal job %q: %v", req.Id, err)
+	}
+	return &pb.SignalJobResponse{}, nil
+}
+
+// Register is called to expose this handler to the gRPC server
+func (s *jobsServer) Register(gs *grpc.Server) {
+	pb.RegisterJobsServer(gs, s)
+}
@@ -0,0 +1,207 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"io"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Snowflake-Labs/sansshell/services/exec"
+	"github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+func dialJobs(t *testing.T) pb.JobsClient {
+	t.Helper()
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	testutil.FatalOnErr("Failed to dial bufnet", err, t)
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewJobsClient(conn)
+}
+
+// attach collects a job's output and final status.
+func attach(t *testing.T, client pb.JobsClient, req *pb.AttachJobRequest) (string, string, *pb.JobStatus) {
+	t.Helper()
+	stream, err := client.Attach(context.Background(), req)
+	testutil.FatalOnErr("Attach", err, t)
+	var stdout, stderr []byte
+	var st *pb.JobStatus
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		testutil.FatalOnErr("Recv", err, t)
+		if got, want := resp.StdoutOffset, req.StdoutOffset+int64(len(stdout)); got != want {
+			t.Errorf("stdout offset = %d, want %d", got, want)
+		}
+		stdout = append(stdout, resp.Stdout...)
+		stderr = append(stderr, resp.Stderr...)
+		if resp.Status != nil {
+			st = resp.Status
+		}
+	}
+	if st == nil {
+		t.Fatal("no status in final response")
+	}
+	return string(stdout), string(stderr), st
+}
+
+func TestJobs(t *testing.T) {
+	ctx := context.Background()
+	client := dialJobs(t)
+	sh := testutil.ResolvePath(t, "sh")
+
+	start, err := client.Start(ctx, &pb.StartJobRequest{
+		Id:      "basic",
+		Request: &pb.ExecRequest{Command: sh, Args: []string{"-c", "echo hello; sleep 0.2; echo world; echo oops >&2; exit 3"}},
+	})
+	testutil.FatalOnErr("Start", err, t)
+	if start.Id != "basic" {
+		t.Errorf("id = %q, want basic", start.Id)
+	}
+
+	stdout, stderr, st := attach(t, client, &pb.AttachJobRequest{Id: "basic", Follow: true})
+	if stdout != "hello\nworld\n" || stderr != "oops\n" {
+		t.Errorf("output = %q, %q", stdout, stderr)
+	}
+	if st.State != pb.JobState_JOB_STATE_EXITED || st.RetCode != 3 || st.EndTime == nil {
+		t.Errorf("status = %v, want exited with code 3", st)
+	}
+
+	// Re-attaching from an offset only returns the rest.
+	stdout, _, _ = attach(t, client, &pb.AttachJobRequest{Id: "basic", StdoutOffset: 6, StderrOffset: 5})
+	if stdout != "world\n" {
+		t.Errorf("stdout from offset = %q, want world", stdout)
+	}
+
+	if _, err := client.Start(ctx, &pb.StartJobRequest{Id: "basic", Request: &pb.ExecRequest{Command: sh}}); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("duplicate ID: err = %v, want AlreadyExists", err)
+	}
+	if _, err := client.Start(ctx, &pb.StartJobRequest{Id: "../etc", Request: &pb.ExecRequest{Command: sh}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("bad ID: err = %v, want InvalidArgument", err)
+	}
+	if _, err := client.Start(ctx, &pb.StartJobRequest{Request: &pb.ExecRequest{Command: "sh"}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("relative command: err = %v, want InvalidArgument", err)
+	}
+	if _, err := client.Status(ctx, &pb.JobStatusRequest{Id: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("missing job: err = %v, want NotFound", err)
+	}
+	if _, err := client.Signal(ctx, &pb.SignalJobRequest{Id: "basic"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("signal exited job: err = %v, want FailedPrecondition", err)
+	}
+}
+
+func TestJobsSignal(t *testing.T) {
+	ctx := context.Background()
+	client := dialJobs(t)
+
+	start, err := client.Start(ctx, &pb.StartJobRequest{Request: &pb.ExecRequest{Command: testutil.ResolvePath(t, "sleep"), Args: []string{"60"}}})
+	testutil.FatalOnErr("Start", err, t)
+	st, err := client.Status(ctx, &pb.JobStatusRequest{Id: start.Id})
+	testutil.FatalOnErr("Status", err, t)
+	if st.State != pb.JobState_JOB_STATE_RUNNING {
+		t.Fatalf("state = %v, want running", st.State)
+	}
+
+	_, err = client.Signal(ctx, &pb.SignalJobRequest{Id: start.Id})
+	testutil.FatalOnErr("Signal", err, t)
+	_, _, st = attach(t, client, &pb.AttachJobRequest{Id: start.Id, Follow: true})
+	if st.State != pb.JobState_JOB_STATE_EXITED || st.Signal != int32(syscall.SIGTERM) {
+		t.Errorf("status = %v, want killed by SIGTERM", st)
+	}
+}
+
+func TestJobsSignalWhileStarting(t *testing.T) {
+	ctx := context.Background()
+	client := dialJobs(t)
+	const id = "racing"
+
+	// Signal and Status a client-chosen ID while it's being started, which
+	// must never see a half set up job.
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				_, err := client.Signal(ctx, &pb.SignalJobRequest{Id: id, Signal: int32(syscall.SIGKILL)})
+				switch status.Code(err) {
+				case codes.OK, codes.NotFound, codes.FailedPrecondition:
+				default:
+					t.Errorf("Signal: %v", err)
+				}
+				if _, err := client.Status(ctx, &pb.JobStatusRequest{Id: id}); err != nil && status.Code(err) != codes.NotFound {
+					t.Errorf("Status: %v", err)
+				}
+			}
+		}()
+	}
+	_, err := client.Start(ctx, &pb.StartJobRequest{Id: id, Request: &pb.ExecRequest{Command: testutil.ResolvePath(t, "sleep"), Args: []string{"60"}}})
+	close(done)
+	wg.Wait()
+	testutil.FatalOnErr("Start", err, t)
+	client.Signal(ctx, &pb.SignalJobRequest{Id: id, Signal: int32(syscall.SIGKILL)})
+	if _, _, st := attach(t, client, &pb.AttachJobRequest{Id: id, Follow: true}); st.State != pb.JobState_JOB_STATE_EXITED {
+		t.Errorf("status = %v, want exited", st)
+	}
+}
+
+func TestJobsOutputLimit(t *testing.T) {
+	ctx := context.Background()
+	client := dialJobs(t)
+	old := JobOutputMax
+	JobOutputMax = 4
+	t.Cleanup(func() { JobOutputMax = old })
+
+	start, err := client.Start(ctx, &pb.StartJobRequest{Request: &pb.ExecRequest{Command: testutil.ResolvePath(t, "echo"), Args: []string{"truncated"}}})
+	testutil.FatalOnErr("Start", err, t)
+	stdout, _, st := attach(t, client, &pb.AttachJobRequest{Id: start.Id, Follow: true})
+	if stdout != "trun" || !st.StdoutTruncated || st.StdoutSize != 4 {
+		t.Errorf("stdout = %q, status = %v, want 4 bytes and truncated", stdout, st)
+	}
+	if st.RetCode != 0 {
+		t.Errorf("truncation disturbed the job: %v", st)
+	}
+
+	// Attaching with a deadline while the job runs returns when the
+	// deadline passes.
+	start, err = client.Start(ctx, &pb.StartJobRequest{Request: &pb.ExecRequest{Command: testutil.ResolvePath(t, "sleep"), Args: []string{"60"}}})
+	testutil.FatalOnErr("Start", err, t)
+	t.Cleanup(func() { client.Signal(ctx, &pb.SignalJobRequest{Id: start.Id, Signal: int32(syscall.SIGKILL)}) })
+	dctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	stream, err := client.Attach(dctx, &pb.AttachJobRequest{Id: start.Id, Follow: true})
+	testutil.FatalOnErr("Attach", err, t)
+	if _, err := stream.Recv(); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("attach err = %v, want DeadlineExceeded", err)
+	}
+}
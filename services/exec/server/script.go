@@ -0,0 +1,166 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"gocloud.dev/blob"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	pb "github.com/Snowflake-Labs/sansshell/services/exec"
+	"github.com/Snowflake-Labs/sansshell/telemetry/metrics"
+)
+
+// Metrics
+var (
+	execRunScriptFailureCounter = metrics.MetricDefinition{Name: "actions_exec_runscript_failure",
+		Description: "number of failures when performing exec.RunScript"}
+)
+
+var (
+	// ScriptDir is the directory RunScript creates a private directory in
+	// for each script. If empty, the default temporary directory is used.
+	// It must not be on a filesystem mounted noexec.
+	ScriptDir = ""
+
+	// ScriptMaxSize is the largest script RunScript accepts, in bytes.
+	ScriptMaxSize int64 = 16 << 20
+)
+
+// RunScript writes the script in req to a private directory, runs it and
+// streams back its output. The directory is always removed.
+func (s *server) RunScript(req *pb.RunScriptRequest, stream pb.Exec_RunScriptServer) error {
+	ctx := stream.Context()
+	logger := logr.FromContextOrDiscard(ctx)
+	recorder := metrics.RecorderFromContextOrNoop(ctx)
+
+	want, err := hex.DecodeString(req.Sha256)
+	if err != nil || len(want) != sha256.Size {
+		recorder.CounterOrLog(ctx, execRunScriptFailureCounter, 1, attribute.String("reason", "invalid_sha256"))
+		return status.Errorf(codes.InvalidArgument, "sha256 must be a hex encoded SHA-256, not %q", req.Sha256)
+	}
+	execReq := &pb.ExecRequest{}
+	if req.Request != nil {
+		execReq = proto.Clone(req.Request).(*pb.ExecRequest)
+	}
+	if execReq.Command != "" {
+		recorder.CounterOrLog(ctx, execRunScriptFailureCounter, 1, attribute.String("reason", "command_set"))
+		return status.Error(codes.InvalidArgument, "request.command must be empty as the script is run")
+	}
+	dir, err := os.MkdirTemp(ScriptDir, "sansshell-script-")
+	if err != nil {
+		recorder.CounterOrLog(ctx, execRunScriptFailureCounter, 1, attribute.String("reason", "mkdir_err"))
+		return status.Errorf(codes.Internal, "can't create script directory: %v", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			logger.Error(err, "can't remove script directory", "dir", dir)
+		}
+	}()
+	// The directory and script stay owned by us so that whoever runs the
+	// script can read and execute it but can't replace it after the
+	// SHA-256 check.
+	if err := os.Chmod(dir, 0711); err != nil {
+		recorder.CounterOrLog(ctx, execRunScriptFailureCounter, 1, attribute.String("reason", "chmod_err"))
+		return status.Errorf(codes.Internal, "can't chmod script directory: %v", err)
+	}
+	path := filepath.Join(dir, "script")
+	if err := writeScript(ctx, req, path, want); err != nil {
+		recorder.CounterOrLog(ctx, execRunScriptFailureCounter, 1, attribute.String("reason", "write_err"))
+		return err
+	}
+	logger.Info("running script", "sha256", req.Sha256)
+
+	execReq.Command = path
+	cmd, err := startStreaming(ctx, execReq, stream)
+	if err != nil {
+		recorder.CounterOrLog(ctx, execRunScriptFailureCounter, 1, attribute.String("reason", "start_err"))
+		return err
+	}
+	resp, err := exitResponse(cmd, cmd.Wait())
+	if err != nil {
+		recorder.CounterOrLog(ctx, execRunScriptFailureCounter, 1, attribute.String("reason", "wait_err"))
+		return err
+	}
+	if resp != nil {
+		return stream.Send(resp)
+	}
+	return nil
+}
+
+// writeScript writes the script in req to a new file at path, checks that its
+// SHA-256 is want and only then makes it executable.
+func writeScript(ctx context.Context, req *pb.RunScriptRequest, path string, want []byte) (retErr error) {
+	var src io.Reader
+	switch script := req.Script.(type) {
+	case *pb.RunScriptRequest_Content:
+		src = bytes.NewReader(script.Content)
+	case *pb.RunScriptRequest_Blob:
+		if script.Blob.GetBucket() == "" || script.Blob.GetKey() == "" {
+			return status.Error(codes.InvalidArgument, "blob bucket and key must be filled in")
+		}
+		b, err := blob.OpenBucket(ctx, script.Blob.Bucket)
+		if err != nil {
+			return status.Errorf(codes.Internal, "can't open bucket %s - %v", script.Blob.Bucket, err)
+		}
+		defer b.Close()
+		r, err := b.NewReader(ctx, script.Blob.Key, nil)
+		if err != nil {
+			return status.Errorf(codes.Internal, "can't open key %s in bucket %s - %v", script.Blob.Key, script.Blob.Bucket, err)
+		}
+		defer r.Close()
+		src = r
+	default:
+		return status.Error(codes.InvalidArgument, "script content or blob must be filled in")
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return status.Errorf(codes.Internal, "can't create script: %v", err)
+	}
+	defer func() {
+		if err := f.Close(); err != nil && retErr == nil {
+			retErr = status.Errorf(codes.Internal, "can't write script: %v", err)
+		}
+	}()
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(src, ScriptMaxSize+1))
+	if err != nil {
+		return status.Errorf(codes.Internal, "can't write script: %v", err)
+	}
+	if n > ScriptMaxSize {
+		return status.Errorf(codes.InvalidArgument, "script is larger than %d bytes", ScriptMaxSize)
+	}
+	if got := h.Sum(nil); !bytes.Equal(got, want) {
+		return status.Errorf(codes.InvalidArgument, "script has sha256 %s, not %s", hex.EncodeToString(got), hex.EncodeToString(want))
+	}
+	if err := f.Chmod(0755); err != nil {
+		return status.Errorf(codes.Internal, "can't chmod script: %v", err)
+	}
+	return nil
+}
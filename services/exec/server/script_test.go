@@ -0,0 +1,170 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "gocloud.dev/blob/fileblob"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/Snowflake-Labs/sansshell/services/exec"
+	"github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+func TestRunScript(t *testing.T) {
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	testutil.FatalOnErr("Failed to dial bufnet", err, t)
+	t.Cleanup(func() { conn.Close() })
+	client := pb.NewExecClient(conn)
+
+	scriptDir := t.TempDir()
+	savedDir := ScriptDir
+	ScriptDir = scriptDir
+	t.Cleanup(func() { ScriptDir = savedDir })
+
+	script := []byte("#!/bin/sh\necho \"$@\"\ncat\nexit 2\n")
+	sum := sha256.Sum256(script)
+	hash := hex.EncodeToString(sum[:])
+
+	bucketDir := t.TempDir()
+	err = os.WriteFile(filepath.Join(bucketDir, "script.sh"), script, 0644)
+	testutil.FatalOnErr("WriteFile", err, t)
+	bucket := "file://" + bucketDir
+
+	for _, tc := range []struct {
+		name     string
+		req      *pb.RunScriptRequest
+		wantCode codes.Code
+	}{
+		{
+			name: "inline",
+			req: &pb.RunScriptRequest{
+				Script:  &pb.RunScriptRequest_Content{Content: script},
+				Sha256:  hash,
+				Request: &pb.ExecRequest{Args: []string{"a", "b"}, Stdin: []byte("input")},
+			},
+		},
+		{
+			name: "blob",
+			req: &pb.RunScriptRequest{
+				Script:  &pb.RunScriptRequest_Blob{Blob: &pb.ScriptBlob{Bucket: bucket, Key: "script.sh"}},
+				Sha256:  hash,
+				Request: &pb.ExecRequest{Args: []string{"a", "b"}, Stdin: []byte("input")},
+			},
+		},
+		{
+			name: "wrong sha256",
+			req: &pb.RunScriptRequest{
+				Script: &pb.RunScriptRequest_Content{Content: []byte("#!/bin/sh\nrm -rf /\n")},
+				Sha256: hash,
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "missing sha256",
+			req: &pb.RunScriptRequest{
+				Script: &pb.RunScriptRequest_Content{Content: script},
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "missing script",
+			req: &pb.RunScriptRequest{
+				Sha256: hash,
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "missing blob key",
+			req: &pb.RunScriptRequest{
+				Script: &pb.RunScriptRequest_Blob{Blob: &pb.ScriptBlob{Bucket: bucket}},
+				Sha256: hash,
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "command set",
+			req: &pb.RunScriptRequest{
+				Script:  &pb.RunScriptRequest_Content{Content: script},
+				Sha256:  hash,
+				Request: &pb.ExecRequest{Command: testutil.ResolvePath(t, "true")},
+			},
+			wantCode: codes.InvalidArgument,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			stream, err := client.RunScript(ctx, tc.req)
+			testutil.FatalOnErr("RunScript", err, t)
+			resp, err := collect(stream)
+			if got := status.Code(err); got != tc.wantCode {
+				t.Fatalf("got code %v (%v), want %v", got, err, tc.wantCode)
+			}
+			if err == nil {
+				if got, want := string(resp.Stdout), "a b\ninput"; got != want {
+					t.Errorf("stdout doesn't match. Want %q Got %q", want, got)
+				}
+				if got, want := resp.RetCode, int32(2); got != want {
+					t.Errorf("RetCode = %d, want %d", got, want)
+				}
+			}
+			left, err := os.ReadDir(scriptDir)
+			testutil.FatalOnErr("ReadDir", err, t)
+			if len(left) != 0 {
+				t.Errorf("script directory not cleaned up: %v", left)
+			}
+		})
+	}
+}
+
+func TestRunScriptPermissions(t *testing.T) {
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	testutil.FatalOnErr("Failed to dial bufnet", err, t)
+	t.Cleanup(func() { conn.Close() })
+	client := pb.NewExecClient(conn)
+
+	savedDir := ScriptDir
+	ScriptDir = t.TempDir()
+	t.Cleanup(func() { ScriptDir = savedDir })
+
+	// Whoever runs the script must not be able to rewrite it or its directory.
+	script := []byte("#!/bin/sh\nstat -c '%a %u' \"$0\" \"$(dirname \"$0\")\"\n")
+	sum := sha256.Sum256(script)
+	stream, err := client.RunScript(ctx, &pb.RunScriptRequest{
+		Script: &pb.RunScriptRequest_Content{Content: script},
+		Sha256: hex.EncodeToString(sum[:]),
+	})
+	testutil.FatalOnErr("RunScript", err, t)
+	resp, err := collect(stream)
+	testutil.FatalOnErr("collect", err, t)
+	uid := os.Getuid()
+	if got, want := string(resp.Stdout), fmt.Sprintf("755 %d\n711 %d\n", uid, uid); got != want {
+		t.Errorf("script permissions don't match. Want %q Got %q", want, got)
+	}
+}
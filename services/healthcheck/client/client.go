@@ -26,6 +26,7 @@ import (
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/Snowflake-Labs/sansshell/client"
+	"github.com/Snowflake-Labs/sansshell/proxy/proxy"
 	pb "github.com/Snowflake-Labs/sansshell/services/healthcheck"
 	"github.com/Snowflake-Labs/sansshell/services/util"
 )
@@ -34,6 +35,11 @@ const subPackage = "healthcheck"
 
 func init() {
 	subcommands.Register(&healthcheckCmd{}, subPackage)
+	// Ok has no side effects, so it can be retried when a target is briefly
+	// unreachable.
+	if err := proxy.RegisterRetryPolicy(pb.HealthCheck_Ok_FullMethodName, proxy.DefaultRetryPolicy); err != nil {
+		panic(err)
+	}
 }
 
 func (*healthcheckCmd) GetSubpackage(f *flag.FlagSet) *subcommands.Commander {
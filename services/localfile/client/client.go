@@ -35,6 +35,7 @@ import (
 	"github.com/schollz/progressbar/v3"
 
 	"github.com/Snowflake-Labs/sansshell/client"
+	"github.com/Snowflake-Labs/sansshell/proxy/proxy"
 	pb "github.com/Snowflake-Labs/sansshell/services/localfile"
 	"github.com/Snowflake-Labs/sansshell/services/util"
 )
@@ -43,6 +44,18 @@ const subPackage = "file"
 
 func init() {
 	subcommands.Register(&fileCmd{}, subPackage)
+	// Reads, listings and data lookups don't modify any files, so these can
+	// be retried. Anything that writes or removes files isn't.
+	for _, m := range []string{
+		pb.LocalFile_Read_FullMethodName,
+		pb.LocalFile_List_FullMethodName,
+		pb.LocalFile_Readlink_FullMethodName,
+		pb.LocalFile_DataGet_FullMethodName,
+	} {
+		if err := proxy.RegisterRetryPolicy(m, proxy.DefaultRetryPolicy); err != nil {
+			panic(err)
+		}
+	}
 }
 
 func (*fileCmd) GetSubpackage(f *flag.FlagSet) *subcommands.Commander {
@@ -67,6 +80,7 @@ func (*fileCmd) GetSubpackage(f *flag.FlagSet) *subcommands.Commander {
 	c.Register(&symlinkCmd{}, "")
 	c.Register(&sumCmd{}, "")
 	c.Register(&tailCmd{}, "")
+	c.Register(&transferCmd{}, "")
 	c.Register(&mkdirCmd{}, "")
 	c.Register(&shredCmd{}, "")
 	return c
@@ -0,0 +1,133 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/google/subcommands"
+	"google.golang.org/grpc/codes"
+
+	transferpb "github.com/Snowflake-Labs/sansshell/services/transfer"
+	"github.com/Snowflake-Labs/sansshell/services/util"
+)
+
+type transferCmd struct {
+	from      string
+	overwrite bool
+	uid       int
+	username  string
+	gid       int
+	group     string
+	mode      string
+}
+
+func (*transferCmd) Name() string     { return "transfer" }
+func (*transferCmd) Synopsis() string { return "Copy a file from one target to the others through the proxy." }
+func (*transferCmd) Usage() string {
+	return `transfer --from=host:port [--overwrite] --uid=X|username=Y --gid=X|group=Y --mode=X <source> <destination>
+  Copy the source file on the --from target to the destination path on each
+  of the targets. The proxy reads the file from --from and writes it to the
+  targets itself, so the contents never pass through sanssh, and checks the
+  SHA-256 of every copy against the source. This needs a proxy hosting the
+  Transfer service.
+`
+}
+
+func (p *transferCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&p.from, "from", "", "The target, as host:port, to copy the file from.")
+	f.BoolVar(&p.overwrite, "overwrite", false, "If true will overwrite the remote file. Otherwise the file pre-existing is an error.")
+	f.IntVar(&p.uid, "uid", -1, "The uid the remote file will be set via chown.")
+	f.IntVar(&p.gid, "gid", -1, "The gid the remote file will be set via chown.")
+	f.StringVar(&p.mode, "mode", "", "The mode the remote file will be set via chmod. Must be an octal number (e.g. 644, 755, 0777).")
+	f.StringVar(&p.username, "username", "", "The remote file will be set to this username via chown.")
+	f.StringVar(&p.group, "group", "", "The remote file will be set to this group via chown.")
+}
+
+func (p *transferCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	state := args[0].(*util.ExecuteState)
+	if f.NArg() != 2 {
+		fmt.Fprintln(os.Stderr, "Please specify a source file on --from and a destination filename to write it to.")
+		return subcommands.ExitUsageError
+	}
+	if _, _, err := net.SplitHostPort(p.from); err != nil {
+		fmt.Fprintf(os.Stderr, "--from must be a target as host:port - %v\n", err)
+		return subcommands.ExitUsageError
+	}
+	if (p.uid == -1 && p.username == "") || (p.gid == -1 && p.group == "") || p.mode == "" {
+		fmt.Fprintln(os.Stderr, "Must set --uid|username, --gid|group and --mode")
+		return subcommands.ExitUsageError
+	}
+	if p.uid >= 0 && p.username != "" {
+		fmt.Fprintln(os.Stderr, "cannot set both --uid and --username")
+		return subcommands.ExitFailure
+	}
+	if p.gid >= 0 && p.group != "" {
+		fmt.Fprintln(os.Stderr, "cannot set both --gid and --group")
+		return subcommands.ExitFailure
+	}
+	mode, err := parseFileMode(p.mode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --mode '%s'. An octal number expected (e.g. 644, 755, 0777).\n", p.mode)
+		return subcommands.ExitUsageError
+	}
+	if state.Conn.Direct() {
+		fmt.Fprintln(os.Stderr, "transfer needs --proxy")
+		return subcommands.ExitUsageError
+	}
+
+	req := &transferpb.CopyRequest{
+		SourceTarget:        p.from,
+		SourceFilename:      f.Arg(0),
+		DestinationTargets:  state.Conn.Targets,
+		DestinationFilename: f.Arg(1),
+		Username:            p.username,
+		Group:               p.group,
+		Mode:                uint32(mode),
+		Overwrite:           p.overwrite,
+	}
+	if p.uid >= 0 {
+		req.Uid = uint32(p.uid)
+	}
+	if p.gid >= 0 {
+		req.Gid = uint32(p.gid)
+	}
+
+	c := transferpb.NewTransferClient(state.Conn.Proxy())
+	resp, err := c.Copy(ctx, req)
+	if err != nil {
+		// Emit this to every error file as it's not specific to a given target.
+		for _, e := range state.Err {
+			fmt.Fprintf(e, "All targets - error copying: %v\n", err)
+		}
+		return subcommands.ExitFailure
+	}
+	retCode := subcommands.ExitSuccess
+	for i, d := range resp.Destinations {
+		if code := codes.Code(d.Code); code != codes.OK {
+			fmt.Fprintf(state.Err[i], "Got error from target %s (%d) - %v: %s\n", d.Target, i, code, d.Message)
+			retCode = subcommands.ExitFailure
+			continue
+		}
+		fmt.Fprintf(state.Out[i], "Copied %d bytes, sha256 %s\n", resp.Size, resp.Sha256)
+	}
+	return retCode
+}
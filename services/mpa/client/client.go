@@ -22,15 +22,19 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"math"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/Snowflake-Labs/sansshell/client"
 	pb "github.com/Snowflake-Labs/sansshell/services/mpa"
+	"github.com/Snowflake-Labs/sansshell/services/mpa/mpahooks"
 	"github.com/Snowflake-Labs/sansshell/services/util"
 	"github.com/google/subcommands"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 const subPackage = "mpa"
@@ -42,6 +46,7 @@ func init() {
 func (*mpaCmd) GetSubpackage(f *flag.FlagSet) *subcommands.Commander {
 	c := client.SetupSubpackage(subPackage, f)
 	c.Register(&approveCmd{}, "")
+	c.Register(&rejectCmd{}, "")
 	c.Register(&listCmd{}, "")
 	c.Register(&getCmd{}, "")
 	c.Register(&clearCmd{}, "")
@@ -106,21 +111,28 @@ func getAction(ctx context.Context, state *util.ExecuteState, c pb.MpaClientProx
 
 type approveCmd struct {
 	skipConfirmation bool
+	validFor         time.Duration
+	maxUses          int
 }
 
 func (*approveCmd) Name() string     { return "approve" }
 func (*approveCmd) Synopsis() string { return "Approves an MPA request" }
 func (*approveCmd) Usage() string {
-	return `approve <id> [--skip-confirmation]:
+	return `approve <id> [--skip-confirmation] [--valid-for <duration>] [--max-uses <n>]:
     Approves an MPA request with the specified ID.
 
 	The --skip-confirmation flag can be used to bypass
 	the confirmation prompt, proceeding with the request approval.
+
+	The --valid-for and --max-uses flags limit how long the approval
+	lasts and how many times the approved command can be run with it.
 `
 }
 
 func (p *approveCmd) SetFlags(f *flag.FlagSet) {
 	f.BoolVar(&p.skipConfirmation, "skip-confirmation", false, "If true won't ask for confirmation")
+	f.DurationVar(&p.validFor, "valid-for", 0, "If set, how long the approval remains valid")
+	f.IntVar(&p.maxUses, "max-uses", 0, "If set, how many times the approved command can be run before the approval is used up")
 }
 
 func (p *approveCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
@@ -130,6 +142,10 @@ func (p *approveCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...inter
 		return subcommands.ExitUsageError
 	}
 	id := f.Args()[0]
+	if p.validFor < 0 || p.maxUses < 0 || p.maxUses > math.MaxInt32 {
+		fmt.Fprintln(os.Stderr, "--valid-for and --max-uses can't be negative and --max-uses must fit in 32 bits.")
+		return subcommands.ExitUsageError
+	}
 	c := pb.NewMpaClientProxy(state.Conn)
 	action := getAction(ctx, state, c, id)
 	if action == nil {
@@ -157,9 +173,14 @@ func (p *approveCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...inter
 		}
 	}
 
-	approved, err := c.ApproveOneMany(ctx, &pb.ApproveRequest{
-		Action: action,
-	})
+	req := &pb.ApproveRequest{
+		Action:  action,
+		MaxUses: int32(p.maxUses),
+	}
+	if p.validFor > 0 {
+		req.ValidFor = durationpb.New(p.validFor)
+	}
+	approved, err := c.ApproveOneMany(ctx, req)
 	if err != nil {
 		// Emit this to every error file as it's not specific to a given target.
 		for _, e := range state.Err {
@@ -184,6 +205,64 @@ func (p *approveCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...inter
 	return subcommands.ExitSuccess
 }
 
+type rejectCmd struct {
+	reason string
+}
+
+func (*rejectCmd) Name() string     { return "reject" }
+func (*rejectCmd) Synopsis() string { return "Rejects an MPA request" }
+func (*rejectCmd) Usage() string {
+	return `reject <id> --reason <reason>:
+    Rejects an MPA request with the specified ID. The reason is
+    shown to the requestor and recorded with the request.
+`
+}
+
+func (p *rejectCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&p.reason, "reason", "", "Why the request is being rejected (required)")
+}
+
+func (p *rejectCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	state := args[0].(*util.ExecuteState)
+	if f.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Please specify a single ID to reject.")
+		return subcommands.ExitUsageError
+	}
+	if p.reason == "" {
+		fmt.Fprintln(os.Stderr, "Please specify a reason with --reason.")
+		return subcommands.ExitUsageError
+	}
+	c := pb.NewMpaClientProxy(state.Conn)
+	action := getAction(ctx, state, c, f.Args()[0])
+	if action == nil {
+		return subcommands.ExitFailure
+	}
+
+	rejected, err := c.RejectOneMany(ctx, &pb.RejectRequest{
+		Action: action,
+		Reason: p.reason,
+	})
+	if err != nil {
+		// Emit this to every error file as it's not specific to a given target.
+		for _, e := range state.Err {
+			fmt.Fprintf(e, "All targets - could not execute: %v\n", err)
+		}
+		return subcommands.ExitFailure
+	}
+	for r := range rejected {
+		if r.Error != nil {
+			fmt.Fprintf(state.Err[r.Index], "Unable to reject: %v\n", r.Error)
+			continue
+		}
+		msg := []string{"Rejected", action.Method}
+		if action.GetUser() != "" {
+			msg = append(msg, "from", action.GetUser())
+		}
+		fmt.Fprintln(state.Out[r.Index], strings.Join(msg, " "))
+	}
+	return subcommands.ExitSuccess
+}
+
 type listCmd struct {
 	verbose bool
 }
@@ -232,6 +311,15 @@ func (p *listCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interfac
 					}
 					msg = append(msg, fmt.Sprintf("(approved by %v)", strings.Join(approvers, ",")))
 				}
+				if len(item.MissingApprovals) > 0 {
+					msg = append(msg, fmt.Sprintf("(missing %v)", mpahooks.DescribeRequirements(item.MissingApprovals)))
+				}
+				if item.Rejection != nil {
+					msg = append(msg, fmt.Sprintf("(rejected by %v: %v)", item.Rejection.GetRejecter().GetId(), item.Rejection.Reason))
+				}
+				if item.Limits != nil {
+					msg = append(msg, fmt.Sprintf("(%v)", describeLimits(item.Limits)))
+				}
 				msg = append(msg, protojson.MarshalOptions{UseProtoNames: true}.Format(item.Action))
 			} else {
 				msg = append(msg, item.Action.GetMethod())
@@ -241,7 +329,16 @@ func (p *listCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interfac
 				if item.Action.GetJustification() != "" {
 					msg = append(msg, "for", item.Action.GetJustification())
 				}
-				if len(item.Approver) > 0 {
+				switch {
+				case item.Rejection != nil:
+					msg = append(msg, fmt.Sprintf("(rejected by %v)", item.Rejection.GetRejecter().GetId()))
+				case len(item.MissingApprovals) > 0 && len(item.Approver) > 0:
+					msg = append(msg, fmt.Sprintf("(partially approved, missing %v)", mpahooks.DescribeRequirements(item.MissingApprovals)))
+				case len(item.MissingApprovals) > 0 && item.Action.GetRequiredApprovals() != nil:
+					msg = append(msg, fmt.Sprintf("(needs %v)", mpahooks.DescribeRequirements(item.MissingApprovals)))
+				case len(item.Approver) > 0 && item.Limits != nil:
+					msg = append(msg, fmt.Sprintf("(approved, %v)", describeLimits(item.Limits)))
+				case len(item.Approver) > 0:
 					msg = append(msg, "(approved)")
 				}
 			}
@@ -251,6 +348,19 @@ func (p *listCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interfac
 	return subcommands.ExitSuccess
 }
 
+// describeLimits renders approval limits for humans, such as
+// "expires in 29m0s, used 1 of 3 times".
+func describeLimits(l *pb.ApprovalLimits) string {
+	var parts []string
+	if l.ExpiresAt != nil {
+		parts = append(parts, fmt.Sprintf("expires in %v", time.Until(l.ExpiresAt.AsTime()).Round(time.Second)))
+	}
+	if l.MaxUses > 0 {
+		parts = append(parts, fmt.Sprintf("used %d of %d times", l.Uses, l.MaxUses))
+	}
+	return strings.Join(parts, ", ")
+}
+
 type clearCmd struct{}
 
 func (*clearCmd) Name() string     { return "clear" }
@@ -25,6 +25,8 @@ import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
 	anypb "google.golang.org/protobuf/types/known/anypb"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 )
@@ -49,6 +51,9 @@ type Action struct {
 	Method string `protobuf:"bytes,3,opt,name=method,proto3" json:"method,omitempty"`
 	// The request protocol buffer.
 	Message *anypb.Any `protobuf:"bytes,4,opt,name=message,proto3" json:"message,omitempty"`
+	// The approvals required before the action counts as approved. If unset,
+	// a single approval from anybody is sufficient.
+	RequiredApprovals *ApprovalSpec `protobuf:"bytes,5,opt,name=required_approvals,json=requiredApprovals,proto3" json:"required_approvals,omitempty"`
 }
 
 func (x *Action) Reset() {
@@ -111,6 +116,13 @@ func (x *Action) GetMessage() *anypb.Any {
 	return nil
 }
 
+func (x *Action) GetRequiredApprovals() *ApprovalSpec {
+	if x != nil {
+		return x.RequiredApprovals
+	}
+	return nil
+}
+
 type Principal struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -168,6 +180,244 @@ func (x *Principal) GetGroups() []string {
 	return nil
 }
 
+// Rejection records why a request was rejected.
+type Rejection struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The principal that rejected the request.
+	Rejecter *Principal `protobuf:"bytes,1,opt,name=rejecter,proto3" json:"rejecter,omitempty"`
+	// Why the request was rejected.
+	Reason string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (x *Rejection) Reset() {
+	*x = Rejection{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mpa_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Rejection) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rejection) ProtoMessage() {}
+
+func (x *Rejection) ProtoReflect() protoreflect.Message {
+	mi := &file_mpa_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rejection.ProtoReflect.Descriptor instead.
+func (*Rejection) Descriptor() ([]byte, []int) {
+	return file_mpa_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Rejection) GetRejecter() *Principal {
+	if x != nil {
+		return x.Rejecter
+	}
+	return nil
+}
+
+func (x *Rejection) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// ApprovalLimits bound how long and how often an approved request can be
+// used. Approvers set them when approving, and the tightest limits across
+// all approvers apply. Once the limits are reached the approvals are
+// dropped and the request needs to be approved again.
+type ApprovalLimits struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// If set, approvals are no longer valid after this time.
+	ExpiresAt *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// If positive, the number of times the approved method can be called
+	// before the approvals are used up.
+	MaxUses int32 `protobuf:"varint,2,opt,name=max_uses,json=maxUses,proto3" json:"max_uses,omitempty"`
+	// The number of times the approved method has been called.
+	Uses int32 `protobuf:"varint,3,opt,name=uses,proto3" json:"uses,omitempty"`
+}
+
+func (x *ApprovalLimits) Reset() {
+	*x = ApprovalLimits{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mpa_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ApprovalLimits) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApprovalLimits) ProtoMessage() {}
+
+func (x *ApprovalLimits) ProtoReflect() protoreflect.Message {
+	mi := &file_mpa_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApprovalLimits.ProtoReflect.Descriptor instead.
+func (*ApprovalLimits) Descriptor() ([]byte, []int) {
+	return file_mpa_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ApprovalLimits) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+func (x *ApprovalLimits) GetMaxUses() int32 {
+	if x != nil {
+		return x.MaxUses
+	}
+	return 0
+}
+
+func (x *ApprovalLimits) GetUses() int32 {
+	if x != nil {
+		return x.Uses
+	}
+	return 0
+}
+
+// A single requirement that the approvers of a request must satisfy.
+type ApprovalRequirement struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The number of distinct approvers needed. Must be positive.
+	Count int32 `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	// If set, only approvers belonging to this group count towards
+	// the requirement.
+	Group string `protobuf:"bytes,2,opt,name=group,proto3" json:"group,omitempty"`
+}
+
+func (x *ApprovalRequirement) Reset() {
+	*x = ApprovalRequirement{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mpa_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ApprovalRequirement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApprovalRequirement) ProtoMessage() {}
+
+func (x *ApprovalRequirement) ProtoReflect() protoreflect.Message {
+	mi := &file_mpa_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApprovalRequirement.ProtoReflect.Descriptor instead.
+func (*ApprovalRequirement) Descriptor() ([]byte, []int) {
+	return file_mpa_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ApprovalRequirement) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *ApprovalRequirement) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+// ApprovalSpec describes the approvals a request needs. All requirements
+// must be satisfied and each approver counts towards at most one of them,
+// so "one approver from sre plus one from owners" needs two people.
+type ApprovalSpec struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Requirement []*ApprovalRequirement `protobuf:"bytes,1,rep,name=requirement,proto3" json:"requirement,omitempty"`
+}
+
+func (x *ApprovalSpec) Reset() {
+	*x = ApprovalSpec{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mpa_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ApprovalSpec) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApprovalSpec) ProtoMessage() {}
+
+func (x *ApprovalSpec) ProtoReflect() protoreflect.Message {
+	mi := &file_mpa_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApprovalSpec.ProtoReflect.Descriptor instead.
+func (*ApprovalSpec) Descriptor() ([]byte, []int) {
+	return file_mpa_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ApprovalSpec) GetRequirement() []*ApprovalRequirement {
+	if x != nil {
+		return x.Requirement
+	}
+	return nil
+}
+
 type StoreRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -177,12 +427,15 @@ type StoreRequest struct {
 	Method string `protobuf:"bytes,1,opt,name=method,proto3" json:"method,omitempty"`
 	// The request protocol buffer.
 	Message *anypb.Any `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	// Optional approvals required for the request. Requests with different
+	// requirements are stored separately.
+	RequiredApprovals *ApprovalSpec `protobuf:"bytes,3,opt,name=required_approvals,json=requiredApprovals,proto3" json:"required_approvals,omitempty"`
 }
 
 func (x *StoreRequest) Reset() {
 	*x = StoreRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_mpa_proto_msgTypes[2]
+		mi := &file_mpa_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -195,7 +448,7 @@ func (x *StoreRequest) String() string {
 func (*StoreRequest) ProtoMessage() {}
 
 func (x *StoreRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_mpa_proto_msgTypes[2]
+	mi := &file_mpa_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -208,7 +461,7 @@ func (x *StoreRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StoreRequest.ProtoReflect.Descriptor instead.
 func (*StoreRequest) Descriptor() ([]byte, []int) {
-	return file_mpa_proto_rawDescGZIP(), []int{2}
+	return file_mpa_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *StoreRequest) GetMethod() string {
@@ -225,6 +478,13 @@ func (x *StoreRequest) GetMessage() *anypb.Any {
 	return nil
 }
 
+func (x *StoreRequest) GetRequiredApprovals() *ApprovalSpec {
+	if x != nil {
+		return x.RequiredApprovals
+	}
+	return nil
+}
+
 type StoreResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -236,12 +496,19 @@ type StoreResponse struct {
 	// approvers may be non-empty if we're storing a previously
 	// approved command.
 	Approver []*Principal `protobuf:"bytes,3,rep,name=approver,proto3" json:"approver,omitempty"`
+	// Requirements which still need approvals. Empty once the request
+	// is fully approved.
+	MissingApprovals []*ApprovalRequirement `protobuf:"bytes,4,rep,name=missing_approvals,json=missingApprovals,proto3" json:"missing_approvals,omitempty"`
+	// Set if the request has been rejected.
+	Rejection *Rejection `protobuf:"bytes,5,opt,name=rejection,proto3" json:"rejection,omitempty"`
+	// Limits on the use of the approvals, if any were given.
+	Limits *ApprovalLimits `protobuf:"bytes,6,opt,name=limits,proto3" json:"limits,omitempty"`
 }
 
 func (x *StoreResponse) Reset() {
 	*x = StoreResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_mpa_proto_msgTypes[3]
+		mi := &file_mpa_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -254,7 +521,7 @@ func (x *StoreResponse) String() string {
 func (*StoreResponse) ProtoMessage() {}
 
 func (x *StoreResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_mpa_proto_msgTypes[3]
+	mi := &file_mpa_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -267,7 +534,7 @@ func (x *StoreResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StoreResponse.ProtoReflect.Descriptor instead.
 func (*StoreResponse) Descriptor() ([]byte, []int) {
-	return file_mpa_proto_rawDescGZIP(), []int{3}
+	return file_mpa_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *StoreResponse) GetId() string {
@@ -291,6 +558,27 @@ func (x *StoreResponse) GetApprover() []*Principal {
 	return nil
 }
 
+func (x *StoreResponse) GetMissingApprovals() []*ApprovalRequirement {
+	if x != nil {
+		return x.MissingApprovals
+	}
+	return nil
+}
+
+func (x *StoreResponse) GetRejection() *Rejection {
+	if x != nil {
+		return x.Rejection
+	}
+	return nil
+}
+
+func (x *StoreResponse) GetLimits() *ApprovalLimits {
+	if x != nil {
+		return x.Limits
+	}
+	return nil
+}
+
 type ApproveRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -299,12 +587,18 @@ type ApproveRequest struct {
 	// Approve takes an action instead of an ID to improve auditability
 	// and allow richer authorization logic.
 	Action *Action `protobuf:"bytes,1,opt,name=action,proto3" json:"action,omitempty"`
+	// If set, the approval is only valid for this long. The server may still
+	// drop approvals sooner if they get too old.
+	ValidFor *durationpb.Duration `protobuf:"bytes,2,opt,name=valid_for,json=validFor,proto3" json:"valid_for,omitempty"`
+	// If positive, the approval is used up after the approved method has
+	// been called this many times.
+	MaxUses int32 `protobuf:"varint,3,opt,name=max_uses,json=maxUses,proto3" json:"max_uses,omitempty"`
 }
 
 func (x *ApproveRequest) Reset() {
 	*x = ApproveRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_mpa_proto_msgTypes[4]
+		mi := &file_mpa_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -317,7 +611,7 @@ func (x *ApproveRequest) String() string {
 func (*ApproveRequest) ProtoMessage() {}
 
 func (x *ApproveRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_mpa_proto_msgTypes[4]
+	mi := &file_mpa_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -330,7 +624,7 @@ func (x *ApproveRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ApproveRequest.ProtoReflect.Descriptor instead.
 func (*ApproveRequest) Descriptor() ([]byte, []int) {
-	return file_mpa_proto_rawDescGZIP(), []int{4}
+	return file_mpa_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *ApproveRequest) GetAction() *Action {
@@ -340,6 +634,20 @@ func (x *ApproveRequest) GetAction() *Action {
 	return nil
 }
 
+func (x *ApproveRequest) GetValidFor() *durationpb.Duration {
+	if x != nil {
+		return x.ValidFor
+	}
+	return nil
+}
+
+func (x *ApproveRequest) GetMaxUses() int32 {
+	if x != nil {
+		return x.MaxUses
+	}
+	return 0
+}
+
 type ApproveResponse struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -349,7 +657,7 @@ type ApproveResponse struct {
 func (x *ApproveResponse) Reset() {
 	*x = ApproveResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_mpa_proto_msgTypes[5]
+		mi := &file_mpa_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -362,7 +670,7 @@ func (x *ApproveResponse) String() string {
 func (*ApproveResponse) ProtoMessage() {}
 
 func (x *ApproveResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_mpa_proto_msgTypes[5]
+	mi := &file_mpa_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -375,7 +683,102 @@ func (x *ApproveResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ApproveResponse.ProtoReflect.Descriptor instead.
 func (*ApproveResponse) Descriptor() ([]byte, []int) {
-	return file_mpa_proto_rawDescGZIP(), []int{5}
+	return file_mpa_proto_rawDescGZIP(), []int{9}
+}
+
+type RejectRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Like approvals, rejections take an action instead of an ID.
+	Action *Action `protobuf:"bytes,1,opt,name=action,proto3" json:"action,omitempty"`
+	// Why the request is being rejected. Must be non-empty.
+	Reason string `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+}
+
+func (x *RejectRequest) Reset() {
+	*x = RejectRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mpa_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RejectRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RejectRequest) ProtoMessage() {}
+
+func (x *RejectRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mpa_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RejectRequest.ProtoReflect.Descriptor instead.
+func (*RejectRequest) Descriptor() ([]byte, []int) {
+	return file_mpa_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *RejectRequest) GetAction() *Action {
+	if x != nil {
+		return x.Action
+	}
+	return nil
+}
+
+func (x *RejectRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type RejectResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *RejectResponse) Reset() {
+	*x = RejectResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_mpa_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RejectResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RejectResponse) ProtoMessage() {}
+
+func (x *RejectResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_mpa_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RejectResponse.ProtoReflect.Descriptor instead.
+func (*RejectResponse) Descriptor() ([]byte, []int) {
+	return file_mpa_proto_rawDescGZIP(), []int{11}
 }
 
 type WaitForApprovalRequest struct {
@@ -389,7 +792,7 @@ type WaitForApprovalRequest struct {
 func (x *WaitForApprovalRequest) Reset() {
 	*x = WaitForApprovalRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_mpa_proto_msgTypes[6]
+		mi := &file_mpa_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -402,7 +805,7 @@ func (x *WaitForApprovalRequest) String() string {
 func (*WaitForApprovalRequest) ProtoMessage() {}
 
 func (x *WaitForApprovalRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_mpa_proto_msgTypes[6]
+	mi := &file_mpa_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -415,7 +818,7 @@ func (x *WaitForApprovalRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use WaitForApprovalRequest.ProtoReflect.Descriptor instead.
 func (*WaitForApprovalRequest) Descriptor() ([]byte, []int) {
-	return file_mpa_proto_rawDescGZIP(), []int{6}
+	return file_mpa_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *WaitForApprovalRequest) GetId() string {
@@ -434,7 +837,7 @@ type WaitForApprovalResponse struct {
 func (x *WaitForApprovalResponse) Reset() {
 	*x = WaitForApprovalResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_mpa_proto_msgTypes[7]
+		mi := &file_mpa_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -447,7 +850,7 @@ func (x *WaitForApprovalResponse) String() string {
 func (*WaitForApprovalResponse) ProtoMessage() {}
 
 func (x *WaitForApprovalResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_mpa_proto_msgTypes[7]
+	mi := &file_mpa_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -460,7 +863,7 @@ func (x *WaitForApprovalResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use WaitForApprovalResponse.ProtoReflect.Descriptor instead.
 func (*WaitForApprovalResponse) Descriptor() ([]byte, []int) {
-	return file_mpa_proto_rawDescGZIP(), []int{7}
+	return file_mpa_proto_rawDescGZIP(), []int{13}
 }
 
 type ListRequest struct {
@@ -472,7 +875,7 @@ type ListRequest struct {
 func (x *ListRequest) Reset() {
 	*x = ListRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_mpa_proto_msgTypes[8]
+		mi := &file_mpa_proto_msgTypes[14]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -485,7 +888,7 @@ func (x *ListRequest) String() string {
 func (*ListRequest) ProtoMessage() {}
 
 func (x *ListRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_mpa_proto_msgTypes[8]
+	mi := &file_mpa_proto_msgTypes[14]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -498,7 +901,7 @@ func (x *ListRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListRequest.ProtoReflect.Descriptor instead.
 func (*ListRequest) Descriptor() ([]byte, []int) {
-	return file_mpa_proto_rawDescGZIP(), []int{8}
+	return file_mpa_proto_rawDescGZIP(), []int{14}
 }
 
 type ListResponse struct {
@@ -512,7 +915,7 @@ type ListResponse struct {
 func (x *ListResponse) Reset() {
 	*x = ListResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_mpa_proto_msgTypes[9]
+		mi := &file_mpa_proto_msgTypes[15]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -525,7 +928,7 @@ func (x *ListResponse) String() string {
 func (*ListResponse) ProtoMessage() {}
 
 func (x *ListResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_mpa_proto_msgTypes[9]
+	mi := &file_mpa_proto_msgTypes[15]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -538,7 +941,7 @@ func (x *ListResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListResponse.ProtoReflect.Descriptor instead.
 func (*ListResponse) Descriptor() ([]byte, []int) {
-	return file_mpa_proto_rawDescGZIP(), []int{9}
+	return file_mpa_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *ListResponse) GetItem() []*ListResponse_Item {
@@ -559,7 +962,7 @@ type GetRequest struct {
 func (x *GetRequest) Reset() {
 	*x = GetRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_mpa_proto_msgTypes[10]
+		mi := &file_mpa_proto_msgTypes[16]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -572,7 +975,7 @@ func (x *GetRequest) String() string {
 func (*GetRequest) ProtoMessage() {}
 
 func (x *GetRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_mpa_proto_msgTypes[10]
+	mi := &file_mpa_proto_msgTypes[16]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -585,7 +988,7 @@ func (x *GetRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetRequest.ProtoReflect.Descriptor instead.
 func (*GetRequest) Descriptor() ([]byte, []int) {
-	return file_mpa_proto_rawDescGZIP(), []int{10}
+	return file_mpa_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *GetRequest) GetId() string {
@@ -603,12 +1006,19 @@ type GetResponse struct {
 	Action *Action `protobuf:"bytes,1,opt,name=action,proto3" json:"action,omitempty"`
 	// All approvers of the request.
 	Approver []*Principal `protobuf:"bytes,2,rep,name=approver,proto3" json:"approver,omitempty"`
+	// Requirements which still need approvals. Empty once the request
+	// is fully approved.
+	MissingApprovals []*ApprovalRequirement `protobuf:"bytes,3,rep,name=missing_approvals,json=missingApprovals,proto3" json:"missing_approvals,omitempty"`
+	// Set if the request has been rejected.
+	Rejection *Rejection `protobuf:"bytes,4,opt,name=rejection,proto3" json:"rejection,omitempty"`
+	// Limits on the use of the approvals, if any were given.
+	Limits *ApprovalLimits `protobuf:"bytes,5,opt,name=limits,proto3" json:"limits,omitempty"`
 }
 
 func (x *GetResponse) Reset() {
 	*x = GetResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_mpa_proto_msgTypes[11]
+		mi := &file_mpa_proto_msgTypes[17]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -621,7 +1031,7 @@ func (x *GetResponse) String() string {
 func (*GetResponse) ProtoMessage() {}
 
 func (x *GetResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_mpa_proto_msgTypes[11]
+	mi := &file_mpa_proto_msgTypes[17]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -634,7 +1044,7 @@ func (x *GetResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetResponse.ProtoReflect.Descriptor instead.
 func (*GetResponse) Descriptor() ([]byte, []int) {
-	return file_mpa_proto_rawDescGZIP(), []int{11}
+	return file_mpa_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *GetResponse) GetAction() *Action {
@@ -651,6 +1061,27 @@ func (x *GetResponse) GetApprover() []*Principal {
 	return nil
 }
 
+func (x *GetResponse) GetMissingApprovals() []*ApprovalRequirement {
+	if x != nil {
+		return x.MissingApprovals
+	}
+	return nil
+}
+
+func (x *GetResponse) GetRejection() *Rejection {
+	if x != nil {
+		return x.Rejection
+	}
+	return nil
+}
+
+func (x *GetResponse) GetLimits() *ApprovalLimits {
+	if x != nil {
+		return x.Limits
+	}
+	return nil
+}
+
 type ClearRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -662,7 +1093,7 @@ type ClearRequest struct {
 func (x *ClearRequest) Reset() {
 	*x = ClearRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_mpa_proto_msgTypes[12]
+		mi := &file_mpa_proto_msgTypes[18]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -675,7 +1106,7 @@ func (x *ClearRequest) String() string {
 func (*ClearRequest) ProtoMessage() {}
 
 func (x *ClearRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_mpa_proto_msgTypes[12]
+	mi := &file_mpa_proto_msgTypes[18]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -688,7 +1119,7 @@ func (x *ClearRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClearRequest.ProtoReflect.Descriptor instead.
 func (*ClearRequest) Descriptor() ([]byte, []int) {
-	return file_mpa_proto_rawDescGZIP(), []int{12}
+	return file_mpa_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *ClearRequest) GetAction() *Action {
@@ -707,7 +1138,7 @@ type ClearResponse struct {
 func (x *ClearResponse) Reset() {
 	*x = ClearResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_mpa_proto_msgTypes[13]
+		mi := &file_mpa_proto_msgTypes[19]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -720,7 +1151,7 @@ func (x *ClearResponse) String() string {
 func (*ClearResponse) ProtoMessage() {}
 
 func (x *ClearResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_mpa_proto_msgTypes[13]
+	mi := &file_mpa_proto_msgTypes[19]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -733,7 +1164,7 @@ func (x *ClearResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClearResponse.ProtoReflect.Descriptor instead.
 func (*ClearResponse) Descriptor() ([]byte, []int) {
-	return file_mpa_proto_rawDescGZIP(), []int{13}
+	return file_mpa_proto_rawDescGZIP(), []int{19}
 }
 
 type ListResponse_Item struct {
@@ -744,12 +1175,18 @@ type ListResponse_Item struct {
 	Action   *Action      `protobuf:"bytes,1,opt,name=action,proto3" json:"action,omitempty"`
 	Approver []*Principal `protobuf:"bytes,2,rep,name=approver,proto3" json:"approver,omitempty"`
 	Id       string       `protobuf:"bytes,3,opt,name=id,proto3" json:"id,omitempty"`
+	// Requirements which still need approvals.
+	MissingApprovals []*ApprovalRequirement `protobuf:"bytes,4,rep,name=missing_approvals,json=missingApprovals,proto3" json:"missing_approvals,omitempty"`
+	// Set if the request has been rejected.
+	Rejection *Rejection `protobuf:"bytes,5,opt,name=rejection,proto3" json:"rejection,omitempty"`
+	// Limits on the use of the approvals, if any were given.
+	Limits *ApprovalLimits `protobuf:"bytes,6,opt,name=limits,proto3" json:"limits,omitempty"`
 }
 
 func (x *ListResponse_Item) Reset() {
 	*x = ListResponse_Item{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_mpa_proto_msgTypes[14]
+		mi := &file_mpa_proto_msgTypes[20]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -762,7 +1199,7 @@ func (x *ListResponse_Item) String() string {
 func (*ListResponse_Item) ProtoMessage() {}
 
 func (x *ListResponse_Item) ProtoReflect() protoreflect.Message {
-	mi := &file_mpa_proto_msgTypes[14]
+	mi := &file_mpa_proto_msgTypes[20]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -775,7 +1212,7 @@ func (x *ListResponse_Item) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ListResponse_Item.ProtoReflect.Descriptor instead.
 func (*ListResponse_Item) Descriptor() ([]byte, []int) {
-	return file_mpa_proto_rawDescGZIP(), []int{9, 0}
+	return file_mpa_proto_rawDescGZIP(), []int{15, 0}
 }
 
 func (x *ListResponse_Item) GetAction() *Action {
@@ -799,94 +1236,194 @@ func (x *ListResponse_Item) GetId() string {
 	return ""
 }
 
+func (x *ListResponse_Item) GetMissingApprovals() []*ApprovalRequirement {
+	if x != nil {
+		return x.MissingApprovals
+	}
+	return nil
+}
+
+func (x *ListResponse_Item) GetRejection() *Rejection {
+	if x != nil {
+		return x.Rejection
+	}
+	return nil
+}
+
+func (x *ListResponse_Item) GetLimits() *ApprovalLimits {
+	if x != nil {
+		return x.Limits
+	}
+	return nil
+}
+
 var File_mpa_proto protoreflect.FileDescriptor
 
 var file_mpa_proto_rawDesc = []byte{
 	0x0a, 0x09, 0x6d, 0x70, 0x61, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x03, 0x4d, 0x70, 0x61,
 	0x1a, 0x19, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
-	0x66, 0x2f, 0x61, 0x6e, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x8a, 0x01, 0x0a, 0x06,
-	0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x73, 0x65, 0x72, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x75, 0x73, 0x65, 0x72, 0x12, 0x24, 0x0a, 0x0d, 0x6a, 0x75,
-	0x73, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x0d, 0x6a, 0x75, 0x73, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e,
-	0x12, 0x16, 0x0a, 0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x12, 0x2e, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73,
-	0x61, 0x67, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
-	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x41, 0x6e, 0x79, 0x52,
-	0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0x33, 0x0a, 0x09, 0x50, 0x72, 0x69, 0x6e,
-	0x63, 0x69, 0x70, 0x61, 0x6c, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x18,
-	0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x22, 0x56, 0x0a,
+	0x66, 0x2f, 0x61, 0x6e, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x64, 0x75, 0x72,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x1f, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d,
+	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xcc, 0x01, 0x0a,
+	0x06, 0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x73, 0x65, 0x72, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x75, 0x73, 0x65, 0x72, 0x12, 0x24, 0x0a, 0x0d, 0x6a,
+	0x75, 0x73, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0d, 0x6a, 0x75, 0x73, 0x74, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x16, 0x0a, 0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x12, 0x2e, 0x0a, 0x07, 0x6d, 0x65, 0x73,
+	0x73, 0x61, 0x67, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x67, 0x6f, 0x6f,
+	0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x41, 0x6e, 0x79,
+	0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x40, 0x0a, 0x12, 0x72, 0x65, 0x71,
+	0x75, 0x69, 0x72, 0x65, 0x64, 0x5f, 0x61, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x73, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x41, 0x70, 0x70, 0x72,
+	0x6f, 0x76, 0x61, 0x6c, 0x53, 0x70, 0x65, 0x63, 0x52, 0x11, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72,
+	0x65, 0x64, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x73, 0x22, 0x33, 0x0a, 0x09, 0x50,
+	0x72, 0x69, 0x6e, 0x63, 0x69, 0x70, 0x61, 0x6c, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x67, 0x72, 0x6f, 0x75,
+	0x70, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x06, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x73,
+	0x22, 0x4f, 0x0a, 0x09, 0x52, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2a, 0x0a,
+	0x08, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x0e, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x50, 0x72, 0x69, 0x6e, 0x63, 0x69, 0x70, 0x61, 0x6c, 0x52,
+	0x08, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61,
+	0x73, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f,
+	0x6e, 0x22, 0x7a, 0x0a, 0x0e, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x4c, 0x69, 0x6d,
+	0x69, 0x74, 0x73, 0x12, 0x39, 0x0a, 0x0a, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x5f, 0x61,
+	0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74,
+	0x61, 0x6d, 0x70, 0x52, 0x09, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x73, 0x41, 0x74, 0x12, 0x19,
+	0x0a, 0x08, 0x6d, 0x61, 0x78, 0x5f, 0x75, 0x73, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x07, 0x6d, 0x61, 0x78, 0x55, 0x73, 0x65, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x75, 0x73, 0x65,
+	0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x75, 0x73, 0x65, 0x73, 0x22, 0x41, 0x0a,
+	0x13, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65,
+	0x6d, 0x65, 0x6e, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x05, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x67, 0x72,
+	0x6f, 0x75, 0x70, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x67, 0x72, 0x6f, 0x75, 0x70,
+	0x22, 0x4a, 0x0a, 0x0c, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x53, 0x70, 0x65, 0x63,
+	0x12, 0x3a, 0x0a, 0x0b, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x18,
+	0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x41, 0x70, 0x70, 0x72,
+	0x6f, 0x76, 0x61, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x52,
+	0x0b, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x22, 0x98, 0x01, 0x0a,
 	0x0c, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a,
 	0x06, 0x6d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x6d,
 	0x65, 0x74, 0x68, 0x6f, 0x64, 0x12, 0x2e, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65,
 	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
 	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x41, 0x6e, 0x79, 0x52, 0x07, 0x6d, 0x65,
-	0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0x70, 0x0a, 0x0d, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x52, 0x65,
-	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x23, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x41, 0x63, 0x74,
-	0x69, 0x6f, 0x6e, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2a, 0x0a, 0x08, 0x61,
-	0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x72, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e,
-	0x4d, 0x70, 0x61, 0x2e, 0x50, 0x72, 0x69, 0x6e, 0x63, 0x69, 0x70, 0x61, 0x6c, 0x52, 0x08, 0x61,
-	0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x72, 0x22, 0x35, 0x0a, 0x0e, 0x41, 0x70, 0x70, 0x72, 0x6f,
-	0x76, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x23, 0x0a, 0x06, 0x61, 0x63, 0x74,
-	0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x4d, 0x70, 0x61, 0x2e,
-	0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0x11,
-	0x0a, 0x0f, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x22, 0x28, 0x0a, 0x16, 0x57, 0x61, 0x69, 0x74, 0x46, 0x6f, 0x72, 0x41, 0x70, 0x70, 0x72,
-	0x6f, 0x76, 0x61, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69,
-	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x19, 0x0a, 0x17, 0x57,
-	0x61, 0x69, 0x74, 0x46, 0x6f, 0x72, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x52, 0x65,
-	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x0d, 0x0a, 0x0b, 0x4c, 0x69, 0x73, 0x74, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0xa3, 0x01, 0x0a, 0x0c, 0x4c, 0x69, 0x73, 0x74, 0x52, 0x65,
-	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2a, 0x0a, 0x04, 0x69, 0x74, 0x65, 0x6d, 0x18, 0x01,
-	0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x52,
-	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x49, 0x74, 0x65, 0x6d, 0x52, 0x04, 0x69, 0x74,
-	0x65, 0x6d, 0x1a, 0x67, 0x0a, 0x04, 0x49, 0x74, 0x65, 0x6d, 0x12, 0x23, 0x0a, 0x06, 0x61, 0x63,
-	0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x4d, 0x70, 0x61,
-	0x2e, 0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12,
-	0x2a, 0x0a, 0x08, 0x61, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x72, 0x18, 0x02, 0x20, 0x03, 0x28,
-	0x0b, 0x32, 0x0e, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x50, 0x72, 0x69, 0x6e, 0x63, 0x69, 0x70, 0x61,
-	0x6c, 0x52, 0x08, 0x61, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69,
-	0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x1c, 0x0a, 0x0a, 0x47,
-	0x65, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x5e, 0x0a, 0x0b, 0x47, 0x65, 0x74,
-	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x23, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69,
-	0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x41,
-	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2a, 0x0a,
-	0x08, 0x61, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x72, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32,
-	0x0e, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x50, 0x72, 0x69, 0x6e, 0x63, 0x69, 0x70, 0x61, 0x6c, 0x52,
-	0x08, 0x61, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x72, 0x22, 0x33, 0x0a, 0x0c, 0x43, 0x6c, 0x65,
-	0x61, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x23, 0x0a, 0x06, 0x61, 0x63, 0x74,
-	0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x4d, 0x70, 0x61, 0x2e,
-	0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0x0f,
-	0x0a, 0x0d, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x32,
-	0xcc, 0x02, 0x0a, 0x03, 0x4d, 0x70, 0x61, 0x12, 0x30, 0x0a, 0x05, 0x53, 0x74, 0x6f, 0x72, 0x65,
-	0x12, 0x11, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x52,
-	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x36, 0x0a, 0x07, 0x41, 0x70, 0x70,
-	0x72, 0x6f, 0x76, 0x65, 0x12, 0x13, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x41, 0x70, 0x70, 0x72, 0x6f,
-	0x76, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x4d, 0x70, 0x61, 0x2e,
-	0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
-	0x00, 0x12, 0x4e, 0x0a, 0x0f, 0x57, 0x61, 0x69, 0x74, 0x46, 0x6f, 0x72, 0x41, 0x70, 0x70, 0x72,
-	0x6f, 0x76, 0x61, 0x6c, 0x12, 0x1b, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x57, 0x61, 0x69, 0x74, 0x46,
-	0x6f, 0x72, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x1a, 0x1c, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x57, 0x61, 0x69, 0x74, 0x46, 0x6f, 0x72, 0x41,
+	0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x40, 0x0a, 0x12, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65,
+	0x64, 0x5f, 0x61, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x11, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c,
+	0x53, 0x70, 0x65, 0x63, 0x52, 0x11, 0x72, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x64, 0x41, 0x70,
+	0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x73, 0x22, 0x92, 0x02, 0x0a, 0x0d, 0x53, 0x74, 0x6f, 0x72,
+	0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x23, 0x0a, 0x06, 0x61, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x4d, 0x70, 0x61, 0x2e,
+	0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2a,
+	0x0a, 0x08, 0x61, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x72, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x0e, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x50, 0x72, 0x69, 0x6e, 0x63, 0x69, 0x70, 0x61, 0x6c,
+	0x52, 0x08, 0x61, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x72, 0x12, 0x45, 0x0a, 0x11, 0x6d, 0x69,
+	0x73, 0x73, 0x69, 0x6e, 0x67, 0x5f, 0x61, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x73, 0x18,
+	0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x41, 0x70, 0x70, 0x72,
+	0x6f, 0x76, 0x61, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x52,
+	0x10, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6e, 0x67, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c,
+	0x73, 0x12, 0x2c, 0x0a, 0x09, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x05,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x52, 0x65, 0x6a, 0x65, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x09, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x2b, 0x0a, 0x06, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x13, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x4c, 0x69,
+	0x6d, 0x69, 0x74, 0x73, 0x52, 0x06, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x22, 0x88, 0x01, 0x0a,
+	0x0e, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x23, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x0b, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x61, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x36, 0x0a, 0x09, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x5f, 0x66, 0x6f,
+	0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x52, 0x08, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x46, 0x6f, 0x72, 0x12, 0x19, 0x0a, 0x08,
+	0x6d, 0x61, 0x78, 0x5f, 0x75, 0x73, 0x65, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07,
+	0x6d, 0x61, 0x78, 0x55, 0x73, 0x65, 0x73, 0x22, 0x11, 0x0a, 0x0f, 0x41, 0x70, 0x70, 0x72, 0x6f,
+	0x76, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x4c, 0x0a, 0x0d, 0x52, 0x65,
+	0x6a, 0x65, 0x63, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x23, 0x0a, 0x06, 0x61,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x4d, 0x70,
+	0x61, 0x2e, 0x41, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x22, 0x10, 0x0a, 0x0e, 0x52, 0x65, 0x6a, 0x65,
+	0x63, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x28, 0x0a, 0x16, 0x57, 0x61,
+	0x69, 0x74, 0x46, 0x6f, 0x72, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x02, 0x69, 0x64, 0x22, 0x19, 0x0a, 0x17, 0x57, 0x61, 0x69, 0x74, 0x46, 0x6f, 0x72, 0x41,
 	0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22,
-	0x00, 0x12, 0x2d, 0x0a, 0x04, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x10, 0x2e, 0x4d, 0x70, 0x61, 0x2e,
-	0x4c, 0x69, 0x73, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x11, 0x2e, 0x4d, 0x70,
-	0x61, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00,
-	0x12, 0x2a, 0x0a, 0x03, 0x47, 0x65, 0x74, 0x12, 0x0f, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x47, 0x65,
-	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x10, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x47,
-	0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x30, 0x0a, 0x05,
-	0x43, 0x6c, 0x65, 0x61, 0x72, 0x12, 0x11, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x43, 0x6c, 0x65, 0x61,
-	0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x43,
-	0x6c, 0x65, 0x61, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x29,
-	0x5a, 0x27, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x53, 0x6e, 0x6f,
-	0x77, 0x66, 0x6c, 0x61, 0x6b, 0x65, 0x2d, 0x4c, 0x61, 0x62, 0x73, 0x2f, 0x73, 0x61, 0x6e, 0x73,
-	0x73, 0x68, 0x65, 0x6c, 0x6c, 0x2f, 0x6d, 0x70, 0x61, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x33,
+	0x0d, 0x0a, 0x0b, 0x4c, 0x69, 0x73, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0xc6,
+	0x02, 0x0a, 0x0c, 0x4c, 0x69, 0x73, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x2a, 0x0a, 0x04, 0x69, 0x74, 0x65, 0x6d, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e,
+	0x4d, 0x70, 0x61, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x2e, 0x49, 0x74, 0x65, 0x6d, 0x52, 0x04, 0x69, 0x74, 0x65, 0x6d, 0x1a, 0x89, 0x02, 0x0a, 0x04,
+	0x49, 0x74, 0x65, 0x6d, 0x12, 0x23, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x41, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2a, 0x0a, 0x08, 0x61, 0x70, 0x70,
+	0x72, 0x6f, 0x76, 0x65, 0x72, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x4d, 0x70,
+	0x61, 0x2e, 0x50, 0x72, 0x69, 0x6e, 0x63, 0x69, 0x70, 0x61, 0x6c, 0x52, 0x08, 0x61, 0x70, 0x70,
+	0x72, 0x6f, 0x76, 0x65, 0x72, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x02, 0x69, 0x64, 0x12, 0x45, 0x0a, 0x11, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6e, 0x67,
+	0x5f, 0x61, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x18, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x52,
+	0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x10, 0x6d, 0x69, 0x73, 0x73,
+	0x69, 0x6e, 0x67, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x73, 0x12, 0x2c, 0x0a, 0x09,
+	0x72, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x0e, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x52, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52,
+	0x09, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2b, 0x0a, 0x06, 0x6c, 0x69,
+	0x6d, 0x69, 0x74, 0x73, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x4d, 0x70, 0x61,
+	0x2e, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x52,
+	0x06, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x22, 0x1c, 0x0a, 0x0a, 0x47, 0x65, 0x74, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x02, 0x69, 0x64, 0x22, 0x80, 0x02, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x23, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x41, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2a, 0x0a, 0x08, 0x61, 0x70,
+	0x70, 0x72, 0x6f, 0x76, 0x65, 0x72, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x4d,
+	0x70, 0x61, 0x2e, 0x50, 0x72, 0x69, 0x6e, 0x63, 0x69, 0x70, 0x61, 0x6c, 0x52, 0x08, 0x61, 0x70,
+	0x70, 0x72, 0x6f, 0x76, 0x65, 0x72, 0x12, 0x45, 0x0a, 0x11, 0x6d, 0x69, 0x73, 0x73, 0x69, 0x6e,
+	0x67, 0x5f, 0x61, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x18, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c,
+	0x52, 0x65, 0x71, 0x75, 0x69, 0x72, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x10, 0x6d, 0x69, 0x73,
+	0x73, 0x69, 0x6e, 0x67, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x73, 0x12, 0x2c, 0x0a,
+	0x09, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x0e, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x52, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x52, 0x09, 0x72, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2b, 0x0a, 0x06, 0x6c,
+	0x69, 0x6d, 0x69, 0x74, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x4d, 0x70,
+	0x61, 0x2e, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x73,
+	0x52, 0x06, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x73, 0x22, 0x33, 0x0a, 0x0c, 0x43, 0x6c, 0x65, 0x61,
+	0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x23, 0x0a, 0x06, 0x61, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x41,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x06, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0x0f, 0x0a,
+	0x0d, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x32, 0x81,
+	0x03, 0x0a, 0x03, 0x4d, 0x70, 0x61, 0x12, 0x30, 0x0a, 0x05, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x12,
+	0x11, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x12, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x53, 0x74, 0x6f, 0x72, 0x65, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x36, 0x0a, 0x07, 0x41, 0x70, 0x70, 0x72,
+	0x6f, 0x76, 0x65, 0x12, 0x13, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76,
+	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x41,
+	0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00,
+	0x12, 0x33, 0x0a, 0x06, 0x52, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x12, 0x12, 0x2e, 0x4d, 0x70, 0x61,
+	0x2e, 0x52, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13,
+	0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x52, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x4e, 0x0a, 0x0f, 0x57, 0x61, 0x69, 0x74, 0x46, 0x6f, 0x72,
+	0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x12, 0x1b, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x57,
+	0x61, 0x69, 0x74, 0x46, 0x6f, 0x72, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1c, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x57, 0x61, 0x69, 0x74,
+	0x46, 0x6f, 0x72, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x61, 0x6c, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x2d, 0x0a, 0x04, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x10, 0x2e,
+	0x4d, 0x70, 0x61, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x11, 0x2e, 0x4d, 0x70, 0x61, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x22, 0x00, 0x12, 0x2a, 0x0a, 0x03, 0x47, 0x65, 0x74, 0x12, 0x0f, 0x2e, 0x4d, 0x70,
+	0x61, 0x2e, 0x47, 0x65, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x10, 0x2e, 0x4d,
+	0x70, 0x61, 0x2e, 0x47, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00,
+	0x12, 0x30, 0x0a, 0x05, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x12, 0x11, 0x2e, 0x4d, 0x70, 0x61, 0x2e,
+	0x43, 0x6c, 0x65, 0x61, 0x72, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x4d,
+	0x70, 0x61, 0x2e, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x22, 0x00, 0x42, 0x29, 0x5a, 0x27, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d,
+	0x2f, 0x53, 0x6e, 0x6f, 0x77, 0x66, 0x6c, 0x61, 0x6b, 0x65, 0x2d, 0x4c, 0x61, 0x62, 0x73, 0x2f,
+	0x73, 0x61, 0x6e, 0x73, 0x73, 0x68, 0x65, 0x6c, 0x6c, 0x2f, 0x6d, 0x70, 0x61, 0x62, 0x06, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -901,54 +1438,80 @@ func file_mpa_proto_rawDescGZIP() []byte {
 	return file_mpa_proto_rawDescData
 }
 
-var file_mpa_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_mpa_proto_msgTypes = make([]protoimpl.MessageInfo, 21)
 var file_mpa_proto_goTypes = []any{
 	(*Action)(nil),                  // 0: Mpa.Action
 	(*Principal)(nil),               // 1: Mpa.Principal
-	(*StoreRequest)(nil),            // 2: Mpa.StoreRequest
-	(*StoreResponse)(nil),           // 3: Mpa.StoreResponse
-	(*ApproveRequest)(nil),          // 4: Mpa.ApproveRequest
-	(*ApproveResponse)(nil),         // 5: Mpa.ApproveResponse
-	(*WaitForApprovalRequest)(nil),  // 6: Mpa.WaitForApprovalRequest
-	(*WaitForApprovalResponse)(nil), // 7: Mpa.WaitForApprovalResponse
-	(*ListRequest)(nil),             // 8: Mpa.ListRequest
-	(*ListResponse)(nil),            // 9: Mpa.ListResponse
-	(*GetRequest)(nil),              // 10: Mpa.GetRequest
-	(*GetResponse)(nil),             // 11: Mpa.GetResponse
-	(*ClearRequest)(nil),            // 12: Mpa.ClearRequest
-	(*ClearResponse)(nil),           // 13: Mpa.ClearResponse
-	(*ListResponse_Item)(nil),       // 14: Mpa.ListResponse.Item
-	(*anypb.Any)(nil),               // 15: google.protobuf.Any
+	(*Rejection)(nil),               // 2: Mpa.Rejection
+	(*ApprovalLimits)(nil),          // 3: Mpa.ApprovalLimits
+	(*ApprovalRequirement)(nil),     // 4: Mpa.ApprovalRequirement
+	(*ApprovalSpec)(nil),            // 5: Mpa.ApprovalSpec
+	(*StoreRequest)(nil),            // 6: Mpa.StoreRequest
+	(*StoreResponse)(nil),           // 7: Mpa.StoreResponse
+	(*ApproveRequest)(nil),          // 8: Mpa.ApproveRequest
+	(*ApproveResponse)(nil),         // 9: Mpa.ApproveResponse
+	(*RejectRequest)(nil),           // 10: Mpa.RejectRequest
+	(*RejectResponse)(nil),          // 11: Mpa.RejectResponse
+	(*WaitForApprovalRequest)(nil),  // 12: Mpa.WaitForApprovalRequest
+	(*WaitForApprovalResponse)(nil), // 13: Mpa.WaitForApprovalResponse
+	(*ListRequest)(nil),             // 14: Mpa.ListRequest
+	(*ListResponse)(nil),            // 15: Mpa.ListResponse
+	(*GetRequest)(nil),              // 16: Mpa.GetRequest
+	(*GetResponse)(nil),             // 17: Mpa.GetResponse
+	(*ClearRequest)(nil),            // 18: Mpa.ClearRequest
+	(*ClearResponse)(nil),           // 19: Mpa.ClearResponse
+	(*ListResponse_Item)(nil),       // 20: Mpa.ListResponse.Item
+	(*anypb.Any)(nil),               // 21: google.protobuf.Any
+	(*timestamppb.Timestamp)(nil),   // 22: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),     // 23: google.protobuf.Duration
 }
 var file_mpa_proto_depIdxs = []int32{
-	15, // 0: Mpa.Action.message:type_name -> google.protobuf.Any
-	15, // 1: Mpa.StoreRequest.message:type_name -> google.protobuf.Any
-	0,  // 2: Mpa.StoreResponse.action:type_name -> Mpa.Action
-	1,  // 3: Mpa.StoreResponse.approver:type_name -> Mpa.Principal
-	0,  // 4: Mpa.ApproveRequest.action:type_name -> Mpa.Action
-	14, // 5: Mpa.ListResponse.item:type_name -> Mpa.ListResponse.Item
-	0,  // 6: Mpa.GetResponse.action:type_name -> Mpa.Action
-	1,  // 7: Mpa.GetResponse.approver:type_name -> Mpa.Principal
-	0,  // 8: Mpa.ClearRequest.action:type_name -> Mpa.Action
-	0,  // 9: Mpa.ListResponse.Item.action:type_name -> Mpa.Action
-	1,  // 10: Mpa.ListResponse.Item.approver:type_name -> Mpa.Principal
-	2,  // 11: Mpa.Mpa.Store:input_type -> Mpa.StoreRequest
-	4,  // 12: Mpa.Mpa.Approve:input_type -> Mpa.ApproveRequest
-	6,  // 13: Mpa.Mpa.WaitForApproval:input_type -> Mpa.WaitForApprovalRequest
-	8,  // 14: Mpa.Mpa.List:input_type -> Mpa.ListRequest
-	10, // 15: Mpa.Mpa.Get:input_type -> Mpa.GetRequest
-	12, // 16: Mpa.Mpa.Clear:input_type -> Mpa.ClearRequest
-	3,  // 17: Mpa.Mpa.Store:output_type -> Mpa.StoreResponse
-	5,  // 18: Mpa.Mpa.Approve:output_type -> Mpa.ApproveResponse
-	7,  // 19: Mpa.Mpa.WaitForApproval:output_type -> Mpa.WaitForApprovalResponse
-	9,  // 20: Mpa.Mpa.List:output_type -> Mpa.ListResponse
-	11, // 21: Mpa.Mpa.Get:output_type -> Mpa.GetResponse
-	13, // 22: Mpa.Mpa.Clear:output_type -> Mpa.ClearResponse
-	17, // [17:23] is the sub-list for method output_type
-	11, // [11:17] is the sub-list for method input_type
-	11, // [11:11] is the sub-list for extension type_name
-	11, // [11:11] is the sub-list for extension extendee
-	0,  // [0:11] is the sub-list for field type_name
+	21, // 0: Mpa.Action.message:type_name -> google.protobuf.Any
+	5,  // 1: Mpa.Action.required_approvals:type_name -> Mpa.ApprovalSpec
+	1,  // 2: Mpa.Rejection.rejecter:type_name -> Mpa.Principal
+	22, // 3: Mpa.ApprovalLimits.expires_at:type_name -> google.protobuf.Timestamp
+	4,  // 4: Mpa.ApprovalSpec.requirement:type_name -> Mpa.ApprovalRequirement
+	21, // 5: Mpa.StoreRequest.message:type_name -> google.protobuf.Any
+	5,  // 6: Mpa.StoreRequest.required_approvals:type_name -> Mpa.ApprovalSpec
+	0,  // 7: Mpa.StoreResponse.action:type_name -> Mpa.Action
+	1,  // 8: Mpa.StoreResponse.approver:type_name -> Mpa.Principal
+	4,  // 9: Mpa.StoreResponse.missing_approvals:type_name -> Mpa.ApprovalRequirement
+	2,  // 10: Mpa.StoreResponse.rejection:type_name -> Mpa.Rejection
+	3,  // 11: Mpa.StoreResponse.limits:type_name -> Mpa.ApprovalLimits
+	0,  // 12: Mpa.ApproveRequest.action:type_name -> Mpa.Action
+	23, // 13: Mpa.ApproveRequest.valid_for:type_name -> google.protobuf.Duration
+	0,  // 14: Mpa.RejectRequest.action:type_name -> Mpa.Action
+	20, // 15: Mpa.ListResponse.item:type_name -> Mpa.ListResponse.Item
+	0,  // 16: Mpa.GetResponse.action:type_name -> Mpa.Action
+	1,  // 17: Mpa.GetResponse.approver:type_name -> Mpa.Principal
+	4,  // 18: Mpa.GetResponse.missing_approvals:type_name -> Mpa.ApprovalRequirement
+	2,  // 19: Mpa.GetResponse.rejection:type_name -> Mpa.Rejection
+	3,  // 20: Mpa.GetResponse.limits:type_name -> Mpa.ApprovalLimits
+	0,  // 21: Mpa.ClearRequest.action:type_name -> Mpa.Action
+	0,  // 22: Mpa.ListResponse.Item.action:type_name -> Mpa.Action
+	1,  // 23: Mpa.ListResponse.Item.approver:type_name -> Mpa.Principal
+	4,  // 24: Mpa.ListResponse.Item.missing_approvals:type_name -> Mpa.ApprovalRequirement
+	2,  // 25: Mpa.ListResponse.Item.rejection:type_name -> Mpa.Rejection
+	3,  // 26: Mpa.ListResponse.Item.limits:type_name -> Mpa.ApprovalLimits
+	6,  // 27: Mpa.Mpa.Store:input_type -> Mpa.StoreRequest
+	8,  // 28: Mpa.Mpa.Approve:input_type -> Mpa.ApproveRequest
+	10, // 29: Mpa.Mpa.Reject:input_type -> Mpa.RejectRequest
+	12, // 30: Mpa.Mpa.WaitForApproval:input_type -> Mpa.WaitForApprovalRequest
+	14, // 31: Mpa.Mpa.List:input_type -> Mpa.ListRequest
+	16, // 32: Mpa.Mpa.Get:input_type -> Mpa.GetRequest
+	18, // 33: Mpa.Mpa.Clear:input_type -> Mpa.ClearRequest
+	7,  // 34: Mpa.Mpa.Store:output_type -> Mpa.StoreResponse
+	9,  // 35: Mpa.Mpa.Approve:output_type -> Mpa.ApproveResponse
+	11, // 36: Mpa.Mpa.Reject:output_type -> Mpa.RejectResponse
+	13, // 37: Mpa.Mpa.WaitForApproval:output_type -> Mpa.WaitForApprovalResponse
+	15, // 38: Mpa.Mpa.List:output_type -> Mpa.ListResponse
+	17, // 39: Mpa.Mpa.Get:output_type -> Mpa.GetResponse
+	19, // 40: Mpa.Mpa.Clear:output_type -> Mpa.ClearResponse
+	34, // [34:41] is the sub-list for method output_type
+	27, // [27:34] is the sub-list for method input_type
+	27, // [27:27] is the sub-list for extension type_name
+	27, // [27:27] is the sub-list for extension extendee
+	0,  // [0:27] is the sub-list for field type_name
 }
 
 func init() { file_mpa_proto_init() }
@@ -982,7 +1545,7 @@ func file_mpa_proto_init() {
 			}
 		}
 		file_mpa_proto_msgTypes[2].Exporter = func(v any, i int) any {
-			switch v := v.(*StoreRequest); i {
+			switch v := v.(*Rejection); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -994,7 +1557,7 @@ func file_mpa_proto_init() {
 			}
 		}
 		file_mpa_proto_msgTypes[3].Exporter = func(v any, i int) any {
-			switch v := v.(*StoreResponse); i {
+			switch v := v.(*ApprovalLimits); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1006,7 +1569,7 @@ func file_mpa_proto_init() {
 			}
 		}
 		file_mpa_proto_msgTypes[4].Exporter = func(v any, i int) any {
-			switch v := v.(*ApproveRequest); i {
+			switch v := v.(*ApprovalRequirement); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1018,7 +1581,7 @@ func file_mpa_proto_init() {
 			}
 		}
 		file_mpa_proto_msgTypes[5].Exporter = func(v any, i int) any {
-			switch v := v.(*ApproveResponse); i {
+			switch v := v.(*ApprovalSpec); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1030,7 +1593,7 @@ func file_mpa_proto_init() {
 			}
 		}
 		file_mpa_proto_msgTypes[6].Exporter = func(v any, i int) any {
-			switch v := v.(*WaitForApprovalRequest); i {
+			switch v := v.(*StoreRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1042,7 +1605,7 @@ func file_mpa_proto_init() {
 			}
 		}
 		file_mpa_proto_msgTypes[7].Exporter = func(v any, i int) any {
-			switch v := v.(*WaitForApprovalResponse); i {
+			switch v := v.(*StoreResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1054,7 +1617,7 @@ func file_mpa_proto_init() {
 			}
 		}
 		file_mpa_proto_msgTypes[8].Exporter = func(v any, i int) any {
-			switch v := v.(*ListRequest); i {
+			switch v := v.(*ApproveRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1066,7 +1629,7 @@ func file_mpa_proto_init() {
 			}
 		}
 		file_mpa_proto_msgTypes[9].Exporter = func(v any, i int) any {
-			switch v := v.(*ListResponse); i {
+			switch v := v.(*ApproveResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1078,7 +1641,7 @@ func file_mpa_proto_init() {
 			}
 		}
 		file_mpa_proto_msgTypes[10].Exporter = func(v any, i int) any {
-			switch v := v.(*GetRequest); i {
+			switch v := v.(*RejectRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1090,7 +1653,7 @@ func file_mpa_proto_init() {
 			}
 		}
 		file_mpa_proto_msgTypes[11].Exporter = func(v any, i int) any {
-			switch v := v.(*GetResponse); i {
+			switch v := v.(*RejectResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1102,7 +1665,7 @@ func file_mpa_proto_init() {
 			}
 		}
 		file_mpa_proto_msgTypes[12].Exporter = func(v any, i int) any {
-			switch v := v.(*ClearRequest); i {
+			switch v := v.(*WaitForApprovalRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1114,7 +1677,7 @@ func file_mpa_proto_init() {
 			}
 		}
 		file_mpa_proto_msgTypes[13].Exporter = func(v any, i int) any {
-			switch v := v.(*ClearResponse); i {
+			switch v := v.(*WaitForApprovalResponse); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1126,6 +1689,78 @@ func file_mpa_proto_init() {
 			}
 		}
 		file_mpa_proto_msgTypes[14].Exporter = func(v any, i int) any {
+			switch v := v.(*ListRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mpa_proto_msgTypes[15].Exporter = func(v any, i int) any {
+			switch v := v.(*ListResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mpa_proto_msgTypes[16].Exporter = func(v any, i int) any {
+			switch v := v.(*GetRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mpa_proto_msgTypes[17].Exporter = func(v any, i int) any {
+			switch v := v.(*GetResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mpa_proto_msgTypes[18].Exporter = func(v any, i int) any {
+			switch v := v.(*ClearRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mpa_proto_msgTypes[19].Exporter = func(v any, i int) any {
+			switch v := v.(*ClearResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_mpa_proto_msgTypes[20].Exporter = func(v any, i int) any {
 			switch v := v.(*ListResponse_Item); i {
 			case 0:
 				return &v.state
@@ -1144,7 +1779,7 @@ func file_mpa_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_mpa_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   15,
+			NumMessages:   21,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -36,6 +36,7 @@ const _ = grpc.SupportPackageIsVersion9
 const (
 	Mpa_Store_FullMethodName           = "/Mpa.Mpa/Store"
 	Mpa_Approve_FullMethodName         = "/Mpa.Mpa/Approve"
+	Mpa_Reject_FullMethodName          = "/Mpa.Mpa/Reject"
 	Mpa_WaitForApproval_FullMethodName = "/Mpa.Mpa/WaitForApproval"
 	Mpa_List_FullMethodName            = "/Mpa.Mpa/List"
 	Mpa_Get_FullMethodName             = "/Mpa.Mpa/Get"
@@ -49,7 +50,8 @@ const (
 // The Mpa service definition
 type MpaClient interface {
 	// Store a request that we'd like to try running in the future. Requests
-	// are stored in-memory and older requests may be cleared automatically.
+	// are stored in-memory by default and older requests may be cleared
+	// automatically.
 	// This call is idempotent - requests from the same user with the same
 	// contents will return the same id.
 	//
@@ -62,7 +64,16 @@ type MpaClient interface {
 	// The user for the request is implicitly passed in via inspecting the
 	// peer of the RPC or via gRPC metadata.
 	Approve(ctx context.Context, in *ApproveRequest, opts ...grpc.CallOption) (*ApproveResponse, error)
-	// Block until at least one approval has been granted. This is used
+	// Reject a previously stored request, recording why. A rejected request
+	// can't be approved and any WaitForApproval calls for it fail with
+	// PermissionDenied. Like approvals, requests can be rejected by anybody
+	// but the original user that stored the request.
+	//
+	// The user for the request is implicitly passed in via inspecting the
+	// peer of the RPC or via gRPC metadata.
+	Reject(ctx context.Context, in *RejectRequest, opts ...grpc.CallOption) (*RejectResponse, error)
+	// Block until the request's approval requirements have been met, which
+	// by default means at least one approval has been granted. This is used
 	// as an optimization to avoid needing to poll for MPA approval.
 	WaitForApproval(ctx context.Context, in *WaitForApprovalRequest, opts ...grpc.CallOption) (*WaitForApprovalResponse, error)
 	// List available requests.
@@ -71,8 +82,8 @@ type MpaClient interface {
 	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*GetResponse, error)
 	// Clear a stored request.
 	//
-	// This is typically unnecessary due to how requests are stored in memory
-	// and forgotten after a process restart.
+	// This is typically unnecessary due to how older requests are cleared
+	// automatically.
 	Clear(ctx context.Context, in *ClearRequest, opts ...grpc.CallOption) (*ClearResponse, error)
 }
 
@@ -104,6 +115,16 @@ func (c *mpaClient) Approve(ctx context.Context, in *ApproveRequest, opts ...grp
 	return out, nil
 }
 
+func (c *mpaClient) Reject(ctx context.Context, in *RejectRequest, opts ...grpc.CallOption) (*RejectResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RejectResponse)
+	err := c.cc.Invoke(ctx, Mpa_Reject_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *mpaClient) WaitForApproval(ctx context.Context, in *WaitForApprovalRequest, opts ...grpc.CallOption) (*WaitForApprovalResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(WaitForApprovalResponse)
@@ -151,7 +172,8 @@ func (c *mpaClient) Clear(ctx context.Context, in *ClearRequest, opts ...grpc.Ca
 // The Mpa service definition
 type MpaServer interface {
 	// Store a request that we'd like to try running in the future. Requests
-	// are stored in-memory and older requests may be cleared automatically.
+	// are stored in-memory by default and older requests may be cleared
+	// automatically.
 	// This call is idempotent - requests from the same user with the same
 	// contents will return the same id.
 	//
@@ -164,7 +186,16 @@ type MpaServer interface {
 	// The user for the request is implicitly passed in via inspecting the
 	// peer of the RPC or via gRPC metadata.
 	Approve(context.Context, *ApproveRequest) (*ApproveResponse, error)
-	// Block until at least one approval has been granted. This is used
+	// Reject a previously stored request, recording why. A rejected request
+	// can't be approved and any WaitForApproval calls for it fail with
+	// PermissionDenied. Like approvals, requests can be rejected by anybody
+	// but the original user that stored the request.
+	//
+	// The user for the request is implicitly passed in via inspecting the
+	// peer of the RPC or via gRPC metadata.
+	Reject(context.Context, *RejectRequest) (*RejectResponse, error)
+	// Block until the request's approval requirements have been met, which
+	// by default means at least one approval has been granted. This is used
 	// as an optimization to avoid needing to poll for MPA approval.
 	WaitForApproval(context.Context, *WaitForApprovalRequest) (*WaitForApprovalResponse, error)
 	// List available requests.
@@ -173,8 +204,8 @@ type MpaServer interface {
 	Get(context.Context, *GetRequest) (*GetResponse, error)
 	// Clear a stored request.
 	//
-	// This is typically unnecessary due to how requests are stored in memory
-	// and forgotten after a process restart.
+	// This is typically unnecessary due to how older requests are cleared
+	// automatically.
 	Clear(context.Context, *ClearRequest) (*ClearResponse, error)
 }
 
@@ -191,6 +222,9 @@ func (UnimplementedMpaServer) Store(context.Context, *StoreRequest) (*StoreRespo
 func (UnimplementedMpaServer) Approve(context.Context, *ApproveRequest) (*ApproveResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Approve not implemented")
 }
+func (UnimplementedMpaServer) Reject(context.Context, *RejectRequest) (*RejectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Reject not implemented")
+}
 func (UnimplementedMpaServer) WaitForApproval(context.Context, *WaitForApprovalRequest) (*WaitForApprovalResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method WaitForApproval not implemented")
 }
@@ -259,6 +293,24 @@ func _Mpa_Approve_Handler(srv interface{}, ctx context.Context, dec func(interfa
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Mpa_Reject_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RejectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MpaServer).Reject(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Mpa_Reject_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MpaServer).Reject(ctx, req.(*RejectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Mpa_WaitForApproval_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(WaitForApprovalRequest)
 	if err := dec(in); err != nil {
@@ -346,6 +398,10 @@ var Mpa_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Approve",
 			Handler:    _Mpa_Approve_Handler,
 		},
+		{
+			MethodName: "Reject",
+			Handler:    _Mpa_Reject_Handler,
+		},
 		{
 			MethodName: "WaitForApproval",
 			Handler:    _Mpa_WaitForApproval_Handler,
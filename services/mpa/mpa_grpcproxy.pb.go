@@ -20,6 +20,7 @@ type MpaClientProxy interface {
 	MpaClient
 	StoreOneMany(ctx context.Context, in *StoreRequest, opts ...grpc.CallOption) (<-chan *StoreManyResponse, error)
 	ApproveOneMany(ctx context.Context, in *ApproveRequest, opts ...grpc.CallOption) (<-chan *ApproveManyResponse, error)
+	RejectOneMany(ctx context.Context, in *RejectRequest, opts ...grpc.CallOption) (<-chan *RejectManyResponse, error)
 	WaitForApprovalOneMany(ctx context.Context, in *WaitForApprovalRequest, opts ...grpc.CallOption) (<-chan *WaitForApprovalManyResponse, error)
 	ListOneMany(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (<-chan *ListManyResponse, error)
 	GetOneMany(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (<-chan *GetManyResponse, error)
@@ -171,6 +172,73 @@ func (c *mpaClientProxy) ApproveOneMany(ctx context.Context, in *ApproveRequest,
 	return ret, nil
 }
 
+// RejectManyResponse encapsulates a proxy data packet.
+// It includes the target, index, response and possible error returned.
+type RejectManyResponse struct {
+	Target string
+	// As targets can be duplicated this is the index into the slice passed to proxy.Conn.
+	Index int
+	Resp  *RejectResponse
+	Error error
+}
+
+// RejectOneMany provides the same API as Reject but sends the same request to N destinations at once.
+// N can be a single destination.
+//
+// NOTE: The returned channel must be read until it closes in order to avoid leaking goroutines.
+func (c *mpaClientProxy) RejectOneMany(ctx context.Context, in *RejectRequest, opts ...grpc.CallOption) (<-chan *RejectManyResponse, error) {
+	conn := c.cc.(*proxy.Conn)
+	ret := make(chan *RejectManyResponse)
+	// If this is a single case we can just use Invoke and marshal it onto the channel once and be done.
+	if len(conn.Targets) == 1 {
+		go func() {
+			out := &RejectManyResponse{
+				Target: conn.Targets[0],
+				Index:  0,
+				Resp:   &RejectResponse{},
+			}
+			err := conn.Invoke(ctx, "/Mpa.Mpa/Reject", in, out.Resp, opts...)
+			if err != nil {
+				out.Error = err
+			}
+			// Send and close.
+			ret <- out
+			close(ret)
+		}()
+		return ret, nil
+	}
+	manyRet, err := conn.InvokeOneMany(ctx, "/Mpa.Mpa/Reject", in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// A goroutine to retrive untyped responses and convert them to typed ones.
+	go func() {
+		for {
+			typedResp := &RejectManyResponse{
+				Resp: &RejectResponse{},
+			}
+
+			resp, ok := <-manyRet
+			if !ok {
+				// All done so we can shut down.
+				close(ret)
+				return
+			}
+			typedResp.Target = resp.Target
+			typedResp.Index = resp.Index
+			typedResp.Error = resp.Error
+			if resp.Error == nil {
+				if err := resp.Resp.UnmarshalTo(typedResp.Resp); err != nil {
+					typedResp.Error = fmt.Errorf("can't decode any response - %v. Original Error - %v", err, resp.Error)
+				}
+			}
+			ret <- typedResp
+		}
+	}()
+
+	return ret, nil
+}
+
 // WaitForApprovalManyResponse encapsulates a proxy data packet.
 // It includes the target, index, response and possible error returned.
 type WaitForApprovalManyResponse struct {
@@ -23,7 +23,9 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
 	"github.com/Snowflake-Labs/sansshell/proxy/auth/proxiedidentity"
@@ -32,7 +34,9 @@ import (
 	"github.com/Snowflake-Labs/sansshell/services/util"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoregistry"
@@ -46,6 +50,46 @@ const (
 	reqMPAKey = "sansshell-mpa-request-id"
 )
 
+var (
+	firstMessageMu sync.RWMutex
+	// firstMessageMethods holds the client streaming methods whose
+	// approvals only cover the first message of each stream.
+	firstMessageMethods = make(map[string]bool)
+)
+
+// ApproveFirstMessageOnly makes approvals for method, a client streaming
+// method, cover only the first message of each stream. Later messages are
+// authorized without approvers. For other methods every message of a
+// stream must match the approved action, so streams of more than one
+// message can't be approved. Service packages typically call this during
+// init() for the methods they implement. A proxy only knows about methods
+// registered by the packages linked into it.
+func ApproveFirstMessageOnly(method string) {
+	firstMessageMu.Lock()
+	defer firstMessageMu.Unlock()
+	firstMessageMethods[method] = true
+}
+
+// ApproveEveryMessage undoes ApproveFirstMessageOnly for method, so every
+// message of its streams must match the approved action again.
+func ApproveEveryMessage(method string) {
+	firstMessageMu.Lock()
+	defer firstMessageMu.Unlock()
+	delete(firstMessageMethods, method)
+}
+
+// AfterApprovedMessage returns true if ctx is for a message after the first
+// of a stream of method, and method was registered with
+// ApproveFirstMessageOnly. MPA hooks leave such messages alone.
+func AfterApprovedMessage(ctx context.Context, method string) bool {
+	if rpcauth.StreamMessageIndex(ctx) == 0 {
+		return false
+	}
+	firstMessageMu.RLock()
+	defer firstMessageMu.RUnlock()
+	return firstMessageMethods[method]
+}
+
 // WithMPAInMetadata adds a MPA ID to the grpc metadata of an outgoing RPC call
 func WithMPAInMetadata(ctx context.Context, mpaID string) context.Context {
 	return metadata.AppendToOutgoingContext(ctx, reqMPAKey, mpaID)
@@ -64,6 +108,93 @@ func MPAFromIncomingContext(ctx context.Context) (mpaID string, ok bool) {
 	return v[0], true
 }
 
+type requiredApprovalsKey struct{}
+
+// WithRequiredApprovals returns a context that makes the MPA client
+// interceptors ask for the given approvals when creating MPA requests.
+func WithRequiredApprovals(ctx context.Context, spec *mpa.ApprovalSpec) context.Context {
+	return context.WithValue(ctx, requiredApprovalsKey{}, spec)
+}
+
+func requiredApprovalsFromContext(ctx context.Context) *mpa.ApprovalSpec {
+	spec, _ := ctx.Value(requiredApprovalsKey{}).(*mpa.ApprovalSpec)
+	return spec
+}
+
+// ParseApprovalSpec parses a comma separated list of requirements of the
+// form COUNT[:GROUP]. For example, "2:sre" requires two approvers from the
+// sre group and "1:sre,1:owners" requires one approver from each group.
+func ParseApprovalSpec(s string) (*mpa.ApprovalSpec, error) {
+	spec := &mpa.ApprovalSpec{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		countStr, group, _ := strings.Cut(part, ":")
+		count, err := strconv.ParseInt(countStr, 10, 32)
+		if err != nil || count <= 0 {
+			return nil, fmt.Errorf("invalid approval requirement %q: count must be a positive integer", part)
+		}
+		spec.Requirement = append(spec.Requirement, &mpa.ApprovalRequirement{
+			Count: int32(count),
+			Group: group,
+		})
+	}
+	if len(spec.Requirement) == 0 {
+		return nil, fmt.Errorf("no approval requirements in %q", s)
+	}
+	return spec, nil
+}
+
+// DescribeRequirements renders approval requirements for humans, such as
+// "2 from sre, 1 from anyone".
+func DescribeRequirements(reqs []*mpa.ApprovalRequirement) string {
+	var out []string
+	for _, r := range reqs {
+		group := r.Group
+		if group == "" {
+			group = "anyone"
+		}
+		out = append(out, fmt.Sprintf("%d from %s", r.Count, group))
+	}
+	return strings.Join(out, ", ")
+}
+
+// rejectionPrefix starts the message of every error from RejectionError.
+const rejectionPrefix = "MPA request rejected by "
+
+// RejectionError returns the error given to requestors of a rejected request.
+func RejectionError(r *mpa.Rejection) error {
+	return status.Errorf(codes.PermissionDenied, "%s%v: %v", rejectionPrefix, r.GetRejecter().GetId(), r.GetReason())
+}
+
+// isRejection returns true if err was created by RejectionError, as opposed
+// to being some other PermissionDenied error such as an authz denial.
+func isRejection(err error) bool {
+	s := status.Convert(err)
+	return s.Code() == codes.PermissionDenied && strings.HasPrefix(s.Message(), rejectionPrefix)
+}
+
+// RejectionAuthInput converts a rejection into its RPCAuthInput form.
+func RejectionAuthInput(r *mpa.Rejection) *rpcauth.RejectionAuthInput {
+	out := &rpcauth.RejectionAuthInput{Reason: r.GetReason()}
+	if r.GetRejecter() != nil {
+		out.Rejecter = &rpcauth.PrincipalAuthInput{
+			ID:     r.Rejecter.Id,
+			Groups: r.Rejecter.Groups,
+		}
+	}
+	return out
+}
+
+// needsApproval returns true if a stored request is still waiting on approvals.
+// Servers predating approval requirements never report missing approvals,
+// so a request without any approvers is treated as pending too.
+func needsApproval(resp *mpa.StoreResponse) bool {
+	return len(resp.Approver) == 0 || len(resp.MissingApprovals) > 0
+}
+
 // ActionMatchesInput returns an error if an MPA action doesn't match the
 // message being checked in the RPCAuthInput.
 func ActionMatchesInput(ctx context.Context, action *mpa.Action, input *rpcauth.RPCAuthInput) error {
@@ -101,6 +232,9 @@ func ActionMatchesInput(ctx context.Context, action *mpa.Action, input *rpcauth.
 		Method:        input.Method,
 		Justification: justification,
 		Message:       &msg,
+		// Approval requirements don't change what the request does, so
+		// they aren't part of the comparison.
+		RequiredApprovals: action.RequiredApprovals,
 	}
 	// Make sure to use an any-proto-aware comparison
 	if !cmp.Equal(action, sentAct, protocmp.Transform()) {
@@ -122,15 +256,22 @@ func createAndBlockOnSingleTargetMPA(ctx context.Context, method string, req any
 
 	mpaClient := mpa.NewMpaClient(cc)
 	result, err := mpaClient.Store(ctx, &mpa.StoreRequest{
-		Method:  method,
-		Message: &msg,
+		Method:            method,
+		Message:           &msg,
+		RequiredApprovals: requiredApprovalsFromContext(ctx),
 	})
 	if err != nil {
 		return "", err
 	}
-	if len(result.Approver) == 0 {
+	if result.Rejection != nil {
+		return "", RejectionError(result.Rejection)
+	}
+	if needsApproval(result) {
 		fmt.Fprintln(os.Stderr, "Multi party auth requested, ask an approver to run:")
 		fmt.Fprintf(os.Stderr, "  sanssh -targets %v mpa approve %v\n", cc.Target(), result.Id)
+		if len(result.MissingApprovals) > 0 {
+			fmt.Fprintf(os.Stderr, "Approvals needed: %v\n", DescribeRequirements(result.MissingApprovals))
+		}
 		_, err := mpaClient.WaitForApproval(ctx, &mpa.WaitForApprovalRequest{Id: result.Id})
 		if err != nil {
 			return "", err
@@ -244,22 +385,28 @@ func createAndBlockOnProxiedMPA(ctx context.Context, method string, args any, co
 	}
 	mpaClient := mpa.NewMpaClientProxy(conn)
 	ch, err := mpaClient.StoreOneMany(ctx, &mpa.StoreRequest{
-		Method:  method,
-		Message: &msg,
+		Method:            method,
+		Message:           &msg,
+		RequiredApprovals: requiredApprovalsFromContext(ctx),
 	})
 	if err != nil {
 		return "", err
 	}
 	mpaIdToTargets := make(map[string][]string)
 	var targetsNeedingApproval []string
+	var missing []*mpa.ApprovalRequirement
 	for r := range ch {
 		if r.Error != nil {
 			fmt.Fprintf(state.Err[r.Index], "Unable to request MPA: %v\n", r.Error)
 		}
+		if r.Resp.GetRejection() != nil {
+			return "", RejectionError(r.Resp.Rejection)
+		}
 		mpaIdToTargets[r.Resp.Id] = append(mpaIdToTargets[r.Resp.Id], r.Target)
-		if len(r.Resp.Approver) == 0 {
+		if needsApproval(r.Resp) {
 			// Only print out messages for not-yet-approved requests
 			targetsNeedingApproval = append(targetsNeedingApproval, r.Target)
+			missing = r.Resp.MissingApprovals
 		}
 		mpaID = r.Resp.Id
 	}
@@ -280,16 +427,26 @@ func createAndBlockOnProxiedMPA(ctx context.Context, method string, args any, co
 	if len(targetsNeedingApproval) > 0 {
 		fmt.Fprintln(os.Stderr, "Waiting for multi-party approval on all targets, ask an approver to run:")
 		fmt.Fprintf(os.Stderr, "  sanssh -proxy %v -targets %v mpa approve %v\n", conn.Proxy().Target(), strings.Join(targetsNeedingApproval, ","), mpaID)
+		if len(missing) > 0 {
+			fmt.Fprintf(os.Stderr, "Approvals needed: %v\n", DescribeRequirements(missing))
+		}
 		// We call WaitForApproval on all targets, even ones already approved. This is silly but not harmful.
 		waitCh, err := mpaClient.WaitForApprovalOneMany(ctx, &mpa.WaitForApprovalRequest{Id: mpaID})
 		if err != nil {
 			return "", err
 		}
+		var rejected error
 		for r := range waitCh {
 			if r.Error != nil {
 				fmt.Fprintf(state.Err[r.Index], "Error when waiting for MPA approval: %v\n", r.Error)
+				if isRejection(r.Error) {
+					rejected = r.Error
+				}
 			}
 		}
+		if rejected != nil {
+			return "", rejected
+		}
 	}
 	return mpaID, nil
 }
@@ -353,6 +510,9 @@ func ProxyMPAAuthzHook() rpcauth.RPCAuthzHook {
 			// If there's no host, we can't call out to the host.
 			return nil
 		}
+		if AfterApprovedMessage(ctx, input.Method) {
+			return nil
+		}
 
 		client := mpa.NewMpaClient(input.TargetConn)
 		resp, err := client.Get(ctx, &mpa.GetRequest{Id: mpaID})
@@ -363,6 +523,16 @@ func ProxyMPAAuthzHook() rpcauth.RPCAuthzHook {
 		if err := ActionMatchesInput(ctx, resp.Action, input); err != nil {
 			return err
 		}
+		if resp.Rejection != nil {
+			// Surface the rejection so that policies can act on it, but never
+			// treat a rejected request as approved.
+			input.Rejection = RejectionAuthInput(resp.Rejection)
+			return nil
+		}
+		if len(resp.MissingApprovals) > 0 {
+			// Approvals only count once the action's requirements are met.
+			return nil
+		}
 		for _, a := range resp.Approver {
 			input.Approvers = append(input.Approvers, &rpcauth.PrincipalAuthInput{
 				ID:     a.Id,
@@ -18,15 +18,21 @@ package mpahooks_test
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
 	"fmt"
 	"github.com/Snowflake-Labs/sansshell/auth/opa"
 	"io"
 	"log"
 	"net"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/Snowflake-Labs/sansshell/auth/jwt"
 	"github.com/Snowflake-Labs/sansshell/auth/mtls"
 	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
 	"github.com/Snowflake-Labs/sansshell/proxy/proxy"
@@ -38,12 +44,15 @@ import (
 	"github.com/Snowflake-Labs/sansshell/services/mpa/mpahooks"
 	"github.com/Snowflake-Labs/sansshell/services/util"
 	"github.com/Snowflake-Labs/sansshell/telemetry"
+	"github.com/go-jose/go-jose/v4"
+	josejwt "github.com/go-jose/go-jose/v4/jwt"
 	"github.com/go-logr/logr"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/emptypb"
 
@@ -173,6 +182,39 @@ func TestActionMatchesInput(t *testing.T) {
 	}
 }
 
+func TestParseApprovalSpec(t *testing.T) {
+	for _, tc := range []struct {
+		in      string
+		want    *mpa.ApprovalSpec
+		wantErr bool
+	}{
+		{
+			in:   "2",
+			want: &mpa.ApprovalSpec{Requirement: []*mpa.ApprovalRequirement{{Count: 2}}},
+		},
+		{
+			in: "1:sre, 1:owners",
+			want: &mpa.ApprovalSpec{Requirement: []*mpa.ApprovalRequirement{
+				{Count: 1, Group: "sre"},
+				{Count: 1, Group: "owners"},
+			}},
+		},
+		{in: "", wantErr: true},
+		{in: "0:sre", wantErr: true},
+		{in: "sre", wantErr: true},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := mpahooks.ParseApprovalSpec(tc.in)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("got error %v, wantErr %v", err, tc.wantErr)
+			}
+			if !proto.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
 func pollForAction(ctx context.Context, m mpa.MpaClient, method string) (*mpa.Action, error) {
 	for {
 		l, err := m.List(ctx, &mpa.ListRequest{})
@@ -571,3 +613,199 @@ func TestProxiedClientInterceptors(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+var bearerTokenProxyPolicy = `
+package sansshell.authz
+
+default allow = false
+
+allow {
+	input.method = "/HealthCheck.HealthCheck/Ok"
+	input.peer.principal.id = "alice"
+	input.approvers[_].id = "bob"
+}
+
+allow {
+	startswith(input.method, "/Mpa.Mpa/")
+}
+
+allow {
+	input.method = "/Proxy.Proxy/Proxy"
+}
+`
+
+// writeTokens signs a token for each of subs with a new key, returning the
+// path of a JSON Web Key Set holding the key and the paths of the tokens.
+func writeTokens(t *testing.T, subs ...string) (jwksPath string, tokenPaths []string) {
+	t.Helper()
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: &key.PublicKey, KeyID: "test", Algorithm: string(jose.ES256), Use: "sig"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwksPath = filepath.Join(dir, "jwks.json")
+	if err := os.WriteFile(jwksPath, set, 0644); err != nil {
+		t.Fatal(err)
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: jose.JSONWebKey{Key: key, KeyID: "test"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sub := range subs {
+		token, err := josejwt.Signed(signer).Claims(map[string]any{"sub": sub, "exp": time.Now().Add(time.Hour).Unix()}).Serialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, sub)
+		if err := os.WriteFile(path, []byte(token), 0600); err != nil {
+			t.Fatal(err)
+		}
+		tokenPaths = append(tokenPaths, path)
+	}
+	return jwksPath, tokenPaths
+}
+
+func TestProxiedBearerToken(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rot, err := mtls.LoadRootOfTrust("../../../auth/mtls/testdata/root.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyLis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srvAddr := lis.Addr().String()
+	proxyAddr := proxyLis.Addr().String()
+	authz, err := opa.NewOpaRPCAuthorizer(ctx, serverBehindProxyPolicy, rpcauth.PeerPrincipalFromCertHook(), mpaserver.ServerMPAAuthzHook())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srvCreds, err := mtls.LoadServerTLS("../../../auth/mtls/testdata/leaf.pem", "../../../auth/mtls/testdata/leaf.key", rot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer(
+		grpc.ChainStreamInterceptor(authz.AuthorizeStream),
+		grpc.ChainUnaryInterceptor(authz.Authorize),
+		grpc.Creds(srvCreds),
+	)
+	for _, svc := range services.ListServices() {
+		svc.Register(s)
+	}
+	go func() {
+		if err := s.Serve(lis); err != nil {
+			log.Fatalf("Server exited with error: %v", err)
+		}
+	}()
+	defer s.GracefulStop()
+
+	// The requester and approver share a client certificate, so only their
+	// tokens tell them apart.
+	jwksPath, tokens := writeTokens(t, "alice", "bob")
+	verifier, err := jwt.NewVerifier(jwksPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyAuthz, err := opa.NewOpaRPCAuthorizer(ctx, bearerTokenProxyPolicy, rpcauth.PeerPrincipalFromCertHook(), verifier.PeerPrincipalHook(), mpahooks.ProxyMPAAuthzHook())
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyCreds, err := mtls.LoadServerTLS("../testdata/proxy.pem", "../testdata/proxy.key", rot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxyClientCreds, err := mtls.LoadClientTLS("../testdata/proxy.pem", "../testdata/proxy.key", rot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxySrv := grpc.NewServer(
+		grpc.Creds(proxyCreds),
+		grpc.ChainUnaryInterceptor(verifier.UnaryServerInterceptor(), proxyAuthz.Authorize),
+		grpc.ChainStreamInterceptor(verifier.StreamServerInterceptor(), proxyAuthz.AuthorizeStream),
+	)
+	proxyserver := proxyserver.New(
+		proxyserver.NewDialer(grpc.WithTransportCredentials(proxyClientCreds)),
+		proxyAuthz,
+	)
+	proxyserver.Register(proxySrv)
+	go func() {
+		if err := proxySrv.Serve(proxyLis); err != nil {
+			log.Fatalf("Server exited with error: %v", err)
+		}
+	}()
+	defer proxySrv.GracefulStop()
+
+	clientCreds, err := mtls.LoadClientTLS("../../../auth/mtls/testdata/client.pem", "../../../auth/mtls/testdata/client.key", rot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var g errgroup.Group
+	g.Go(func() error {
+		conn, err := proxy.DialContext(ctx, proxyAddr, []string{srvAddr},
+			grpc.WithTransportCredentials(clientCreds),
+			grpc.WithPerRPCCredentials(jwt.NewTokenFileCredentials(tokens[1])),
+		)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		m := mpa.NewMpaClientProxy(conn)
+
+		action, err := pollForAction(ctx, m, "/HealthCheck.HealthCheck/Ok")
+		if err != nil {
+			return err
+		}
+		if action.User != "alice" {
+			return fmt.Errorf("action requested by %q, want alice", action.User)
+		}
+		if _, err := m.Approve(ctx, &mpa.ApproveRequest{Action: action}); err != nil {
+			return fmt.Errorf("unable to approve %v: %v", action, err)
+		}
+		return nil
+	})
+
+	conn, err := proxy.DialContext(ctx, proxyAddr, []string{srvAddr},
+		grpc.WithTransportCredentials(clientCreds),
+		grpc.WithPerRPCCredentials(jwt.NewTokenFileCredentials(tokens[0])),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	state := &util.ExecuteState{
+		Conn: conn,
+		Out:  []io.Writer{os.Stdout},
+		Err:  []io.Writer{os.Stderr},
+	}
+	conn.StreamInterceptors = []proxy.StreamInterceptor{
+		mpahooks.ProxyClientStreamInterceptor(state),
+	}
+	conn.UnaryInterceptors = []proxy.UnaryInterceptor{
+		mpahooks.ProxyClientUnaryInterceptor(state),
+	}
+	if _, err := healthcheck.NewHealthCheckClientProxy(conn).Ok(ctx, &emptypb.Empty{}); err != nil {
+		t.Error(err)
+	}
+	if err := g.Wait(); err != nil {
+		t.Error(err)
+	}
+
+	// MPA approvals are stored in a global singleton, so let's clear them
+	// to prevent interference with other tests
+	conn.StreamInterceptors = nil
+	conn.UnaryInterceptors = nil
+	if err := clearAll(ctx, mpa.NewMpaClientProxy(conn)); err != nil {
+		t.Error(err)
+	}
+}
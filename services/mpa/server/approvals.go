@@ -0,0 +1,171 @@
+/* Copyright (c) 2023 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Snowflake-Labs/sansshell/services/mpa"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// defaultRequirements is used for actions without an explicit ApprovalSpec.
+var defaultRequirements = []*mpa.ApprovalRequirement{{Count: 1}}
+
+// maxRequiredApprovals bounds the total approvals a spec can ask for so
+// that matching stays cheap.
+const maxRequiredApprovals = 100
+
+// validateSpec returns an error if spec can't be satisfied or is unreasonable.
+func validateSpec(spec *mpa.ApprovalSpec) error {
+	var total int32
+	for _, r := range spec.GetRequirement() {
+		if r.Count <= 0 {
+			return fmt.Errorf("approval requirement count must be positive, got %d", r.Count)
+		}
+		total += r.Count
+		if total > maxRequiredApprovals {
+			return fmt.Errorf("approval spec can require at most %d approvals", maxRequiredApprovals)
+		}
+	}
+	return nil
+}
+
+func inGroup(p *mpa.Principal, group string) bool {
+	if group == "" {
+		return true
+	}
+	for _, g := range p.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// missingApprovals returns the requirements of spec which aren't met by
+// approvers, with counts reduced to the number of approvals still needed.
+// Each approver fills at most one slot, so this computes a maximum bipartite
+// matching between approvers and the slots required by spec.
+func missingApprovals(spec *mpa.ApprovalSpec, approvers []*mpa.Principal) []*mpa.ApprovalRequirement {
+	reqs := spec.GetRequirement()
+	if len(reqs) == 0 {
+		reqs = defaultRequirements
+	}
+
+	// Expand requirements into one slot per needed approval.
+	var slots []int
+	for i, r := range reqs {
+		for j := int32(0); j < r.Count; j++ {
+			slots = append(slots, i)
+		}
+	}
+	// slotOwner[s] is the index of the approver filling slot s, or -1.
+	slotOwner := make([]int, len(slots))
+	for i := range slotOwner {
+		slotOwner[i] = -1
+	}
+
+	// assign tries to place approver a into some slot, displacing existing
+	// owners onto other slots when possible.
+	var assign func(a int, visited []bool) bool
+	assign = func(a int, visited []bool) bool {
+		for s, r := range slots {
+			if visited[s] || !inGroup(approvers[a], reqs[r].Group) {
+				continue
+			}
+			visited[s] = true
+			if slotOwner[s] == -1 || assign(slotOwner[s], visited) {
+				slotOwner[s] = a
+				return true
+			}
+		}
+		return false
+	}
+	for a := range approvers {
+		assign(a, make([]bool, len(slots)))
+	}
+
+	unfilled := make([]int32, len(reqs))
+	for s, r := range slots {
+		if slotOwner[s] == -1 {
+			unfilled[r]++
+		}
+	}
+	var missing []*mpa.ApprovalRequirement
+	for i, r := range reqs {
+		if unfilled[i] > 0 {
+			missing = append(missing, &mpa.ApprovalRequirement{
+				Count: unfilled[i],
+				Group: r.Group,
+			})
+		}
+	}
+	return missing
+}
+
+// validateLimits returns an error if the limits requested by an approver
+// are nonsensical.
+func validateLimits(validFor *durationpb.Duration, maxUses int32) error {
+	if validFor != nil {
+		if err := validFor.CheckValid(); err != nil {
+			return fmt.Errorf("invalid approval duration: %v", err)
+		}
+		if validFor.AsDuration() <= 0 {
+			return fmt.Errorf("approval duration must be positive, got %v", validFor.AsDuration())
+		}
+	}
+	if maxUses < 0 {
+		return fmt.Errorf("approval max uses can't be negative, got %d", maxUses)
+	}
+	return nil
+}
+
+// tightenLimits merges the limits asked for by a new approver into l,
+// keeping whichever expiry and use count is more restrictive. It returns
+// nil if there are no limits at all.
+func tightenLimits(l *mpa.ApprovalLimits, now time.Time, validFor *durationpb.Duration, maxUses int32) *mpa.ApprovalLimits {
+	if validFor == nil && maxUses == 0 {
+		return l
+	}
+	if l == nil {
+		l = &mpa.ApprovalLimits{}
+	}
+	if validFor != nil {
+		expiry := now.Add(validFor.AsDuration())
+		if l.ExpiresAt == nil || expiry.Before(l.ExpiresAt.AsTime()) {
+			l.ExpiresAt = timestamppb.New(expiry)
+		}
+	}
+	if maxUses > 0 && (l.MaxUses == 0 || maxUses < l.MaxUses) {
+		l.MaxUses = maxUses
+	}
+	return l
+}
+
+// limitsReached returns true if approvals bounded by l can no longer be used.
+func limitsReached(l *mpa.ApprovalLimits, now time.Time) bool {
+	if l == nil {
+		return false
+	}
+	if l.ExpiresAt != nil && !now.Before(l.ExpiresAt.AsTime()) {
+		return true
+	}
+	return l.MaxUses > 0 && l.Uses >= l.MaxUses
+}
@@ -0,0 +1,143 @@
+/* Copyright (c) 2023 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Snowflake-Labs/sansshell/services/mpa"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestMissingApprovals(t *testing.T) {
+	sre := &mpa.Principal{Id: "sre1", Groups: []string{"sre"}}
+	sre2 := &mpa.Principal{Id: "sre2", Groups: []string{"sre"}}
+	owner := &mpa.Principal{Id: "owner", Groups: []string{"owners"}}
+	both := &mpa.Principal{Id: "both", Groups: []string{"owners", "sre"}}
+	nobody := &mpa.Principal{Id: "nobody"}
+
+	sreAndOwner := &mpa.ApprovalSpec{Requirement: []*mpa.ApprovalRequirement{
+		{Count: 1, Group: "sre"},
+		{Count: 1, Group: "owners"},
+	}}
+	for _, tc := range []struct {
+		desc      string
+		spec      *mpa.ApprovalSpec
+		approvers []*mpa.Principal
+		want      []*mpa.ApprovalRequirement
+	}{
+		{
+			desc: "default spec without approvals",
+			want: []*mpa.ApprovalRequirement{{Count: 1}},
+		},
+		{
+			desc:      "default spec with an approval",
+			approvers: []*mpa.Principal{nobody},
+		},
+		{
+			desc:      "two from a group with one approval",
+			spec:      &mpa.ApprovalSpec{Requirement: []*mpa.ApprovalRequirement{{Count: 2, Group: "sre"}}},
+			approvers: []*mpa.Principal{sre, owner},
+			want:      []*mpa.ApprovalRequirement{{Count: 1, Group: "sre"}},
+		},
+		{
+			desc:      "two from a group",
+			spec:      &mpa.ApprovalSpec{Requirement: []*mpa.ApprovalRequirement{{Count: 2, Group: "sre"}}},
+			approvers: []*mpa.Principal{sre, sre2},
+		},
+		{
+			desc:      "one approver can't fill two requirements",
+			spec:      sreAndOwner,
+			approvers: []*mpa.Principal{both},
+			want:      []*mpa.ApprovalRequirement{{Count: 1, Group: "owners"}},
+		},
+		{
+			desc:      "approvers are rearranged to satisfy requirements",
+			spec:      sreAndOwner,
+			approvers: []*mpa.Principal{both, sre},
+		},
+		{
+			desc:      "ungrouped approvers don't count for groups",
+			spec:      sreAndOwner,
+			approvers: []*mpa.Principal{nobody, sre},
+			want:      []*mpa.ApprovalRequirement{{Count: 1, Group: "owners"}},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := missingApprovals(tc.spec, tc.approvers)
+			if diff := cmp.Diff(tc.want, got, protocmp.Transform()); diff != "" {
+				t.Errorf("unexpected diff (-want +got):\n%v", diff)
+			}
+		})
+	}
+}
+
+func TestValidateSpec(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		spec    *mpa.ApprovalSpec
+		wantErr bool
+	}{
+		{desc: "nil spec"},
+		{desc: "valid spec", spec: &mpa.ApprovalSpec{Requirement: []*mpa.ApprovalRequirement{{Count: 2, Group: "sre"}}}},
+		{desc: "zero count", spec: &mpa.ApprovalSpec{Requirement: []*mpa.ApprovalRequirement{{Group: "sre"}}}, wantErr: true},
+		{desc: "too many approvals", spec: &mpa.ApprovalSpec{Requirement: []*mpa.ApprovalRequirement{{Count: maxRequiredApprovals + 1}}}, wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := validateSpec(tc.spec)
+			if got := err != nil; got != tc.wantErr {
+				t.Errorf("got error %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := tightenLimits(nil, now, nil, 0)
+	if l != nil {
+		t.Fatalf("got %v, want no limits", l)
+	}
+	l = tightenLimits(l, now, durationpb.New(time.Hour), 5)
+	l = tightenLimits(l, now, durationpb.New(2*time.Hour), 3)
+	l = tightenLimits(l, now, nil, 10)
+	want := &mpa.ApprovalLimits{ExpiresAt: timestamppb.New(now.Add(time.Hour)), MaxUses: 3}
+	if diff := cmp.Diff(want, l, protocmp.Transform()); diff != "" {
+		t.Errorf("unexpected diff (-want +got):\n%v", diff)
+	}
+
+	for _, tc := range []struct {
+		desc   string
+		limits *mpa.ApprovalLimits
+		at     time.Time
+		want   bool
+	}{
+		{desc: "no limits", at: now},
+		{desc: "before expiry", limits: want, at: now.Add(time.Minute)},
+		{desc: "at expiry", limits: want, at: now.Add(time.Hour), want: true},
+		{desc: "uses left", limits: &mpa.ApprovalLimits{MaxUses: 2, Uses: 1}, at: now},
+		{desc: "used up", limits: &mpa.ApprovalLimits{MaxUses: 2, Uses: 2}, at: now, want: true},
+	} {
+		if got := limitsReached(tc.limits, tc.at); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.desc, got, tc.want)
+		}
+	}
+}
@@ -20,6 +20,7 @@ package server
 import (
 	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"reflect"
 	"sort"
@@ -48,36 +49,76 @@ var (
 )
 
 // ServerMPAAuthzHook populates approver information based on an internal MPA store.
+// A use of the approvals is only recorded once policy allows the request.
 func ServerMPAAuthzHook() rpcauth.RPCAuthzHook {
-	return rpcauth.RPCAuthzHookFunc(func(ctx context.Context, input *rpcauth.RPCAuthInput) error {
-		mpaID, ok := mpahooks.MPAFromIncomingContext(ctx)
-		if !ok {
-			// Nothing to look up if MPA wasn't requested
-			return nil
-		}
-		resp, err := serverSingleton.Get(ctx, &mpa.GetRequest{Id: mpaID})
-		if err != nil {
-			return err
-		}
-		if resp.Action.Method != input.Method {
-			// Proxies may make extra calls to the server as part of authz hooks. If
-			// we get an MPA id that corresponds to a different method than the one
-			// being called, it's probably from the proxy and can be ignored.
-			// The right method but wrong args indicates a bigger issue and checked below.
-			return nil
-		}
+	return serverMPAAuthzHook{}
+}
 
-		if err := mpahooks.ActionMatchesInput(ctx, resp.Action, input); err != nil {
-			return err
-		}
-		for _, a := range resp.Approver {
-			input.Approvers = append(input.Approvers, &rpcauth.PrincipalAuthInput{
-				ID:     a.Id,
-				Groups: a.Groups,
-			})
-		}
+type serverMPAAuthzHook struct{}
+
+// Hook adds the approvers of the MPA request, if it's approved, to the input.
+func (serverMPAAuthzHook) Hook(ctx context.Context, input *rpcauth.RPCAuthInput) error {
+	mpaID, ok := mpahooks.MPAFromIncomingContext(ctx)
+	if !ok {
+		// Nothing to look up if MPA wasn't requested
 		return nil
-	})
+	}
+	resp, err := serverSingleton.Get(ctx, &mpa.GetRequest{Id: mpaID})
+	if err != nil {
+		return err
+	}
+	if resp.Action.Method != input.Method {
+		// Proxies may make extra calls to the server as part of authz hooks. If
+		// we get an MPA id that corresponds to a different method than the one
+		// being called, it's probably from the proxy and can be ignored.
+		// The right method but wrong args indicates a bigger issue and checked below.
+		return nil
+	}
+	if mpahooks.AfterApprovedMessage(ctx, input.Method) {
+		// The approval of the first message covers the rest of the
+		// stream, such as the input to a terminal session. Later
+		// messages are left to the policy.
+		return nil
+	}
+
+	if err := mpahooks.ActionMatchesInput(ctx, resp.Action, input); err != nil {
+		return err
+	}
+	if resp.Rejection != nil {
+		// Surface the rejection so that policies can act on it, but never
+		// treat a rejected request as approved.
+		input.Rejection = mpahooks.RejectionAuthInput(resp.Rejection)
+		return nil
+	}
+	if len(resp.MissingApprovals) > 0 {
+		// Approvals only count once the action's requirements are met.
+		return nil
+	}
+	for _, a := range resp.Approver {
+		input.Approvers = append(input.Approvers, &rpcauth.PrincipalAuthInput{
+			ID:     a.Id,
+			Groups: a.Groups,
+		})
+	}
+	return nil
+}
+
+// Allowed records a use of the approvals added by Hook. Denied requests
+// never get here, so they don't use up an approval.
+func (serverMPAAuthzHook) Allowed(ctx context.Context, input *rpcauth.RPCAuthInput) error {
+	mpaID, ok := mpahooks.MPAFromIncomingContext(ctx)
+	if !ok || len(input.Approvers) == 0 {
+		return nil
+	}
+	act, err := serverSingleton.useApprovals(ctx, mpaID, input.Method)
+	if err != nil {
+		return err
+	}
+	if act == nil {
+		// Another request used up the approvals after we added them.
+		return status.Error(codes.PermissionDenied, "MPA approvals have been used up")
+	}
+	return nil
 }
 
 // actionId generates the id for an action by hashing it
@@ -103,17 +144,25 @@ func actionId(action *mpa.Action) (string, error) {
 
 }
 
-type storedAction struct {
-	action       *mpa.Action
-	lastModified time.Time
-	approvers    []*mpa.Principal
-	approved     chan struct{}
-}
-
 // server is used to implement the gRPC server
 type server struct {
-	actions map[string]*storedAction
-	mu      sync.Mutex
+	store Store
+
+	// mu serializes all read-modify-write cycles against store and
+	// guards approved.
+	mu sync.Mutex
+	// approved holds a channel per pending action which is closed once
+	// the action is approved or removed, waking any WaitForApproval calls.
+	approved map[string]chan struct{}
+}
+
+// SetStore replaces the store used to persist MPA requests. It should be
+// called before the server starts handling requests, typically from main.
+// Requests held by the previous store are not carried over.
+func SetStore(store Store) {
+	serverSingleton.mu.Lock()
+	defer serverSingleton.mu.Unlock()
+	serverSingleton.store = store
 }
 
 func callerIdentity(ctx context.Context) (*rpcauth.PrincipalAuthInput, bool) {
@@ -129,17 +178,90 @@ func callerIdentity(ctx context.Context) (*rpcauth.PrincipalAuthInput, bool) {
 	return nil, false
 }
 
-func (s *server) clearOutdatedApprovals() {
+// storeError converts errors from the store into status errors.
+func storeError(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return status.Error(codes.NotFound, "MPA request not found")
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Errorf(codes.Internal, "MPA store error: %v", err)
+}
+
+// notifyLocked wakes up anybody waiting on the action with the given id.
+// s.mu must be held.
+func (s *server) notifyLocked(id string) {
+	if ch, ok := s.approved[id]; ok {
+		close(ch)
+		delete(s.approved, id)
+	}
+}
+
+// deleteLocked removes an action from the store. s.mu must be held.
+func (s *server) deleteLocked(ctx context.Context, id string) error {
+	if err := s.store.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.notifyLocked(id)
+	return nil
+}
+
+// dropSpentApprovalsLocked removes the approvals of act once their limits
+// have been reached so that the request needs to be approved again.
+// s.mu must be held.
+func (s *server) dropSpentApprovalsLocked(ctx context.Context, id string, act *StoredAction) error {
+	if !limitsReached(act.Limits, time.Now()) {
+		return nil
+	}
+	act.Approvers = nil
+	act.Limits = nil
+	act.LastModified = time.Now()
+	return s.store.Put(ctx, id, act)
+}
+
+// useApprovals records a use of the approvals for the action with the
+// given id and returns the action. It returns nil if the action isn't
+// currently approved, or isn't for the given method.
+func (s *server) useApprovals(ctx context.Context, id string, method string) (*StoredAction, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	act, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	if err := s.dropSpentApprovalsLocked(ctx, id, act); err != nil {
+		return nil, storeError(err)
+	}
+	if act.Action.Method != method || act.Rejection != nil || len(missingApprovals(act.Action.RequiredApprovals, act.Approvers)) > 0 {
+		return nil, nil
+	}
+	if act.Limits.GetMaxUses() > 0 {
+		act.Limits.Uses++
+		if err := s.store.Put(ctx, id, act); err != nil {
+			return nil, storeError(err)
+		}
+	}
+	return act, nil
+}
+
+func (s *server) clearOutdatedApprovals(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	actions, err := s.store.List(ctx)
+	if err != nil {
+		return err
+	}
 	staleTime := time.Now().Add(-maxMPAApprovedAge)
-	for id, act := range s.actions {
-		if act.lastModified.Before(staleTime) {
-			delete(s.actions, id)
+	for id, act := range actions {
+		if act.LastModified.Before(staleTime) {
+			if err := s.deleteLocked(ctx, id); err != nil {
+				return err
+			}
 		}
 	}
-
+	return nil
 }
 
 func (s *server) Store(ctx context.Context, in *mpa.StoreRequest) (*mpa.StoreResponse, error) {
@@ -153,11 +275,16 @@ func (s *server) Store(ctx context.Context, in *mpa.StoreRequest) (*mpa.StoreRes
 		return nil, status.Error(codes.FailedPrecondition, "unable to determine caller's identity")
 	}
 
+	if err := validateSpec(in.RequiredApprovals); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	action := &mpa.Action{
-		User:          p.ID,
-		Justification: justification,
-		Method:        in.Method,
-		Message:       in.Message,
+		User:              p.ID,
+		Justification:     justification,
+		Method:            in.Method,
+		Message:           in.Message,
+		RequiredApprovals: in.RequiredApprovals,
 	}
 	id, err := actionId(action)
 	if err != nil {
@@ -166,32 +293,47 @@ func (s *server) Store(ctx context.Context, in *mpa.StoreRequest) (*mpa.StoreRes
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Time to clear out excessive approvals!
-	for len(s.actions) >= maxMPAApprovals {
-		var oldestID string
-		oldestTime := time.Now()
-		for id, act := range s.actions {
-			if act.lastModified.Before(oldestTime) {
-				oldestID = id
-				oldestTime = act.lastModified
+	act, err := s.store.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		actions, err := s.store.List(ctx)
+		if err != nil {
+			return nil, storeError(err)
+		}
+		// Time to clear out excessive approvals!
+		for len(actions) >= maxMPAApprovals {
+			var oldestID string
+			oldestTime := time.Now()
+			for id, act := range actions {
+				if act.LastModified.Before(oldestTime) {
+					oldestID = id
+					oldestTime = act.LastModified
+				}
 			}
+			if err := s.deleteLocked(ctx, oldestID); err != nil {
+				return nil, storeError(err)
+			}
+			delete(actions, oldestID)
 		}
-		delete(s.actions, oldestID)
-	}
 
-	act, ok := s.actions[id]
-	if !ok {
-		act = &storedAction{
-			action:       action,
-			approved:     make(chan struct{}),
-			lastModified: time.Now(),
+		act = &StoredAction{
+			Action:       action,
+			LastModified: time.Now(),
+		}
+		if err := s.store.Put(ctx, id, act); err != nil {
+			return nil, storeError(err)
 		}
-		s.actions[id] = act
+	} else if err != nil {
+		return nil, storeError(err)
+	} else if err := s.dropSpentApprovalsLocked(ctx, id, act); err != nil {
+		return nil, storeError(err)
 	}
 	return &mpa.StoreResponse{
-		Id:       id,
-		Action:   action,
-		Approver: act.approvers,
+		Id:               id,
+		Action:           action,
+		Approver:         act.Approvers,
+		MissingApprovals: missingApprovals(action.RequiredApprovals, act.Approvers),
+		Rejection:        act.Rejection,
+		Limits:           act.Limits,
 	}, nil
 }
 
@@ -209,44 +351,119 @@ func (s *server) Approve(ctx context.Context, in *mpa.ApproveRequest) (*mpa.Appr
 	if !ok {
 		return nil, status.Error(codes.FailedPrecondition, "unable to determine caller's identity")
 	}
+	if err := validateLimits(in.ValidFor, in.MaxUses); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 	id, err := actionId(in.Action)
 	if err != nil {
 		return nil, err
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	act, ok := s.actions[id]
-	if !ok {
+	act, err := s.store.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
 		return nil, status.Error(codes.NotFound, "MPA request with provided input not found")
+	} else if err != nil {
+		return nil, storeError(err)
 	}
-	if act.action.User == p.ID {
+	if act.Action.User == p.ID {
 		return nil, status.Error(codes.InvalidArgument, "MPA requests cannot be approved by their requestor")
 	}
-	act.lastModified = time.Now()
+	if act.Rejection != nil {
+		return nil, status.Error(codes.FailedPrecondition, "MPA request has been rejected and can no longer be approved")
+	}
+	if err := s.dropSpentApprovalsLocked(ctx, id, act); err != nil {
+		return nil, storeError(err)
+	}
+	act.LastModified = time.Now()
+	act.Limits = tightenLimits(act.Limits, act.LastModified, in.ValidFor, in.MaxUses)
 	// Only add the approver if it's new compared to existing approvals
-	if !containsPrincipal(act.approvers, p) {
-		act.approvers = append(act.approvers, &mpa.Principal{
+	if !containsPrincipal(act.Approvers, p) {
+		act.Approvers = append(act.Approvers, &mpa.Principal{
 			Id:     p.ID,
 			Groups: p.Groups,
 		})
-		// The first approval lets any WaitForApproval calls finish immediately.
-		if len(act.approvers) == 1 {
-			close(act.approved)
-		}
 	}
+	if err := s.store.Put(ctx, id, act); err != nil {
+		return nil, storeError(err)
+	}
+	// Wake up any WaitForApproval calls so they can check whether the
+	// approval requirements are now met.
+	s.notifyLocked(id)
 	return &mpa.ApproveResponse{}, nil
 }
+func (s *server) Reject(ctx context.Context, in *mpa.RejectRequest) (*mpa.RejectResponse, error) {
+	p, ok := callerIdentity(ctx)
+	if !ok || p == nil {
+		return nil, status.Error(codes.FailedPrecondition, "unable to determine caller's identity")
+	}
+	if in.Reason == "" {
+		return nil, status.Error(codes.InvalidArgument, "a reason must be given when rejecting MPA requests")
+	}
+	id, err := actionId(in.Action)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	act, err := s.store.Get(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "MPA request with provided input not found")
+	} else if err != nil {
+		return nil, storeError(err)
+	}
+	if act.Action.User == p.ID {
+		return nil, status.Error(codes.InvalidArgument, "MPA requests cannot be rejected by their requestor, clear them instead")
+	}
+	if act.Rejection != nil {
+		// The first rejection wins.
+		return &mpa.RejectResponse{}, nil
+	}
+	act.LastModified = time.Now()
+	act.Rejection = &mpa.Rejection{
+		Rejecter: &mpa.Principal{
+			Id:     p.ID,
+			Groups: p.Groups,
+		},
+		Reason: in.Reason,
+	}
+	if err := s.store.Put(ctx, id, act); err != nil {
+		return nil, storeError(err)
+	}
+	// Wake up any WaitForApproval calls so they can report the rejection.
+	s.notifyLocked(id)
+	return &mpa.RejectResponse{}, nil
+}
+
 func (s *server) WaitForApproval(ctx context.Context, in *mpa.WaitForApprovalRequest) (*mpa.WaitForApprovalResponse, error) {
 	for {
 		s.mu.Lock()
-		act, ok := s.actions[in.Id]
-		s.mu.Unlock()
+		act, err := s.store.Get(ctx, in.Id)
+		if err != nil {
+			s.mu.Unlock()
+			return nil, storeError(err)
+		}
+		if act.Rejection != nil {
+			s.mu.Unlock()
+			return nil, mpahooks.RejectionError(act.Rejection)
+		}
+		if err := s.dropSpentApprovalsLocked(ctx, in.Id, act); err != nil {
+			s.mu.Unlock()
+			return nil, storeError(err)
+		}
+		if len(missingApprovals(act.Action.RequiredApprovals, act.Approvers)) == 0 {
+			s.mu.Unlock()
+			return &mpa.WaitForApprovalResponse{}, nil
+		}
+		ch, ok := s.approved[in.Id]
 		if !ok {
-			return nil, status.Error(codes.NotFound, "MPA request not found")
+			ch = make(chan struct{})
+			s.approved[in.Id] = ch
 		}
+		s.mu.Unlock()
 		select {
-		case <-act.approved:
-			return &mpa.WaitForApprovalResponse{}, nil
+		case <-ch:
+			// Loop around to see whether we were approved or removed.
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		case <-time.After(time.Minute):
@@ -255,15 +472,27 @@ func (s *server) WaitForApproval(ctx context.Context, in *mpa.WaitForApprovalReq
 	}
 }
 func (s *server) List(ctx context.Context, in *mpa.ListRequest) (*mpa.ListResponse, error) {
-	s.clearOutdatedApprovals()
+	if err := s.clearOutdatedApprovals(ctx); err != nil {
+		return nil, storeError(err)
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	actions, err := s.store.List(ctx)
+	if err != nil {
+		return nil, storeError(err)
+	}
 	var items []*mpa.ListResponse_Item
-	for id, action := range s.actions {
+	for id, action := range actions {
+		if err := s.dropSpentApprovalsLocked(ctx, id, action); err != nil {
+			return nil, storeError(err)
+		}
 		items = append(items, &mpa.ListResponse_Item{
-			Id:       id,
-			Action:   action.action,
-			Approver: action.approvers,
+			Id:               id,
+			Action:           action.Action,
+			Approver:         action.Approvers,
+			MissingApprovals: missingApprovals(action.Action.RequiredApprovals, action.Approvers),
+			Rejection:        action.Rejection,
+			Limits:           action.Limits,
 		})
 	}
 	sort.Slice(items, func(i, j int) bool {
@@ -272,16 +501,24 @@ func (s *server) List(ctx context.Context, in *mpa.ListRequest) (*mpa.ListRespon
 	return &mpa.ListResponse{Item: items}, nil
 }
 func (s *server) Get(ctx context.Context, in *mpa.GetRequest) (*mpa.GetResponse, error) {
-	s.clearOutdatedApprovals()
+	if err := s.clearOutdatedApprovals(ctx); err != nil {
+		return nil, storeError(err)
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	act, ok := s.actions[in.Id]
-	if !ok {
-		return nil, status.Error(codes.NotFound, "MPA request not found")
+	act, err := s.store.Get(ctx, in.Id)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	if err := s.dropSpentApprovalsLocked(ctx, in.Id, act); err != nil {
+		return nil, storeError(err)
 	}
 	return &mpa.GetResponse{
-		Action:   act.action,
-		Approver: act.approvers,
+		Action:           act.Action,
+		Approver:         act.Approvers,
+		MissingApprovals: missingApprovals(act.Action.RequiredApprovals, act.Approvers),
+		Rejection:        act.Rejection,
+		Limits:           act.Limits,
 	}, nil
 }
 func (s *server) Clear(ctx context.Context, in *mpa.ClearRequest) (*mpa.ClearResponse, error) {
@@ -291,7 +528,9 @@ func (s *server) Clear(ctx context.Context, in *mpa.ClearRequest) (*mpa.ClearRes
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	delete(s.actions, id)
+	if err := s.deleteLocked(ctx, id); err != nil {
+		return nil, storeError(err)
+	}
 	return &mpa.ClearResponse{}, nil
 }
 
@@ -300,7 +539,10 @@ func (s *server) Register(gs *grpc.Server) {
 	mpa.RegisterMpaServer(gs, s)
 }
 
-var serverSingleton = &server{actions: make(map[string]*storedAction)}
+var serverSingleton = &server{
+	store:    NewMemoryStore(),
+	approved: make(map[string]chan struct{}),
+}
 
 func init() {
 	services.RegisterSansShellService(serverSingleton)
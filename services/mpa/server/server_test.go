@@ -20,15 +20,19 @@ import (
 	"context"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
 	"github.com/Snowflake-Labs/sansshell/services/mpa"
+	"github.com/Snowflake-Labs/sansshell/services/mpa/mpahooks"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -108,6 +112,25 @@ func TestAuthzHook(t *testing.T) {
 	if err := hook.Hook(mpaCtx, wrongInput); err == nil {
 		t.Fatal("unexpectedly nil err")
 	}
+
+	// Later messages of a stream have to match the action as well, unless
+	// only the first message of the method's streams needs approval.
+	laterCtx := rpcauth.WithStreamMessageIndex(mpaCtx, 1)
+	laterInput, err := rpcauth.NewRPCAuthInput(laterCtx, "foobar", &mpa.ApproveRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := hook.Hook(laterCtx, laterInput); err == nil {
+		t.Fatal("later stream message unexpectedly matched the action")
+	}
+	mpahooks.ApproveFirstMessageOnly("foobar")
+	t.Cleanup(func() { mpahooks.ApproveEveryMessage("foobar") })
+	if err := hook.Hook(laterCtx, laterInput); err != nil {
+		t.Fatal(err)
+	}
+	if len(laterInput.Approvers) != 0 {
+		t.Errorf("later stream message got approvers %+v", laterInput.Approvers)
+	}
 }
 
 func TestMaxNumApprovals(t *testing.T) {
@@ -245,3 +268,288 @@ func TestActionIdIsDeterministic(t *testing.T) {
 		})
 	}
 }
+
+func TestWaitForQuorum(t *testing.T) {
+	ctx := context.Background()
+	s := &server{store: NewMemoryStore(), approved: make(map[string]chan struct{})}
+
+	rCtx := rpcauth.AddPeerToContext(ctx, &rpcauth.PeerAuthInput{
+		Principal: &rpcauth.PrincipalAuthInput{ID: "requester"},
+	})
+	stored, err := s.Store(rCtx, &mpa.StoreRequest{
+		Method:  "foobar",
+		Message: mustAny(anypb.New(&emptypb.Empty{})),
+		RequiredApprovals: &mpa.ApprovalSpec{Requirement: []*mpa.ApprovalRequirement{
+			{Count: 2, Group: "sre"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.MissingApprovals) != 1 || stored.MissingApprovals[0].Count != 2 {
+		t.Fatalf("unexpected missing approvals: %v", stored.MissingApprovals)
+	}
+
+	approve := func(id string) {
+		aCtx := rpcauth.AddPeerToContext(ctx, &rpcauth.PeerAuthInput{
+			Principal: &rpcauth.PrincipalAuthInput{ID: id, Groups: []string{"sre"}},
+		})
+		if _, err := s.Approve(aCtx, &mpa.ApproveRequest{Action: stored.Action}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	approve("approver1")
+
+	// One approval isn't enough, so waiting should time out.
+	shortCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := s.WaitForApproval(shortCtx, &mpa.WaitForApprovalRequest{Id: stored.Id}); err == nil {
+		t.Fatal("WaitForApproval returned before requirements were met")
+	}
+	got, err := s.Get(ctx, &mpa.GetRequest{Id: stored.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.MissingApprovals) != 1 || got.MissingApprovals[0].Count != 1 {
+		t.Fatalf("unexpected missing approvals: %v", got.MissingApprovals)
+	}
+
+	var g errgroup.Group
+	g.Go(func() error {
+		_, err := s.WaitForApproval(ctx, &mpa.WaitForApprovalRequest{Id: stored.Id})
+		return err
+	})
+	approve("approver2")
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreRejectsInvalidSpec(t *testing.T) {
+	ctx := context.Background()
+	rCtx := rpcauth.AddPeerToContext(ctx, &rpcauth.PeerAuthInput{
+		Principal: &rpcauth.PrincipalAuthInput{ID: "requester"},
+	})
+	_, err := serverSingleton.Store(rCtx, &mpa.StoreRequest{
+		Method:            "foobar",
+		Message:           mustAny(anypb.New(&emptypb.Empty{})),
+		RequiredApprovals: &mpa.ApprovalSpec{Requirement: []*mpa.ApprovalRequirement{{Count: -1}}},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("got %v, want InvalidArgument", err)
+	}
+}
+
+func TestReject(t *testing.T) {
+	ctx := context.Background()
+	s := &server{store: NewMemoryStore(), approved: make(map[string]chan struct{})}
+
+	rCtx := rpcauth.AddPeerToContext(ctx, &rpcauth.PeerAuthInput{
+		Principal: &rpcauth.PrincipalAuthInput{ID: "requester"},
+	})
+	stored, err := s.Store(rCtx, &mpa.StoreRequest{
+		Method:  "foobar",
+		Message: mustAny(anypb.New(&emptypb.Empty{})),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aCtx := rpcauth.AddPeerToContext(ctx, &rpcauth.PeerAuthInput{
+		Principal: &rpcauth.PrincipalAuthInput{ID: "approver", Groups: []string{"g1"}},
+	})
+
+	if _, err := s.Reject(aCtx, &mpa.RejectRequest{Action: stored.Action}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected failure when rejecting without a reason: %v", err)
+	}
+	if _, err := s.Reject(rCtx, &mpa.RejectRequest{Action: stored.Action, Reason: "nope"}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected failure when self-rejecting: %v", err)
+	}
+
+	var g errgroup.Group
+	g.Go(func() error {
+		_, err := s.WaitForApproval(ctx, &mpa.WaitForApprovalRequest{Id: stored.Id})
+		return err
+	})
+	if _, err := s.Reject(aCtx, &mpa.RejectRequest{Action: stored.Action, Reason: "too risky"}); err != nil {
+		t.Fatal(err)
+	}
+	err = g.Wait()
+	if status.Code(err) != codes.PermissionDenied || !strings.Contains(err.Error(), "too risky") {
+		t.Fatalf("got %v, want PermissionDenied with the reason", err)
+	}
+
+	if _, err := s.Approve(aCtx, &mpa.ApproveRequest{Action: stored.Action}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected failure when approving a rejected request: %v", err)
+	}
+	got, err := s.Get(ctx, &mpa.GetRequest{Id: stored.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Rejection.GetReason() != "too risky" || got.Rejection.GetRejecter().GetId() != "approver" {
+		t.Errorf("unexpected rejection: %v", got.Rejection)
+	}
+	restored, err := s.Store(rCtx, &mpa.StoreRequest{
+		Method:  "foobar",
+		Message: mustAny(anypb.New(&emptypb.Empty{})),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Rejection == nil {
+		t.Error("storing a rejected request again should report the rejection")
+	}
+}
+
+func TestAuthzHookRejection(t *testing.T) {
+	ctx := context.Background()
+	rCtx := rpcauth.AddPeerToContext(ctx, &rpcauth.PeerAuthInput{
+		Principal: &rpcauth.PrincipalAuthInput{ID: "requester"},
+	})
+	stored, err := serverSingleton.Store(rCtx, &mpa.StoreRequest{
+		Method:  "rejected",
+		Message: mustAny(anypb.New(&emptypb.Empty{})),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aCtx := rpcauth.AddPeerToContext(ctx, &rpcauth.PeerAuthInput{
+		Principal: &rpcauth.PrincipalAuthInput{ID: "approver", Groups: []string{"g1"}},
+	})
+	if _, err := serverSingleton.Reject(aCtx, &mpa.RejectRequest{Action: stored.Action, Reason: "no"}); err != nil {
+		t.Fatal(err)
+	}
+
+	mpaCtx := metadata.NewIncomingContext(rCtx, map[string][]string{"sansshell-mpa-request-id": {stored.Id}})
+	input, err := rpcauth.NewRPCAuthInput(mpaCtx, "rejected", &emptypb.Empty{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ServerMPAAuthzHook().Hook(mpaCtx, input); err != nil {
+		t.Fatal(err)
+	}
+	want := &rpcauth.RejectionAuthInput{
+		Rejecter: &rpcauth.PrincipalAuthInput{ID: "approver", Groups: []string{"g1"}},
+		Reason:   "no",
+	}
+	if !reflect.DeepEqual(input.Rejection, want) {
+		t.Errorf("got %+v, want %+v", input.Rejection, want)
+	}
+	if len(input.Approvers) != 0 {
+		t.Errorf("rejected requests shouldn't have approvers, got %+v", input.Approvers)
+	}
+}
+
+func TestApprovalLimits(t *testing.T) {
+	ctx := context.Background()
+	rCtx := rpcauth.AddPeerToContext(ctx, &rpcauth.PeerAuthInput{
+		Principal: &rpcauth.PrincipalAuthInput{ID: "requester"},
+	})
+	aCtx := rpcauth.AddPeerToContext(ctx, &rpcauth.PeerAuthInput{
+		Principal: &rpcauth.PrincipalAuthInput{ID: "approver"},
+	})
+	store := func(method string) *mpa.StoreResponse {
+		t.Helper()
+		stored, err := serverSingleton.Store(rCtx, &mpa.StoreRequest{
+			Method:  method,
+			Message: mustAny(anypb.New(&emptypb.Empty{})),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stored
+	}
+	// authorize evaluates a call through an authorizer running the MPA hook,
+	// with a policy that allows the call if allow is set. It returns the
+	// number of approvers the policy saw.
+	authorize := func(stored *mpa.StoreResponse, allow bool) int {
+		t.Helper()
+		mpaCtx := metadata.NewIncomingContext(rCtx, map[string][]string{"sansshell-mpa-request-id": {stored.Id}})
+		input, err := rpcauth.NewRPCAuthInput(mpaCtx, stored.Action.Method, &emptypb.Empty{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var approvers int
+		policy := rpcauth.NewMockAuthzPolicy(func(_ context.Context, input *rpcauth.RPCAuthInput) (bool, error) {
+			approvers = len(input.Approvers)
+			return allow, nil
+		}, func(context.Context, *rpcauth.RPCAuthInput) ([]string, error) {
+			return nil, nil
+		})
+		err = rpcauth.NewRPCAuthorizer(policy, ServerMPAAuthzHook()).Eval(mpaCtx, input)
+		if allow && err != nil {
+			t.Fatal(err)
+		}
+		if !allow && status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("got %v, want PermissionDenied", err)
+		}
+		return approvers
+	}
+	hookApprovers := func(stored *mpa.StoreResponse) int {
+		t.Helper()
+		return authorize(stored, true)
+	}
+
+	for _, req := range []*mpa.ApproveRequest{
+		{MaxUses: -1},
+		{ValidFor: durationpb.New(-time.Second)},
+	} {
+		req.Action = store("invalid").Action
+		if _, err := serverSingleton.Approve(aCtx, req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Approve(%v) got %v, want InvalidArgument", req, err)
+		}
+	}
+
+	stored := store("twice")
+	if _, err := serverSingleton.Approve(aCtx, &mpa.ApproveRequest{Action: stored.Action, MaxUses: 2}); err != nil {
+		t.Fatal(err)
+	}
+	// Denied calls don't use up the approval.
+	for i := 0; i < 3; i++ {
+		if got := authorize(stored, false); got != 1 {
+			t.Fatalf("denied call %d: got %d approvers, want 1", i, got)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if got := hookApprovers(stored); got != 1 {
+			t.Fatalf("use %d: got %d approvers, want 1", i, got)
+		}
+	}
+	if got := hookApprovers(stored); got != 0 {
+		t.Errorf("approval wasn't used up, got %d approvers", got)
+	}
+	got, err := serverSingleton.Get(ctx, &mpa.GetRequest{Id: stored.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Approver) != 0 || got.Limits != nil {
+		t.Errorf("used up approvals should be dropped, got %v", got)
+	}
+	// Approving again starts from scratch.
+	if _, err := serverSingleton.Approve(aCtx, &mpa.ApproveRequest{Action: stored.Action, MaxUses: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got := hookApprovers(stored); got != 1 {
+		t.Errorf("got %d approvers after approving again, want 1", got)
+	}
+
+	stored = store("briefly")
+	if _, err := serverSingleton.Approve(aCtx, &mpa.ApproveRequest{Action: stored.Action, ValidFor: durationpb.New(time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	// A second approver can only shorten the validity.
+	if _, err := serverSingleton.Approve(rpcauth.AddPeerToContext(ctx, &rpcauth.PeerAuthInput{
+		Principal: &rpcauth.PrincipalAuthInput{ID: "other"},
+	}), &mpa.ApproveRequest{Action: stored.Action, ValidFor: durationpb.New(50 * time.Millisecond)}); err != nil {
+		t.Fatal(err)
+	}
+	if got := hookApprovers(stored); got != 2 {
+		t.Fatalf("got %d approvers, want 2", got)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if got := hookApprovers(stored); got != 0 {
+		t.Errorf("approval didn't expire, got %d approvers", got)
+	}
+	if again := store("briefly"); len(again.Approver) != 0 {
+		t.Errorf("expired approvals should be dropped, got %v", again.Approver)
+	}
+}
@@ -0,0 +1,332 @@
+/* Copyright (c) 2023 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Snowflake-Labs/sansshell/services/mpa"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrNotFound is returned by a Store when no action exists for an id.
+var ErrNotFound = errors.New("MPA request not found")
+
+// StoredAction is the state kept for a single MPA request.
+type StoredAction struct {
+	// Action is the request being approved.
+	Action *mpa.Action
+	// Approvers are all principals who have approved Action.
+	Approvers []*mpa.Principal
+	// Rejection is set once somebody rejects Action.
+	Rejection *mpa.Rejection
+	// Limits bound the use of Approvers, if any approver asked for them.
+	Limits *mpa.ApprovalLimits
+	// LastModified is when the request was last stored or approved. It
+	// drives expiry of old requests.
+	LastModified time.Time
+}
+
+// clone returns a deep copy so that callers can't mutate data held by a Store.
+func (s *StoredAction) clone() *StoredAction {
+	out := &StoredAction{
+		Action:       proto.Clone(s.Action).(*mpa.Action),
+		LastModified: s.LastModified,
+	}
+	for _, a := range s.Approvers {
+		out.Approvers = append(out.Approvers, proto.Clone(a).(*mpa.Principal))
+	}
+	if s.Rejection != nil {
+		out.Rejection = proto.Clone(s.Rejection).(*mpa.Rejection)
+	}
+	if s.Limits != nil {
+		out.Limits = proto.Clone(s.Limits).(*mpa.ApprovalLimits)
+	}
+	return out
+}
+
+// A Store persists MPA requests and their approvals. The MPA server
+// serializes all calls, so implementations don't need to handle
+// concurrent read-modify-write cycles themselves.
+type Store interface {
+	// Get returns the action stored under id, or ErrNotFound.
+	Get(ctx context.Context, id string) (*StoredAction, error)
+	// Put creates or replaces the action stored under id.
+	Put(ctx context.Context, id string, action *StoredAction) error
+	// Delete removes the action stored under id. Deleting a missing
+	// id is not an error.
+	Delete(ctx context.Context, id string) error
+	// List returns all stored actions keyed by id.
+	List(ctx context.Context) (map[string]*StoredAction, error)
+}
+
+// memoryStore keeps all actions in memory. Everything is lost when the
+// process restarts.
+type memoryStore struct {
+	mu      sync.Mutex
+	actions map[string]*StoredAction
+}
+
+// NewMemoryStore returns a Store which only keeps state in memory. This
+// is the default store for the MPA server.
+func NewMemoryStore() Store {
+	return &memoryStore{actions: make(map[string]*StoredAction)}
+}
+
+func (m *memoryStore) Get(_ context.Context, id string) (*StoredAction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	act, ok := m.actions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return act.clone(), nil
+}
+
+func (m *memoryStore) Put(_ context.Context, id string, action *StoredAction) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.actions[id] = action.clone()
+	return nil
+}
+
+func (m *memoryStore) Delete(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.actions, id)
+	return nil
+}
+
+func (m *memoryStore) List(_ context.Context) (map[string]*StoredAction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]*StoredAction, len(m.actions))
+	for id, act := range m.actions {
+		out[id] = act.clone()
+	}
+	return out, nil
+}
+
+// fileSuffix is appended to the id of every action written by fileStore.
+const fileSuffix = ".json"
+
+// validID matches the ids generated by actionId. Anything else is rejected
+// before being turned into a path.
+var validID = regexp.MustCompile(`^[0-9a-f]+-[0-9a-f]+-[0-9a-f]+$`)
+
+// fileRecord is the on-disk representation of a StoredAction. Protos are
+// embedded as protojson so the files stay human readable.
+type fileRecord struct {
+	Action       json.RawMessage   `json:"action"`
+	Approvers    []json.RawMessage `json:"approvers,omitempty"`
+	Rejection    json.RawMessage   `json:"rejection,omitempty"`
+	Limits       json.RawMessage   `json:"limits,omitempty"`
+	LastModified time.Time         `json:"last_modified"`
+}
+
+// fileStore keeps one JSON file per action in a directory and mirrors the
+// directory contents in memory. It assumes it is the only writer of the
+// directory.
+type fileStore struct {
+	dir string
+
+	mu    sync.Mutex
+	cache map[string]*StoredAction
+}
+
+// NewFileStore returns a Store which persists actions as files in dir so
+// that they survive restarts of sansshell-server. The directory is created
+// if it doesn't exist and any actions already in it are loaded.
+func NewFileStore(dir string) (Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("can't create MPA store directory: %v", err)
+	}
+	f := &fileStore{
+		dir:   dir,
+		cache: make(map[string]*StoredAction),
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("can't read MPA store directory: %v", err)
+	}
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), fileSuffix)
+		if !ok || e.IsDir() || !validID.MatchString(id) {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("can't read MPA request %v: %v", id, err)
+		}
+		act, err := unmarshalRecord(b)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse MPA request %v: %v", id, err)
+		}
+		f.cache[id] = act
+	}
+	return f, nil
+}
+
+func marshalRecord(action *StoredAction) ([]byte, error) {
+	act, err := protojson.Marshal(action.Action)
+	if err != nil {
+		return nil, err
+	}
+	rec := fileRecord{
+		Action:       act,
+		LastModified: action.LastModified,
+	}
+	for _, a := range action.Approvers {
+		b, err := protojson.Marshal(a)
+		if err != nil {
+			return nil, err
+		}
+		rec.Approvers = append(rec.Approvers, b)
+	}
+	if action.Rejection != nil {
+		b, err := protojson.Marshal(action.Rejection)
+		if err != nil {
+			return nil, err
+		}
+		rec.Rejection = b
+	}
+	if action.Limits != nil {
+		b, err := protojson.Marshal(action.Limits)
+		if err != nil {
+			return nil, err
+		}
+		rec.Limits = b
+	}
+	return json.Marshal(rec)
+}
+
+func unmarshalRecord(b []byte) (*StoredAction, error) {
+	var rec fileRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, err
+	}
+	out := &StoredAction{
+		Action:       &mpa.Action{},
+		LastModified: rec.LastModified,
+	}
+	if err := protojson.Unmarshal(rec.Action, out.Action); err != nil {
+		return nil, err
+	}
+	for _, a := range rec.Approvers {
+		p := &mpa.Principal{}
+		if err := protojson.Unmarshal(a, p); err != nil {
+			return nil, err
+		}
+		out.Approvers = append(out.Approvers, p)
+	}
+	if rec.Rejection != nil {
+		out.Rejection = &mpa.Rejection{}
+		if err := protojson.Unmarshal(rec.Rejection, out.Rejection); err != nil {
+			return nil, err
+		}
+	}
+	if rec.Limits != nil {
+		out.Limits = &mpa.ApprovalLimits{}
+		if err := protojson.Unmarshal(rec.Limits, out.Limits); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func (f *fileStore) path(id string) string {
+	return filepath.Join(f.dir, id+fileSuffix)
+}
+
+func (f *fileStore) Get(_ context.Context, id string) (*StoredAction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	act, ok := f.cache[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return act.clone(), nil
+}
+
+func (f *fileStore) Put(_ context.Context, id string, action *StoredAction) error {
+	if !validID.MatchString(id) {
+		return fmt.Errorf("invalid MPA request id %q", id)
+	}
+	b, err := marshalRecord(action)
+	if err != nil {
+		return fmt.Errorf("can't marshal MPA request %v: %v", id, err)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Write to a temp file and rename it into place so a crash never
+	// leaves a partially written request behind.
+	tmp, err := os.CreateTemp(f.dir, "."+id+"-*")
+	if err != nil {
+		return fmt.Errorf("can't create MPA request file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("can't write MPA request file: %v", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("can't sync MPA request file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("can't close MPA request file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path(id)); err != nil {
+		return fmt.Errorf("can't rename MPA request file: %v", err)
+	}
+	f.cache[id] = action.clone()
+	return nil
+}
+
+func (f *fileStore) Delete(_ context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.cache[id]; !ok {
+		return nil
+	}
+	if err := os.Remove(f.path(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("can't remove MPA request file: %v", err)
+	}
+	delete(f.cache, id)
+	return nil
+}
+
+func (f *fileStore) List(_ context.Context) (map[string]*StoredAction, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make(map[string]*StoredAction, len(f.cache))
+	for id, act := range f.cache {
+		out[id] = act.clone()
+	}
+	return out, nil
+}
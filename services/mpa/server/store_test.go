@@ -0,0 +1,178 @@
+/* Copyright (c) 2023 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+	"github.com/Snowflake-Labs/sansshell/services/mpa"
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestStores(t *testing.T) {
+	ctx := context.Background()
+	fileStore, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	act := &StoredAction{
+		Action: &mpa.Action{
+			User:    "requester",
+			Method:  "foobar",
+			Message: mustAny(anypb.New(&emptypb.Empty{})),
+		},
+		Approvers:    []*mpa.Principal{{Id: "approver", Groups: []string{"g1"}}},
+		Rejection:    &mpa.Rejection{Rejecter: &mpa.Principal{Id: "other"}, Reason: "no"},
+		Limits:       &mpa.ApprovalLimits{ExpiresAt: timestamppb.New(time.Unix(5678, 0)), MaxUses: 3, Uses: 1},
+		LastModified: time.Unix(1234, 0).UTC(),
+	}
+
+	for _, tc := range []struct {
+		desc  string
+		store Store
+	}{
+		{desc: "memory", store: NewMemoryStore()},
+		{desc: "file", store: fileStore},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if _, err := tc.store.Get(ctx, "1-2-3"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("got %v, want ErrNotFound", err)
+			}
+			if err := tc.store.Put(ctx, "1-2-3", act); err != nil {
+				t.Fatal(err)
+			}
+			got, err := tc.store.Get(ctx, "1-2-3")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(act, got, protocmp.Transform()); diff != "" {
+				t.Errorf("unexpected diff (-want +got):\n%v", diff)
+			}
+			// Mutating the result must not affect what's stored.
+			got.Approvers = nil
+			list, err := tc.store.List(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(map[string]*StoredAction{"1-2-3": act}, list, protocmp.Transform()); diff != "" {
+				t.Errorf("unexpected diff (-want +got):\n%v", diff)
+			}
+			if err := tc.store.Delete(ctx, "1-2-3"); err != nil {
+				t.Fatal(err)
+			}
+			if err := tc.store.Delete(ctx, "1-2-3"); err != nil {
+				t.Fatalf("deleting twice: %v", err)
+			}
+			if _, err := tc.store.Get(ctx, "1-2-3"); !errors.Is(err, ErrNotFound) {
+				t.Fatalf("got %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestFileStoreRejectsBadIDs(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"../escape", "a/b-c-d", ""} {
+		if err := store.Put(ctx, id, &StoredAction{Action: &mpa.Action{}}); err == nil {
+			t.Errorf("Put(%q) unexpectedly succeeded", id)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape.json")); !os.IsNotExist(err) {
+		t.Errorf("file written outside of store: %v", err)
+	}
+}
+
+func TestFileStoreSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	newServer := func() *server {
+		store, err := NewFileStore(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &server{store: store, approved: make(map[string]chan struct{})}
+	}
+
+	s := newServer()
+	rCtx := rpcauth.AddPeerToContext(ctx, &rpcauth.PeerAuthInput{
+		Principal: &rpcauth.PrincipalAuthInput{ID: "requester"},
+	})
+	stored, err := s.Store(rCtx, &mpa.StoreRequest{
+		Method:  "foobar",
+		Message: mustAny(anypb.New(&emptypb.Empty{})),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aCtx := rpcauth.AddPeerToContext(ctx, &rpcauth.PeerAuthInput{
+		Principal: &rpcauth.PrincipalAuthInput{ID: "approver", Groups: []string{"g1"}},
+	})
+	if _, err := s.Approve(aCtx, &mpa.ApproveRequest{Action: stored.Action}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh server reading the same directory sees the approval.
+	s = newServer()
+	got, err := s.Get(ctx, &mpa.GetRequest{Id: stored.Id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &mpa.GetResponse{
+		Action:   stored.Action,
+		Approver: []*mpa.Principal{{Id: "approver", Groups: []string{"g1"}}},
+	}
+	if diff := cmp.Diff(want, got, protocmp.Transform()); diff != "" {
+		t.Errorf("unexpected diff (-want +got):\n%v", diff)
+	}
+	if _, err := s.WaitForApproval(ctx, &mpa.WaitForApprovalRequest{Id: stored.Id}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Expiry still applies to requests loaded from disk.
+	oldAge := maxMPAApprovedAge
+	maxMPAApprovedAge = 0
+	defer func() { maxMPAApprovedAge = oldAge }()
+	s = newServer()
+	if _, err := s.Get(ctx, &mpa.GetRequest{Id: stored.Id}); status.Code(err) != codes.NotFound {
+		t.Fatalf("got %v, want NotFound", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected expired request to be removed from disk, found %v", entries)
+	}
+}
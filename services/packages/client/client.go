@@ -29,6 +29,7 @@ import (
 	"github.com/google/subcommands"
 
 	"github.com/Snowflake-Labs/sansshell/client"
+	"github.com/Snowflake-Labs/sansshell/proxy/proxy"
 	pb "github.com/Snowflake-Labs/sansshell/services/packages"
 	"github.com/Snowflake-Labs/sansshell/services/util"
 )
@@ -37,6 +38,18 @@ const subPackage = "packages"
 
 func init() {
 	subcommands.Register(&packagesCmd{}, subPackage)
+	// Searching and listing packages or repos doesn't change what's
+	// installed, so these can be retried. Installs, removals and updates
+	// aren't.
+	for _, m := range []string{
+		pb.Packages_Search_FullMethodName,
+		pb.Packages_ListInstalled_FullMethodName,
+		pb.Packages_RepoList_FullMethodName,
+	} {
+		if err := proxy.RegisterRetryPolicy(m, proxy.DefaultRetryPolicy); err != nil {
+			panic(err)
+		}
+	}
 }
 
 func (*packagesCmd) GetSubpackage(f *flag.FlagSet) *subcommands.Commander {
@@ -29,6 +29,7 @@ import (
 	"github.com/google/subcommands"
 
 	"github.com/Snowflake-Labs/sansshell/client"
+	"github.com/Snowflake-Labs/sansshell/proxy/proxy"
 	pb "github.com/Snowflake-Labs/sansshell/services/process"
 	"github.com/Snowflake-Labs/sansshell/services/util"
 )
@@ -37,6 +38,17 @@ const subPackage = "process"
 
 func init() {
 	subcommands.Register(&processCmd{}, subPackage)
+	// Listing processes and taking stacks only inspect the target, so these
+	// can be retried. Kill and dumps aren't.
+	for _, m := range []string{
+		pb.Process_List_FullMethodName,
+		pb.Process_GetStacks_FullMethodName,
+		pb.Process_GetJavaStacks_FullMethodName,
+	} {
+		if err := proxy.RegisterRetryPolicy(m, proxy.DefaultRetryPolicy); err != nil {
+			panic(err)
+		}
+	}
 }
 
 func (*processCmd) GetSubpackage(f *flag.FlagSet) *subcommands.Commander {
@@ -27,6 +27,7 @@ import (
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/Snowflake-Labs/sansshell/client"
+	"github.com/Snowflake-Labs/sansshell/proxy/proxy"
 	pb "github.com/Snowflake-Labs/sansshell/services/sansshell"
 	"github.com/Snowflake-Labs/sansshell/services/util"
 )
@@ -35,6 +36,16 @@ const subPackage = "sansshell"
 
 func init() {
 	subcommands.Register(&sansshellCmd{}, subPackage)
+	// Reading the version or log verbosity doesn't change the target, so
+	// these can be retried.
+	for _, m := range []string{
+		pb.State_Version_FullMethodName,
+		pb.Logging_GetVerbosity_FullMethodName,
+	} {
+		if err := proxy.RegisterRetryPolicy(m, proxy.DefaultRetryPolicy); err != nil {
+			panic(err)
+		}
+	}
 }
 
 func (*sansshellCmd) GetSubpackage(f *flag.FlagSet) *subcommands.Commander {
@@ -29,6 +29,7 @@ import (
 	"github.com/google/subcommands"
 
 	"github.com/Snowflake-Labs/sansshell/client"
+	"github.com/Snowflake-Labs/sansshell/proxy/proxy"
 	pb "github.com/Snowflake-Labs/sansshell/services/service"
 	"github.com/Snowflake-Labs/sansshell/services/util"
 )
@@ -37,6 +38,16 @@ const subPackage = "service"
 
 func init() {
 	subcommands.Register(&serviceCmd{}, subPackage)
+	// Listing services and reading their status can be retried, unlike
+	// actions such as restart.
+	for _, m := range []string{
+		pb.Service_List_FullMethodName,
+		pb.Service_Status_FullMethodName,
+	} {
+		if err := proxy.RegisterRetryPolicy(m, proxy.DefaultRetryPolicy); err != nil {
+			panic(err)
+		}
+	}
 }
 
 func (*serviceCmd) GetSubpackage(f *flag.FlagSet) *subcommands.Commander {
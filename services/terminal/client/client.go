@@ -0,0 +1,207 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+// Package client provides the client interface for 'terminal'
+package client
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/google/subcommands"
+	"golang.org/x/term"
+
+	"github.com/Snowflake-Labs/sansshell/client"
+	pb "github.com/Snowflake-Labs/sansshell/services/terminal"
+	"github.com/Snowflake-Labs/sansshell/services/util"
+)
+
+const subPackage = "terminal"
+
+// DefaultCommand is the command run by 'terminal open' if none is given.
+var DefaultCommand = "/bin/bash"
+
+func init() {
+	subcommands.Register(&terminalCmd{}, subPackage)
+}
+
+func (*terminalCmd) GetSubpackage(f *flag.FlagSet) *subcommands.Commander {
+	c := client.SetupSubpackage(subPackage, f)
+	c.Register(&openCmd{}, "")
+	return c
+}
+
+type terminalCmd struct{}
+
+func (*terminalCmd) Name() string { return subPackage }
+func (p *terminalCmd) Synopsis() string {
+	return client.GenerateSynopsis(p.GetSubpackage(flag.NewFlagSet("", flag.ContinueOnError)), 2)
+}
+func (p *terminalCmd) Usage() string {
+	return client.GenerateUsage(subPackage, p.Synopsis())
+}
+func (*terminalCmd) SetFlags(f *flag.FlagSet) {}
+
+func (p *terminalCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	c := p.GetSubpackage(f)
+	return c.Execute(ctx, args...)
+}
+
+type openCmd struct {
+	user string
+}
+
+func (*openCmd) Name() string { return "open" }
+func (*openCmd) Synopsis() string {
+	return "Open an interactive, recorded, terminal on a single target."
+}
+func (*openCmd) Usage() string {
+	return `open [--user=user] [<command> [<args>...]]:
+  Run a command, by default ` + DefaultCommand + `, on a new terminal on the target and
+  connect it to this one. The whole session is recorded on the target.
+`
+}
+
+func (p *openCmd) SetFlags(f *flag.FlagSet) {
+	f.StringVar(&p.user, "user", "", "If specified, the user to run the command as.")
+}
+
+func (p *openCmd) Execute(ctx context.Context, f *flag.FlagSet, args ...interface{}) subcommands.ExitStatus {
+	state := args[0].(*util.ExecuteState)
+	if len(state.Conn.Targets) != 1 {
+		fmt.Fprintln(os.Stderr, "terminal open only supports a single target")
+		return subcommands.ExitUsageError
+	}
+	start := &pb.StartSession{
+		Command: DefaultCommand,
+		User:    p.user,
+		Term:    os.Getenv("TERM"),
+	}
+	if f.NArg() > 0 {
+		start.Command = f.Arg(0)
+		start.Args = f.Args()[1:]
+	}
+	stdin := int(os.Stdin.Fd())
+	interactive := term.IsTerminal(stdin)
+	if interactive {
+		start.Size = windowSize(stdin)
+	}
+
+	c := pb.NewTerminalClientProxy(state.Conn)
+	stream, err := c.SessionOneMany(ctx)
+	if err == nil {
+		err = stream.Send(&pb.SessionRequest{Request: &pb.SessionRequest_Start{Start: start}})
+	}
+	if err != nil {
+		fmt.Fprintf(state.Err[0], "Could not start session: %v\n", err)
+		return subcommands.ExitFailure
+	}
+
+	if interactive {
+		old, err := term.MakeRaw(stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Can't put terminal in raw mode: %v\n", err)
+			return subcommands.ExitFailure
+		}
+		defer term.Restore(stdin, old)
+	}
+
+	// Input and window size changes are sent from their own goroutines.
+	var sendMu sync.Mutex
+	send := func(req *pb.SessionRequest) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		return stream.Send(req)
+	}
+	if interactive {
+		winch := make(chan os.Signal, 1)
+		signal.Notify(winch, syscall.SIGWINCH)
+		defer signal.Stop(winch)
+		go func() {
+			for range winch {
+				if err := send(&pb.SessionRequest{Request: &pb.SessionRequest_Resize{Resize: windowSize(stdin)}}); err != nil {
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if n > 0 {
+				if err := send(&pb.SessionRequest{Request: &pb.SessionRequest_Input{Input: buf[:n]}}); err != nil {
+					return
+				}
+			}
+			if err != nil {
+				sendMu.Lock()
+				stream.CloseSend()
+				sendMu.Unlock()
+				return
+			}
+		}
+	}()
+
+	status := subcommands.ExitSuccess
+	for {
+		rs, err := stream.Recv()
+		if err == io.EOF {
+			return status
+		}
+		if err != nil {
+			fmt.Fprintf(state.Err[0], "\r\nStream failure: %v\r\n", err)
+			return subcommands.ExitFailure
+		}
+		for _, r := range rs {
+			if r.Error != nil {
+				if r.Error != io.EOF {
+					fmt.Fprintf(state.Err[0], "\r\nSession failure: %v\r\n", r.Error)
+					status = subcommands.ExitFailure
+				}
+				continue
+			}
+			switch reply := r.Resp.Reply.(type) {
+			case *pb.SessionReply_Started:
+				fmt.Fprintf(state.Err[0], "Session %s is recorded to %s\r\n", reply.Started.Id, reply.Started.RecordingPath)
+			case *pb.SessionReply_Output:
+				state.Out[0].Write(reply.Output)
+			case *pb.SessionReply_Exit:
+				if reply.Exit.RecordingUrl != "" {
+					fmt.Fprintf(state.Err[0], "\r\nSession recording uploaded to %s\r\n", reply.Exit.RecordingUrl)
+				}
+				if reply.Exit.RetCode != 0 {
+					status = subcommands.ExitFailure
+				}
+			}
+		}
+	}
+}
+
+// windowSize returns the size of the terminal fd.
+func windowSize(fd int) *pb.WindowSize {
+	cols, rows, err := term.GetSize(fd)
+	if err != nil {
+		return nil
+	}
+	return &pb.WindowSize{Rows: uint32(rows), Cols: uint32(cols)}
+}
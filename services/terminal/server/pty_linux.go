@@ -0,0 +1,82 @@
+//go:build linux
+// +build linux
+
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// openPTY allocates a PTY, returning its controlling side and its terminal
+// side.
+func openPTY() (*os.File, *os.File, error) {
+	ptm, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.Internal, "can't open /dev/ptmx: %v", err)
+	}
+	// Use the raw connection rather than Fd(), which would make reads
+	// blocking and so impossible to interrupt with Close.
+	var n int
+	var ioctlErr error
+	err = control(ptm, func(fd int) {
+		if ioctlErr = unix.IoctlSetPointerInt(fd, unix.TIOCSPTLCK, 0); ioctlErr != nil {
+			return
+		}
+		n, ioctlErr = unix.IoctlGetInt(fd, unix.TIOCGPTN)
+	})
+	if err == nil {
+		err = ioctlErr
+	}
+	if err != nil {
+		ptm.Close()
+		return nil, nil, status.Errorf(codes.Internal, "can't set up PTY: %v", err)
+	}
+	pts, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		ptm.Close()
+		return nil, nil, status.Errorf(codes.Internal, "can't open PTY: %v", err)
+	}
+	return ptm, pts, nil
+}
+
+// setWindowSize sets the size of the terminal on ptm.
+func setWindowSize(ptm *os.File, rows, cols uint32) error {
+	var ioctlErr error
+	err := control(ptm, func(fd int) {
+		ioctlErr = unix.IoctlSetWinsize(fd, unix.TIOCSWINSZ, &unix.Winsize{Row: uint16(rows), Col: uint16(cols)})
+	})
+	if err == nil {
+		err = ioctlErr
+	}
+	return err
+}
+
+// control runs fn with f's file descriptor.
+func control(f *os.File, fn func(fd int)) error {
+	raw, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	return raw.Control(func(fd uintptr) { fn(int(fd)) })
+}
@@ -0,0 +1,146 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// castHeader is the first line of an asciicast v2 recording. See
+// https://docs.asciinema.org/manual/asciicast/v2/
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     uint32            `json:"width"`
+	Height    uint32            `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Command   string            `json:"command,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// Event codes in an asciicast recording.
+const (
+	castOutput = "o"
+	castInput  = "i"
+	castResize = "r"
+)
+
+// castWriter records a session in asciicast v2 format.
+type castWriter struct {
+	start time.Time
+
+	mu sync.Mutex
+	f  *os.File
+	w  *bufio.Writer
+	// partial holds the end of the last data of each event code if it was
+	// cut off in the middle of a UTF-8 sequence, as asciicast events must
+	// be valid UTF-8.
+	partial map[string][]byte
+	err     error
+}
+
+// newCastWriter creates a recording at path, starting with header.
+func newCastWriter(path string, header castHeader) (*castWriter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, err
+	}
+	c := &castWriter{
+		start:   time.Now(),
+		f:       f,
+		w:       bufio.NewWriter(f),
+		partial: make(map[string][]byte),
+	}
+	header.Version = 2
+	header.Timestamp = c.start.Unix()
+	c.writeLine(header)
+	return c, nil
+}
+
+// event records data as an event with the given code.
+func (c *castWriter) event(code string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.partial == nil {
+		// Already closed.
+		return
+	}
+	data = append(c.partial[code], data...)
+	cut := incompleteSuffix(data)
+	c.partial[code] = append([]byte(nil), data[cut:]...)
+	if cut == 0 {
+		return
+	}
+	c.writeLine([]any{time.Since(c.start).Seconds(), code, string(data[:cut])})
+}
+
+// writeLine writes v as a line of JSON. Errors are kept for close.
+func (c *castWriter) writeLine(v any) {
+	if c.err != nil {
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		c.err = err
+		return
+	}
+	b = append(b, '\n')
+	_, c.err = c.w.Write(b)
+}
+
+// close writes anything left over and closes the recording.
+func (c *castWriter) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.partial == nil {
+		// Already closed.
+		return c.err
+	}
+	for code, data := range c.partial {
+		if len(data) > 0 {
+			c.writeLine([]any{time.Since(c.start).Seconds(), code, string(data)})
+		}
+	}
+	c.partial = nil
+	if err := c.w.Flush(); err != nil && c.err == nil {
+		c.err = err
+	}
+	if err := c.f.Close(); err != nil && c.err == nil {
+		c.err = err
+	}
+	return c.err
+}
+
+// incompleteSuffix returns the length of data excluding a UTF-8 sequence
+// cut off at its end, if any.
+func incompleteSuffix(data []byte) int {
+	// A sequence is at most utf8.UTFMax bytes, so only look that far back
+	// for its start.
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(data)
+}
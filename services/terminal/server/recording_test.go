@@ -0,0 +1,83 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+func TestCastWriterSplitsUTF8(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.cast")
+	c, err := newCastWriter(path, castHeader{Width: 80, Height: 24})
+	testutil.FatalOnErr("newCastWriter", err, t)
+	// "é" is two bytes, sent in separate events.
+	e := []byte("é")
+	c.event(castOutput, append([]byte("caf"), e[0]))
+	c.event(castOutput, e[1:])
+	// A trailing partial sequence is written out on close.
+	c.event(castInput, e[:1])
+	testutil.FatalOnErr("close", c.close(), t)
+
+	f, err := os.Open(path)
+	testutil.FatalOnErr("opening recording", err, t)
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	var got []string
+	for scanner.Scan() {
+		var event []any
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			// The header isn't an array.
+			continue
+		}
+		got = append(got, event[1].(string)+":"+event[2].(string))
+	}
+	want := []string{"o:caf", "o:é", "i:\ufffd"}
+	if len(got) != len(want) {
+		t.Fatalf("events = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("events = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestIncompleteSuffix(t *testing.T) {
+	for _, tc := range []struct {
+		data string
+		want int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"abé", 4},
+		{"ab\xc3", 2},
+		{"\xe2\x82", 0},
+		{"a\xe2\x82\xac", 4},
+		// Invalid data is passed through as is.
+		{"a\x80", 2},
+	} {
+		if got := incompleteSuffix([]byte(tc.data)); got != tc.want {
+			t.Errorf("incompleteSuffix(%q) = %d, want %d", tc.data, got, tc.want)
+		}
+	}
+}
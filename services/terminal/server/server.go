@@ -0,0 +1,362 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+// Package server implements the sansshell 'Terminal' service.
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"gocloud.dev/blob"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Snowflake-Labs/sansshell/services"
+	"github.com/Snowflake-Labs/sansshell/services/mpa/mpahooks"
+	pb "github.com/Snowflake-Labs/sansshell/services/terminal"
+	"github.com/Snowflake-Labs/sansshell/services/util"
+	"github.com/Snowflake-Labs/sansshell/telemetry/metrics"
+)
+
+var (
+	// RecordingDir is where sessions are recorded, one asciicast file per
+	// session.
+	RecordingDir = filepath.Join(os.TempDir(), "sansshell-recordings")
+
+	// RecordingBucket, if set, is a blob bucket URL, such as
+	// s3://bucket?region=us-west-2, which recordings are uploaded to when
+	// their session ends. They are stored under the host's name.
+	RecordingBucket = ""
+
+	// DefaultPath is the PATH given to session commands.
+	DefaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+)
+
+// Metrics
+var (
+	terminalSessionFailureCounter = metrics.MetricDefinition{Name: "actions_terminal_session_failure",
+		Description: "number of failures when performing terminal.Session"}
+)
+
+// Default terminal settings for sessions which don't give them.
+const (
+	defaultTerm = "xterm"
+	defaultRows = 24
+	defaultCols = 80
+)
+
+// server is used to implement the gRPC server
+type server struct{}
+
+// Session implements TerminalServer.
+func (s *server) Session(stream pb.Terminal_SessionServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	logger := logr.FromContextOrDiscard(ctx)
+	recorder := metrics.RecorderFromContextOrNoop(ctx)
+
+	in, err := stream.Recv()
+	if err == io.EOF {
+		return status.Error(codes.InvalidArgument, "no session started")
+	}
+	if err != nil {
+		return err
+	}
+	start := in.GetStart()
+	if start == nil {
+		recorder.CounterOrLog(ctx, terminalSessionFailureCounter, 1, attribute.String("reason", "invalid_args"))
+		return status.Error(codes.InvalidArgument, "the first message must start the session")
+	}
+	if err := util.ValidPath(start.Command); err != nil {
+		recorder.CounterOrLog(ctx, terminalSessionFailureCounter, 1, attribute.String("reason", "invalid_args"))
+		return err
+	}
+	term := start.Term
+	if term == "" {
+		term = defaultTerm
+	}
+	rows, cols := uint32(defaultRows), uint32(defaultCols)
+	if start.Size != nil && start.Size.Rows != 0 && start.Size.Cols != 0 {
+		rows, cols = start.Size.Rows, start.Size.Cols
+	}
+	opts, err := userOptions(start.User)
+	if err != nil {
+		recorder.CounterOrLog(ctx, terminalSessionFailureCounter, 1, attribute.String("reason", "user_err"))
+		return err
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(RecordingDir, 0700); err != nil {
+		recorder.CounterOrLog(ctx, terminalSessionFailureCounter, 1, attribute.String("reason", "recording_err"))
+		return status.Errorf(codes.Internal, "can't create recording directory: %v", err)
+	}
+	recordingPath := filepath.Join(RecordingDir, id+".cast")
+	cast, err := newCastWriter(recordingPath, castHeader{
+		Width:   cols,
+		Height:  rows,
+		Command: strings.Join(append([]string{start.Command}, start.Args...), " "),
+		Env:     map[string]string{"TERM": term},
+	})
+	if err != nil {
+		recorder.CounterOrLog(ctx, terminalSessionFailureCounter, 1, attribute.String("reason", "recording_err"))
+		return status.Errorf(codes.Internal, "can't create recording: %v", err)
+	}
+	defer cast.close()
+
+	ptm, pts, err := openPTY()
+	if err != nil {
+		recorder.CounterOrLog(ctx, terminalSessionFailureCounter, 1, attribute.String("reason", "pty_err"))
+		return err
+	}
+	defer ptm.Close()
+	if err := setWindowSize(ptm, rows, cols); err != nil {
+		pts.Close()
+		recorder.CounterOrLog(ctx, terminalSessionFailureCounter, 1, attribute.String("reason", "pty_err"))
+		return status.Errorf(codes.Internal, "can't set window size: %v", err)
+	}
+	opts = append(opts, util.CommandStdin(pts), util.CommandNewSession(), util.EnvVar("TERM="+term))
+	cmd, err := util.StartCommand(ctx, start.Command, start.Args, pts, pts, opts...)
+	// The command has its own copy now.
+	pts.Close()
+	if err != nil {
+		recorder.CounterOrLog(ctx, terminalSessionFailureCounter, 1, attribute.String("reason", "start_err"))
+		return err
+	}
+	logger.Info("terminal session started", "id", id, "command", start.Command, "user", start.User, "recording", recordingPath)
+
+	go func() {
+		// Unblock relayOutput if the client goes away.
+		<-ctx.Done()
+		ptm.Close()
+	}()
+	inputErr := make(chan error, 1)
+	started := &pb.SessionReply{Reply: &pb.SessionReply_Started{Started: &pb.SessionStarted{Id: id, RecordingPath: recordingPath}}}
+	if err := stream.Send(started); err != nil {
+		cancel()
+	} else {
+		go relayInput(ctx, stream, ptm, cast, inputErr, cancel)
+		if err := relayOutput(stream, ptm, cast); err != nil {
+			// The client has gone away, so kill the command.
+			cancel()
+		}
+	}
+	err = cmd.Wait()
+	retCode := cmd.ProcessState.ExitCode()
+	logger.Info("terminal session ended", "id", id, "code", retCode, "err", err)
+
+	exit := &pb.SessionExit{RetCode: int32(retCode)}
+	if err := cast.close(); err != nil {
+		recorder.CounterOrLog(ctx, terminalSessionFailureCounter, 1, attribute.String("reason", "recording_err"))
+		logger.Error(err, "can't write recording", "id", id, "recording", recordingPath)
+	} else if RecordingBucket != "" {
+		// Upload even if the client has gone away.
+		url, err := uploadRecording(context.WithoutCancel(ctx), recordingPath, id)
+		if err != nil {
+			recorder.CounterOrLog(ctx, terminalSessionFailureCounter, 1, attribute.String("reason", "upload_err"))
+			logger.Error(err, "can't upload recording", "id", id, "recording", recordingPath, "bucket", RecordingBucket)
+		}
+		exit.RecordingUrl = url
+	}
+
+	select {
+	case err := <-inputErr:
+		return err
+	default:
+	}
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		recorder.CounterOrLog(ctx, terminalSessionFailureCounter, 1, attribute.String("reason", "wait_err"))
+		return status.Errorf(codes.Internal, "error waiting for command: %v", err)
+	}
+	if stream.Context().Err() != nil {
+		// Nobody to tell.
+		return stream.Context().Err()
+	}
+	return stream.Send(&pb.SessionReply{Reply: &pb.SessionReply_Exit{Exit: exit}})
+}
+
+// relayInput writes input from the client to the terminal and applies
+// window size changes until the client stops sending. A message trying to
+// start another session is sent to errs, and ends the session.
+func relayInput(ctx context.Context, stream pb.Terminal_SessionServer, ptm *os.File, cast *castWriter, errs chan<- error, cancel func()) {
+	logger := logr.FromContextOrDiscard(ctx)
+	for {
+		in, err := stream.Recv()
+		if err != nil {
+			// EOF means the client won't send any more input, but the
+			// command carries on until it exits. Anything else means
+			// the stream is done.
+			return
+		}
+		switch r := in.Request.(type) {
+		case *pb.SessionRequest_Input:
+			cast.event(castInput, r.Input)
+			if _, err := ptm.Write(r.Input); err != nil {
+				return
+			}
+		case *pb.SessionRequest_Resize:
+			rows, cols := r.Resize.GetRows(), r.Resize.GetCols()
+			cast.event(castResize, []byte(fmt.Sprintf("%dx%d", cols, rows)))
+			if err := setWindowSize(ptm, rows, cols); err != nil {
+				logger.Error(err, "can't resize terminal")
+			}
+		default:
+			errs <- status.Error(codes.InvalidArgument, "only the first message may start a session")
+			cancel()
+			return
+		}
+	}
+}
+
+// relayOutput sends everything written to the terminal to the client until
+// the terminal is closed, which happens once every process using it has
+// exited. It returns an error if sending fails.
+func relayOutput(stream pb.Terminal_SessionServer, ptm *os.File, cast *castWriter) error {
+	buf := make([]byte, util.StreamingChunkSize)
+	for {
+		n, err := ptm.Read(buf)
+		if n > 0 {
+			cast.event(castOutput, buf[:n])
+			if err := stream.Send(&pb.SessionReply{Reply: &pb.SessionReply_Output{Output: buf[:n]}}); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			// Linux returns EIO once the other side of the PTY is closed.
+			return nil
+		}
+	}
+}
+
+// userOptions returns the options to run a session's command as username,
+// or as the server's user if it's empty, along with its environment.
+func userOptions(username string) ([]util.Option, error) {
+	var u *user.User
+	var err error
+	if username == "" {
+		u, err = user.Current()
+	} else {
+		u, err = user.Lookup(username)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "can't find user %q: %v", username, err)
+	}
+	opts := []util.Option{
+		util.EnvVar("HOME=" + u.HomeDir),
+		util.EnvVar("USER=" + u.Username),
+		util.EnvVar("LOGNAME=" + u.Username),
+		util.EnvVar("PATH=" + DefaultPath),
+	}
+	if fi, err := os.Stat(u.HomeDir); err == nil && fi.IsDir() {
+		opts = append(opts, util.CommandDir(u.HomeDir))
+	}
+	if username != "" {
+		// This will work only on POSIX (Windows has non-decimal uids) yet these are our targets.
+		uid, err := strconv.ParseUint(u.Uid, 10, 32)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "%q has a non-numeric uid %s", username, u.Uid)
+		}
+		gid, err := strconv.ParseUint(u.Gid, 10, 32)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "%q has a non-numeric gid %s", username, u.Gid)
+		}
+		opts = append(opts, util.CommandUser(uint32(uid)), util.CommandGroup(uint32(gid)))
+	}
+	return opts, nil
+}
+
+// newSessionID returns a new, sortable, session ID.
+func newSessionID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", status.Errorf(codes.Internal, "can't generate session ID: %v", err)
+	}
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b), nil
+}
+
+// uploadRecording copies the recording at path to RecordingBucket, under
+// the host's name, and returns its URL.
+func uploadRecording(ctx context.Context, path, id string) (string, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	key := host + "/" + id + ".cast"
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	b, err := blob.OpenBucket(ctx, RecordingBucket)
+	if err != nil {
+		return "", err
+	}
+	defer b.Close()
+	w, err := b.NewWriter(ctx, key, &blob.WriterOptions{ContentType: "application/x-asciicast"})
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(w, f); err != nil {
+		w.Close()
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	return objectURL(RecordingBucket, key), nil
+}
+
+// objectURL returns the URL of the object key in the bucket at bucketURL.
+func objectURL(bucketURL, key string) string {
+	u, err := url.Parse(bucketURL)
+	if err != nil {
+		return bucketURL + " " + key
+	}
+	u.Path = path.Join(u.Path, key)
+	return u.String()
+}
+
+// Register is called to expose this handler to the gRPC server
+func (s *server) Register(gs *grpc.Server) {
+	pb.RegisterTerminalServer(gs, s)
+}
+
+func init() {
+	services.RegisterSansShellService(&server{})
+	// Terminal input can't be known when a session is approved, so
+	// approving the start of a session approves the whole session.
+	mpahooks.ApproveFirstMessageOnly(pb.Terminal_Session_FullMethodName)
+}
@@ -0,0 +1,221 @@
+//go:build linux
+// +build linux
+
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "gocloud.dev/blob/fileblob"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+	"github.com/Snowflake-Labs/sansshell/services/mpa/mpahooks"
+	pb "github.com/Snowflake-Labs/sansshell/services/terminal"
+	"github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+var (
+	bufSize = 1024 * 1024
+	lis     *bufconn.Listener
+)
+
+func bufDialer(context.Context, string) (net.Conn, error) {
+	return lis.Dial()
+}
+
+func TestMain(m *testing.M) {
+	lis = bufconn.Listen(bufSize)
+	s := grpc.NewServer()
+	lfs := &server{}
+	lfs.Register(s)
+	go func() {
+		if err := s.Serve(lis); err != nil {
+			log.Fatalf("Server exited with error: %v", err)
+		}
+	}()
+	defer s.GracefulStop()
+
+	os.Exit(m.Run())
+}
+
+func TestSession(t *testing.T) {
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	testutil.FatalOnErr("Failed to dial bufnet", err, t)
+	t.Cleanup(func() { conn.Close() })
+	client := pb.NewTerminalClient(conn)
+
+	RecordingDir = t.TempDir()
+	bucket := t.TempDir()
+	RecordingBucket = "file://" + bucket
+	t.Cleanup(func() { RecordingBucket = "" })
+
+	stream, err := client.Session(ctx)
+	testutil.FatalOnErr("Session", err, t)
+	err = stream.Send(&pb.SessionRequest{Request: &pb.SessionRequest_Start{Start: &pb.StartSession{
+		Command: testutil.ResolvePath(t, "sh"),
+		Size:    &pb.WindowSize{Rows: 40, Cols: 100},
+	}}})
+	testutil.FatalOnErr("Send start", err, t)
+	resp, err := stream.Recv()
+	testutil.FatalOnErr("Recv started", err, t)
+	started := resp.GetStarted()
+	if started == nil {
+		t.Fatalf("first reply %v isn't started", resp)
+	}
+	for _, req := range []*pb.SessionRequest{
+		{Request: &pb.SessionRequest_Resize{Resize: &pb.WindowSize{Rows: 50, Cols: 120}}},
+		{Request: &pb.SessionRequest_Input{Input: []byte("stty size; echo $TERM; exit 3\n")}},
+	} {
+		testutil.FatalOnErr("Send", stream.Send(req), t)
+	}
+
+	var output strings.Builder
+	var exit *pb.SessionExit
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		testutil.FatalOnErr("Recv", err, t)
+		output.Write(resp.GetOutput())
+		if resp.GetExit() != nil {
+			exit = resp.GetExit()
+		}
+	}
+	t.Logf("output: %q", output.String())
+	for _, want := range []string{"50 120", "xterm"} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("output %q doesn't contain %q", output.String(), want)
+		}
+	}
+	if exit == nil {
+		t.Fatal("no exit reply")
+	}
+	if got, want := exit.RetCode, int32(3); got != want {
+		t.Errorf("RetCode = %d, want %d", got, want)
+	}
+
+	// The recording has a header, then the events in order.
+	f, err := os.Open(started.RecordingPath)
+	testutil.FatalOnErr("opening recording", err, t)
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	if !scanner.Scan() {
+		t.Fatal("empty recording")
+	}
+	var header castHeader
+	testutil.FatalOnErr("parsing header", json.Unmarshal(scanner.Bytes(), &header), t)
+	if header.Version != 2 || header.Width != 100 || header.Height != 40 {
+		t.Errorf("header = %+v, want version 2 100x40", header)
+	}
+	var events []string
+	for scanner.Scan() {
+		var event []any
+		testutil.FatalOnErr("parsing event", json.Unmarshal(scanner.Bytes(), &event), t)
+		code := event[1].(string)
+		if len(events) == 0 || events[len(events)-1] != code {
+			events = append(events, code)
+		}
+	}
+	// The shell may print a prompt at any point, but the resize and input
+	// are recorded in the order they were sent.
+	got := strings.Join(events, ",")
+	r, i, o := strings.Index(got, "r"), strings.Index(got, "i"), strings.Index(got, "o")
+	if r == -1 || i == -1 || o == -1 || r > i {
+		t.Errorf("event codes %s, want r before i and some o", got)
+	}
+
+	host, err := os.Hostname()
+	testutil.FatalOnErr("Hostname", err, t)
+	uploaded := filepath.Join(bucket, host, started.Id+".cast")
+	if _, err := os.Stat(uploaded); err != nil {
+		t.Errorf("recording wasn't uploaded: %v", err)
+	}
+	if !strings.HasSuffix(exit.RecordingUrl, host+"/"+started.Id+".cast") {
+		t.Errorf("RecordingUrl = %q, want it to name the uploaded recording", exit.RecordingUrl)
+	}
+}
+
+func TestSessionErrors(t *testing.T) {
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	testutil.FatalOnErr("Failed to dial bufnet", err, t)
+	t.Cleanup(func() { conn.Close() })
+	client := pb.NewTerminalClient(conn)
+	RecordingDir = t.TempDir()
+
+	start := &pb.SessionRequest{Request: &pb.SessionRequest_Start{Start: &pb.StartSession{Command: testutil.ResolvePath(t, "cat")}}}
+	for _, tc := range []struct {
+		name string
+		reqs []*pb.SessionRequest
+	}{
+		{
+			name: "input first",
+			reqs: []*pb.SessionRequest{{Request: &pb.SessionRequest_Input{Input: []byte("ls\n")}}},
+		},
+		{
+			name: "relative command",
+			reqs: []*pb.SessionRequest{{Request: &pb.SessionRequest_Start{Start: &pb.StartSession{Command: "sh"}}}},
+		},
+		{
+			name: "second start",
+			reqs: []*pb.SessionRequest{start, start},
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			stream, err := client.Session(ctx)
+			testutil.FatalOnErr("Session", err, t)
+			for _, req := range tc.reqs {
+				testutil.FatalOnErr("Send", stream.Send(req), t)
+			}
+			for {
+				_, err = stream.Recv()
+				if err != nil {
+					break
+				}
+			}
+			if got, want := status.Code(err), codes.InvalidArgument; got != want {
+				t.Fatalf("got code %v (%v), want %v", got, err, want)
+			}
+		})
+	}
+}
+
+func TestSessionApprovesFirstMessageOnly(t *testing.T) {
+	ctx := rpcauth.WithStreamMessageIndex(context.Background(), 1)
+	if !mpahooks.AfterApprovedMessage(ctx, pb.Terminal_Session_FullMethodName) {
+		t.Errorf("approving the start of a session doesn't cover the rest of it")
+	}
+}
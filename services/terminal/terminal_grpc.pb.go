@@ -0,0 +1,140 @@
+// Copyright (c) 2025 Snowflake Inc. All rights reserved.
+//
+//Licensed under the Apache License, Version 2.0 (the
+//"License"); you may not use this file except in compliance
+//with the License.  You may obtain a copy of the License at
+//
+//http://www.apache.org/licenses/LICENSE-2.0
+//
+//Unless required by applicable law or agreed to in writing,
+//software distributed under the License is distributed on an
+//"AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+//KIND, either express or implied.  See the License for the
+//specific language governing permissions and limitations
+//under the License.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: terminal.proto
+
+package terminal
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Terminal_Session_FullMethodName = "/Terminal.Terminal/Session"
+)
+
+// TerminalClient is the client API for Terminal service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// The Terminal service runs interactive commands, usually a shell, on a
+// PTY. Every session is recorded on the target.
+type TerminalClient interface {
+	// Session runs a command on a new PTY. The first message must be start,
+	// and later ones carry input and window size changes. The replies carry
+	// the PTY's output, and end with the command's exit status.
+	Session(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SessionRequest, SessionReply], error)
+}
+
+type terminalClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTerminalClient(cc grpc.ClientConnInterface) TerminalClient {
+	return &terminalClient{cc}
+}
+
+func (c *terminalClient) Session(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[SessionRequest, SessionReply], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Terminal_ServiceDesc.Streams[0], Terminal_Session_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[SessionRequest, SessionReply]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Terminal_SessionClient = grpc.BidiStreamingClient[SessionRequest, SessionReply]
+
+// TerminalServer is the server API for Terminal service.
+// All implementations should embed UnimplementedTerminalServer
+// for forward compatibility.
+//
+// The Terminal service runs interactive commands, usually a shell, on a
+// PTY. Every session is recorded on the target.
+type TerminalServer interface {
+	// Session runs a command on a new PTY. The first message must be start,
+	// and later ones carry input and window size changes. The replies carry
+	// the PTY's output, and end with the command's exit status.
+	Session(grpc.BidiStreamingServer[SessionRequest, SessionReply]) error
+}
+
+// UnimplementedTerminalServer should be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTerminalServer struct{}
+
+func (UnimplementedTerminalServer) Session(grpc.BidiStreamingServer[SessionRequest, SessionReply]) error {
+	return status.Errorf(codes.Unimplemented, "method Session not implemented")
+}
+func (UnimplementedTerminalServer) testEmbeddedByValue() {}
+
+// UnsafeTerminalServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TerminalServer will
+// result in compilation errors.
+type UnsafeTerminalServer interface {
+	mustEmbedUnimplementedTerminalServer()
+}
+
+func RegisterTerminalServer(s grpc.ServiceRegistrar, srv TerminalServer) {
+	// If the following call pancis, it indicates UnimplementedTerminalServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Terminal_ServiceDesc, srv)
+}
+
+func _Terminal_Session_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(TerminalServer).Session(&grpc.GenericServerStream[SessionRequest, SessionReply]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Terminal_SessionServer = grpc.BidiStreamingServer[SessionRequest, SessionReply]
+
+// Terminal_ServiceDesc is the grpc.ServiceDesc for Terminal service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Terminal_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "Terminal.Terminal",
+	HandlerType: (*TerminalServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Session",
+			Handler:       _Terminal_Session_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "terminal.proto",
+}
@@ -0,0 +1,121 @@
+// Auto generated code by protoc-gen-go-grpcproxy
+// DO NOT EDIT
+
+// Adds OneMany versions of RPC methods for use by proxy clients
+
+package terminal
+
+import (
+	context "context"
+	proxy "github.com/Snowflake-Labs/sansshell/proxy/proxy"
+	grpc "google.golang.org/grpc"
+)
+
+import (
+	"fmt"
+	"io"
+)
+
+// TerminalClientProxy is the superset of TerminalClient which additionally includes the OneMany proxy methods
+type TerminalClientProxy interface {
+	TerminalClient
+	SessionOneMany(ctx context.Context, opts ...grpc.CallOption) (Terminal_SessionClientProxy, error)
+}
+
+// Embed the original client inside of this so we get the other generated methods automatically.
+type terminalClientProxy struct {
+	*terminalClient
+}
+
+// NewTerminalClientProxy creates a TerminalClientProxy for use in proxied connections.
+// NOTE: This takes a proxy.Conn instead of a generic ClientConnInterface as the methods here are only valid in proxy.Conn contexts.
+func NewTerminalClientProxy(cc *proxy.Conn) TerminalClientProxy {
+	return &terminalClientProxy{NewTerminalClient(cc).(*terminalClient)}
+}
+
+// SessionManyResponse encapsulates a proxy data packet.
+// It includes the target, index, response and possible error returned.
+type SessionManyResponse struct {
+	Target string
+	// As targets can be duplicated this is the index into the slice passed to proxy.Conn.
+	Index int
+	Resp  *SessionReply
+	Error error
+}
+
+type Terminal_SessionClientProxy interface {
+	Send(*SessionRequest) error
+	Recv() ([]*SessionManyResponse, error)
+	grpc.ClientStream
+}
+
+type terminalClientSessionClientProxy struct {
+	cc         *proxy.Conn
+	directDone bool
+	grpc.ClientStream
+}
+
+func (x *terminalClientSessionClientProxy) Send(m *SessionRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *terminalClientSessionClientProxy) Recv() ([]*SessionManyResponse, error) {
+	var ret []*SessionManyResponse
+	// If this is a direct connection the RecvMsg call is to a standard grpc.ClientStream
+	// and not our proxy based one. This means we need to receive a typed response and
+	// convert it into a single slice entry return. This ensures the OneMany style calls
+	// can be used by proxy with 1:N targets and non proxy with 1 target without client changes.
+	if x.cc.Direct() {
+		// Check if we're done. Just return EOF now. Any real error was already sent inside
+		// of a ManyResponse.
+		if x.directDone {
+			return nil, io.EOF
+		}
+		m := &SessionReply{}
+		err := x.ClientStream.RecvMsg(m)
+		ret = append(ret, &SessionManyResponse{
+			Resp:   m,
+			Error:  err,
+			Target: x.cc.Targets[0],
+			Index:  0,
+		})
+		// An error means we're done so set things so a later call now gets an EOF.
+		if err != nil {
+			x.directDone = true
+		}
+		return ret, nil
+	}
+
+	m := []*proxy.Ret{}
+	if err := x.ClientStream.RecvMsg(&m); err != nil {
+		return nil, err
+	}
+	for _, r := range m {
+		typedResp := &SessionManyResponse{
+			Resp: &SessionReply{},
+		}
+		typedResp.Target = r.Target
+		typedResp.Index = r.Index
+		typedResp.Error = r.Error
+		if r.Error == nil {
+			if err := r.Resp.UnmarshalTo(typedResp.Resp); err != nil {
+				typedResp.Error = fmt.Errorf("can't decode any response - %v. Original Error - %v", err, r.Error)
+			}
+		}
+		ret = append(ret, typedResp)
+	}
+	return ret, nil
+}
+
+// SessionOneMany provides the same API as Session but sends the same request to N destinations at once.
+// N can be a single destination.
+//
+// NOTE: The returned channel must be read until it closes in order to avoid leaking goroutines.
+func (c *terminalClientProxy) SessionOneMany(ctx context.Context, opts ...grpc.CallOption) (Terminal_SessionClientProxy, error) {
+	stream, err := c.cc.NewStream(ctx, &Terminal_ServiceDesc.Streams[0], "/Terminal.Terminal/Session", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &terminalClientSessionClientProxy{c.cc.(*proxy.Conn), false, stream}
+	return x, nil
+}
@@ -0,0 +1,367 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+// Package server implements the sansshell 'Transfer' service.
+//
+// Unlike other services it doesn't register itself when imported, as it is
+// hosted by a proxy and talks to targets with the proxy's dialer and
+// authorizer. Create one with New and register it with the proxy's gRPC
+// server.
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/Snowflake-Labs/sansshell/auth/rpcauth"
+	proxypb "github.com/Snowflake-Labs/sansshell/proxy"
+	proxyserver "github.com/Snowflake-Labs/sansshell/proxy/server"
+	lfpb "github.com/Snowflake-Labs/sansshell/services/localfile"
+	pb "github.com/Snowflake-Labs/sansshell/services/transfer"
+	"github.com/Snowflake-Labs/sansshell/services/util"
+	"github.com/Snowflake-Labs/sansshell/telemetry/metrics"
+)
+
+// Metrics
+var (
+	transferCopyFailureCounter = metrics.MetricDefinition{Name: "actions_transfer_copy_failure",
+		Description: "number of failures when performing transfer.Copy"}
+)
+
+const (
+	readMethod  = "/LocalFile.LocalFile/Read"
+	writeMethod = "/LocalFile.LocalFile/Write"
+	sumMethod   = "/LocalFile.LocalFile/Sum"
+)
+
+// Server implements the Transfer gRPC service.
+type Server struct {
+	dialer     proxyserver.TargetDialer
+	authorizer rpcauth.RPCAuthorizer
+	serviceMap map[string]*proxyserver.ServiceMethod
+}
+
+// New returns a Server which connects to targets with dialer. Every
+// request it sends to a target is authorized with authorizer, as the proxy
+// does for requests it forwards.
+func New(dialer proxyserver.TargetDialer, authorizer rpcauth.RPCAuthorizer) *Server {
+	return &Server{
+		dialer:     dialer,
+		authorizer: authorizer,
+		serviceMap: proxyserver.LoadGlobalServiceMap(),
+	}
+}
+
+// Register is called to expose this handler to the gRPC server.
+func (s *Server) Register(gs grpc.ServiceRegistrar) {
+	pb.RegisterTransferServer(gs, s)
+}
+
+// Copy implements TransferServer.
+func (s *Server) Copy(ctx context.Context, req *pb.CopyRequest) (*pb.CopyReply, error) {
+	logger := logr.FromContextOrDiscard(ctx)
+	recorder := metrics.RecorderFromContextOrNoop(ctx)
+
+	if err := validate(req); err != nil {
+		recorder.CounterOrLog(ctx, transferCopyFailureCounter, 1, attribute.String("reason", "invalid_request"))
+		return nil, err
+	}
+	logger.Info("copy", "source", req.SourceTarget, "filename", req.SourceFilename, "destinations", req.DestinationTargets)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	want, err := s.sum(ctx, req.SourceTarget, req.SourceFilename)
+	if err != nil {
+		recorder.CounterOrLog(ctx, transferCopyFailureCounter, 1, attribute.String("reason", "source_sum_err"))
+		return nil, targetError(req.SourceTarget, "can't sum source", err)
+	}
+	reply := &pb.CopyReply{Sha256: want}
+
+	// Start every write before reading anything so that a destination
+	// which is denied or unreachable doesn't hold up the others.
+	dests := make([]*destination, len(req.DestinationTargets))
+	for i, target := range req.DestinationTargets {
+		d := &destination{result: &pb.DestinationResult{Target: target}}
+		dests[i] = d
+		reply.Destinations = append(reply.Destinations, d.result)
+		d.call, d.err = s.call(ctx, target, writeMethod)
+		if d.err != nil {
+			continue
+		}
+		defer d.call.close()
+		d.err = d.call.Send(&lfpb.WriteRequest{Request: &lfpb.WriteRequest_Description{Description: fileWrite(req)}})
+	}
+
+	src, err := s.call(ctx, req.SourceTarget, readMethod)
+	if err != nil {
+		recorder.CounterOrLog(ctx, transferCopyFailureCounter, 1, attribute.String("reason", "source_read_err"))
+		return nil, targetError(req.SourceTarget, "can't read source", err)
+	}
+	defer src.close()
+	if err := src.Send(&lfpb.ReadActionRequest{Request: &lfpb.ReadActionRequest_File{File: &lfpb.ReadRequest{Filename: req.SourceFilename}}}); err != nil {
+		recorder.CounterOrLog(ctx, transferCopyFailureCounter, 1, attribute.String("reason", "source_read_err"))
+		return nil, targetError(req.SourceTarget, "can't read source", src.finish(err))
+	}
+	h := sha256.New()
+	for {
+		resp := &lfpb.ReadReply{}
+		err := src.Recv(resp)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Returning cancels the writes, so nothing is committed.
+			recorder.CounterOrLog(ctx, transferCopyFailureCounter, 1, attribute.String("reason", "source_read_err"))
+			return nil, targetError(req.SourceTarget, "can't read source", err)
+		}
+		h.Write(resp.Contents)
+		reply.Size += int64(len(resp.Contents))
+		for _, d := range dests {
+			if d.err == nil {
+				d.err = d.call.Send(&lfpb.WriteRequest{Request: &lfpb.WriteRequest_Contents{Contents: resp.Contents}})
+			}
+		}
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		recorder.CounterOrLog(ctx, transferCopyFailureCounter, 1, attribute.String("reason", "source_changed"))
+		return nil, status.Errorf(codes.Aborted, "%s changed on %s while it was copied: its sha256 was %s but %s was read", req.SourceFilename, req.SourceTarget, want, got)
+	}
+
+	// What was read is what was summed, so commit the writes and check
+	// that each destination has the same.
+	wg := sync.WaitGroup{}
+	for _, d := range dests {
+		wg.Add(1)
+		go func(d *destination) {
+			defer wg.Done()
+			d.commit(ctx, s, req.DestinationFilename, want)
+		}(d)
+	}
+	wg.Wait()
+	for _, d := range dests {
+		if d.result.Code != int32(codes.OK) {
+			recorder.CounterOrLog(ctx, transferCopyFailureCounter, 1, attribute.String("reason", "destination_err"))
+			logger.Info("copy to destination failed", "target", d.result.Target, "code", codes.Code(d.result.Code), "message", d.result.Message)
+		}
+	}
+	return reply, nil
+}
+
+// validate checks that req is complete.
+func validate(req *pb.CopyRequest) error {
+	if req.SourceTarget == "" {
+		return status.Error(codes.InvalidArgument, "source_target must be filled in")
+	}
+	if err := util.ValidPath(req.SourceFilename); err != nil {
+		return err
+	}
+	if len(req.DestinationTargets) == 0 {
+		return status.Error(codes.InvalidArgument, "at least one destination target must be given")
+	}
+	seen := make(map[string]bool)
+	for _, t := range req.DestinationTargets {
+		if t == "" {
+			return status.Error(codes.InvalidArgument, "destination targets can't be empty")
+		}
+		if seen[t] {
+			return status.Errorf(codes.InvalidArgument, "destination target %s given more than once", t)
+		}
+		seen[t] = true
+	}
+	return util.ValidPath(req.DestinationFilename)
+}
+
+// fileWrite returns the description of the file to write for req.
+func fileWrite(req *pb.CopyRequest) *lfpb.FileWrite {
+	attrs := []*lfpb.FileAttribute{
+		{Value: &lfpb.FileAttribute_Mode{Mode: req.Mode}},
+	}
+	if req.Username != "" {
+		attrs = append(attrs, &lfpb.FileAttribute{Value: &lfpb.FileAttribute_Username{Username: req.Username}})
+	} else {
+		attrs = append(attrs, &lfpb.FileAttribute{Value: &lfpb.FileAttribute_Uid{Uid: req.Uid}})
+	}
+	if req.Group != "" {
+		attrs = append(attrs, &lfpb.FileAttribute{Value: &lfpb.FileAttribute_Group{Group: req.Group}})
+	} else {
+		attrs = append(attrs, &lfpb.FileAttribute{Value: &lfpb.FileAttribute_Gid{Gid: req.Gid}})
+	}
+	return &lfpb.FileWrite{
+		Attrs:     &lfpb.FileAttributes{Filename: req.DestinationFilename, Attributes: attrs},
+		Overwrite: req.Overwrite,
+	}
+}
+
+// targetError describes err, which came from calling target, keeping its
+// code.
+func targetError(target string, what string, err error) error {
+	return status.Errorf(status.Code(err), "%s on %s: %s", what, target, status.Convert(err).Message())
+}
+
+// sum returns the hex encoded SHA-256 of filename on target.
+func (s *Server) sum(ctx context.Context, target string, filename string) (string, error) {
+	c, err := s.call(ctx, target, sumMethod)
+	if err != nil {
+		return "", err
+	}
+	defer c.close()
+	if err := c.Send(&lfpb.SumRequest{Filename: filename, SumType: lfpb.SumType_SUM_TYPE_SHA256}); err != nil {
+		return "", c.finish(err)
+	}
+	c.stream.CloseSend()
+	resp := &lfpb.SumReply{}
+	if err := c.Recv(resp); err != nil {
+		if err == io.EOF {
+			return "", status.Errorf(codes.Internal, "no sum returned for %s", filename)
+		}
+		return "", err
+	}
+	if err := c.finish(nil); err != nil {
+		return "", err
+	}
+	if resp.SumType != lfpb.SumType_SUM_TYPE_SHA256 {
+		return "", status.Errorf(codes.Internal, "asked for a sha256 of %s but got %v", filename, resp.SumType)
+	}
+	return resp.Sum, nil
+}
+
+// A destination is a file being written to one destination target.
+type destination struct {
+	call *targetCall
+	// err is the first error sending to call.
+	err    error
+	result *pb.DestinationResult
+}
+
+// commit finishes writing the file and checks it on the target has the
+// sha256 want. The outcome is recorded in d.result.
+func (d *destination) commit(ctx context.Context, s *Server, filename string, want string) {
+	err := d.err
+	if d.call != nil {
+		err = d.call.finish(d.err)
+	}
+	if err == nil {
+		var got string
+		got, err = s.sum(ctx, d.result.Target, filename)
+		if err == nil && got != want {
+			err = status.Errorf(codes.DataLoss, "%s was written with sha256 %s, want %s", filename, got, want)
+		}
+	}
+	if err != nil {
+		st := status.Convert(err)
+		d.result.Code = int32(st.Code())
+		d.result.Message = st.Message()
+	}
+}
+
+// A targetCall is a call to a target made through a TargetStream, which
+// authorizes each request exactly as the proxy does for requests from
+// clients.
+type targetCall struct {
+	stream  *proxyserver.TargetStream
+	method  *proxyserver.ServiceMethod
+	replies chan *proxypb.ProxyReply
+	// err is the final status of the call, once it has been received.
+	err error
+}
+
+// call starts a call of method on target.
+func (s *Server) call(ctx context.Context, target string, method string) (*targetCall, error) {
+	sm, ok := s.serviceMap[method]
+	if !ok {
+		return nil, status.Errorf(codes.Internal, "unknown method %s", method)
+	}
+	ts, err := proxyserver.NewTargetStream(ctx, target, s.dialer, nil, sm, s.authorizer, false)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "can't create stream to %s: %v", target, err)
+	}
+	c := &targetCall{
+		stream:  ts,
+		method:  sm,
+		replies: make(chan *proxypb.ProxyReply),
+	}
+	go func() {
+		ts.Run(0, c.replies)
+		close(c.replies)
+	}()
+	return c, nil
+}
+
+// Send sends req on the call.
+func (c *targetCall) Send(req proto.Message) error {
+	return c.stream.Send(req)
+}
+
+// Recv receives the next reply into msg. It returns io.EOF once the call
+// has finished successfully or its status otherwise.
+func (c *targetCall) Recv(msg proto.Message) error {
+	if c.err != nil {
+		return c.err
+	}
+	reply, ok := <-c.replies
+	if !ok {
+		c.err = status.Error(codes.Internal, "stream ended without a status")
+		return c.err
+	}
+	switch r := reply.Reply.(type) {
+	case *proxypb.ProxyReply_StreamData:
+		return r.StreamData.Payload.UnmarshalTo(msg)
+	case *proxypb.ProxyReply_ServerClose:
+		c.err = io.EOF
+		if st := r.ServerClose.Status; st != nil && st.Code != int32(codes.OK) {
+			c.err = status.ErrorProto(&spb.Status{Code: st.Code, Message: st.Message, Details: st.Details})
+		}
+		return c.err
+	default:
+		c.err = status.Errorf(codes.Internal, "unexpected reply %T", r)
+		return c.err
+	}
+}
+
+// finish half-closes the call and waits for its status, discarding any
+// replies. If sendErr, an error sending on the call, is set it's returned
+// when the status doesn't explain it.
+func (c *targetCall) finish(sendErr error) error {
+	c.stream.CloseSend()
+	for {
+		err := c.Recv(c.method.NewReply())
+		if err == nil {
+			continue
+		}
+		if err == io.EOF {
+			return sendErr
+		}
+		return err
+	}
+}
+
+// close cancels the call if it's still running and waits for it to end.
+func (c *targetCall) close() {
+	c.stream.ClientCancel()
+	for range c.replies {
+	}
+}
@@ -0,0 +1,180 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	proxyserver "github.com/Snowflake-Labs/sansshell/proxy/server"
+	"github.com/Snowflake-Labs/sansshell/proxy/testutil"
+	"github.com/Snowflake-Labs/sansshell/services"
+	_ "github.com/Snowflake-Labs/sansshell/services/localfile/server"
+	pb "github.com/Snowflake-Labs/sansshell/services/transfer"
+	tu "github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+const policy = `
+package sansshell.authz
+
+default allow = false
+
+allow {
+	input.method = "/LocalFile.LocalFile/Sum"
+}
+
+allow {
+	input.method = "/LocalFile.LocalFile/Read"
+	input.host.net.address != "denied"
+}
+
+allow {
+	input.method = "/LocalFile.LocalFile/Write"
+	input.host.net.address != "denied"
+}
+`
+
+// startTargets starts a sansshell server with every imported service for
+// each name.
+func startTargets(t *testing.T, names ...string) map[string]*bufconn.Listener {
+	t.Helper()
+	targets := make(map[string]*bufconn.Listener)
+	for _, name := range names {
+		lis := bufconn.Listen(testutil.BufSize)
+		s := grpc.NewServer()
+		for _, svc := range services.ListServices() {
+			svc.Register(s)
+		}
+		go func() {
+			_ = s.Serve(lis)
+		}()
+		t.Cleanup(s.Stop)
+		targets[name] = lis
+	}
+	return targets
+}
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+	targets := startTargets(t, "src", "dst1", "dst2", "denied")
+	dialer := proxyserver.NewDialer(testutil.WithBufDialer(targets), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	s := New(dialer, testutil.NewOpaRPCAuthorizer(ctx, t, policy))
+
+	// All the targets share a filesystem, so the destinations overwrite
+	// the same file.
+	contents := make([]byte, 5*1024*1024)
+	for i := range contents {
+		contents[i] = byte(i % 251)
+	}
+	sum := sha256.Sum256(contents)
+	wantSum := hex.EncodeToString(sum[:])
+	src := filepath.Join(t.TempDir(), "src")
+	tu.FatalOnErr("WriteFile", os.WriteFile(src, contents, 0644), t)
+	dst := filepath.Join(t.TempDir(), "dst")
+
+	uid, gid := uint32(os.Getuid()), uint32(os.Getgid())
+	reply, err := s.Copy(ctx, &pb.CopyRequest{
+		SourceTarget:        "src",
+		SourceFilename:      src,
+		DestinationTargets:  []string{"dst1", "denied", "dst2"},
+		DestinationFilename: dst,
+		Uid:                 uid,
+		Gid:                 gid,
+		Mode:                0600,
+		Overwrite:           true,
+	})
+	tu.FatalOnErr("Copy", err, t)
+	if reply.Sha256 != wantSum {
+		t.Errorf("Sha256 = %s, want %s", reply.Sha256, wantSum)
+	}
+	if reply.Size != int64(len(contents)) {
+		t.Errorf("Size = %d, want %d", reply.Size, len(contents))
+	}
+	wantCodes := map[string]codes.Code{"dst1": codes.OK, "denied": codes.PermissionDenied, "dst2": codes.OK}
+	if len(reply.Destinations) != len(wantCodes) {
+		t.Fatalf("got %d destination results, want %d", len(reply.Destinations), len(wantCodes))
+	}
+	for _, d := range reply.Destinations {
+		if got, want := codes.Code(d.Code), wantCodes[d.Target]; got != want {
+			t.Errorf("%s: code %v (%s), want %v", d.Target, got, d.Message, want)
+		}
+	}
+	got, err := os.ReadFile(dst)
+	tu.FatalOnErr("ReadFile", err, t)
+	if string(got) != string(contents) {
+		t.Errorf("%s doesn't have the source's contents", dst)
+	}
+
+	for _, tc := range []struct {
+		name string
+		req  *pb.CopyRequest
+		want codes.Code
+	}{
+		{
+			name: "source denied",
+			req:  &pb.CopyRequest{SourceTarget: "denied", SourceFilename: src, DestinationTargets: []string{"dst1"}, DestinationFilename: dst, Overwrite: true},
+			want: codes.PermissionDenied,
+		},
+		{
+			name: "no destinations",
+			req:  &pb.CopyRequest{SourceTarget: "src", SourceFilename: src, DestinationFilename: dst},
+			want: codes.InvalidArgument,
+		},
+		{
+			name: "duplicate destination",
+			req:  &pb.CopyRequest{SourceTarget: "src", SourceFilename: src, DestinationTargets: []string{"dst1", "dst1"}, DestinationFilename: dst},
+			want: codes.InvalidArgument,
+		},
+		{
+			name: "relative source",
+			req:  &pb.CopyRequest{SourceTarget: "src", SourceFilename: "src", DestinationTargets: []string{"dst1"}, DestinationFilename: dst},
+			want: codes.InvalidArgument,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := s.Copy(ctx, tc.req)
+			if got := status.Code(err); got != tc.want {
+				t.Fatalf("got code %v (%v), want %v", got, err, tc.want)
+			}
+		})
+	}
+
+	// Without overwrite an existing destination fails, and is left alone.
+	reply, err = s.Copy(ctx, &pb.CopyRequest{
+		SourceTarget:        "src",
+		SourceFilename:      src,
+		DestinationTargets:  []string{"dst1"},
+		DestinationFilename: dst,
+		Uid:                 uid,
+		Gid:                 gid,
+		Mode:                0600,
+	})
+	tu.FatalOnErr("Copy", err, t)
+	if got := codes.Code(reply.Destinations[0].Code); got == codes.OK {
+		t.Errorf("copy over existing file without overwrite succeeded")
+	}
+}
@@ -0,0 +1,89 @@
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package util
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CgroupRoot is where the cgroup v2 hierarchy is mounted.
+var CgroupRoot = "/sys/fs/cgroup"
+
+// CgroupParent is the cgroup, relative to CgroupRoot, under which commands
+// run with CommandLimits get a transient cgroup. If empty the process's own
+// cgroup is used. As cgroup v2 doesn't allow processes in a cgroup whose
+// controllers are enabled for its children, the process then moves itself
+// into a "server" leaf beneath it. Either way the cgroup must be delegated
+// to the process, such as with systemd's Delegate=yes.
+var CgroupParent = ""
+
+// ResourceLimits are limits on the resources a command may use, enforced
+// by running it in its own cgroup v2. Fields which are zero aren't limited.
+type ResourceLimits struct {
+	// CPUMillis is the CPU time the command may use per second, in
+	// thousandths of a CPU. 500 is half a CPU and 2000 two CPUs.
+	CPUMillis int64
+	// MemoryBytes is the most memory the command may use.
+	MemoryBytes int64
+	// Pids is the most processes and threads the command may have.
+	Pids int64
+	// IOReadBPS and IOWriteBPS limit the bytes per second the command may
+	// read from and write to each block device.
+	IOReadBPS  int64
+	IOWriteBPS int64
+}
+
+// ResourceUsage is what a command run with CommandLimits used.
+type ResourceUsage struct {
+	// PeakMemoryBytes is the most memory the command used at once. It is
+	// zero on kernels which don't report it.
+	PeakMemoryBytes int64
+	// CPUTime is the CPU time, user and system, used by the command.
+	CPUTime time.Duration
+}
+
+// minCPUMillis is the smallest CPU limit, as cpu.max won't accept a quota
+// below 1ms per 100ms period.
+const minCPUMillis = 10
+
+// validate checks that the limits are ones a cgroup can enforce.
+func (l ResourceLimits) validate() error {
+	if l.CPUMillis < 0 || l.MemoryBytes < 0 || l.Pids < 0 || l.IOReadBPS < 0 || l.IOWriteBPS < 0 {
+		return status.Errorf(codes.InvalidArgument, "resource limits can't be negative: %+v", l)
+	}
+	if l.CPUMillis != 0 && l.CPUMillis < minCPUMillis {
+		return status.Errorf(codes.InvalidArgument, "CPU limit must be at least %d millis, got %d", minCPUMillis, l.CPUMillis)
+	}
+	return nil
+}
+
+// CommandLimits is an option which runs the sub-process in a transient
+// cgroup enforcing limits, under CgroupParent. The resources it used are
+// then reported in CommandRun.Usage, or by Usage on a started command.
+// Running the command fails if cgroup v2 isn't available.
+func CommandLimits(limits ResourceLimits) Option {
+	return optionfunc(func(o *cmdOptions) {
+		if limits == (ResourceLimits{}) {
+			o.limits = nil
+			return
+		}
+		o.limits = &limits
+	})
+}
@@ -0,0 +1,41 @@
+//go:build !linux
+// +build !linux
+
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package util
+
+import (
+	"os/exec"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// cgroup is a transient cgroup v2 leaf holding a single command. cgroups
+// only exist on Linux.
+type cgroup struct{}
+
+func newCgroup(limits *ResourceLimits) (*cgroup, error) {
+	return nil, status.Errorf(codes.Unimplemented, "resource limits need cgroup v2, which is only on Linux")
+}
+
+func (c *cgroup) apply(cmd *exec.Cmd) {}
+
+func (c *cgroup) usage() *ResourceUsage { return nil }
+
+func (c *cgroup) remove() {}
@@ -0,0 +1,306 @@
+//go:build linux
+// +build linux
+
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	// cgroupParentMu guards finding, and setting up, the parent cgroup.
+	cgroupParentMu sync.Mutex
+	// cgroupParentPath is the parent cgroup once it has been set up.
+	cgroupParentPath string // GUARDED_BY(cgroupParentMu)
+)
+
+// cgroup is a transient cgroup v2 leaf holding a single command.
+type cgroup struct {
+	path string
+	dir  *os.File
+}
+
+// newCgroup creates a cgroup under the parent cgroup which enforces
+// limits.
+func newCgroup(limits *ResourceLimits) (*cgroup, error) {
+	if err := limits.validate(); err != nil {
+		return nil, err
+	}
+	parent, err := cgroupParent()
+	if err != nil {
+		return nil, err
+	}
+	return createCgroup(parent, limits)
+}
+
+// cgroupParent returns the parent cgroup, setting it up if that hasn't yet
+// succeeded. Failures aren't remembered so a later command can try again.
+func cgroupParent() (string, error) {
+	cgroupParentMu.Lock()
+	defer cgroupParentMu.Unlock()
+	if cgroupParentPath != "" {
+		return cgroupParentPath, nil
+	}
+	parent, err := setupCgroupParent()
+	if err != nil {
+		return "", err
+	}
+	cgroupParentPath = parent
+	return parent, nil
+}
+
+// createCgroup creates a cgroup under parent which enforces limits.
+func createCgroup(parent string, limits *ResourceLimits) (*cgroup, error) {
+	path, err := os.MkdirTemp(parent, "sansshell-")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "can't create cgroup: %v", err)
+	}
+	cg := &cgroup{path: path}
+	if err := cg.setLimits(limits); err != nil {
+		cg.remove()
+		return nil, err
+	}
+	if cg.dir, err = os.Open(path); err != nil {
+		cg.remove()
+		return nil, status.Errorf(codes.Internal, "can't open cgroup: %v", err)
+	}
+	return cg, nil
+}
+
+// setupCgroupParent finds the cgroup to create command cgroups under and
+// enables the controllers they need in it.
+func setupCgroupParent() (string, error) {
+	if _, err := os.Stat(filepath.Join(CgroupRoot, "cgroup.controllers")); err != nil {
+		return "", status.Errorf(codes.FailedPrecondition, "resource limits need cgroup v2 mounted at %s: %v", CgroupRoot, err)
+	}
+	if CgroupParent != "" {
+		parent := filepath.Join(CgroupRoot, CgroupParent)
+		return parent, enableControllers(parent)
+	}
+
+	own, err := ownCgroup()
+	if err != nil {
+		return "", err
+	}
+	parent := filepath.Join(CgroupRoot, own)
+	err = enableControllers(parent)
+	if !errors.Is(err, syscall.EBUSY) {
+		return parent, err
+	}
+	// Our own cgroup has processes in it, namely us and any commands run
+	// without limits, so move them all into a leaf of our own first. More
+	// may be started while doing so, in which case go round again.
+	leaf := filepath.Join(parent, "server")
+	if err := os.Mkdir(leaf, 0755); err != nil && !os.IsExist(err) {
+		return "", status.Errorf(codes.FailedPrecondition, "can't create cgroup for the server: %v", err)
+	}
+	for i := 0; i < 10 && errors.Is(err, syscall.EBUSY); i++ {
+		if err := moveProcs(parent, leaf); err != nil {
+			return "", err
+		}
+		err = enableControllers(parent)
+	}
+	return parent, err
+}
+
+// moveProcs moves every process in the cgroup at from into the one at to.
+func moveProcs(from, to string) error {
+	b, err := os.ReadFile(filepath.Join(from, "cgroup.procs"))
+	if err != nil {
+		return status.Errorf(codes.FailedPrecondition, "can't list processes in %s: %v", from, err)
+	}
+	for _, pid := range strings.Fields(string(b)) {
+		err := os.WriteFile(filepath.Join(to, "cgroup.procs"), []byte(pid), 0644)
+		// The process may have exited since it was listed.
+		if err != nil && !errors.Is(err, syscall.ESRCH) {
+			return status.Errorf(codes.FailedPrecondition, "can't move process %s into %s: %v", pid, to, err)
+		}
+	}
+	return nil
+}
+
+// ownCgroup returns the cgroup v2 path of this process.
+func ownCgroup() (string, error) {
+	b, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return "", status.Errorf(codes.FailedPrecondition, "can't read own cgroup: %v", err)
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return path, nil
+		}
+	}
+	return "", status.Errorf(codes.FailedPrecondition, "not in a cgroup v2 hierarchy")
+}
+
+// enableControllers makes the controllers needed for limits available to
+// the children of the cgroup at path. Any which the cgroup doesn't have
+// are skipped, so limits needing them fail later. The returned error wraps
+// the underlying one so callers can check for EBUSY.
+func enableControllers(path string) error {
+	b, err := os.ReadFile(filepath.Join(path, "cgroup.controllers"))
+	if err != nil {
+		return status.Errorf(codes.FailedPrecondition, "can't read controllers of %s: %v", path, err)
+	}
+	available := strings.Fields(string(b))
+	var enable []string
+	for _, c := range []string{"cpu", "memory", "pids", "io"} {
+		for _, a := range available {
+			if a == c {
+				enable = append(enable, "+"+c)
+			}
+		}
+	}
+	if len(enable) == 0 {
+		return nil
+	}
+	if err := os.WriteFile(filepath.Join(path, "cgroup.subtree_control"), []byte(strings.Join(enable, " ")), 0644); err != nil {
+		return fmt.Errorf("can't enable controllers in %s: %w", path, err)
+	}
+	return nil
+}
+
+// setLimits writes limits to the cgroup's interface files.
+func (c *cgroup) setLimits(limits *ResourceLimits) error {
+	if limits.CPUMillis != 0 {
+		// cpu.max is a quota per period, both in microseconds.
+		const period = 100000
+		if err := c.write("cpu.max", fmt.Sprintf("%d %d", limits.CPUMillis*period/1000, period)); err != nil {
+			return err
+		}
+	}
+	if limits.MemoryBytes != 0 {
+		if err := c.write("memory.max", strconv.FormatInt(limits.MemoryBytes, 10)); err != nil {
+			return err
+		}
+	}
+	if limits.Pids != 0 {
+		if err := c.write("pids.max", strconv.FormatInt(limits.Pids, 10)); err != nil {
+			return err
+		}
+	}
+	if limits.IOReadBPS != 0 || limits.IOWriteBPS != 0 {
+		return c.setIOLimits(limits)
+	}
+	return nil
+}
+
+// setIOLimits limits bandwidth to each block device. Devices which can't
+// be throttled, such as some virtual ones, are skipped but it is an error
+// if none can be.
+func (c *cgroup) setIOLimits(limits *ResourceLimits) error {
+	bps := func(n int64) string {
+		if n == 0 {
+			return "max"
+		}
+		return strconv.FormatInt(n, 10)
+	}
+	devs, err := filepath.Glob("/sys/block/*/dev")
+	if err != nil {
+		return status.Errorf(codes.Internal, "can't list block devices: %v", err)
+	}
+	var lastErr error
+	limited := 0
+	for _, d := range devs {
+		dev, err := os.ReadFile(d)
+		if err != nil {
+			continue
+		}
+		lastErr = c.write("io.max", fmt.Sprintf("%s rbps=%s wbps=%s", bytes.TrimSpace(dev), bps(limits.IOReadBPS), bps(limits.IOWriteBPS)))
+		if lastErr == nil {
+			limited++
+		}
+	}
+	if limited == 0 {
+		if lastErr == nil {
+			lastErr = status.Errorf(codes.FailedPrecondition, "no block devices found")
+		}
+		return lastErr
+	}
+	return nil
+}
+
+// write sets the cgroup interface file name to val.
+func (c *cgroup) write(name, val string) error {
+	if err := os.WriteFile(filepath.Join(c.path, name), []byte(val), 0644); err != nil {
+		return status.Errorf(codes.FailedPrecondition, "can't set %s to %q: %v", name, val, err)
+	}
+	return nil
+}
+
+// apply has cmd start in the cgroup.
+func (c *cgroup) apply(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(c.dir.Fd())
+}
+
+// usage returns the resources used by processes in the cgroup so far.
+// Anything the kernel doesn't report is left as zero.
+func (c *cgroup) usage() *ResourceUsage {
+	u := &ResourceUsage{}
+	if b, err := os.ReadFile(filepath.Join(c.path, "memory.peak")); err == nil {
+		u.PeakMemoryBytes, _ = strconv.ParseInt(string(bytes.TrimSpace(b)), 10, 64)
+	}
+	if f, err := os.Open(filepath.Join(c.path, "cpu.stat")); err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if usec, ok := strings.CutPrefix(scanner.Text(), "usage_usec "); ok {
+				n, _ := strconv.ParseInt(usec, 10, 64)
+				u.CPUTime = time.Duration(n) * time.Microsecond
+			}
+		}
+	}
+	return u
+}
+
+// remove kills anything left in the cgroup, such as background processes
+// the command started, and removes it.
+func (c *cgroup) remove() {
+	if c.dir != nil {
+		c.dir.Close()
+	}
+	// cgroup.kill needs Linux 5.14. Without it leftover processes keep the
+	// cgroup around until they exit.
+	_ = os.WriteFile(filepath.Join(c.path, "cgroup.kill"), []byte("1"), 0644)
+	// Killed processes take a moment to leave.
+	for i := 0; i < 10; i++ {
+		if err := os.Remove(c.path); err == nil || os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,162 @@
+//go:build linux
+// +build linux
+
+/* Copyright (c) 2025 Snowflake Inc. All rights reserved.
+
+   Licensed under the Apache License, Version 2.0 (the
+   "License"); you may not use this file except in compliance
+   with the License.  You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing,
+   software distributed under the License is distributed on an
+   "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+   KIND, either express or implied.  See the License for the
+   specific language governing permissions and limitations
+   under the License.
+*/
+
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Snowflake-Labs/sansshell/testing/testutil"
+)
+
+// fakeCgroupRoot points CgroupRoot at a directory laid out like a cgroup v2
+// hierarchy, with a "parent" cgroup beneath it.
+func fakeCgroupRoot(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, dir := range []string{root, filepath.Join(root, "parent")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cgroup.controllers"), []byte("cpuset cpu io memory pids\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldRoot, oldParent := CgroupRoot, CgroupParent
+	t.Cleanup(func() { CgroupRoot, CgroupParent = oldRoot, oldParent })
+	CgroupRoot, CgroupParent = root, "parent"
+	return root
+}
+
+func TestSetupCgroupParent(t *testing.T) {
+	root := fakeCgroupRoot(t)
+	parent, err := setupCgroupParent()
+	testutil.FatalOnErr("setupCgroupParent", err, t)
+	if want := filepath.Join(root, "parent"); parent != want {
+		t.Fatalf("parent = %q, want %q", parent, want)
+	}
+	b, err := os.ReadFile(filepath.Join(parent, "cgroup.subtree_control"))
+	testutil.FatalOnErr("reading subtree_control", err, t)
+	if got, want := string(b), "+cpu +memory +pids +io"; got != want {
+		t.Fatalf("subtree_control = %q, want %q", got, want)
+	}
+
+	CgroupRoot = t.TempDir()
+	_, err = setupCgroupParent()
+	if got, want := status.Code(err), codes.FailedPrecondition; got != want {
+		t.Fatalf("without cgroup v2: got code %v (%v), want %v", got, err, want)
+	}
+}
+
+func TestCgroupParentRetriesFailures(t *testing.T) {
+	root := fakeCgroupRoot(t)
+	t.Cleanup(func() { cgroupParentPath = "" })
+
+	// A failed setup isn't remembered, so fixing the problem lets a later
+	// command succeed.
+	CgroupRoot = t.TempDir()
+	if _, err := cgroupParent(); err == nil {
+		t.Fatal("cgroupParent without cgroup v2 didn't fail")
+	}
+	CgroupRoot = root
+	parent, err := cgroupParent()
+	testutil.FatalOnErr("cgroupParent", err, t)
+	if want := filepath.Join(root, "parent"); parent != want {
+		t.Fatalf("parent = %q, want %q", parent, want)
+	}
+}
+
+func TestMoveProcs(t *testing.T) {
+	from, to := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(from, "cgroup.procs"), []byte("1\n2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A real cgroup.procs takes one process per write.
+	testutil.FatalOnErr("moveProcs", moveProcs(from, to), t)
+	b, err := os.ReadFile(filepath.Join(to, "cgroup.procs"))
+	testutil.FatalOnErr("reading cgroup.procs", err, t)
+	if got, want := string(b), "2"; got != want {
+		t.Fatalf("last process moved = %q, want %q", got, want)
+	}
+}
+
+func TestCgroupLimitsAndUsage(t *testing.T) {
+	root := fakeCgroupRoot(t)
+	cg, err := createCgroup(filepath.Join(root, "parent"), &ResourceLimits{CPUMillis: 500, MemoryBytes: 1 << 20, Pids: 10})
+	testutil.FatalOnErr("createCgroup", err, t)
+	defer cg.dir.Close()
+	for file, want := range map[string]string{
+		"cpu.max":    "50000 100000",
+		"memory.max": "1048576",
+		"pids.max":   "10",
+	} {
+		b, err := os.ReadFile(filepath.Join(cg.path, file))
+		testutil.FatalOnErr("reading "+file, err, t)
+		if got := string(b); got != want {
+			t.Errorf("%s = %q, want %q", file, got, want)
+		}
+	}
+
+	for file, contents := range map[string]string{
+		"memory.peak": "4096\n",
+		"cpu.stat":    "usage_usec 1500000\nuser_usec 1000000\nsystem_usec 500000\n",
+	} {
+		if err := os.WriteFile(filepath.Join(cg.path, file), []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	u := cg.usage()
+	if got, want := *u, (ResourceUsage{PeakMemoryBytes: 4096, CPUTime: 1500 * time.Millisecond}); got != want {
+		t.Fatalf("usage = %+v, want %+v", got, want)
+	}
+}
+
+func TestResourceLimitsValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		limits  ResourceLimits
+		wantErr bool
+	}{
+		{
+			name:   "valid",
+			limits: ResourceLimits{CPUMillis: 10, MemoryBytes: 1, Pids: 1, IOReadBPS: 1, IOWriteBPS: 1},
+		},
+		{
+			name:    "negative",
+			limits:  ResourceLimits{MemoryBytes: -1},
+			wantErr: true,
+		},
+		{
+			name:    "CPU below minimum",
+			limits:  ResourceLimits{CPUMillis: 5},
+			wantErr: true,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			testutil.WantErr(tc.name, tc.limits.validate(), tc.wantErr, t)
+		})
+	}
+}
@@ -20,6 +20,7 @@ package util
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	writerUtils "github.com/Snowflake-Labs/sansshell/services/util/writer"
 	"golang.org/x/term"
@@ -29,7 +30,9 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/google/go-cmp/cmp"
@@ -61,6 +64,12 @@ type CommandRun struct {
 	Stderr   *LimitedBuffer
 	Error    error
 	ExitCode int
+	// TimedOut is true if the command was stopped because it ran longer
+	// than the CommandTimeout option allowed.
+	TimedOut bool
+	// Usage is the resources the command used, if it was run with the
+	// CommandLimits option.
+	Usage *ResourceUsage
 }
 
 // A builder pattern for Options so it's easy to add various ones (such as dropping permissions, etc).
@@ -72,6 +81,12 @@ type cmdOptions struct {
 	uid          uint32
 	gid          uint32
 	extraFiles   []*os.File
+	stdin        io.Reader
+	dir          string
+	timeout      time.Duration
+	killGrace    time.Duration
+	limits       *ResourceLimits
+	newSession   bool
 }
 
 // Option will run the apply operation to change required checking/state
@@ -174,6 +189,41 @@ func ExtraFiles(files []*os.File) Option {
 	})
 }
 
+// CommandStdin is an option which connects the sub-process's stdin to r.
+// By default the sub-process has no stdin.
+func CommandStdin(r io.Reader) Option {
+	return optionfunc(func(o *cmdOptions) {
+		o.stdin = r
+	})
+}
+
+// CommandDir is an option which sets the working directory of the
+// sub-process.
+func CommandDir(dir string) Option {
+	return optionfunc(func(o *cmdOptions) {
+		o.dir = dir
+	})
+}
+
+// CommandTimeout is an option which stops the sub-process if it runs longer
+// than timeout. It is sent SIGTERM and then, if it hasn't exited after
+// killGrace, SIGKILL. A killGrace of zero sends SIGKILL immediately.
+func CommandTimeout(timeout, killGrace time.Duration) Option {
+	return optionfunc(func(o *cmdOptions) {
+		o.timeout = timeout
+		o.killGrace = killGrace
+	})
+}
+
+// CommandNewSession is an option which starts the sub-process in a new
+// session with its stdin, which must be a terminal, as the controlling
+// terminal. This is what a shell on a PTY needs for job control.
+func CommandNewSession() Option {
+	return optionfunc(func(o *cmdOptions) {
+		o.newSession = true
+	})
+}
+
 // DefRunBufLimit is the default limit we'll buffer for stdout/stderr from RunCommand exec'ing
 // a process.
 const DefRunBufLimit = 10 * 1024 * 1024
@@ -249,35 +299,164 @@ func (l *LimitedBuffer) Truncated() bool {
 func RunCommand(ctx context.Context, bin string, args []string, opts ...Option) (*CommandRun, error) {
 	logger := logr.FromContextOrDiscard(ctx)
 
-	if !filepath.IsAbs(bin) {
-		return nil, status.Errorf(codes.InvalidArgument, "%s is not an absolute path", bin)
+	options := newCmdOptions(opts)
+	cmd, err := newCommand(ctx, bin, args, options)
+	if err != nil {
+		return nil, err
 	}
-	if bin != filepath.Clean(bin) {
-		return nil, status.Errorf(codes.InvalidArgument, "%s is not a clean path", bin)
+	defer cmd.release()
+	run := &CommandRun{
+		Stdout: NewLimitedBuffer(options.stdoutMax),
+		Stderr: NewLimitedBuffer(options.stderrMax),
+	}
+	// These probably should be streaming through a go-routine to rate limit what we
+	// can buffer. In practice output tends to be in the low K range size wise.
+	cmd.Stdout = run.Stdout
+	cmd.Stderr = run.Stderr
+	logger.Info("executing local command", "cmd", cmd.String())
+	run.Error = cmd.Run()
+	run.ExitCode = cmd.ProcessState.ExitCode()
+	run.TimedOut = cmd.TimedOut()
+	run.Usage = cmd.Usage()
+	// If this was an error it could be two different things. Just exiting non-zero results in an exec.ExitError
+	// and we can suppress that as exit code is enough. Otherwise we leave run.Error for callers to use.
+	// Stopping a command at its timeout isn't an error either, as TimedOut
+	// records it.
+	if run.Error != nil {
+		if _, ok := run.Error.(*exec.ExitError); ok || run.TimedOut {
+			run.Error = nil
+		}
+	}
+	if options.failOnStderr && len(run.Stderr.String()) != 0 {
+		return nil, status.Errorf(codes.Internal, "unexpected error output:\n%s", TrimString(run.Stderr.String()))
+	}
+	return run, nil
+}
+
+// StartCommand is like RunCommand except it doesn't wait for the command to
+// complete. Output is written to stdout and stderr as it is produced and the
+// caller must call Wait on the returned command. Options which limit or
+// check output don't apply. Use TimedOut on the returned command after Wait
+// to find out if it was stopped by the CommandTimeout option.
+func StartCommand(ctx context.Context, bin string, args []string, stdout, stderr io.Writer, opts ...Option) (*Command, error) {
+	logger := logr.FromContextOrDiscard(ctx)
+
+	cmd, err := newCommand(ctx, bin, args, newCmdOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	logger.Info("starting local command", "cmd", cmd.String())
+	if err := cmd.Start(); err != nil {
+		cmd.release()
+		return nil, status.Errorf(codes.Internal, "can't start %s: %v", bin, err)
+	}
+	return cmd, nil
+}
+
+// errTimedOut is the context cause set when a command reaches its timeout.
+var errTimedOut = errors.New("command timed out")
+
+// A Command is a command started by StartCommand.
+type Command struct {
+	*exec.Cmd
+	ctx    context.Context
+	cancel context.CancelFunc
+	cgroup *cgroup
+	usage  *ResourceUsage
+
+	releaseOnce sync.Once
+}
+
+// Run is exec.Cmd.Run, using Wait below.
+func (c *Command) Run() error {
+	if err := c.Start(); err != nil {
+		return err
 	}
+	return c.Wait()
+}
+
+// Wait is exec.Cmd.Wait, which also releases the command's timeout and
+// cgroup.
+func (c *Command) Wait() error {
+	defer c.release()
+	err := c.Cmd.Wait()
+	if c.cgroup != nil {
+		c.usage = c.cgroup.usage()
+	}
+	return err
+}
+
+// release frees everything held for the command once it's done.
+func (c *Command) release() {
+	c.releaseOnce.Do(func() {
+		c.cancel()
+		if c.cgroup != nil {
+			c.cgroup.remove()
+		}
+	})
+}
+
+// Usage returns the resources the command used, if it was run with the
+// CommandLimits option. It is only valid after Wait returns.
+func (c *Command) Usage() *ResourceUsage {
+	return c.usage
+}
+
+// TimedOut returns true if the command was stopped because it reached the
+// timeout set by the CommandTimeout option. It is only valid after Wait
+// returns.
+func (c *Command) TimedOut() bool {
+	return context.Cause(c.ctx) == errTimedOut
+}
 
-	euid := uint32(os.Geteuid())
-	gid := uint32(os.Getgid())
+// newCmdOptions applies opts over the defaults.
+func newCmdOptions(opts []Option) *cmdOptions {
 	options := &cmdOptions{
 		stdoutMax: DefRunBufLimit,
 		stderrMax: DefRunBufLimit,
-		uid:       euid,
-		gid:       gid,
+		uid:       uint32(os.Geteuid()),
+		gid:       uint32(os.Getgid()),
 	}
 	for _, opt := range opts {
 		opt.apply(options)
 	}
+	return options
+}
 
+// newCommand validates bin and sets up a command to run it with the
+// environment, credentials and other settings given in options. The
+// command's cancel function must be called once it is done.
+func newCommand(ctx context.Context, bin string, args []string, options *cmdOptions) (*Command, error) {
+	if !filepath.IsAbs(bin) {
+		return nil, status.Errorf(codes.InvalidArgument, "%s is not an absolute path", bin)
+	}
+	if bin != filepath.Clean(bin) {
+		return nil, status.Errorf(codes.InvalidArgument, "%s is not a clean path", bin)
+	}
+
+	var cg *cgroup
+	if options.limits != nil {
+		var err error
+		if cg, err = newCgroup(options.limits); err != nil {
+			return nil, err
+		}
+	}
+	cancel := context.CancelFunc(func() {})
+	if options.timeout > 0 {
+		ctx, cancel = context.WithTimeoutCause(ctx, options.timeout, errTimedOut)
+	}
 	cmd := exec.CommandContext(ctx, bin, args...)
-	run := &CommandRun{
-		Stdout: NewLimitedBuffer(options.stdoutMax),
-		Stderr: NewLimitedBuffer(options.stderrMax),
+	if options.timeout > 0 && options.killGrace > 0 {
+		// Give the command a chance to exit cleanly before it's killed.
+		cmd.Cancel = func() error {
+			return cmd.Process.Signal(syscall.SIGTERM)
+		}
+		cmd.WaitDelay = options.killGrace
 	}
-	// These probably should be streaming through a go-routine to rate limit what we
-	// can buffer. In practice output tends to be in the low K range size wise.
-	cmd.Stdout = run.Stdout
-	cmd.Stderr = run.Stderr
-	cmd.Stdin = nil
+	cmd.Stdin = options.stdin
+	cmd.Dir = options.dir
 	// Set to an empty slice to get an empty environment. Nil means inherit.
 	cmd.Env = []string{}
 	// Now append any we received.
@@ -287,27 +466,25 @@ func RunCommand(ctx context.Context, bin string, args []string, opts ...Option)
 	// Set uid/gid if needed for the sub-process to run under.
 	// Only do this if it's different than our current ones since
 	// attempting to setuid/gid() to even your current values is EPERM.
-	if options.uid != euid || options.gid != gid {
+	if options.uid != uint32(os.Geteuid()) || options.gid != uint32(os.Getgid()) {
 		cmd.SysProcAttr = &syscall.SysProcAttr{
 			Credential: &syscall.Credential{},
 		}
 		cmd.SysProcAttr.Credential.Uid = options.uid
 		cmd.SysProcAttr.Credential.Gid = options.gid
 	}
-	logger.Info("executing local command", "cmd", cmd.String())
-	run.Error = cmd.Run()
-	run.ExitCode = cmd.ProcessState.ExitCode()
-	// If this was an error it could be two different things. Just exiting non-zero results in an exec.ExitError
-	// and we can suppress that as exit code is enough. Otherwise we leave run.Error for callers to use.
-	if run.Error != nil {
-		if _, ok := run.Error.(*exec.ExitError); ok {
-			run.Error = nil
+	if options.newSession {
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
 		}
+		cmd.SysProcAttr.Setsid = true
+		cmd.SysProcAttr.Setctty = true
+		cmd.SysProcAttr.Ctty = 0
 	}
-	if options.failOnStderr && len(run.Stderr.String()) != 0 {
-		return nil, status.Errorf(codes.Internal, "unexpected error output:\n%s", TrimString(run.Stderr.String()))
+	if cg != nil {
+		cg.apply(cmd)
 	}
-	return run, nil
+	return &Command{Cmd: cmd, ctx: ctx, cancel: cancel, cgroup: cg}, nil
 }
 
 // MaxBuf is the maximum we should allow stdout or stderr to be when sending back in an error string.
@@ -20,10 +20,13 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Snowflake-Labs/sansshell/testing/testutil"
 	"golang.org/x/sys/unix"
@@ -466,3 +469,89 @@ func TestOptionsEqual(t *testing.T) {
 		}
 	}
 }
+
+func TestRunCommandInputAndTimeout(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		name         string
+		bin          string
+		args         []string
+		opts         []Option
+		stdout       string
+		wantTimedOut bool
+	}{
+		{
+			name:   "stdin",
+			bin:    testutil.ResolvePath(t, "cat"),
+			opts:   []Option{CommandStdin(strings.NewReader("hello"))},
+			stdout: "hello",
+		},
+		{
+			name:   "working directory",
+			bin:    testutil.ResolvePath(t, "pwd"),
+			opts:   []Option{CommandDir(dir)},
+			stdout: dir + "\n",
+		},
+		{
+			name:   "finishes before timeout",
+			bin:    testutil.ResolvePath(t, "echo"),
+			args:   []string{"done"},
+			opts:   []Option{CommandTimeout(time.Minute, time.Second)},
+			stdout: "done\n",
+		},
+		{
+			name:         "timeout",
+			bin:          testutil.ResolvePath(t, "sleep"),
+			args:         []string{"60"},
+			opts:         []Option{CommandTimeout(100*time.Millisecond, time.Second)},
+			wantTimedOut: true,
+		},
+		{
+			name:         "timeout ignoring SIGTERM is killed after grace",
+			bin:          testutil.ResolvePath(t, "sh"),
+			args:         []string{"-c", "trap '' TERM; echo started; exec sleep 60"},
+			opts:         []Option{CommandTimeout(500*time.Millisecond, 100*time.Millisecond)},
+			stdout:       "started\n",
+			wantTimedOut: true,
+		},
+		{
+			name:         "timeout without grace",
+			bin:          testutil.ResolvePath(t, "sleep"),
+			args:         []string{"60"},
+			opts:         []Option{CommandTimeout(100*time.Millisecond, 0)},
+			wantTimedOut: true,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			start := time.Now()
+			run, err := RunCommand(context.Background(), tc.bin, tc.args, tc.opts...)
+			testutil.FatalOnErr(tc.name, err, t)
+			testutil.FatalOnErr(tc.name, run.Error, t)
+			if got := time.Since(start); got > 30*time.Second {
+				t.Fatalf("%s: command took %v, timeout wasn't enforced", tc.name, got)
+			}
+			if got, want := run.Stdout.String(), tc.stdout; got != want {
+				t.Fatalf("%s: Stdout differs. Want %q Got %q", tc.name, want, got)
+			}
+			if got, want := run.TimedOut, tc.wantTimedOut; got != want {
+				t.Fatalf("%s: TimedOut = %v, want %v", tc.name, got, want)
+			}
+			if tc.wantTimedOut && run.ExitCode == 0 {
+				t.Fatalf("%s: timed out command exited 0", tc.name)
+			}
+		})
+	}
+}
+
+func TestStartCommandTimeout(t *testing.T) {
+	var stdout bytes.Buffer
+	cmd, err := StartCommand(context.Background(), testutil.ResolvePath(t, "sleep"), []string{"60"}, &stdout, io.Discard, CommandTimeout(100*time.Millisecond, time.Second))
+	testutil.FatalOnErr("StartCommand", err, t)
+	if err := cmd.Wait(); err == nil {
+		t.Fatal("Wait didn't return an error for a killed command")
+	}
+	if !cmd.TimedOut() {
+		t.Fatal("TimedOut() = false, want true")
+	}
+}